@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// batchJob is one input/output pair of a batch run. name identifies it in logs.
+type batchJob struct {
+	name, input, output string
+}
+
+// batch simplifies every URDF under inDir into the same relative location
+// under outDir.
+func (r *simplifyRun) batch(inDir, outDir string) int {
+	inputs, err := findURDFs(inDir, outDir)
+	if err != nil {
+		r.logger.Error("scanning input directory", "dir", inDir, "error", err)
+		return exitFailure
+	}
+	if len(inputs) == 0 {
+		r.logger.Error("no .urdf files found", "dir", inDir)
+		return exitFailure
+	}
+
+	var jobs []batchJob
+	for _, rel := range inputs {
+		jobs = append(jobs, batchJob{
+			name:   filepath.ToSlash(rel),
+			input:  filepath.Join(inDir, rel),
+			output: filepath.Join(outDir, rel),
+		})
+	}
+	return r.runJobs(jobs)
+}
+
+// templated simplifies every file matching patterns, naming each output by
+// expanding template against the input path.
+func (r *simplifyRun) templated(patterns []string, template string) int {
+	var jobs []batchJob
+	seen := make(map[string]string)
+	for _, pattern := range patterns {
+		inputs, err := expandGlob(pattern)
+		if err != nil {
+			r.logger.Error(err.Error())
+			return exitUsage
+		}
+		if len(inputs) == 0 {
+			r.logger.Warn("pattern matched no files", "pattern", pattern)
+		}
+		for _, input := range inputs {
+			output := expandOutputTemplate(template, input)
+			if filepath.Clean(output) == filepath.Clean(input) {
+				r.logger.Error("output template maps a file onto itself", "file", input, "template", template)
+				return exitUsage
+			}
+			if other, ok := seen[output]; ok {
+				if other == input {
+					continue
+				}
+				r.logger.Error("output template maps two inputs to the same file", "output", output, "inputs", other+", "+input)
+				return exitUsage
+			}
+			seen[output] = input
+			jobs = append(jobs, batchJob{name: filepath.ToSlash(input), input: input, output: output})
+		}
+	}
+	if len(jobs) == 0 {
+		r.logger.Error("no input files matched")
+		return exitFailure
+	}
+	return r.runJobs(jobs)
+}
+
+// runJobs simplifies each job in order. Failures are reported and counted but
+// do not stop the run, which exits with the highest exit code of any failed file.
+func (r *simplifyRun) runJobs(jobs []batchJob) int {
+	var failed []string
+	code := exitOK
+	for _, job := range jobs {
+		logger := r.logger.With("file", job.name)
+
+		if !r.dryRun && !r.check {
+			if err := os.MkdirAll(filepath.Dir(job.output), 0755); err != nil {
+				logger.Error("creating output directory", "error", err)
+				failed = append(failed, job.name)
+				code = max(code, exitFailure)
+				continue
+			}
+		}
+		if err := r.file(job.input, job.output, logger); err != nil {
+			logger.Error(err.Error())
+			failed = append(failed, job.name)
+			code = max(code, exitCode(err))
+		}
+	}
+
+	r.printBatchSummary(len(jobs), failed)
+	return code
+}
+
+func (r *simplifyRun) printBatchSummary(total int, failed []string) {
+	if r.lf.quiet && len(failed) == 0 {
+		return
+	}
+	if r.lf.format == "json" {
+		r.logger.Info("batch summary", "files", total, "succeeded", total-len(failed), "failed", failed)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\nProcessed %d file(s): %d succeeded, %d failed\n", total, total-len(failed), len(failed))
+	for _, f := range failed {
+		fmt.Fprintf(os.Stderr, "  failed: %s\n", f)
+	}
+}
+
+// findURDFs returns the paths of the .urdf files under dir, relative to dir
+// and in lexical order. outDir is skipped when it sits inside dir so that a
+// previous run's results are not picked up as inputs.
+func findURDFs(dir, outDir string) ([]string, error) {
+	absOut, err := filepath.Abs(outDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []string
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if abs, err := filepath.Abs(path); err == nil && abs == absOut && path != dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.EqualFold(filepath.Ext(path), ".urdf") {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			found = append(found, rel)
+		}
+		return nil
+	})
+	return found, err
+}
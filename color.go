@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+
+	"golang.org/x/term"
+)
+
+// palette applies ANSI colors to terminal output. The zero value leaves text
+// unchanged.
+type palette struct {
+	enabled bool
+}
+
+// newPalette enables colors when f is a terminal, unless noColor is set or the
+// environment asks for plain output (NO_COLOR, or TERM=dumb).
+func newPalette(f *os.File, noColor bool) palette {
+	if noColor || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return palette{}
+	}
+	return palette{enabled: term.IsTerminal(int(f.Fd()))}
+}
+
+func (p palette) paint(code, s string) string {
+	if !p.enabled {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+
+func (p palette) bold(s string) string   { return p.paint("1", s) }
+func (p palette) red(s string) string    { return p.paint("31", s) }
+func (p palette) green(s string) string  { return p.paint("32", s) }
+func (p palette) yellow(s string) string { return p.paint("33", s) }
@@ -0,0 +1,428 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nfranczak/urdf-simplifier/urdf"
+)
+
+// config is the schema of a --config YAML file. Simplification options sit at
+// the top level so that a recipe reads the same as the equivalent flags:
+//
+//	preset: motion-planning
+//	xacro: builtin
+//	xacro_args:
+//	  ur_type: ur20
+//	geometry: box
+//	chain: main
+//	keep_links: [tool0]
+//	keep_visuals: false
+//	links:
+//	  wrist_3_link:
+//	    geometry: mesh
+//	lint:
+//	  single-root: error
+//	  limit-velocity: off
+type config struct {
+	// Preset names the built-in option set the rest of the file is applied on top of.
+	Preset string `yaml:"preset,omitempty"`
+	// PackageMap maps ROS package names to directories. Relative directories
+	// are relative to the config file.
+	PackageMap map[string]string `yaml:"package_map,omitempty"`
+	// Xacro says how xacro input is expanded: builtin, external, or off.
+	Xacro string `yaml:"xacro,omitempty"`
+	// XacroArgs sets arguments of xacro input.
+	XacroArgs map[string]string `yaml:"xacro_args,omitempty"`
+	// Lint sets the severity of validate's rules by name: error, warn, or
+	// off.
+	Lint         map[string]string `yaml:"lint,omitempty"`
+	urdf.Options `yaml:",inline"`
+}
+
+// loadConfig reads and validates the YAML config file at path. Unknown keys
+// are rejected so that typos don't silently fall back to defaults. A
+// non-empty preset replaces the one named in the file.
+func loadConfig(path, preset string) (*config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	defer f.Close()
+
+	cfg, err := decodeConfig(f, preset)
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	for pkg, dir := range cfg.PackageMap {
+		if !filepath.IsAbs(dir) {
+			cfg.PackageMap[pkg] = filepath.Join(filepath.Dir(path), dir)
+		}
+	}
+	return cfg, nil
+}
+
+// decodeConfig parses and validates a YAML config document, layered over
+// its preset: the one given, or else the one the document names. An empty
+// document yields the preset's options, or the defaults.
+func decodeConfig(r io.Reader, preset string) (*config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// The preset has to be known before the rest of the document is decoded
+	// over it.
+	var header struct {
+		Preset string `yaml:"preset"`
+	}
+	if err := yaml.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+	if preset == "" {
+		preset = header.Preset
+	}
+
+	cfg := config{Preset: preset}
+	if preset != "" {
+		if cfg.Options, err = urdf.Preset(preset); err != nil {
+			return nil, err
+		}
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	cfg.Preset = preset
+	if err := cfg.Options.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := cfg.lintPolicy(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// lintPolicy returns the rule severities set by the config's lint section.
+func (c *config) lintPolicy() (urdf.Policy, error) {
+	policy := make(urdf.Policy)
+	for _, name := range slices.Sorted(maps.Keys(c.Lint)) {
+		s, err := urdf.ParseSeverity(c.Lint[name])
+		if err != nil {
+			return nil, fmt.Errorf("lint rule %s: %w", name, err)
+		}
+		policy[name] = s
+	}
+	return policy, policy.Check()
+}
+
+// simplifyFlags holds the simplification options that can be given on the
+// command line, where they override the values from a config file.
+type simplifyFlags struct {
+	configPath    string
+	preset        string
+	name          string
+	geometryOnly  bool
+	baseTransform string
+	geometry      string
+	chain         string
+	keepLinks     []string
+	dropLinks     []string
+	keepTipFrames bool
+	keepVisuals   bool
+	visualFrom    bool
+	visualColor   string
+	visualPalette bool
+	keepInertials bool
+	recompute     string
+	density       float64
+	volumeRatio   float64
+	capsuleRatio  float64
+	massScale     float64
+	lumpMass      bool
+	fixInertia    bool
+	ensureMass    float64
+	payload       string
+	noCollide     bool
+	noCleanup     bool
+	snap          float64
+	canonical     bool
+	linkNames     string
+	sweep         bool
+	workspace     int
+	effort        float64
+	velocity      float64
+	payloadLink   string
+	dynamics      string
+	packageMap    []string
+	xacro         xacroFlags
+	jobs          int
+	cacheDir      string
+}
+
+func (f *simplifyFlags) register(fs *flagSet) {
+	fs.StringVar(&f.configPath, "", "config", "", "YAML file with simplification options")
+	fs.StringVar(&f.preset, "p", "preset", "", "start from a built-in option set: "+strings.Join(urdf.PresetNames(), ", "))
+	fs.StringVar(&f.name, "", "name", "", "name the simplified robot this (default: the original name with _simplified appended)")
+	fs.StringVar(&f.baseTransform, "", "base-transform", "", "move the root link frame by this pose, \"x y z roll pitch yaw\" in meters and radians, such as a work cell's frame")
+	fs.StringVar(&f.geometry, "g", "geometry", "", "collision geometry mode: box, capsule, mesh, or none (default box)")
+	fs.BoolVar(&f.geometryOnly, "", "geometry-only", false, "only replace collision meshes, keeping every link, joint, visual and inertial as it is")
+	fs.StringVar(&f.chain, "", "chain", "", "links to keep: main (actuated chain) or all (default main)")
+	fs.StringsVar(&f.keepLinks, "", "keep-link", "keep this link and the joints attaching it to the chain")
+	fs.StringsVar(&f.dropLinks, "", "drop-link", "remove this link and everything below it")
+	fs.BoolVar(&f.keepTipFrames, "", "keep-tip-frames", false, "keep frames attached by fixed joints below the chain, such as tool0")
+	fs.BoolVar(&f.keepVisuals, "", "keep-visuals", false, "keep <visual> elements")
+	fs.BoolVar(&f.visualFrom, "", "visual-from-collision", false, "replace <visual> elements with copies of the simplified collision geometry, for viewing in RViz or Foxglove")
+	fs.BoolVar(&f.visualPalette, "", "visual-palette", false, "color each link's --visual-from-collision visuals differently, from a palette of ten, at the alpha of --visual-color")
+	fs.StringVar(&f.visualColor, "", "visual-color", "", "color of --visual-from-collision visuals as \"R G B A\", each from 0 to 1 (default \""+urdf.DefaultVisualColor+"\")")
+	fs.BoolVar(&f.keepInertials, "", "keep-inertials", false, "keep <inertial> elements")
+	fs.StringVar(&f.recompute, "", "recompute-inertia", "", "replace kept inertia tensors with those of the links' collision geometry: box or mesh")
+	fs.Float64Var(&f.density, "", "density", 0, "density in kg/m³ for links without a mass, with --recompute-inertia mesh (default 1000)")
+	fs.Float64Var(&f.volumeRatio, "", "max-volume-ratio", 0, "warn about boxes with more than this many times the volume of their mesh (default 3)")
+	fs.Float64Var(&f.capsuleRatio, "", "max-capsule-ratio", 0, "with --geometry capsule, use the box for meshes whose capsule has more than this many times the box's volume (default 1.5)")
+	fs.StringVar(&f.dynamics, "", "dynamics", "", "what to do with joint <dynamics> damping and friction: keep, zero, or remove (default keep)")
+	fs.Float64Var(&f.effort, "", "default-effort", 0, "effort limit for joints whose <limit> leaves it out")
+	fs.Float64Var(&f.velocity, "", "default-velocity", 0, "velocity limit for joints whose <limit> leaves it out")
+	fs.BoolVar(&f.canonical, "", "canonical-names", false, "rename moving joints joint_1..joint_n from base to tip, and links as --link-names says (default numbered)")
+	fs.StringVar(&f.linkNames, "", "link-names", "", "rename links base to tip: numbered (link_0..link_n) or standard (base_link, link_1..link_n, tool)")
+	fs.BoolVar(&f.noCleanup, "", "no-cleanup", false, "keep attributes and elements that only restate the defaults, such as rpy=\"0 0 0\", and rpy angles as written")
+	fs.OptionalFloat64Var(&f.snap, "", "snap", 1e-6, "snap joint axes and origin values within this tolerance of round ones, such as 0.3000001 to 0.3 (1e-6 if no value is given)")
+	fs.BoolVar(&f.noCollide, "", "no-collision-check", false, "do not warn about collision boxes that overlap at the zero pose")
+	fs.BoolVar(&f.sweep, "", "sweep", false, "also warn about collision boxes that overlap as a joint moves through its limits")
+	fs.IntVar(&f.workspace, "", "workspace", 0, "sample this many joint configurations to report the end link's reach and check it against the original robot's")
+	fs.StringVar(&f.payload, "", "payload", "", "add a point mass to the end link, given as MASSkg@X,Y,Z in its frame, with --keep-inertials")
+	fs.StringVar(&f.payloadLink, "", "payload-link", "", "the link carrying --payload (default: the end of the longest chain)")
+	fs.OptionalFloat64Var(&f.ensureMass, "", "ensure-inertials", 0.001, "give links without an inertial a placeholder of this mass in kg (0.001 if no value is given)")
+	fs.BoolVar(&f.fixInertia, "", "fix-inertia", false, "replace invalid kept inertia tensors with the nearest valid ones")
+	fs.BoolVar(&f.lumpMass, "", "lump-mass", false, "add the mass of removed links into the kept links they are fixed to, with --keep-inertials")
+	fs.Float64Var(&f.massScale, "", "mass-scale", 0, "multiply every kept mass and inertia tensor by this factor, before any recomputation")
+	registerPackageMap(fs, &f.packageMap)
+	f.xacro.register(fs)
+	fs.IntVar(&f.jobs, "j", "jobs", 0, "process up to this many meshes and links at once (default: number of CPUs)")
+	fs.StringVar(&f.cacheDir, "", "cache-dir", "", "keep mesh bounds in this directory between runs")
+}
+
+func registerPackageMap(fs *flagSet, p *[]string) {
+	fs.StringsVar(p, "", "package-map", "resolve package://NAME/ URIs under DIR, given as NAME=DIR")
+}
+
+// parsePackageMap parses the NAME=DIR pairs given with --package-map.
+func parsePackageMap(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	packages := make(map[string]string)
+	for _, pair := range pairs {
+		name, dir, ok := strings.Cut(pair, "=")
+		if !ok || name == "" || dir == "" {
+			return nil, fmt.Errorf("invalid --package-map %q (want NAME=DIR)", pair)
+		}
+		packages[name] = dir
+	}
+	return packages, nil
+}
+
+// parsePayload parses a --payload value: a mass in kg, optionally suffixed
+// with "kg", then optionally "@" and the X,Y,Z offset in the link frame.
+func parsePayload(s string) (*urdf.Payload, error) {
+	invalid := fmt.Errorf("invalid --payload %q (want MASSkg@X,Y,Z, such as 2.5kg@0,0,0.1)", s)
+	massText, offsetText, hasOffset := strings.Cut(s, "@")
+	mass, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(massText), "kg"), 64)
+	if err != nil {
+		return nil, invalid
+	}
+	payload := &urdf.Payload{Mass: mass}
+	if hasOffset {
+		parts := strings.Split(offsetText, ",")
+		if len(parts) != 3 {
+			return nil, invalid
+		}
+		for i, part := range parts {
+			if payload.Offset[i], err = strconv.ParseFloat(strings.TrimSpace(part), 64); err != nil {
+				return nil, invalid
+			}
+		}
+	}
+	return payload, nil
+}
+
+// load layers the preset, the config file, and the flags that were set, in
+// that order.
+func (f *simplifyFlags) load(fs *flagSet) (*config, error) {
+	cfg := &config{Preset: f.preset}
+	if f.configPath != "" {
+		var err error
+		if cfg, err = loadConfig(f.configPath, f.preset); err != nil {
+			return nil, err
+		}
+	} else if f.preset != "" {
+		preset, err := urdf.Preset(f.preset)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Options = preset
+	}
+
+	opts := &cfg.Options
+	if fs.isSet("name") {
+		opts.Name = f.name
+	}
+	if fs.isSet("base-transform") {
+		opts.BaseTransform = f.baseTransform
+	}
+	if fs.isSet("geometry", "g") {
+		opts.Geometry = urdf.GeometryMode(f.geometry)
+	}
+	if fs.isSet("geometry-only") {
+		opts.GeometryOnly = f.geometryOnly
+	}
+	if fs.isSet("chain") {
+		opts.Chain = urdf.ChainMode(f.chain)
+	}
+	opts.KeepLinks = append(opts.KeepLinks, f.keepLinks...)
+	opts.DropLinks = append(opts.DropLinks, f.dropLinks...)
+	if fs.isSet("keep-tip-frames") {
+		opts.KeepTipFrames = f.keepTipFrames
+	}
+	if fs.isSet("keep-visuals") {
+		opts.KeepVisuals = f.keepVisuals
+	}
+	if fs.isSet("visual-from-collision") {
+		opts.VisualFromCollision = f.visualFrom
+	}
+	if fs.isSet("visual-palette") {
+		opts.VisualPalette = f.visualPalette
+	}
+	if fs.isSet("visual-color") {
+		opts.VisualColor = f.visualColor
+	}
+	if fs.isSet("keep-inertials") {
+		opts.KeepInertials = f.keepInertials
+	}
+	if fs.isSet("recompute-inertia") {
+		opts.RecomputeInertia = urdf.InertiaSource(f.recompute)
+	}
+	if fs.isSet("density") {
+		opts.Density = f.density
+	}
+	if fs.isSet("max-volume-ratio") {
+		opts.MaxVolumeRatio = f.volumeRatio
+	}
+	if fs.isSet("max-capsule-ratio") {
+		opts.MaxCapsuleRatio = f.capsuleRatio
+	}
+	if fs.isSet("mass-scale") {
+		opts.MassScale = f.massScale
+	}
+	if fs.isSet("ensure-inertials") {
+		opts.EnsureInertials = f.ensureMass
+	}
+	if fs.isSet("default-effort") {
+		opts.DefaultEffort = f.effort
+	}
+	if fs.isSet("default-velocity") {
+		opts.DefaultVelocity = f.velocity
+	}
+	if fs.isSet("canonical-names") {
+		opts.CanonicalNames = f.canonical
+	}
+	if fs.isSet("link-names") {
+		opts.LinkNames = urdf.LinkNaming(f.linkNames)
+	}
+	if fs.isSet("no-cleanup") {
+		opts.SkipCleanup = f.noCleanup
+	}
+	if fs.isSet("snap") {
+		opts.SnapTolerance = f.snap
+	}
+	if fs.isSet("no-collision-check") {
+		opts.SkipCollisionCheck = f.noCollide
+	}
+	if fs.isSet("sweep") {
+		opts.SweepJoints = f.sweep
+	}
+	if fs.isSet("workspace") {
+		opts.WorkspaceSamples = f.workspace
+	}
+	if fs.isSet("payload") {
+		payload, err := parsePayload(f.payload)
+		if err != nil {
+			return nil, err
+		}
+		opts.Payload = payload
+	}
+	if fs.isSet("payload-link") {
+		if opts.Payload == nil {
+			return nil, fmt.Errorf("--payload-link needs a payload (--payload or payload in the config file)")
+		}
+		opts.Payload.Link = f.payloadLink
+	}
+	if fs.isSet("fix-inertia") {
+		opts.FixInertia = f.fixInertia
+	}
+	if fs.isSet("lump-mass") {
+		opts.LumpMass = f.lumpMass
+	}
+	if fs.isSet("dynamics") {
+		opts.Dynamics = urdf.DynamicsMode(f.dynamics)
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if f.jobs < 0 {
+		return nil, fmt.Errorf("invalid --jobs %d (want a positive number)", f.jobs)
+	}
+	opts.Jobs = f.jobs
+	if opts.Jobs == 0 {
+		opts.Jobs = runtime.NumCPU()
+	}
+	opts.CacheDir = f.cacheDir
+
+	packages, err := parsePackageMap(f.packageMap)
+	if err != nil {
+		return nil, err
+	}
+	for name, dir := range packages {
+		if cfg.PackageMap == nil {
+			cfg.PackageMap = make(map[string]string)
+		}
+		cfg.PackageMap[name] = dir
+	}
+
+	if fs.isSet("xacro") {
+		cfg.Xacro = f.xacro.mode
+	}
+	if _, err := parseXacroMode(cfg.Xacro); err != nil {
+		return nil, err
+	}
+	args, err := parseXacroArgs(f.xacro.args)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range args {
+		if cfg.XacroArgs == nil {
+			cfg.XacroArgs = make(map[string]string)
+		}
+		cfg.XacroArgs[name] = value
+	}
+	return cfg, nil
+}
+
+// xacroInput returns the xacro settings of a config whose mode load has
+// validated.
+func (c *config) xacroInput() xacroInput {
+	mode, _ := parseXacroMode(c.Xacro)
+	return xacroInput{mode: mode, args: c.XacroArgs}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nfranczak/urdf-simplifier/urdf"
+)
+
+func runConvert(args []string) int {
+	var maps, packageMap []string
+	var xf xacroFlags
+	var absolute, keepPackages bool
+	fs := newFlagSet("convert", "urdf-simplifier convert [flags] <input.urdf> <output.urdf>",
+		"Rewrites mesh URIs, leaving all geometry and kinematics untouched. URI\n"+
+			"prefixes given with --map are replaced first; then package://, model:// and\n"+
+			"file:// URIs and relative paths are rewritten as paths relative to the\n"+
+			"output file.")
+	fs.StringsVar(&maps, "m", "map", "replace the URI prefix FROM with TO, given as FROM=TO")
+	fs.BoolVar(&absolute, "", "absolute", false, "write absolute paths instead of paths relative to the output file")
+	fs.BoolVar(&keepPackages, "", "keep-packages", false, "leave package:// and model:// URIs as they are after applying --map")
+	registerPackageMap(fs, &packageMap)
+	xf.register(fs)
+	positional := fs.parseOrExit(args)
+	if len(positional) != 2 {
+		fs.printUsage(os.Stderr)
+		return exitUsage
+	}
+	inputPath, outputPath := positional[0], positional[1]
+
+	prefixes, err := parsePrefixMaps(maps)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+
+	packages, err := parsePackageMap(packageMap)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	xacroIn, err := xf.parse()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+
+	robot, err := loadRobot(inputPath, packages, xacroIn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitCode(err)
+	}
+
+	resolver := meshResolver(inputPath, packages)
+	outDir, err := filepath.Abs(inputDir(outputPath))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+
+	rewritten := 0
+	for _, m := range robot.MeshRefs() {
+		uri, err := pathURI(prefixes.apply(m.Filename), resolver, outDir, absolute, keepPackages)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		if uri == m.Filename {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "%s -> %s\n", m.Filename, uri)
+		m.Filename = uri
+		rewritten++
+	}
+
+	if err := writeRobot(outputPath, robot); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	fmt.Fprintf(os.Stderr, "Rewrote %d mesh URI(s): %s -> %s\n", rewritten, displayPath(inputPath), displayOutputPath(outputPath))
+	return exitOK
+}
+
+// pathURI rewrites a package://, model:// or file:// URI or a file path as a
+// path relative to outDir, or as an absolute path. URIs with other schemes
+// are returned unchanged, as are package:// and model:// URIs if keepPackages
+// is set. On error uri is returned as is.
+func pathURI(uri string, resolver urdf.FileResolver, outDir string, absolute, keepPackages bool) (string, error) {
+	isPackage := strings.HasPrefix(uri, "package://") || strings.HasPrefix(uri, "model://")
+	isFile := strings.HasPrefix(uri, "file://")
+	if isPackage && keepPackages || !isPackage && !isFile && strings.Contains(uri, "://") {
+		return uri, nil
+	}
+	resolved, err := filepath.Abs(resolver.Resolve(uri))
+	if err != nil {
+		return uri, fmt.Errorf("could not resolve %s: %w", uri, err)
+	}
+	if absolute {
+		return filepath.ToSlash(resolved), nil
+	}
+	rel, err := filepath.Rel(outDir, resolved)
+	if err != nil {
+		return uri, fmt.Errorf("could not make %s relative to %s: %w", resolved, outDir, err)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// prefixMap replaces URI prefixes, longest match first.
+type prefixMap []struct{ from, to string }
+
+// parsePrefixMaps parses FROM=TO pairs from the command line.
+func parsePrefixMaps(pairs []string) (prefixMap, error) {
+	var m prefixMap
+	for _, pair := range pairs {
+		from, to, ok := strings.Cut(pair, "=")
+		if !ok || from == "" {
+			return nil, fmt.Errorf("invalid --map %q (want FROM=TO)", pair)
+		}
+		m = append(m, struct{ from, to string }{from, to})
+	}
+	sort.SliceStable(m, func(i, j int) bool { return len(m[i].from) > len(m[j].from) })
+	return m, nil
+}
+
+func (m prefixMap) apply(uri string) string {
+	for _, p := range m {
+		if rest, ok := strings.CutPrefix(uri, p.from); ok {
+			return p.to + rest
+		}
+	}
+	return uri
+}
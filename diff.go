@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/nfranczak/urdf-simplifier/urdf"
+)
+
+func runDiff(args []string) int {
+	var tolerance float64
+	fs := newFlagSet("diff", "urdf-simplifier diff [flags] <a.urdf> <b.urdf>",
+		"Compares two URDFs link by link and joint by joint: joint origins, axes,\n"+
+			"limits and dynamics, link geometry types and sizes, and masses. Numbers\n"+
+			"are equal if they are within the tolerance. Exits 1 if they differ.")
+	fs.Float64Var(&tolerance, "t", "tolerance", urdf.DefaultTolerance, "largest numeric difference treated as equal (meters, radians, or the value's unit)")
+	positional := fs.parseOrExit(args)
+	if len(positional) != 2 {
+		fs.printUsage(os.Stderr)
+		return exitUsage
+	}
+
+	a, err := loadRobot(positional[0], nil, xacroInput{mode: xacroBuiltin})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitCode(err)
+	}
+	b, err := loadRobot(positional[1], nil, xacroInput{mode: xacroBuiltin})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitCode(err)
+	}
+
+	opts := urdf.DiffOptions{Tolerance: tolerance}
+	if tolerance == 0 {
+		// On the command line zero means exact, not the library default.
+		opts.Tolerance = -1
+	}
+	diffs := urdf.Diff(a, b, opts)
+	for _, d := range diffs {
+		fmt.Println(d)
+	}
+	if len(diffs) > 0 {
+		fmt.Printf("%d difference(s)\n", len(diffs))
+		return exitFailure
+	}
+	fmt.Println("No differences")
+	return exitOK
+}
@@ -0,0 +1,37 @@
+package main
+
+import "errors"
+
+// Exit codes shared by every command, so that scripts can branch on the kind
+// of failure.
+const (
+	exitOK      = 0 // success
+	exitFailure = 1 // any other failure: I/O errors, stale --check output, differences found by diff
+	exitUsage   = 2 // invalid flags, arguments, or config file
+	exitParse   = 3 // an input URDF could not be parsed
+	exitMesh    = 4 // a collision mesh could not be read (simplify --strict)
+	exitInvalid = 5 // validate found errors, the simplified robot is invalid, or any warning under --strict
+)
+
+// exitError attaches an exit code to an error.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+func withExitCode(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+// exitCode returns the exit code attached to err with withExitCode, or
+// exitFailure if there is none.
+func exitCode(err error) int {
+	var e *exitError
+	if errors.As(err, &e) {
+		return e.code
+	}
+	return exitFailure
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// flagSet wraps flag.FlagSet with paired short/long flag names, flags mixed
+// freely with positional arguments, and grouped help output.
+type flagSet struct {
+	*flag.FlagSet
+	usageLine   string
+	description string
+	entries     []flagEntry
+	// optional holds the names of flags that may be given without a value.
+	optional map[string]bool
+}
+
+type flagEntry struct {
+	short, long string
+	kind        string
+	usage       string
+	value       string
+}
+
+func newFlagSet(name, usageLine, description string) *flagSet {
+	fs := &flagSet{
+		FlagSet:     flag.NewFlagSet(name, flag.ContinueOnError),
+		usageLine:   usageLine,
+		description: description,
+	}
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// StringVar defines a string flag reachable as -short and --long. Either name may be empty.
+func (fs *flagSet) StringVar(p *string, short, long, value, usage string) {
+	for _, name := range []string{short, long} {
+		if name != "" {
+			fs.FlagSet.StringVar(p, name, value, usage)
+		}
+	}
+	fs.entries = append(fs.entries, flagEntry{short, long, "string", usage, value})
+}
+
+// BoolVar defines a boolean flag reachable as -short and --long. Either name may be empty.
+func (fs *flagSet) BoolVar(p *bool, short, long string, value bool, usage string) {
+	for _, name := range []string{short, long} {
+		if name != "" {
+			fs.FlagSet.BoolVar(p, name, value, usage)
+		}
+	}
+	fs.entries = append(fs.entries, flagEntry{short, long, "", usage, ""})
+}
+
+// IntVar defines an integer flag reachable as -short and --long. Either name may be empty.
+func (fs *flagSet) IntVar(p *int, short, long string, value int, usage string) {
+	for _, name := range []string{short, long} {
+		if name != "" {
+			fs.FlagSet.IntVar(p, name, value, usage)
+		}
+	}
+	def := ""
+	if value != 0 {
+		def = fmt.Sprint(value)
+	}
+	fs.entries = append(fs.entries, flagEntry{short, long, "int", usage, def})
+}
+
+// Float64Var defines a float flag reachable as -short and --long. Either name may be empty.
+func (fs *flagSet) Float64Var(p *float64, short, long string, value float64, usage string) {
+	for _, name := range []string{short, long} {
+		if name != "" {
+			fs.FlagSet.Float64Var(p, name, value, usage)
+		}
+	}
+	def := ""
+	if value != 0 {
+		def = fmt.Sprint(value)
+	}
+	fs.entries = append(fs.entries, flagEntry{short, long, "float", usage, def})
+}
+
+// optionalFloatValue is a float flag that can also be given without a value,
+// as --name, to mean implied, or turned off with --name=false. parse rewrites
+// a bare --name to --name=true.
+type optionalFloatValue struct {
+	p       *float64
+	implied float64
+}
+
+func (v optionalFloatValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return fmt.Sprint(*v.p)
+}
+
+func (v optionalFloatValue) Set(s string) error {
+	switch s {
+	case "true":
+		*v.p = v.implied
+	case "false":
+		*v.p = 0
+	default:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return errors.New("parse error")
+		}
+		*v.p = f
+	}
+	return nil
+}
+
+// OptionalFloat64Var defines a float flag reachable as -short and --long that
+// sets *p to implied when given without a value. Either name may be empty.
+func (fs *flagSet) OptionalFloat64Var(p *float64, short, long string, implied float64, usage string) {
+	for _, name := range []string{short, long} {
+		if name != "" {
+			fs.FlagSet.Var(optionalFloatValue{p, implied}, name, usage)
+			if fs.optional == nil {
+				fs.optional = make(map[string]bool)
+			}
+			fs.optional[name] = true
+		}
+	}
+	fs.entries = append(fs.entries, flagEntry{short, long, "[=float]", usage, ""})
+}
+
+// stringsValue is a flag.Value that accumulates every occurrence of a repeated flag.
+type stringsValue []string
+
+func (s *stringsValue) String() string { return strings.Join(*s, ",") }
+
+func (s *stringsValue) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// StringsVar defines a repeatable string flag reachable as -short and --long.
+// Either name may be empty.
+func (fs *flagSet) StringsVar(p *[]string, short, long, usage string) {
+	for _, name := range []string{short, long} {
+		if name != "" {
+			fs.FlagSet.Var((*stringsValue)(p), name, usage)
+		}
+	}
+	fs.entries = append(fs.entries, flagEntry{short, long, "string", usage + " (repeatable)", ""})
+}
+
+// setFlags returns the flags given on the command line as name=value, by
+// their long names where they have one, in the order of the help output.
+func (fs *flagSet) setFlags() []string {
+	var set []string
+	for _, e := range fs.entries {
+		name := e.long
+		if name == "" {
+			name = e.short
+		}
+		if fs.isSet(e.short, e.long) {
+			set = append(set, name+"="+fs.Lookup(name).Value.String())
+		}
+	}
+	return set
+}
+
+// isSet reports whether any of the named flags was given on the command line.
+func (fs *flagSet) isSet(names ...string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		for _, name := range names {
+			if f.Name == name {
+				set = true
+			}
+		}
+	})
+	return set
+}
+
+// parse parses args, allowing flags before, between, and after positional
+// arguments. Everything after a literal "--" is positional.
+func (fs *flagSet) parse(args []string) ([]string, error) {
+	var rest []string
+	for i, arg := range args {
+		if arg == "--" {
+			args, rest = args[:i], args[i+1:]
+			break
+		}
+	}
+
+	// Flags whose value is optional get an explicit one, so that the next
+	// argument isn't taken as their value.
+	args = slices.Clone(args)
+	for i, arg := range args {
+		if name := strings.TrimLeft(arg, "-"); name != arg && fs.optional[name] {
+			args[i] = arg + "=true"
+		}
+	}
+
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	return append(positional, rest...), nil
+}
+
+// printUsage writes the help text for the flag set to w.
+func (fs *flagSet) printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s\n", fs.usageLine)
+	if fs.description != "" {
+		fmt.Fprintf(w, "\n%s\n", fs.description)
+	}
+	if len(fs.entries) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "\nFlags:")
+	var names []string
+	width := 0
+	for _, e := range fs.entries {
+		var parts []string
+		if e.short != "" {
+			parts = append(parts, "-"+e.short)
+		}
+		if e.long != "" {
+			parts = append(parts, "--"+e.long)
+		}
+		name := strings.Join(parts, ", ")
+		switch {
+		case strings.HasPrefix(e.kind, "["):
+			name += e.kind
+		case e.kind != "":
+			name += " " + e.kind
+		}
+		names = append(names, name)
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+	for i, e := range fs.entries {
+		usage := e.usage
+		if e.value != "" {
+			usage += fmt.Sprintf(" (default %s)", e.value)
+		}
+		fmt.Fprintf(w, "  %-*s  %s\n", width, names[i], usage)
+	}
+}
+
+// parseOrExit parses args and handles --help and flag errors the same way for
+// every subcommand: help exits 0, bad flags print usage and exit 2.
+func (fs *flagSet) parseOrExit(args []string) []string {
+	positional, err := fs.parse(args)
+	if err == flag.ErrHelp {
+		fs.printUsage(os.Stdout)
+		os.Exit(exitOK)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		fs.printUsage(os.Stderr)
+		os.Exit(exitUsage)
+	}
+	return positional
+}
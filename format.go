@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nfranczak/urdf-simplifier/urdf"
+)
+
+// formatFlags holds the flags that lay out the output URDF. Those given are
+// applied over the default layout, or the input's with --preserve-format.
+type formatFlags struct {
+	preserve      bool
+	indent        string
+	lineEndings   string
+	noDeclaration bool
+	// indentSet, lineEndingsSet and declarationSet record which of the
+	// others were given.
+	indentSet, lineEndingsSet, declarationSet bool
+}
+
+func (f *formatFlags) register(fs *flagSet) {
+	fs.BoolVar(&f.preserve, "", "preserve-format", false, "lay the output out like the input: its indentation, attribute quotes, self-closing tags, declaration and line endings")
+	fs.StringVar(&f.indent, "", "indent", "2", "indent the output by this many spaces per level, or by a tab with 'tab'")
+	fs.StringVar(&f.lineEndings, "", "line-endings", "lf", "end the output's lines with lf or crlf")
+	fs.BoolVar(&f.noDeclaration, "", "no-xml-declaration", false, "leave the <?xml ...?> declaration out of the output")
+}
+
+// parse checks the flags after fs has parsed them.
+func (f *formatFlags) parse(fs *flagSet) error {
+	f.indentSet, f.lineEndingsSet, f.declarationSet = fs.isSet("indent"), fs.isSet("line-endings"), fs.isSet("no-xml-declaration")
+	if _, err := f.indentString(); err != nil {
+		return err
+	}
+	if f.lineEndings != "lf" && f.lineEndings != "crlf" {
+		return fmt.Errorf("invalid --line-endings %q (want lf or crlf)", f.lineEndings)
+	}
+	return nil
+}
+
+// indentString returns the indentation --indent asks for.
+func (f *formatFlags) indentString() (string, error) {
+	if f.indent == "tab" {
+		return "\t", nil
+	}
+	n, err := strconv.Atoi(f.indent)
+	if err != nil || n < 0 || n > 16 {
+		return "", fmt.Errorf("invalid --indent %q (want a number of spaces from 0 to 16, or tab)", f.indent)
+	}
+	return strings.Repeat(" ", n), nil
+}
+
+// format returns the layout of the output for the input document data.
+func (f *formatFlags) format(data []byte) urdf.Format {
+	format := urdf.DefaultFormat
+	if f.preserve {
+		format = urdf.DetectFormat(data)
+	}
+	if !f.preserve || f.indentSet {
+		format.Indent, _ = f.indentString()
+	}
+	if !f.preserve || f.lineEndingsSet {
+		format.LineEnding = ""
+		if f.lineEndings == "crlf" {
+			format.LineEnding = "\r\n"
+		}
+	}
+	if !f.preserve || f.declarationSet {
+		format.NoDeclaration = f.noDeclaration
+	}
+	return format
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// expandGlob returns the files matching pattern in lexical order. On top of
+// filepath.Match syntax, a "**" path segment matches any number of
+// directories. A pattern without metacharacters is returned as-is.
+func expandGlob(pattern string) ([]string, error) {
+	pattern = filepath.ToSlash(pattern)
+	if !hasMeta(pattern) {
+		return []string{filepath.FromSlash(pattern)}, nil
+	}
+
+	// Walk from the deepest directory that precedes the first wildcard.
+	segments := strings.Split(pattern, "/")
+	root := ""
+	for len(segments) > 1 && !hasMeta(segments[0]) {
+		root = path.Join(root, segments[0])
+		if segments[0] == "" {
+			root = "/"
+		}
+		segments = segments[1:]
+	}
+	walkRoot := root
+	if walkRoot == "" {
+		walkRoot = "."
+	}
+	for _, seg := range segments {
+		if _, err := path.Match(seg, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	var matches []string
+	err := filepath.WalkDir(filepath.FromSlash(walkRoot), func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(filepath.FromSlash(walkRoot), p)
+		if err != nil {
+			return err
+		}
+		if matchSegments(segments, strings.Split(filepath.ToSlash(rel), "/")) {
+			matches = append(matches, p)
+		}
+		return nil
+	})
+	return matches, err
+}
+
+func hasMeta(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+// matchSegments matches path segments against pattern segments, letting "**"
+// stand for zero or more segments.
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// expandOutputTemplate fills the placeholders of an --out template for input:
+//
+//	{dir}   directory of the input file
+//	{name}  file name without extension
+//	{ext}   extension including the dot
+//	{base}  file name with extension
+func expandOutputTemplate(template, input string) string {
+	base := filepath.Base(input)
+	ext := filepath.Ext(base)
+	return strings.NewReplacer(
+		"{dir}", filepath.Dir(input),
+		"{name}", strings.TrimSuffix(base, ext),
+		"{ext}", ext,
+		"{base}", base,
+	).Replace(template)
+}
@@ -2,6 +2,9 @@ module github.com/nfranczak/urdf-simplifier
 
 go 1.23.5
 
-require github.com/nfranczak/stl-bounding-box v0.0.1
+require (
+	golang.org/x/term v0.28.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
-require gonum.org/v1/gonum v0.16.0 // indirect
+require golang.org/x/sys v0.29.0 // indirect
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/nfranczak/urdf-simplifier/mesh"
+	"github.com/nfranczak/urdf-simplifier/urdf"
+)
+
+func runInspect(args []string) int {
+	var packageMap []string
+	var xf xacroFlags
+	var listMasses, listMeshes bool
+	var maxTriangles int
+	fs := newFlagSet("inspect", "urdf-simplifier inspect [flags] <robot.urdf>",
+		"Prints link and joint counts, degrees of freedom, total mass and center of\n"+
+			"mass, mesh and triangle counts, and tree depth without writing any output file.")
+	fs.BoolVar(&listMasses, "", "masses", false, "also list each link's mass and center of mass in its own frame")
+	fs.BoolVar(&listMeshes, "", "meshes", false, "also list each mesh file with its triangle count, size and links, largest first")
+	fs.IntVar(&maxTriangles, "", "max-triangles", 20000, "warn about mesh files with more triangles than this")
+	registerPackageMap(fs, &packageMap)
+	xf.register(fs)
+	positional := fs.parseOrExit(args)
+	if len(positional) != 1 {
+		fs.printUsage(os.Stderr)
+		return exitUsage
+	}
+
+	packages, err := parsePackageMap(packageMap)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	xacroIn, err := xf.parse()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+
+	robot, err := loadRobot(positional[0], packages, xacroIn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitCode(err)
+	}
+
+	tree := urdf.NewKinematicTree(robot)
+	jointTypes := make(map[string]int)
+	var typeOrder []string
+	dof := 0
+	for _, joint := range robot.Joints {
+		if jointTypes[joint.Type] == 0 {
+			typeOrder = append(typeOrder, joint.Type)
+		}
+		jointTypes[joint.Type]++
+		if joint.Type != "fixed" {
+			dof++
+		}
+	}
+
+	fmt.Printf("%-11s %s\n", "Robot:", robot.Name)
+	fmt.Printf("%-11s %d\n", "Links:", len(robot.Links))
+	fmt.Printf("%-11s %d\n", "Joints:", len(robot.Joints))
+	for _, t := range typeOrder {
+		fmt.Printf("  %-9s %d\n", t, jointTypes[t])
+	}
+	fmt.Printf("%-11s %d\n", "DOF:", dof)
+	masses := urdf.Masses(robot, "")
+	fmt.Printf("%-11s %g kg\n", "Mass:", masses.Total)
+	if masses.Total > 0 {
+		c := masses.Center
+		fmt.Printf("%-11s (%.5f, %.5f, %.5f) in %s at the zero pose\n", "COM:", c[0], c[1], c[2], masses.Frame)
+	}
+	if listMasses {
+		for _, m := range masses.Links {
+			fmt.Printf("  %-9s %g kg at (%.5f, %.5f, %.5f)\n", m.Link, m.Mass, m.Center[0], m.Center[1], m.Center[2])
+		}
+	}
+
+	stats := meshStats(robot, meshResolver(positional[0], packages))
+	fmt.Printf("%-11s %d (%d files)\n", "Meshes:", stats.refs, stats.files)
+	fmt.Printf("%-11s %d\n", "Triangles:", stats.triangles)
+	if listMeshes {
+		printMeshList(stats, maxTriangles)
+	}
+	fmt.Printf("%-11s %s\n", "Root:", tree.Root())
+	fmt.Printf("%-11s %d\n", "Depth:", tree.Depth())
+
+	for _, uri := range stats.unreadable {
+		fmt.Fprintf(os.Stderr, "Warning: could not read mesh %s; its triangles are not counted\n", uri)
+	}
+	for _, f := range stats.byFile {
+		if f.triangles > maxTriangles {
+			fmt.Fprintf(os.Stderr, "Warning: mesh %s has %d triangles (over %d); consider decimating it or simplifying %s\n",
+				f.uri, f.triangles, maxTriangles, strings.Join(f.links, ", "))
+		}
+	}
+	return exitOK
+}
+
+// inspectMeshStats counts the mesh references of a robot and the triangles of
+// the files they name. A file referenced more than once is read and counted
+// once per reference, because each reference is a separate piece of geometry.
+type inspectMeshStats struct {
+	refs, files int
+	triangles   int
+	unreadable  []string
+	// byFile describes each readable file, in order of first reference.
+	byFile []*meshFileStats
+}
+
+// meshFileStats describes one mesh file.
+type meshFileStats struct {
+	uri       string
+	triangles int
+	size      int
+	// links lists the links referring to the file, each once.
+	links []string
+}
+
+func meshStats(robot *urdf.Robot, resolver urdf.MeshResolver) inspectMeshStats {
+	var stats inspectMeshStats
+	files := make(map[string]*meshFileStats)
+	failed := make(map[string]bool)
+	for _, link := range robot.Links {
+		var refs []*urdf.Mesh
+		for _, v := range link.Visual {
+			if v.Geometry != nil && v.Geometry.Mesh != nil {
+				refs = append(refs, v.Geometry.Mesh)
+			}
+		}
+		for _, c := range link.Collision {
+			if c.Geometry != nil && c.Geometry.Mesh != nil {
+				refs = append(refs, c.Geometry.Mesh)
+			}
+		}
+		for _, ref := range refs {
+			stats.refs++
+			uri := ref.Filename
+			f, ok := files[uri]
+			if !ok && !failed[uri] {
+				stats.files++
+				var err error
+				if f, err = readMeshStats(resolver, uri); err != nil {
+					failed[uri] = true
+					stats.unreadable = append(stats.unreadable, uri)
+					continue
+				}
+				files[uri] = f
+				stats.byFile = append(stats.byFile, f)
+			}
+			if f == nil {
+				continue
+			}
+			stats.triangles += f.triangles
+			if !slices.Contains(f.links, link.Name) {
+				f.links = append(f.links, link.Name)
+			}
+		}
+	}
+	return stats
+}
+
+func readMeshStats(resolver urdf.MeshResolver, uri string) (*meshFileStats, error) {
+	f, err := resolver.Open(uri)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	m, err := mesh.ParseSTL(data)
+	if err != nil {
+		return nil, err
+	}
+	return &meshFileStats{uri: uri, triangles: len(m.Triangles), size: len(data)}, nil
+}
+
+// printMeshList lists the mesh files of stats from the most triangles to the
+// fewest, with their size and the links using them, marking those over
+// maxTriangles.
+func printMeshList(stats inspectMeshStats, maxTriangles int) {
+	files := slices.Clone(stats.byFile)
+	slices.SortStableFunc(files, func(a, b *meshFileStats) int { return b.triangles - a.triangles })
+	width := 0
+	for _, f := range files {
+		width = max(width, len(path.Base(f.uri)))
+	}
+	for _, f := range files {
+		line := fmt.Sprintf("  %-*s %9d triangles %9s  %s", width, path.Base(f.uri), f.triangles, formatSize(f.size), strings.Join(f.links, ", "))
+		if f.triangles > maxTriangles {
+			line += fmt.Sprintf("  (over %d)", maxTriangles)
+		}
+		fmt.Println(line)
+	}
+}
+
+// formatSize formats a file size in bytes with a binary unit.
+func formatSize(n int) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	v, prefix := float64(n)/unit, "KMGT"
+	i := 0
+	for ; v >= unit && i < len(prefix)-1; i++ {
+		v /= unit
+	}
+	return fmt.Sprintf("%.1f %ciB", v, prefix[i])
+}
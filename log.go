@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+
+	"github.com/nfranczak/urdf-simplifier/urdf"
+)
+
+// logFlags holds the verbosity and format flags shared by commands that log progress.
+type logFlags struct {
+	quiet       bool
+	verbose     bool
+	veryVerbose bool
+	format      string
+	noColor     bool
+
+	// status is the progress line on stderr, set by logger when stderr is a
+	// terminal and logs are plain text.
+	status *statusLine
+}
+
+func (f *logFlags) register(fs *flagSet) {
+	fs.BoolVar(&f.quiet, "q", "quiet", false, "print nothing except errors")
+	fs.BoolVar(&f.verbose, "v", "verbose", false, "print per-mesh details")
+	fs.BoolVar(&f.veryVerbose, "vv", "", false, "print per-mesh details and path resolution traces")
+	fs.StringVar(&f.format, "", "log-format", "text", "log output format: text or json")
+	fs.BoolVar(&f.noColor, "", "no-color", false, "do not color terminal output")
+}
+
+// palette returns the colors to use on stderr.
+func (f *logFlags) palette() palette {
+	return newPalette(os.Stderr, f.noColor)
+}
+
+// logger builds the logger selected by the flags. All logging goes to stderr.
+func (f *logFlags) logger() (*slog.Logger, error) {
+	level := slog.LevelInfo
+	switch {
+	case f.quiet:
+		level = slog.LevelError
+	case f.veryVerbose:
+		level = urdf.LevelTrace
+	case f.verbose:
+		level = slog.LevelDebug
+	}
+
+	switch f.format {
+	case "text", "":
+		var w io.Writer = os.Stderr
+		if term.IsTerminal(int(os.Stderr.Fd())) {
+			f.status = &statusLine{f: os.Stderr}
+			w = f.status
+		}
+		return slog.New(newTextHandler(w, level, f.palette())), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level})), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q (want text or json)", f.format)
+}
+
+// report emits a change report in the selected format: a readable summary
+// for text logs, or a single structured record for JSON logs.
+func (f *logFlags) report(logger *slog.Logger, report *urdf.Report) {
+	switch {
+	case f.quiet:
+	case f.format == "json":
+		logger.Info("change report", "report", report)
+	default:
+		printReport(os.Stderr, report, f.palette())
+	}
+}
+
+// textHandler prints log records as plain lines meant for people: the message
+// followed by key=value attributes, prefixed with the level only for warnings
+// and errors.
+type textHandler struct {
+	w       io.Writer
+	level   slog.Level
+	palette palette
+	attrs   []slog.Attr
+	mu      *sync.Mutex
+}
+
+func newTextHandler(w io.Writer, level slog.Level, p palette) *textHandler {
+	return &textHandler{w: w, level: level, palette: p, mu: &sync.Mutex{}}
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	switch {
+	case r.Level >= slog.LevelError:
+		b.WriteString(h.palette.red("Error:") + " ")
+	case r.Level >= slog.LevelWarn:
+		b.WriteString(h.palette.yellow("Warning:") + " ")
+	}
+	b.WriteString(r.Message)
+
+	writeAttr := func(a slog.Attr) bool {
+		fmt.Fprintf(&b, " %s=%s", a.Key, formatAttrValue(a.Value))
+		return true
+	}
+	for _, a := range h.attrs {
+		writeAttr(a)
+	}
+	r.Attrs(writeAttr)
+	b.WriteString("\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append(append([]slog.Attr(nil), h.attrs...), attrs...)
+	return &clone
+}
+
+// WithGroup is not used by this tool; groups are flattened.
+func (h *textHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+func formatAttrValue(v slog.Value) string {
+	s := v.Resolve().String()
+	if s == "" || strings.ContainsAny(s, " \t\"=") {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/nfranczak/urdf-simplifier/urdf"
+)
+
+func runMerge(args []string) int {
+	var parent, prefix, xyz, rpy string
+	fs := newFlagSet("merge", "urdf-simplifier merge [flags] <base.urdf> <attachment.urdf> <output.urdf>",
+		"Attaches the root link of attachment.urdf to a link of base.urdf with a fixed joint,\n"+
+			"e.g. to mount a gripper on an arm.")
+	fs.StringVar(&parent, "p", "parent", "", "link of base.urdf to attach to (default: the base robot's last leaf link)")
+	fs.StringVar(&prefix, "", "prefix", "", "prefix added to every link and joint name from attachment.urdf")
+	fs.StringVar(&xyz, "", "xyz", "0 0 0", "translation of the attachment relative to the parent link")
+	fs.StringVar(&rpy, "", "rpy", "0 0 0", "rotation of the attachment relative to the parent link")
+	positional := fs.parseOrExit(args)
+	if len(positional) != 3 {
+		fs.printUsage(os.Stderr)
+		return exitUsage
+	}
+
+	base, err := loadRobot(positional[0], nil, xacroInput{mode: xacroBuiltin})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitCode(err)
+	}
+	attachment, err := loadRobot(positional[1], nil, xacroInput{mode: xacroBuiltin})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitCode(err)
+	}
+
+	if parent == "" {
+		leaves := urdf.NewKinematicTree(base).Leaves()
+		if len(leaves) == 0 {
+			fmt.Fprintln(os.Stderr, "Error: base robot has no links")
+			return exitFailure
+		}
+		parent = leaves[len(leaves)-1]
+	}
+
+	if err := base.Attach(attachment, parent, prefix, &urdf.Origin{XYZ: xyz, RPY: rpy}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+
+	if err := writeRobot(positional[2], base); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	fmt.Fprintf(os.Stderr, "Attached %s to link %s: %s\n", attachment.Name, parent, displayOutputPath(positional[2]))
+	return exitOK
+}
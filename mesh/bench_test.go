@@ -0,0 +1,94 @@
+package mesh
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// benchSTL returns a binary STL of a sphere-like grid of about n triangles,
+// with shared vertices as exporters write them.
+func benchSTL(n int) []byte {
+	side := int(math.Sqrt(float64(n) / 2))
+	data := make([]byte, 84, 84+2*side*side*stlTriangleSize)
+	binary.LittleEndian.PutUint32(data[80:], uint32(2*side*side))
+	point := func(i, j int) [3]float32 {
+		u, v := float64(i)/float64(side)*2*math.Pi, float64(j)/float64(side)*math.Pi
+		return [3]float32{float32(math.Cos(u) * math.Sin(v)), float32(math.Sin(u) * math.Sin(v)), float32(math.Cos(v))}
+	}
+	var rec [stlTriangleSize]byte
+	for i := range side {
+		for j := range side {
+			for _, t := range [2][3][3]float32{
+				{point(i, j), point(i+1, j), point(i+1, j+1)},
+				{point(i, j), point(i+1, j+1), point(i, j+1)},
+			} {
+				for k, p := range t {
+					for c := range p {
+						binary.LittleEndian.PutUint32(rec[12+k*12+c*4:], math.Float32bits(p[c]))
+					}
+				}
+				data = append(data, rec[:]...)
+			}
+		}
+	}
+	return data
+}
+
+func BenchmarkParseSTL(b *testing.B) {
+	data := benchSTL(100_000)
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for range b.N {
+		if _, err := ParseSTL(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBoundSTL(b *testing.B) {
+	data := benchSTL(100_000)
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for range b.N {
+		if _, err := BoundSTL(bytes.NewReader(data), int64(len(data)), false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBounds(b *testing.B) {
+	m, err := ParseSTL(benchSTL(100_000))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for range b.N {
+		m.Bounds()
+	}
+}
+
+func BenchmarkClosed(b *testing.B) {
+	m, err := ParseSTL(benchSTL(100_000))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for range b.N {
+		m.Closed()
+	}
+}
+
+func BenchmarkConvexHull(b *testing.B) {
+	m, err := ParseSTL(benchSTL(10_000))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for range b.N {
+		if _, err := m.ConvexHull(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
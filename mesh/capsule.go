@@ -0,0 +1,55 @@
+package mesh
+
+import (
+	"math"
+
+	"github.com/nfranczak/urdf-simplifier/spatialmath"
+)
+
+// Capsule is a cylinder capped with hemispheres: the points within Radius of
+// a segment of Length centered on Center and parallel to the X, Y or Z axis,
+// as Axis is 0, 1 or 2.
+type Capsule struct {
+	Center spatialmath.Vec3 `json:"center"`
+	Axis   int              `json:"axis"`
+	Radius float64          `json:"radius"`
+	Length float64          `json:"length"`
+}
+
+// Volume returns the volume of the capsule.
+func (c Capsule) Volume() float64 {
+	return math.Pi*c.Radius*c.Radius*c.Length + 4.0/3*math.Pi*c.Radius*c.Radius*c.Radius
+}
+
+// BoundingCapsule returns the capsule of least volume that contains every
+// vertex of the mesh among those whose segment runs through the center of
+// its bounding box along one of the axes. Ties go to the earlier axis.
+func (m *Mesh3D) BoundingCapsule() Capsule {
+	box := m.Bounds()
+	center := box.Center()
+	c := [3]float64{center.X, center.Y, center.Z}
+	var best Capsule
+	for axis := range 3 {
+		u, v := (axis+1)%3, (axis+2)%3
+		// The radius is that of the smallest cylinder about the axis holding
+		// every vertex; the segment then only has to reach far enough for
+		// each vertex to fall inside a cap.
+		var r2 float64
+		for _, p := range m.Vertices {
+			q := [3]float64{p.X, p.Y, p.Z}
+			du, dv := q[u]-c[u], q[v]-c[v]
+			r2 = max(r2, du*du+dv*dv)
+		}
+		var half float64
+		for _, p := range m.Vertices {
+			q := [3]float64{p.X, p.Y, p.Z}
+			du, dv := q[u]-c[u], q[v]-c[v]
+			half = max(half, math.Abs(q[axis]-c[axis])-math.Sqrt(max(0, r2-du*du-dv*dv)))
+		}
+		capsule := Capsule{Center: center, Axis: axis, Radius: math.Sqrt(r2), Length: 2 * half}
+		if axis == 0 || capsule.Volume() < best.Volume() {
+			best = capsule
+		}
+	}
+	return best
+}
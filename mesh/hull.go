@@ -0,0 +1,200 @@
+package mesh
+
+import (
+	"errors"
+	"math"
+
+	"github.com/nfranczak/urdf-simplifier/spatialmath"
+)
+
+// ErrDegenerate is returned when a mesh is too flat to have a 3D convex hull.
+var ErrDegenerate = errors.New("mesh is degenerate: all vertices are coplanar")
+
+type hullFace struct {
+	v       [3]int
+	normal  spatialmath.Vec3
+	offset  float64
+	outside []int
+	dead    bool
+}
+
+func (f *hullFace) distance(p spatialmath.Vec3) float64 {
+	return f.normal.Dot(p) - f.offset
+}
+
+// ConvexHull returns the convex hull of the mesh vertices as a closed mesh
+// with outward-facing triangles. It uses the quickhull algorithm, so the cost
+// is driven by the number of hull vertices rather than the input size.
+func (m *Mesh3D) ConvexHull() (*Mesh3D, error) {
+	pts := m.Vertices
+	if len(pts) < 4 {
+		return nil, ErrDegenerate
+	}
+
+	size := m.Bounds().Size()
+	eps := 1e-9 * math.Max(size.X, math.Max(size.Y, size.Z))
+	if eps == 0 {
+		return nil, ErrDegenerate
+	}
+
+	initial, ok := initialSimplex(pts, eps)
+	if !ok {
+		return nil, ErrDegenerate
+	}
+
+	var faces []*hullFace
+	centroid := pts[initial[0]].Add(pts[initial[1]]).Add(pts[initial[2]]).Add(pts[initial[3]]).Scale(0.25)
+	newFace := func(a, b, c int) *hullFace {
+		n := pts[b].Sub(pts[a]).Cross(pts[c].Sub(pts[a])).Normalize()
+		f := &hullFace{v: [3]int{a, b, c}, normal: n, offset: n.Dot(pts[a])}
+		// Orient every face away from the interior.
+		if f.distance(centroid) > 0 {
+			f.v[1], f.v[2] = f.v[2], f.v[1]
+			f.normal = f.normal.Scale(-1)
+			f.offset = -f.offset
+		}
+		faces = append(faces, f)
+		return f
+	}
+
+	i0, i1, i2, i3 := initial[0], initial[1], initial[2], initial[3]
+	start := []*hullFace{newFace(i0, i1, i2), newFace(i0, i1, i3), newFace(i0, i2, i3), newFace(i1, i2, i3)}
+
+	used := map[int]bool{i0: true, i1: true, i2: true, i3: true}
+	assign(pts, start, allIndices(len(pts), used), eps)
+
+	for {
+		var face *hullFace
+		for _, f := range faces {
+			if !f.dead && len(f.outside) > 0 {
+				face = f
+				break
+			}
+		}
+		if face == nil {
+			break
+		}
+
+		// The farthest outside point is guaranteed to be a hull vertex.
+		eye := face.outside[0]
+		best := face.distance(pts[eye])
+		for _, p := range face.outside[1:] {
+			if d := face.distance(pts[p]); d > best {
+				eye, best = p, d
+			}
+		}
+
+		// Every face the eye point can see gets replaced.
+		var visible []*hullFace
+		edges := make(map[[2]int]bool)
+		for _, f := range faces {
+			if !f.dead && f.distance(pts[eye]) > eps {
+				visible = append(visible, f)
+				edges[[2]int{f.v[0], f.v[1]}] = true
+				edges[[2]int{f.v[1], f.v[2]}] = true
+				edges[[2]int{f.v[2], f.v[0]}] = true
+			}
+		}
+
+		var orphans []int
+		var created []*hullFace
+		for _, f := range visible {
+			f.dead = true
+			for _, p := range f.outside {
+				if p != eye {
+					orphans = append(orphans, p)
+				}
+			}
+			for _, e := range [][2]int{{f.v[0], f.v[1]}, {f.v[1], f.v[2]}, {f.v[2], f.v[0]}} {
+				// A horizon edge borders exactly one visible face.
+				if !edges[[2]int{e[1], e[0]}] {
+					created = append(created, newFace(e[0], e[1], eye))
+				}
+			}
+		}
+		assign(pts, created, orphans, eps)
+	}
+
+	b := newBuilder()
+	for _, f := range faces {
+		if !f.dead {
+			b.triangle(pts[f.v[0]], pts[f.v[1]], pts[f.v[2]])
+		}
+	}
+	return b.mesh, nil
+}
+
+// assign distributes points to the first face they lie outside of. Points
+// inside every face are interior to the hull and dropped.
+func assign(pts []spatialmath.Vec3, faces []*hullFace, candidates []int, eps float64) {
+	for _, p := range candidates {
+		for _, f := range faces {
+			if f.distance(pts[p]) > eps {
+				f.outside = append(f.outside, p)
+				break
+			}
+		}
+	}
+}
+
+func allIndices(n int, skip map[int]bool) []int {
+	out := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		if !skip[i] {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// initialSimplex picks four well-spread, non-coplanar points to seed the hull.
+func initialSimplex(pts []spatialmath.Vec3, eps float64) ([4]int, bool) {
+	var s [4]int
+
+	// Two extreme points along X.
+	for i, p := range pts {
+		if p.X < pts[s[0]].X {
+			s[0] = i
+		}
+		if p.X > pts[s[1]].X {
+			s[1] = i
+		}
+	}
+	if pts[s[0]].Sub(pts[s[1]]).Norm() <= eps {
+		// All points share an X; fall back to the point farthest from the first.
+		best := 0.0
+		for i, p := range pts {
+			if d := p.Sub(pts[s[0]]).Norm(); d > best {
+				s[1], best = i, d
+			}
+		}
+		if best <= eps {
+			return s, false
+		}
+	}
+
+	// The point farthest from the line through the first two.
+	dir := pts[s[1]].Sub(pts[s[0]]).Normalize()
+	best := 0.0
+	for i, p := range pts {
+		if d := p.Sub(pts[s[0]]).Cross(dir).Norm(); d > best {
+			s[2], best = i, d
+		}
+	}
+	if best <= eps {
+		return s, false
+	}
+
+	// The point farthest from the plane through the first three.
+	n := pts[s[1]].Sub(pts[s[0]]).Cross(pts[s[2]].Sub(pts[s[0]])).Normalize()
+	best = 0.0
+	for i, p := range pts {
+		if d := math.Abs(n.Dot(p.Sub(pts[s[0]]))); d > best {
+			s[3], best = i, d
+		}
+	}
+	if best <= eps {
+		return s, false
+	}
+	return s, true
+}
@@ -0,0 +1,140 @@
+package mesh
+
+import (
+	"errors"
+	"math"
+
+	"github.com/nfranczak/urdf-simplifier/spatialmath"
+)
+
+// ErrOpen is returned when a mesh does not enclose a volume, because some
+// edge is not shared by exactly two triangles.
+var ErrOpen = errors.New("mesh is not closed")
+
+// MassProperties describes the solid a closed mesh encloses, at unit density.
+type MassProperties struct {
+	// Volume is the enclosed volume, which is also the mass at unit density.
+	Volume float64
+	// Center is the center of mass.
+	Center spatialmath.Vec3
+	// Inertia is the inertia tensor about Center, in the mesh frame.
+	Inertia spatialmath.Mat3
+}
+
+// Closed reports whether every edge of the mesh is shared by exactly two
+// triangles, as for the surface of a solid.
+func (m *Mesh3D) Closed() bool {
+	if len(m.Triangles) == 0 {
+		return false
+	}
+	edges := make(map[[2]int]int)
+	for _, t := range m.Triangles {
+		for i := range 3 {
+			a, b := t[i], t[(i+1)%3]
+			if a > b {
+				a, b = b, a
+			}
+			edges[[2]int{a, b}]++
+		}
+	}
+	for _, n := range edges {
+		if n != 2 {
+			return false
+		}
+	}
+	return true
+}
+
+// MassProperties integrates the volume, center of mass and inertia tensor
+// of the solid the mesh encloses, assuming unit density, by summing signed
+// tetrahedra against the origin. Inverted winding is corrected for. It
+// returns ErrOpen for a mesh that is not closed and ErrDegenerate for one
+// that encloses no volume.
+func (m *Mesh3D) MassProperties() (MassProperties, error) {
+	if !m.Closed() {
+		return MassProperties{}, ErrOpen
+	}
+	var volume float64
+	var first spatialmath.Vec3
+	// second is the integral of x xᵀ over the solid.
+	var second spatialmath.Mat3
+	for _, t := range m.Triangles {
+		a, b, c := m.Vertices[t[0]], m.Vertices[t[1]], m.Vertices[t[2]]
+		det := a.Dot(b.Cross(c))
+		volume += det / 6
+		sum := a.Add(b).Add(c)
+		first = first.Add(sum.Scale(det / 24))
+		// For a tetrahedron with one vertex at the origin, the integral of
+		// x xᵀ is det/120 · (a aᵀ + b bᵀ + c cᵀ + s sᵀ), where s = a + b + c.
+		vs := [4]spatialmath.Vec3{a, b, c, sum}
+		for i := range 3 {
+			for j := range 3 {
+				var acc float64
+				for _, v := range vs {
+					acc += component(v, i) * component(v, j)
+				}
+				second[i][j] += det / 120 * acc
+			}
+		}
+	}
+	if volume < 0 {
+		volume, first = -volume, first.Scale(-1)
+		for i := range 3 {
+			for j := range 3 {
+				second[i][j] = -second[i][j]
+			}
+		}
+	}
+	if volume < 1e-15 {
+		return MassProperties{}, ErrDegenerate
+	}
+
+	center := first.Scale(1 / volume)
+	// Move the second moment to the center of mass, then convert it to an
+	// inertia tensor: I = tr(C)·E - C.
+	for i := range 3 {
+		for j := range 3 {
+			second[i][j] -= volume * component(center, i) * component(center, j)
+		}
+	}
+	trace := second[0][0] + second[1][1] + second[2][2]
+	var inertia spatialmath.Mat3
+	for i := range 3 {
+		for j := range 3 {
+			inertia[i][j] = -second[i][j]
+			if i == j {
+				inertia[i][j] += trace
+			}
+		}
+	}
+	return MassProperties{Volume: volume, Center: center, Inertia: roundOff(inertia)}, nil
+}
+
+func component(v spatialmath.Vec3, i int) float64 {
+	switch i {
+	case 0:
+		return v.X
+	case 1:
+		return v.Y
+	}
+	return v.Z
+}
+
+// roundOff zeroes the entries of m within eps of zero relative to its
+// largest entry, so that symmetric meshes yield exactly diagonal tensors.
+func roundOff(m spatialmath.Mat3) spatialmath.Mat3 {
+	var largest float64
+	for i := range 3 {
+		for j := range 3 {
+			largest = math.Max(largest, math.Abs(m[i][j]))
+		}
+	}
+	for i := range 3 {
+		for j := range 3 {
+			if math.Abs(m[i][j]) < 1e-12*largest {
+				m[i][j] = 0
+			}
+		}
+	}
+	return m
+}
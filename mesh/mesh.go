@@ -0,0 +1,152 @@
+// Package mesh provides an in-memory triangle mesh with the geometric
+// operations the simplifier needs: bounds, convex hulls, volume, mass
+// properties, and rigid transforms.
+package mesh
+
+import (
+	"math"
+
+	"github.com/nfranczak/urdf-simplifier/spatialmath"
+)
+
+// Mesh3D is an indexed triangle mesh.
+type Mesh3D struct {
+	Vertices  []spatialmath.Vec3
+	Triangles [][3]int
+}
+
+// AABB is an axis-aligned bounding box.
+type AABB struct {
+	Min, Max spatialmath.Vec3
+}
+
+// Size returns the extent of the box along each axis.
+func (b AABB) Size() spatialmath.Vec3 { return b.Max.Sub(b.Min) }
+
+// Center returns the midpoint of the box.
+func (b AABB) Center() spatialmath.Vec3 { return b.Min.Add(b.Max).Scale(0.5) }
+
+// Volume returns the volume of the box.
+func (b AABB) Volume() float64 {
+	s := b.Size()
+	return s.X * s.Y * s.Z
+}
+
+// emptyAABB returns a box that any point will expand.
+func emptyAABB() AABB {
+	inf := math.Inf(1)
+	return AABB{
+		Min: spatialmath.Vec3{X: inf, Y: inf, Z: inf},
+		Max: spatialmath.Vec3{X: -inf, Y: -inf, Z: -inf},
+	}
+}
+
+// Bounds returns the axis-aligned bounding box of the mesh vertices.
+func (m *Mesh3D) Bounds() AABB {
+	b := emptyAABB()
+	b.extendAll(m.Vertices)
+	return b
+}
+
+// extendAll grows b to contain every point. It keeps the running bounds in
+// locals and compares plainly rather than calling math.Min and math.Max per
+// coordinate, which is several times faster on large meshes; unlike them, it
+// ignores NaN coordinates.
+func (b *AABB) extendAll(points []spatialmath.Vec3) {
+	minX, minY, minZ := b.Min.X, b.Min.Y, b.Min.Z
+	maxX, maxY, maxZ := b.Max.X, b.Max.Y, b.Max.Z
+	for _, v := range points {
+		if v.X < minX {
+			minX = v.X
+		}
+		if v.X > maxX {
+			maxX = v.X
+		}
+		if v.Y < minY {
+			minY = v.Y
+		}
+		if v.Y > maxY {
+			maxY = v.Y
+		}
+		if v.Z < minZ {
+			minZ = v.Z
+		}
+		if v.Z > maxZ {
+			maxZ = v.Z
+		}
+	}
+	b.Min = spatialmath.Vec3{X: minX, Y: minY, Z: minZ}
+	b.Max = spatialmath.Vec3{X: maxX, Y: maxY, Z: maxZ}
+}
+
+// Volume returns the enclosed volume of the mesh, computed as the sum of
+// signed tetrahedra against the origin. It is only meaningful for closed,
+// consistently oriented meshes; the absolute value is returned so inverted
+// winding still yields a positive volume.
+func (m *Mesh3D) Volume() float64 {
+	var v float64
+	for _, t := range m.Triangles {
+		a, b, c := m.Vertices[t[0]], m.Vertices[t[1]], m.Vertices[t[2]]
+		v += a.Dot(b.Cross(c))
+	}
+	return math.Abs(v) / 6
+}
+
+// SurfaceArea returns the total area of the mesh triangles.
+func (m *Mesh3D) SurfaceArea() float64 {
+	var area float64
+	for _, t := range m.Triangles {
+		a, b, c := m.Vertices[t[0]], m.Vertices[t[1]], m.Vertices[t[2]]
+		area += b.Sub(a).Cross(c.Sub(a)).Norm() / 2
+	}
+	return area
+}
+
+// Transform returns a copy of the mesh with every vertex mapped through pose.
+func (m *Mesh3D) Transform(pose spatialmath.Pose) *Mesh3D {
+	out := &Mesh3D{
+		Vertices:  make([]spatialmath.Vec3, len(m.Vertices)),
+		Triangles: append([][3]int(nil), m.Triangles...),
+	}
+	for i, v := range m.Vertices {
+		out.Vertices[i] = pose.Apply(v)
+	}
+	return out
+}
+
+// Scale returns a copy of the mesh with vertices scaled per axis, as applied
+// by a URDF mesh scale attribute.
+func (m *Mesh3D) Scale(s spatialmath.Vec3) *Mesh3D {
+	out := &Mesh3D{
+		Vertices:  make([]spatialmath.Vec3, len(m.Vertices)),
+		Triangles: append([][3]int(nil), m.Triangles...),
+	}
+	for i, v := range m.Vertices {
+		out.Vertices[i] = spatialmath.Vec3{X: v.X * s.X, Y: v.Y * s.Y, Z: v.Z * s.Z}
+	}
+	return out
+}
+
+// builder collects triangles, welding identical vertices together.
+type builder struct {
+	mesh  *Mesh3D
+	index map[spatialmath.Vec3]int
+}
+
+func newBuilder() *builder {
+	return &builder{mesh: &Mesh3D{}, index: make(map[spatialmath.Vec3]int)}
+}
+
+func (b *builder) vertex(v spatialmath.Vec3) int {
+	if i, ok := b.index[v]; ok {
+		return i
+	}
+	i := len(b.mesh.Vertices)
+	b.mesh.Vertices = append(b.mesh.Vertices, v)
+	b.index[v] = i
+	return i
+}
+
+func (b *builder) triangle(a, c, d spatialmath.Vec3) {
+	b.mesh.Triangles = append(b.mesh.Triangles, [3]int{b.vertex(a), b.vertex(c), b.vertex(d)})
+}
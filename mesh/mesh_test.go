@@ -0,0 +1,318 @@
+package mesh
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/nfranczak/urdf-simplifier/spatialmath"
+)
+
+// boxTriangles returns the 12 outward-facing triangles of an axis-aligned box
+// of the given size with its minimum corner at the origin.
+func boxTriangles(size spatialmath.Vec3) [][3]spatialmath.Vec3 {
+	corner := func(i int) spatialmath.Vec3 {
+		return spatialmath.Vec3{X: float64(i&1) * size.X, Y: float64(i>>1&1) * size.Y, Z: float64(i>>2&1) * size.Z}
+	}
+	center := size.Scale(0.5)
+	var tris [][3]spatialmath.Vec3
+	for _, q := range [6][4]int{{0, 1, 3, 2}, {4, 5, 7, 6}, {0, 1, 5, 4}, {2, 3, 7, 6}, {0, 2, 6, 4}, {1, 3, 7, 5}} {
+		for _, t := range [2][3]int{{q[0], q[1], q[2]}, {q[0], q[2], q[3]}} {
+			a, b, c := corner(t[0]), corner(t[1]), corner(t[2])
+			if b.Sub(a).Cross(c.Sub(a)).Dot(a.Add(b).Add(c).Scale(1.0/3).Sub(center)) < 0 {
+				b, c = c, b
+			}
+			tris = append(tris, [3]spatialmath.Vec3{a, b, c})
+		}
+	}
+	return tris
+}
+
+func binarySTL(tris [][3]spatialmath.Vec3) []byte {
+	data := make([]byte, 84, 84+len(tris)*stlTriangleSize)
+	binary.LittleEndian.PutUint32(data[80:], uint32(len(tris)))
+	var rec [stlTriangleSize]byte
+	for _, t := range tris {
+		for k, p := range t {
+			for c, x := range [3]float64{p.X, p.Y, p.Z} {
+				binary.LittleEndian.PutUint32(rec[12+k*12+c*4:], math.Float32bits(float32(x)))
+			}
+		}
+		data = append(data, rec[:]...)
+	}
+	return data
+}
+
+func asciiSTL(tris [][3]spatialmath.Vec3) string {
+	var sb strings.Builder
+	sb.WriteString("solid box\n")
+	for _, t := range tris {
+		sb.WriteString("  facet normal 0 0 0\n    outer loop\n")
+		for _, p := range t {
+			fmt.Fprintf(&sb, "      vertex %g %g %g\n", p.X, p.Y, p.Z)
+		}
+		sb.WriteString("    endloop\n  endfacet\n")
+	}
+	sb.WriteString("endsolid box\n")
+	return sb.String()
+}
+
+func boxMesh(t *testing.T, size spatialmath.Vec3) *Mesh3D {
+	t.Helper()
+	m, err := ParseSTL([]byte(asciiSTL(boxTriangles(size))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func nearVec(a, b spatialmath.Vec3) bool { return near(a.X, b.X) && near(a.Y, b.Y) && near(a.Z, b.Z) }
+
+func TestParseSTL(t *testing.T) {
+	size := spatialmath.Vec3{X: 1, Y: 2, Z: 3}
+	tris := boxTriangles(size)
+	bin := binarySTL(tris)
+	// Some exporters start the binary header with "solid" too.
+	solid := append([]byte(nil), bin...)
+	copy(solid, "solid box")
+
+	for _, tc := range []struct {
+		name string
+		data []byte
+	}{
+		{"binary", bin},
+		{"binary with solid header", solid},
+		{"ascii", []byte(asciiSTL(tris))},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := ParseSTL(tc.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(m.Triangles) != 12 || len(m.Vertices) != 8 {
+				t.Errorf("got %d triangles and %d vertices, want 12 and 8", len(m.Triangles), len(m.Vertices))
+			}
+			if b := m.Bounds(); !nearVec(b.Min, spatialmath.Vec3{}) || !nearVec(b.Max, size) {
+				t.Errorf("Bounds() = %v", b)
+			}
+			r, err := ReadSTL(bytes.NewReader(tc.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(r.Triangles) != len(m.Triangles) || len(r.Vertices) != len(m.Vertices) {
+				t.Errorf("ReadSTL gave %d triangles and %d vertices, ParseSTL %d and %d",
+					len(r.Triangles), len(r.Vertices), len(m.Triangles), len(m.Vertices))
+			}
+		})
+	}
+}
+
+func TestParseSTLErrors(t *testing.T) {
+	bin := binarySTL(boxTriangles(spatialmath.Vec3{X: 1, Y: 1, Z: 1}))
+	for _, tc := range []struct {
+		name string
+		data string
+		want string
+	}{
+		{"empty binary", string(binarySTL(nil)), "no triangles found in STL file"},
+		{"empty ascii", "solid x\nendsolid x\n", "no triangles found in STL file"},
+		{"too short", "abc", "binary STL too short"},
+		{"truncated", string(bin[:len(bin)-10]), "binary STL truncated: header declares 12 triangles but file holds 11"},
+		{"invalid vertex line", "solid x\nfacet normal 0 0 1\nouter loop\nvertex 1 2\n", "line 4: invalid vertex line"},
+		{"invalid coordinate", "solid x\nfacet normal 0 0 1\nouter loop\nvertex 1 2 z\n", `line 4: invalid coordinate "z"`},
+		{"incomplete triangle", "solid x\nfacet normal 0 0 1\nouter loop\nvertex 0 0 0\nvertex 1 0 0\nendloop\nendfacet\n", "line 7: incomplete triangle, got 2 vertices"},
+		{"too many vertices", "solid x\nfacet normal 0 0 1\nouter loop\nvertex 0 0 0\nvertex 1 0 0\nvertex 0 1 0\nvertex 1 1 0\n", "line 7: too many vertices in facet"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseSTL([]byte(tc.data))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("ParseSTL() error = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestMeasures(t *testing.T) {
+	m := boxMesh(t, spatialmath.Vec3{X: 1, Y: 2, Z: 3})
+	if got := m.Volume(); !near(got, 6) {
+		t.Errorf("Volume() = %v, want 6", got)
+	}
+	if got := m.SurfaceArea(); !near(got, 22) {
+		t.Errorf("SurfaceArea() = %v, want 22", got)
+	}
+	if got := m.Bounds().Volume(); !near(got, 6) {
+		t.Errorf("Bounds().Volume() = %v, want 6", got)
+	}
+	if got := m.Bounds().Center(); !nearVec(got, spatialmath.Vec3{X: 0.5, Y: 1, Z: 1.5}) {
+		t.Errorf("Bounds().Center() = %v", got)
+	}
+
+	scaled := m.Scale(spatialmath.Vec3{X: 2, Y: 1, Z: 0.5})
+	if got := scaled.Volume(); !near(got, 6) {
+		t.Errorf("scaled Volume() = %v, want 6", got)
+	}
+	if b := scaled.Bounds(); !nearVec(b.Max, spatialmath.Vec3{X: 2, Y: 2, Z: 1.5}) {
+		t.Errorf("scaled Bounds() = %v", b)
+	}
+
+	pose := spatialmath.NewPose(spatialmath.Vec3{X: 10}, spatialmath.RPY{Yaw: math.Pi / 2})
+	moved := m.Transform(pose)
+	if got := moved.Volume(); !near(got, 6) {
+		t.Errorf("transformed Volume() = %v, want 6", got)
+	}
+	if b := moved.Bounds(); !nearVec(b.Min, spatialmath.Vec3{X: 8}) || !nearVec(b.Max, spatialmath.Vec3{X: 10, Y: 1, Z: 3}) {
+		t.Errorf("transformed Bounds() = %v", b)
+	}
+}
+
+func TestMassProperties(t *testing.T) {
+	const a, b, c = 1.0, 2.0, 3.0
+	m := boxMesh(t, spatialmath.Vec3{X: a, Y: b, Z: c})
+	if !m.Closed() {
+		t.Fatal("box is not Closed()")
+	}
+	mp, err := m.MassProperties()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !near(mp.Volume, a*b*c) {
+		t.Errorf("Volume = %v, want %v", mp.Volume, a*b*c)
+	}
+	if !nearVec(mp.Center, spatialmath.Vec3{X: a / 2, Y: b / 2, Z: c / 2}) {
+		t.Errorf("Center = %v", mp.Center)
+	}
+	mass := a * b * c
+	want := spatialmath.Mat3{
+		{mass * (b*b + c*c) / 12},
+		{1: mass * (a*a + c*c) / 12},
+		{2: mass * (a*a + b*b) / 12},
+	}
+	for i := range 3 {
+		for j := range 3 {
+			if !near(mp.Inertia[i][j], want[i][j]) {
+				t.Errorf("Inertia[%d][%d] = %v, want %v", i, j, mp.Inertia[i][j], want[i][j])
+			}
+		}
+	}
+
+	// Inward-facing triangles give the same result.
+	flipped := &Mesh3D{Vertices: m.Vertices}
+	for _, tri := range m.Triangles {
+		flipped.Triangles = append(flipped.Triangles, [3]int{tri[0], tri[2], tri[1]})
+	}
+	if got, err := flipped.MassProperties(); err != nil || !near(got.Volume, mp.Volume) {
+		t.Errorf("flipped MassProperties() = %v, %v", got, err)
+	}
+
+	open := &Mesh3D{Vertices: m.Vertices, Triangles: m.Triangles[1:]}
+	if open.Closed() {
+		t.Error("open box is Closed()")
+	}
+	if _, err := open.MassProperties(); !errors.Is(err, ErrOpen) {
+		t.Errorf("open MassProperties() error = %v, want ErrOpen", err)
+	}
+}
+
+func TestConvexHull(t *testing.T) {
+	m := boxMesh(t, spatialmath.Vec3{X: 1, Y: 2, Z: 3})
+	m.Vertices = append(m.Vertices, spatialmath.Vec3{X: 0.5, Y: 1, Z: 1.5}, spatialmath.Vec3{X: 0.2, Y: 0.3, Z: 0.4})
+	hull, err := m.ConvexHull()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hull.Vertices) != 8 || len(hull.Triangles) != 12 {
+		t.Errorf("hull has %d vertices and %d triangles, want 8 and 12", len(hull.Vertices), len(hull.Triangles))
+	}
+	if !hull.Closed() {
+		t.Error("hull is not Closed()")
+	}
+	mp, err := hull.MassProperties()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !near(mp.Volume, 6) {
+		t.Errorf("hull volume = %v, want 6", mp.Volume)
+	}
+
+	for _, tc := range []struct {
+		name string
+		pts  []spatialmath.Vec3
+	}{
+		{"too few", []spatialmath.Vec3{{}, {X: 1}, {Y: 1}}},
+		{"coplanar", []spatialmath.Vec3{{}, {X: 1}, {Y: 1}, {X: 1, Y: 1}, {X: 0.5, Y: 0.2}}},
+		{"coincident", []spatialmath.Vec3{{X: 1}, {X: 1}, {X: 1}, {X: 1}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := (&Mesh3D{Vertices: tc.pts}).ConvexHull(); !errors.Is(err, ErrDegenerate) {
+				t.Errorf("ConvexHull() error = %v, want ErrDegenerate", err)
+			}
+		})
+	}
+}
+
+func TestBoundingCapsule(t *testing.T) {
+	m := boxMesh(t, spatialmath.Vec3{X: 4, Y: 1, Z: 1})
+	c := m.BoundingCapsule()
+	if c.Axis != 0 {
+		t.Errorf("Axis = %d, want 0", c.Axis)
+	}
+	if !nearVec(c.Center, spatialmath.Vec3{X: 2, Y: 0.5, Z: 0.5}) {
+		t.Errorf("Center = %v", c.Center)
+	}
+	for _, v := range m.Vertices {
+		d := v.Sub(c.Center)
+		along := math.Max(math.Abs(d.X)-c.Length/2, 0)
+		if math.Hypot(along, math.Hypot(d.Y, d.Z)) > c.Radius+1e-9 {
+			t.Errorf("vertex %v is outside the capsule %+v", v, c)
+		}
+	}
+	want := math.Pi*c.Radius*c.Radius*c.Length + 4.0/3*math.Pi*c.Radius*c.Radius*c.Radius
+	if got := c.Volume(); !near(got, want) {
+		t.Errorf("Volume() = %v, want %v", got, want)
+	}
+}
+
+func TestBoundSTL(t *testing.T) {
+	tris := boxTriangles(spatialmath.Vec3{X: 1, Y: 2, Z: 3})
+	for _, tc := range []struct {
+		name string
+		data []byte
+	}{
+		{"binary", binarySTL(tris)},
+		{"ascii", []byte(asciiSTL(tris))},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := ParseSTL(tc.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sb, err := BoundSTL(bytes.NewReader(tc.data), int64(len(tc.data)), true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sb.Box != m.Bounds() {
+				t.Errorf("Box = %v, want %v", sb.Box, m.Bounds())
+			}
+			if sb.Triangles != 12 {
+				t.Errorf("Triangles = %d, want 12", sb.Triangles)
+			}
+			if len(sb.Points) != 8 {
+				t.Errorf("got %d extreme points, want the 8 corners", len(sb.Points))
+			}
+
+			sb, err = BoundSTL(bytes.NewReader(tc.data), int64(len(tc.data)), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sb.Points != nil {
+				t.Errorf("Points = %v without hull", sb.Points)
+			}
+		})
+	}
+}
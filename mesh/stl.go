@@ -0,0 +1,187 @@
+package mesh
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nfranczak/urdf-simplifier/spatialmath"
+)
+
+// stlTriangleSize is the size in bytes of one binary STL facet record.
+const stlTriangleSize = 50
+
+// stlChunk is how many binary STL records are read at once.
+const stlChunk = 4096
+
+// ReadSTL reads a binary or ASCII STL file. The format is detected from the
+// content: a file whose length matches its binary triangle count is treated
+// as binary even if its header starts with "solid", which many exporters emit.
+func ReadSTL(r io.Reader) (*Mesh3D, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading STL: %w", err)
+	}
+	return ParseSTL(data)
+}
+
+// ParseSTL parses the contents of a binary or ASCII STL file, detecting the
+// format the same way as ReadSTL.
+func ParseSTL(data []byte) (*Mesh3D, error) {
+	if len(data) >= 84 {
+		n := binary.LittleEndian.Uint32(data[80:84])
+		if uint64(len(data)) == 84+uint64(n)*stlTriangleSize {
+			return parseBinarySTL(data)
+		}
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("solid")) {
+		return parseASCIISTL(bytes.NewReader(data))
+	}
+	return parseBinarySTL(data)
+}
+
+func parseBinarySTL(data []byte) (*Mesh3D, error) {
+	b := newBuilder()
+	err := scanBinarySTL(bytes.NewReader(data), func(a, c, d spatialmath.Vec3) error {
+		b.triangle(a, c, d)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b.mesh, nil
+}
+
+func parseASCIISTL(r io.Reader) (*Mesh3D, error) {
+	b := newBuilder()
+	err := scanASCIISTL(r, func(a, c, d spatialmath.Vec3) error {
+		b.triangle(a, c, d)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b.mesh, nil
+}
+
+// ScanSTL reads a binary or ASCII STL file from r one triangle at a time,
+// calling fn with the vertices of each, so that memory use does not grow
+// with the file. size is the length of the file, or -1 if it is unknown;
+// the format is detected like ReadSTL's when it is known, and from the
+// header alone otherwise. An error from fn stops the scan and is returned.
+func ScanSTL(r io.Reader, size int64, fn func(a, b, c spatialmath.Vec3) error) error {
+	br := bufio.NewReaderSize(r, 1<<16)
+	header, err := br.Peek(84)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("error reading STL: %w", err)
+	}
+	if len(header) == 84 && size >= 0 {
+		n := binary.LittleEndian.Uint32(header[80:84])
+		if uint64(size) == 84+uint64(n)*stlTriangleSize {
+			return scanBinarySTL(br, fn)
+		}
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(header), []byte("solid")) {
+		return scanASCIISTL(br, fn)
+	}
+	return scanBinarySTL(br, fn)
+}
+
+func scanBinarySTL(r io.Reader, fn func(a, b, c spatialmath.Vec3) error) error {
+	var header [84]byte
+	if n, err := io.ReadFull(r, header[:]); err != nil {
+		return fmt.Errorf("binary STL too short: %d bytes", n)
+	}
+	n := int(binary.LittleEndian.Uint32(header[80:84]))
+	if n == 0 {
+		return fmt.Errorf("no triangles found in STL file")
+	}
+
+	// Records are read stlChunk at a time into one buffer, instead of one at a
+	// time.
+	buf := make([]byte, min(n, stlChunk)*stlTriangleSize)
+	for i := 0; i < n; {
+		k := min(n-i, stlChunk)
+		if got, err := io.ReadFull(r, buf[:k*stlTriangleSize]); err != nil {
+			return fmt.Errorf("binary STL truncated: header declares %d triangles but file holds %d", n, i+got/stlTriangleSize)
+		}
+		for rec := range slices.Chunk(buf[:k*stlTriangleSize], stlTriangleSize) {
+			// Skip the 12-byte normal; vertices follow, then a 2-byte attribute count.
+			var v [3]spatialmath.Vec3
+			for j := range v {
+				v[j] = spatialmath.Vec3{
+					X: float64(math.Float32frombits(binary.LittleEndian.Uint32(rec[12+j*12:]))),
+					Y: float64(math.Float32frombits(binary.LittleEndian.Uint32(rec[12+j*12+4:]))),
+					Z: float64(math.Float32frombits(binary.LittleEndian.Uint32(rec[12+j*12+8:]))),
+				}
+			}
+			if err := fn(v[0], v[1], v[2]); err != nil {
+				return err
+			}
+		}
+		i += k
+	}
+	return nil
+}
+
+func scanASCIISTL(r io.Reader, fn func(a, b, c spatialmath.Vec3) error) error {
+	scanner := bufio.NewScanner(r)
+	var current [3]spatialmath.Vec3
+	vertexIndex := 0
+	inFacet := false
+	lineNum := 0
+	triangles := 0
+
+	for scanner.Scan() {
+		lineNum++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "facet":
+			inFacet = true
+			vertexIndex = 0
+		case "vertex":
+			if !inFacet || len(fields) < 4 {
+				return fmt.Errorf("line %d: invalid vertex line", lineNum)
+			}
+			if vertexIndex >= 3 {
+				return fmt.Errorf("line %d: too many vertices in facet", lineNum)
+			}
+			var coords [3]float64
+			for i := range coords {
+				c, err := strconv.ParseFloat(fields[i+1], 64)
+				if err != nil {
+					return fmt.Errorf("line %d: invalid coordinate %q", lineNum, fields[i+1])
+				}
+				coords[i] = c
+			}
+			current[vertexIndex] = spatialmath.Vec3{X: coords[0], Y: coords[1], Z: coords[2]}
+			vertexIndex++
+		case "endfacet":
+			if vertexIndex != 3 {
+				return fmt.Errorf("line %d: incomplete triangle, got %d vertices", lineNum, vertexIndex)
+			}
+			if err := fn(current[0], current[1], current[2]); err != nil {
+				return err
+			}
+			triangles++
+			inFacet = false
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading STL: %w", err)
+	}
+	if triangles == 0 {
+		return fmt.Errorf("no triangles found in STL file")
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package mesh
+
+import (
+	"io"
+	"math"
+
+	"github.com/nfranczak/urdf-simplifier/spatialmath"
+)
+
+// extremeDirections are the 26 directions of a k-DOP: the axes, the face
+// diagonals and the body diagonals.
+var extremeDirections = func() []spatialmath.Vec3 {
+	var dirs []spatialmath.Vec3
+	for x := -1.0; x <= 1; x++ {
+		for y := -1.0; y <= 1; y++ {
+			for z := -1.0; z <= 1; z++ {
+				if x != 0 || y != 0 || z != 0 {
+					dirs = append(dirs, spatialmath.Vec3{X: x, Y: y, Z: z})
+				}
+			}
+		}
+	}
+	return dirs
+}()
+
+// StreamBounds is what BoundSTL accumulates from a mesh without keeping it
+// in memory.
+type StreamBounds struct {
+	Box       AABB
+	Triangles int
+	// Points holds, if requested, the vertex furthest along each of 26
+	// fixed directions. They are vertices of the convex hull, so the hull of
+	// Points is a conservative approximation of the mesh's from the inside.
+	Points []spatialmath.Vec3
+}
+
+// BoundSTL reads an STL file from r like ScanSTL and returns its bounding
+// box and triangle count, and its extreme points if hull is set, in memory
+// independent of the size of the file.
+func BoundSTL(r io.Reader, size int64, hull bool) (*StreamBounds, error) {
+	s := &StreamBounds{Box: emptyAABB()}
+	var best []float64
+	if hull {
+		s.Points = make([]spatialmath.Vec3, len(extremeDirections))
+		best = make([]float64, len(extremeDirections))
+		for i := range best {
+			best[i] = math.Inf(-1)
+		}
+	}
+	// Vertices are bounded a chunk at a time, which is faster than one at a
+	// time.
+	chunk := make([]spatialmath.Vec3, 0, 3*stlChunk)
+	err := ScanSTL(r, size, func(a, b, c spatialmath.Vec3) error {
+		s.Triangles++
+		chunk = append(chunk, a, b, c)
+		if len(chunk) == cap(chunk) {
+			s.Box.extendAll(chunk)
+			chunk = chunk[:0]
+		}
+		for _, v := range [3]spatialmath.Vec3{a, b, c} {
+			for i, d := range best {
+				if p := v.Dot(extremeDirections[i]); p > d {
+					best[i], s.Points[i] = p, v
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.Box.extendAll(chunk)
+	if hull {
+		s.Points = uniquePoints(s.Points)
+	}
+	return s, nil
+}
+
+// uniquePoints returns points without repeats, in their first order.
+func uniquePoints(points []spatialmath.Vec3) []spatialmath.Vec3 {
+	seen := make(map[spatialmath.Vec3]bool, len(points))
+	unique := points[:0]
+	for _, p := range points {
+		if !seen[p] {
+			seen[p] = true
+			unique = append(unique, p)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// profileFlags holds the flags that write Go profiles of a run, for use with
+// go tool pprof.
+type profileFlags struct {
+	cpu string
+	mem string
+}
+
+func (f *profileFlags) register(fs *flagSet) {
+	fs.StringVar(&f.cpu, "", "cpuprofile", "", "write a CPU profile of the run to this file")
+	fs.StringVar(&f.mem, "", "memprofile", "", "write a heap profile to this file when the run ends")
+}
+
+// start begins CPU profiling if it was asked for. The returned function
+// stops it and writes the heap profile, logging rather than returning
+// failures so that it can be deferred.
+func (f *profileFlags) start(logger *slog.Logger) (func(), error) {
+	var cpu *os.File
+	if f.cpu != "" {
+		var err error
+		if cpu, err = os.Create(f.cpu); err != nil {
+			return nil, fmt.Errorf("error creating CPU profile: %w", err)
+		}
+		if err := pprof.StartCPUProfile(cpu); err != nil {
+			cpu.Close()
+			return nil, fmt.Errorf("error starting CPU profile: %w", err)
+		}
+	}
+	return func() {
+		if cpu != nil {
+			pprof.StopCPUProfile()
+			if err := cpu.Close(); err != nil {
+				logger.Error("error writing CPU profile", "error", err)
+			}
+		}
+		if f.mem != "" {
+			if err := writeHeapProfile(f.mem); err != nil {
+				logger.Error("error writing heap profile", "error", err)
+			}
+		}
+	}, nil
+}
+
+func writeHeapProfile(path string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	// Collect first, so the profile shows what is still live.
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(out); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
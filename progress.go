@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/nfranczak/urdf-simplifier/urdf"
+)
+
+// statusLine is a transient line at the bottom of the terminal, such as a
+// progress bar. Everything written through it appears above the line.
+type statusLine struct {
+	f    *os.File
+	mu   sync.Mutex
+	text string
+}
+
+// set replaces the status line with text, cut to the terminal width so that
+// it never wraps. An empty text removes the line.
+func (s *statusLine) set(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if width, _, err := term.GetSize(int(s.f.Fd())); err == nil && width > 0 && len(text) >= width {
+		text = text[:width-1]
+	}
+	s.text = text
+	fmt.Fprint(s.f, "\r\x1b[K"+text)
+}
+
+func (s *statusLine) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.text != "" {
+		io.WriteString(s.f, "\r\x1b[K")
+	}
+	n, err := s.f.Write(p)
+	if s.text != "" {
+		io.WriteString(s.f, s.text)
+	}
+	return n, err
+}
+
+// progress returns an Options.Progress callback that draws a progress bar on
+// the status line, or nil if stderr is not a terminal or output is quiet.
+func (f *logFlags) progress() func(urdf.Progress) {
+	if f.status == nil || f.quiet {
+		return nil
+	}
+	return func(p urdf.Progress) {
+		f.status.set(progressBar(p, 30))
+	}
+}
+
+// clearProgress removes the progress bar, if one is shown.
+func (f *logFlags) clearProgress() {
+	if f.status != nil && !f.quiet {
+		f.status.set("")
+	}
+}
+
+// progressBar renders p as a bar width characters wide, followed by the count
+// and the time the last mesh took.
+func progressBar(p urdf.Progress, width int) string {
+	filled := width
+	if p.Total > 0 {
+		filled = width * p.Done / p.Total
+	}
+	return fmt.Sprintf("[%s%s] %d/%d %s (%s)", strings.Repeat("=", filled), strings.Repeat(" ", width-filled),
+		p.Done, p.Total, path.Base(p.Mesh), roundDuration(p.Elapsed))
+}
+
+// roundDuration formats d rounded to microseconds below a millisecond and to
+// milliseconds above.
+func roundDuration(d time.Duration) string {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
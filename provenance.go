@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// provenancePattern matches the comment stampProvenance adds, with the line
+// ending after it.
+var provenancePattern = regexp.MustCompile(`(?s)<!--\r?\n  Generated by urdf-simplifier .*?-->\r?\n`)
+
+// runFlags lists the simplify flags that change how a run goes, what it
+// reports, or which other files it writes, but not the URDF it generates.
+// The provenance comment leaves them out, so that they don't change its
+// bytes either.
+var runFlags = map[string]bool{
+	"check": true, "dry-run": true, "watch": true, "strict": true, "round-trip": true,
+	"in-dir": true, "out-dir": true, "out": true, "force": true, "in-place": true, "backup": true,
+	"srdf": true, "srdf-samples": true, "viam-frames": true, "viam-kinematics": true,
+	"viam-geometries": true, "viam-geometry-frame": true, "viam-orientation": true,
+	"emit-go": true, "emit-go-package": true, "name-map": true, "no-provenance": true,
+	"verify-against": true, "verify-tolerance": true,
+	"max-volume-ratio": true, "no-collision-check": true, "sweep": true, "workspace": true,
+	"jobs": true, "cache-dir": true, "cpuprofile": true, "memprofile": true,
+	"quiet": true, "verbose": true, "vv": true, "log-format": true, "no-color": true,
+}
+
+// provenance returns the comment stamped into a URDF generated from input,
+// read from inputPath, by a run with the given options, the flags set as
+// name=value, of which those in runFlags are left out.
+//
+// The comment holds no time unless SOURCE_DATE_EPOCH gives one, as for
+// reproducible builds: a time of generation would make every run's output
+// differ, which --check and committed outputs cannot have.
+func provenance(input []byte, inputPath string, options []string) string {
+	at := ""
+	if epoch, err := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10, 64); err == nil {
+		at = "\n  at " + time.Unix(epoch, 0).UTC().Format(time.RFC3339)
+	}
+	var kept []string
+	for _, option := range options {
+		if name, _, _ := strings.Cut(option, "="); !runFlags[name] {
+			kept = append(kept, option)
+		}
+	}
+	opts := "none"
+	if len(kept) > 0 {
+		opts = strings.Join(kept, " ")
+	}
+	// A comment cannot contain "--".
+	opts = strings.ReplaceAll(opts, "--", "- -")
+	return fmt.Sprintf("<!--\n  Generated by urdf-simplifier %s%s\n  from %s, sha256 %x\n  options: %s\n-->\n",
+		toolVersion(), at, strings.ReplaceAll(displayPath(inputPath), "--", "- -"), sha256.Sum256(input), opts)
+}
+
+// toolVersion returns the module version the binary was built from, or for
+// a build from a checkout, the commit, marked if the tree was modified.
+func toolVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(unknown version)"
+	}
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+	var revision string
+	modified := false
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+	if revision == "" {
+		return "(devel)"
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if modified {
+		revision += "-dirty"
+	}
+	return "(devel " + revision + ")"
+}
+
+// stampProvenance inserts comment into the URDF document out, after its XML
+// declaration if it has one, with out's own line endings.
+func stampProvenance(out []byte, comment string) []byte {
+	if bytes.Contains(out, []byte("\r\n")) {
+		comment = strings.ReplaceAll(comment, "\n", "\r\n")
+	}
+	at := 0
+	if bytes.HasPrefix(out, []byte("<?xml")) {
+		if i := bytes.IndexByte(out, '\n'); i >= 0 {
+			at = i + 1
+		}
+	}
+	stamped := make([]byte, 0, len(out)+len(comment))
+	stamped = append(stamped, out[:at]...)
+	stamped = append(stamped, comment...)
+	return append(stamped, out[at:]...)
+}
+
+// stripProvenance returns data without the comment stampProvenance added,
+// so that files generated at different times compare equal.
+func stripProvenance(data []byte) []byte {
+	if loc := provenancePattern.FindIndex(data); loc != nil {
+		return append(data[:loc[0]:loc[0]], data[loc[1]:]...)
+	}
+	return data
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/nfranczak/urdf-simplifier/urdf"
+)
+
+// printReport writes a human-readable summary of a simplification report:
+// one section per link whose collision meshes were processed, the number of
+// boxes reused for duplicate meshes, the collision volume before and after,
+// then the removed links and joints, a summary of the changes by category,
+// then any warnings.
+func printReport(w io.Writer, report *urdf.Report, p palette) {
+	fmt.Fprintf(w, "%s: %d links, %d joints after simplification\n",
+		p.bold("Robot "+report.Robot), report.Links, report.Joints)
+	if report.Name != report.Robot {
+		fmt.Fprintf(w, "  renamed to %s\n", report.Name)
+	}
+
+	removed := make(map[string]bool)
+	for _, link := range report.RemovedLinks {
+		removed[link] = true
+	}
+
+	// Group meshes by link, in the order the links were processed.
+	var links []string
+	replaced := make(map[string][]urdf.MeshReport)
+	failed := make(map[string][]urdf.FailedMesh)
+	width := 0
+	for _, m := range report.Meshes {
+		if replaced[m.Link] == nil {
+			links = append(links, m.Link)
+		}
+		replaced[m.Link] = append(replaced[m.Link], m)
+		width = max(width, len(path.Base(m.Mesh)))
+	}
+	for _, f := range report.FailedMeshes {
+		if replaced[f.Link] == nil && failed[f.Link] == nil {
+			links = append(links, f.Link)
+		}
+		failed[f.Link] = append(failed[f.Link], f)
+		width = max(width, len(path.Base(f.Mesh)))
+	}
+
+	volumes := make(map[string]urdf.LinkVolume)
+	if v := report.CollisionVolume; v != nil {
+		for _, l := range v.Links {
+			volumes[l.Link] = l
+		}
+	}
+
+	for _, link := range links {
+		header := p.bold(link)
+		if removed[link] {
+			header += " " + p.red("(removed)")
+		}
+		fmt.Fprintf(w, "\n%s\n", header)
+		for _, m := range replaced[link] {
+			if c := m.Capsule; c != nil {
+				fmt.Fprintf(w, "  %-*s  %s r %.5f, %.5f along %s at (%.5f, %.5f, %.5f)\n",
+					width, path.Base(m.Mesh), p.green("capsule"), c.Radius, c.Length, c.Axis, c.Center[0], c.Center[1], c.Center[2])
+				continue
+			}
+			fmt.Fprintf(w, "  %-*s  %s %.5f x %.5f x %.5f at (%.5f, %.5f, %.5f)\n",
+				width, path.Base(m.Mesh), p.green("box"), m.Size[0], m.Size[1], m.Size[2], m.Center[0], m.Center[1], m.Center[2])
+		}
+		for _, f := range failed[link] {
+			fmt.Fprintf(w, "  %-*s  %s\n", width, path.Base(f.Mesh), p.yellow("kept: could not be read"))
+		}
+		if v, ok := volumes[link]; ok {
+			fmt.Fprintf(w, "  %-*s  %s\n", width, "volume", describeVolume(v.Mesh, v.Box))
+		}
+	}
+
+	if report.DuplicateMeshes > 0 {
+		fmt.Fprintf(w, "\nReused %d bounding box(es) for duplicate meshes\n", report.DuplicateMeshes)
+	}
+	if v := report.CollisionVolume; v != nil && v.Mesh > 0 {
+		fmt.Fprintf(w, "\nCollision volume: %s\n", describeVolume(v.Mesh, v.Box))
+		if v.Open > 0 {
+			fmt.Fprintf(w, "  not counting %d open mesh(es), which enclose no volume\n", v.Open)
+		}
+	}
+	if len(report.Inertias) > 0 {
+		fmt.Fprintf(w, "\n%s\n", p.bold(fmt.Sprintf("Inertia recomputed (%d):", len(report.Inertias))))
+		for _, in := range report.Inertias {
+			fmt.Fprintf(w, "  %s  %g kg from %s, diagonal %.3g %.3g %.3g at (%.5f, %.5f, %.5f)\n",
+				in.Link, in.Mass, in.Source, in.Inertia[0], in.Inertia[3], in.Inertia[5], in.Center[0], in.Center[1], in.Center[2])
+		}
+	}
+
+	if len(report.FixedInertias) > 0 {
+		fmt.Fprintf(w, "\n%s\n", p.bold(fmt.Sprintf("Inertia fixed (%d):", len(report.FixedInertias))))
+		for _, f := range report.FixedInertias {
+			fmt.Fprintf(w, "  %s  %s\n", f.Link, f.Problem)
+		}
+	}
+	if len(report.LumpedMasses) > 0 {
+		fmt.Fprintf(w, "\n%s\n", p.bold(fmt.Sprintf("Mass moved from removed links (%d):", len(report.LumpedMasses))))
+		for _, m := range report.LumpedMasses {
+			fmt.Fprintf(w, "  %s -> %s  %g kg\n", m.From, m.Into, m.Mass)
+		}
+	}
+
+	if report.PayloadLink != "" {
+		fmt.Fprintf(w, "\nPayload added to %s\n", p.bold(report.PayloadLink))
+	}
+	printMasses(w, report.MassBefore, report.MassAfter, p)
+	if ws := report.Workspace; ws != nil {
+		fmt.Fprintf(w, "\n%s\n", p.bold(fmt.Sprintf("Workspace of %s in %s (%d samples):", ws.Link, ws.Frame, ws.Samples)))
+		fmt.Fprintf(w, "  reach   %.3f m\n", ws.Reach)
+		fmt.Fprintf(w, "  bounds  (%.3f, %.3f, %.3f) to (%.3f, %.3f, %.3f), %.4f m³\n",
+			ws.Min[0], ws.Min[1], ws.Min[2], ws.Max[0], ws.Max[1], ws.Max[2], ws.Volume())
+		deviation := fmt.Sprintf("%.3f mm from the original", ws.Deviation*1000)
+		if ws.Deviation > 1e-6 {
+			deviation = p.yellow(deviation)
+		}
+		fmt.Fprintf(w, "  moved   %s\n", deviation)
+	}
+
+	printList(w, "Links removed", report.RemovedLinks, p.red)
+	printList(w, "Joints removed", report.RemovedJoints, p.red)
+	printList(w, "Joint limits filled in", report.FilledLimits, p.green)
+	printChanges(w, report.Changes, p)
+	printList(w, "Warnings", report.Warnings, p.yellow)
+	printList(w, "Errors", report.Errors, p.red)
+}
+
+// printMasses lists the total and per-link masses and centers of mass before
+// and after simplification, if the simplified robot has any mass: when
+// inertials are removed, the whole mass goes and there is nothing to compare.
+func printMasses(w io.Writer, before, after *urdf.MassReport, p palette) {
+	if before == nil || after == nil || after.Total == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s\n", p.bold(fmt.Sprintf("Mass before -> after (centers of mass in %s at the zero pose):", after.Frame)))
+	describe := func(m float64, c [3]float64) string {
+		return fmt.Sprintf("%g kg at (%.5f, %.5f, %.5f)", m, c[0], c[1], c[2])
+	}
+
+	var names []string
+	masses := make(map[string][2]*urdf.LinkMass)
+	for side, r := range []*urdf.MassReport{before, after} {
+		for i := range r.Links {
+			name := r.Links[i].Link
+			if _, ok := masses[name]; !ok {
+				names = append(names, name)
+			}
+			m := masses[name]
+			m[side] = &r.Links[i]
+			masses[name] = m
+		}
+	}
+	width := len("total")
+	for _, name := range names {
+		width = max(width, len(name))
+	}
+
+	line := func(name, from, to string, changed bool) {
+		if changed {
+			to = p.yellow(to)
+		}
+		fmt.Fprintf(w, "  %-*s  %s -> %s\n", width, name, from, to)
+	}
+	from, to := describe(before.Total, before.Center), describe(after.Total, after.Center)
+	line("total", from, to, from != to)
+	for _, name := range names {
+		from, to := "none", "none"
+		if m := masses[name][0]; m != nil {
+			from = describe(m.Mass, m.Center)
+		}
+		if m := masses[name][1]; m != nil {
+			to = describe(m.Mass, m.Center)
+		}
+		line(name, from, to, from != to)
+	}
+}
+
+// printChanges writes the non-zero counts of changes, one category per line.
+func printChanges(w io.Writer, c urdf.Changes, p palette) {
+	type category struct {
+		name  string
+		count int
+		items []string
+	}
+	categories := []category{
+		{"visuals removed", c.VisualsRemoved, nil},
+		{"visuals added from collisions", c.VisualsAdded, nil},
+		{"inertials removed", c.InertialsRemoved, nil},
+		{"meshes replaced with boxes", c.MeshesReplaced, nil},
+		{"collisions removed", c.CollisionsRemoved, nil},
+		{"duplicate collisions merged", c.CollisionsMerged, nil},
+		{"links removed", c.LinksRemoved, nil},
+		{"fixed joints removed", len(c.FixedJointsRemoved), c.FixedJointsRemoved},
+		{"moving joints removed", len(c.MovingJointsRemoved), c.MovingJointsRemoved},
+		{"joint limits overridden", len(c.LimitsOverridden), c.LimitsOverridden},
+		{"joints locked", len(c.JointsLocked), c.JointsLocked},
+		{"joint dynamics changed", c.DynamicsChanged, nil},
+		{"origins and axes snapped", c.ValuesSnapped, nil},
+		{"rpy angles normalized", c.RPYNormalized, nil},
+		{"redundant attributes and elements removed", c.RedundantRemoved, nil},
+		{"inertials changed", c.InertiasChanged, nil},
+	}
+	width := 0
+	for _, cat := range categories {
+		width = max(width, len(cat.name))
+	}
+	var lines []string
+	for _, cat := range categories {
+		if cat.count == 0 {
+			continue
+		}
+		line := fmt.Sprintf("  %-*s  %d", width, cat.name, cat.count)
+		if len(cat.items) > 0 {
+			line += ": " + strings.Join(cat.items, ", ")
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s\n%s\n", p.bold("Summary:"), strings.Join(lines, "\n"))
+}
+
+// describeVolume compares the volume of meshes to that of the boxes that
+// replaced them.
+func describeVolume(mesh, box float64) string {
+	return fmt.Sprintf("%.3g m³ of mesh -> %.3g m³ of boxes (%.2fx)", mesh, box, box/mesh)
+}
+
+func printList(w io.Writer, title string, items []string, color func(string) string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s\n", color(fmt.Sprintf("%s (%d):", title, len(items))))
+	fmt.Fprintf(w, "  %s\n", strings.Join(items, "\n  "))
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nfranczak/urdf-simplifier/urdf"
+)
+
+func runScene(args []string) int {
+	var specPath string
+	var force bool
+	var sf simplifyFlags
+	var lf logFlags
+	fs := newFlagSet("scene", "urdf-simplifier scene [flags] [--scene <scene.yaml>] <input.urdf> <output.json>",
+		"Simplifies input.urdf and writes it, with the obstacles and the start and goal\n"+
+			"joint positions of scene.yaml, as a motion-planning scene in JSON. Without a\n"+
+			"goal the scene gets a random collision-free one, seeded by the file's seed.")
+	fs.StringVar(&specPath, "s", "scene", "", "YAML file with the obstacle boxes and the start and goal joint positions")
+	fs.BoolVar(&force, "f", "force", false, "overwrite the output file if it already exists")
+	sf.register(fs)
+	lf.register(fs)
+	positional := fs.parseOrExit(args)
+	if len(positional) != 2 {
+		fs.printUsage(os.Stderr)
+		return exitUsage
+	}
+	inputPath, outputPath := positional[0], positional[1]
+
+	logger, err := lf.logger()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	cfg, err := sf.load(fs)
+	if err != nil {
+		logger.Error(err.Error())
+		return exitUsage
+	}
+	var spec urdf.SceneSpec
+	if specPath != "" {
+		if spec, err = loadSceneSpec(specPath); err != nil {
+			logger.Error(err.Error())
+			return exitUsage
+		}
+	}
+	if outputPath != "-" && !force {
+		if _, err := os.Stat(outputPath); err == nil {
+			logger.Error(fmt.Sprintf("%s already exists; use --force to overwrite it", outputPath))
+			return exitFailure
+		}
+	}
+
+	robot, err := loadRobot(inputPath, cfg.PackageMap, cfg.xacroInput())
+	if err != nil {
+		logger.Error(err.Error())
+		return exitCode(err)
+	}
+	opts := cfg.Options
+	opts.Logger = logger
+	opts.Progress = lf.progress()
+	report := urdf.Simplify(robot, meshResolver(inputPath, cfg.PackageMap), opts)
+	lf.clearProgress()
+	if len(report.Errors) > 0 {
+		logger.Error(fmt.Sprintf("the simplified robot is invalid: %s", strings.Join(report.Errors, "; ")))
+		return exitInvalid
+	}
+
+	scene, warnings, err := urdf.NewScene(robot, spec)
+	if err != nil {
+		logger.Error(fmt.Sprintf("building the scene: %v", err))
+		return exitFailure
+	}
+	for _, w := range warnings {
+		logger.Warn(w)
+	}
+
+	var buf bytes.Buffer
+	if err := urdf.WriteScene(&buf, scene); err != nil {
+		logger.Error(fmt.Sprintf("generating scene: %v", err))
+		return exitFailure
+	}
+	if err := writeOutput(outputPath, buf.Bytes()); err != nil {
+		logger.Error(fmt.Sprintf("writing scene: %v", err))
+		return exitFailure
+	}
+	logger.Info("wrote planning scene", "input", displayPath(inputPath), "output", displayOutputPath(outputPath), "obstacles", len(scene.Obstacles))
+	return exitOK
+}
+
+// loadSceneSpec reads the scene file at path. Unknown keys are rejected, as
+// in config files.
+func loadSceneSpec(path string) (urdf.SceneSpec, error) {
+	var spec urdf.SceneSpec
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return spec, fmt.Errorf("reading scene: %w", err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&spec); err != nil && !errors.Is(err, io.EOF) {
+		return spec, fmt.Errorf("scene %s: %w", path, err)
+	}
+	return spec, nil
+}
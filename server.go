@@ -0,0 +1,199 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nfranczak/urdf-simplifier/urdf"
+)
+
+// maxUploadSize limits the size of a single multipart request.
+const maxUploadSize = 256 << 20
+
+// The server's timeouts allow a maximum-size upload over a slow link and
+// the simplification of a large robot, but not a client that stalls.
+const (
+	readHeaderTimeout = 10 * time.Second
+	readTimeout       = 5 * time.Minute
+	writeTimeout      = 10 * time.Minute
+	idleTimeout       = 2 * time.Minute
+)
+
+// maxWorkspaceSamples and maxSweepJoints bound the checks an uploaded
+// config can ask for, whose cost grows with the samples and, for the
+// sweep, with the joints times the pairs of links.
+const (
+	maxWorkspaceSamples = 100000
+	maxSweepJoints      = 64
+)
+
+// simplifyResponse is the JSON body returned by the /simplify endpoint.
+type simplifyResponse struct {
+	URDF   string       `json:"urdf"`
+	Report *urdf.Report `json:"report"`
+}
+
+func runServe(args []string) int {
+	var addr string
+	var lf logFlags
+	fs := newFlagSet("serve", "urdf-simplifier serve [flags] [addr]",
+		"Runs an HTTP server. POST /simplify with a multipart \"urdf\" file and an\n"+
+			"optional \"meshes\" zip archive to receive the simplified URDF and a JSON report.")
+	fs.StringVar(&addr, "a", "addr", ":8080", "address to listen on")
+	lf.register(fs)
+	positional := fs.parseOrExit(args)
+	if len(positional) > 0 {
+		addr = positional[0]
+	}
+
+	logger, err := lf.logger()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+
+	if err := serve(addr, logger); err != nil {
+		logger.Error("running server", "error", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+// serve starts the HTTP server on addr.
+//
+// POST /simplify accepts a multipart form with a required "urdf" file field,
+// an optional "meshes" zip archive, and an optional "config" YAML file using
+// the same schema as --config. Mesh references in the URDF are resolved
+// inside the archive the same way they are resolved against the URDF's
+// directory on the command line.
+func serve(addr string, logger *slog.Logger) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/simplify", func(w http.ResponseWriter, r *http.Request) {
+		handleSimplify(w, r, logger)
+	})
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+	logger.Info("listening", "addr", addr)
+	return server.ListenAndServe()
+}
+
+func handleSimplify(w http.ResponseWriter, r *http.Request, logger *slog.Logger) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		httpError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		httpError(w, http.StatusBadRequest, fmt.Sprintf("invalid multipart form: %v", err))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	urdfFile, _, err := r.FormFile("urdf")
+	if err != nil {
+		httpError(w, http.StatusBadRequest, "missing \"urdf\" file field")
+		return
+	}
+	defer urdfFile.Close()
+
+	robot, err := urdf.ParseURDF(urdfFile)
+	if err != nil {
+		httpError(w, http.StatusUnprocessableEntity, fmt.Sprintf("error parsing URDF: %v", err))
+		return
+	}
+
+	// Mesh references are resolved against the uploaded archive, if any. With
+	// no archive every mesh lookup fails and is reported as a warning.
+	var meshes fs.FS = emptyFS{}
+	if meshFile, header, err := r.FormFile("meshes"); err == nil {
+		defer meshFile.Close()
+		archive, err := zip.NewReader(meshFile, header.Size)
+		if err != nil {
+			httpError(w, http.StatusBadRequest, fmt.Sprintf("error reading meshes archive: %v", err))
+			return
+		}
+		meshes = archive
+	}
+
+	var opts urdf.Options
+	if configFile, _, err := r.FormFile("config"); err == nil {
+		defer configFile.Close()
+		cfg, err := decodeConfig(configFile, "")
+		if err != nil {
+			httpError(w, http.StatusBadRequest, fmt.Sprintf("invalid config: %v", err))
+			return
+		}
+		// The other sections configure reading files and validating on the
+		// command line, and would be silently ignored here.
+		for _, s := range []struct {
+			name string
+			set  bool
+		}{
+			{"package_map", len(cfg.PackageMap) > 0},
+			{"xacro", cfg.Xacro != ""},
+			{"xacro_args", len(cfg.XacroArgs) > 0},
+			{"lint", len(cfg.Lint) > 0},
+		} {
+			if s.set {
+				httpError(w, http.StatusBadRequest, fmt.Sprintf("invalid config: the %s section only applies on the command line", s.name))
+				return
+			}
+		}
+		if cfg.WorkspaceSamples > maxWorkspaceSamples {
+			httpError(w, http.StatusBadRequest, fmt.Sprintf("invalid config: workspace_samples %d is more than the server allows (%d)", cfg.WorkspaceSamples, maxWorkspaceSamples))
+			return
+		}
+		if cfg.SweepJoints && len(robot.Joints) > maxSweepJoints {
+			httpError(w, http.StatusBadRequest, fmt.Sprintf("invalid config: sweep_joints is only allowed for robots of up to %d joints, not %d", maxSweepJoints, len(robot.Joints)))
+			return
+		}
+		opts = cfg.Options
+	}
+
+	opts.Logger = logger.With("robot", robot.Name)
+	report := urdf.Simplify(robot, urdf.FSResolver{FS: meshes}, opts)
+	if len(report.Errors) > 0 {
+		httpError(w, http.StatusUnprocessableEntity, fmt.Sprintf("the simplified robot is invalid: %s", strings.Join(report.Errors, "; ")))
+		return
+	}
+
+	var output bytes.Buffer
+	if err := urdf.WriteURDF(&output, robot); err != nil {
+		httpError(w, http.StatusInternalServerError, fmt.Sprintf("error generating output XML: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(simplifyResponse{URDF: output.String(), Report: report})
+}
+
+// emptyFS is a file system without files, for requests without a meshes
+// archive.
+type emptyFS struct{}
+
+func (emptyFS) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func httpError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const serverURDF = `<robot name="arm">
+  <link name="base"/><link name="l1"/>
+  <joint name="j1" type="revolute"><parent link="base"/><child link="l1"/><axis xyz="0 0 1"/>
+    <limit lower="-1" upper="1" effort="10" velocity="1"/></joint>
+</robot>`
+
+// postSimplify posts the multipart fields to handleSimplify and returns the
+// response.
+func postSimplify(t *testing.T, fields map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, data := range fields {
+		fw, err := mw.CreateFormFile(name, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(data))
+	}
+	mw.Close()
+	r := httptest.NewRequest(http.MethodPost, "/simplify", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	handleSimplify(w, r, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	return w
+}
+
+func TestHandleSimplify(t *testing.T) {
+	w := postSimplify(t, map[string]string{"urdf": serverURDF, "config": "name: small\nworkspace_samples: 10\n"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var resp simplifyResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(resp.URDF, `<robot name="small">`) || resp.Report.Name != "small" {
+		t.Errorf("response %s, want the robot named small", resp.URDF)
+	}
+}
+
+func TestHandleSimplifyErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		fields map[string]string
+		status int
+		want   string
+	}{
+		{"no urdf", map[string]string{"config": "name: x\n"}, http.StatusBadRequest, `missing \"urdf\"`},
+		{"bad urdf", map[string]string{"urdf": "<robot"}, http.StatusUnprocessableEntity, "error parsing URDF"},
+		{"bad meshes", map[string]string{"urdf": serverURDF, "meshes": "not a zip"}, http.StatusBadRequest, "meshes archive"},
+		{"unknown field", map[string]string{"urdf": serverURDF, "config": "nosuch: 1\n"}, http.StatusBadRequest, "invalid config"},
+		{"package map", map[string]string{"urdf": serverURDF, "config": "package_map:\n  arm: /tmp\n"}, http.StatusBadRequest, "the package_map section only applies on the command line"},
+		{"lint", map[string]string{"urdf": serverURDF, "config": "lint:\n  orphan-link: error\n"}, http.StatusBadRequest, "the lint section"},
+		{"workspace samples", map[string]string{"urdf": serverURDF, "config": "workspace_samples: 100000000\n"}, http.StatusBadRequest, "workspace_samples 100000000 is more than the server allows"},
+		{"sweep", map[string]string{"urdf": bigURDF(maxSweepJoints + 1), "config": "sweep_joints: true\n"}, http.StatusBadRequest, "sweep_joints is only allowed"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postSimplify(t, tt.fields)
+			if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("status %d: %s, want %d mentioning %s", w.Code, w.Body, tt.status, tt.want)
+			}
+		})
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/simplify", nil)
+	w := httptest.NewRecorder()
+	handleSimplify(w, r, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: status %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+// bigURDF returns a serial robot of n revolute joints.
+func bigURDF(n int) string {
+	var b strings.Builder
+	b.WriteString(`<robot name="big"><link name="l0"/>`)
+	for i := 1; i <= n; i++ {
+		b.WriteString(`<link name="l` + strconv.Itoa(i) + `"/><joint name="j` + strconv.Itoa(i) + `" type="revolute"><parent link="l` + strconv.Itoa(i-1) +
+			`"/><child link="l` + strconv.Itoa(i) + `"/><axis xyz="0 0 1"/><limit lower="-1" upper="1" effort="1" velocity="1"/></joint>`)
+	}
+	b.WriteString(`</robot>`)
+	return b.String()
+}
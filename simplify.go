@@ -0,0 +1,689 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/token"
+	"io"
+	"io/fs"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/nfranczak/urdf-simplifier/urdf"
+	"github.com/nfranczak/urdf-simplifier/xacro"
+)
+
+func runSimplify(args []string) int {
+	var check, dryRun, watch, strict, force, inPlace, backup, keepXacro, roundTrip, noProvenance bool
+	var inDir, outDir, outTemplate, srdfPath, verifyPath, viamFramesPath, viamKinematicsPath string
+	var viamGeometriesDir, viamGeometryFrame, viamOrientation, emitGoPath, emitGoPackage, nameMapPath string
+	var srdfSamples int
+	var verifyTolerance float64
+	var sf simplifyFlags
+	var lf logFlags
+	var pf profileFlags
+	var ff formatFlags
+	fs := newFlagSet("simplify", "urdf-simplifier simplify [flags] <input.urdf> <output.urdf>\n"+
+		"       urdf-simplifier simplify [flags] --in-dir <dir> --out-dir <dir>\n"+
+		"       urdf-simplifier simplify [flags] <pattern>... --out <template>\n"+
+		"       urdf-simplifier simplify [flags] --in-place <file.urdf>...",
+		"Replaces collision meshes with bounding boxes, removes visual and inertial\n"+
+			"elements, and keeps only the main kinematic chain.")
+	fs.BoolVar(&check, "c", "check", false, "exit non-zero if output.urdf is not up to date instead of writing it")
+	fs.BoolVar(&dryRun, "n", "dry-run", false, "do all processing and print the change report, but write nothing")
+	fs.BoolVar(&watch, "w", "watch", false, "re-run whenever the input or its meshes change")
+	fs.BoolVar(&strict, "", "strict", false, "fail instead of warning about unreadable meshes and unsupported elements")
+	fs.StringVar(&inDir, "", "in-dir", "", "simplify every .urdf file under this directory")
+	fs.StringVar(&outDir, "", "out-dir", "", "write batch results here, mirroring the --in-dir layout")
+	fs.StringVar(&outTemplate, "o", "out", "", "output path template for glob inputs, e.g. '{dir}/{name}_simplified.urdf'")
+	fs.BoolVar(&force, "f", "force", false, "overwrite output files that already exist")
+	fs.BoolVar(&inPlace, "i", "in-place", false, "replace each input file with its simplified version")
+	fs.BoolVar(&backup, "b", "backup", false, "before overwriting a file, keep the previous version as <file>.bak")
+	fs.BoolVar(&keepXacro, "", "keep-xacro", false, "write xacro input back out as xacro, keeping its top-level args, properties and the expressions that use them")
+	fs.BoolVar(&roundTrip, "", "round-trip", false, "fail if the output does not parse back to exactly the simplified robot")
+	fs.StringVar(&srdfPath, "", "srdf", "", "also write a MoveIt SRDF disabling collision checks between links that are adjacent, or always or never in collision")
+	fs.IntVar(&srdfSamples, "", "srdf-samples", 10000, "random configurations to sample for --srdf")
+	fs.StringVar(&viamFramesPath, "", "viam-frames", "", "also write a Viam machine config frame, with its geometry, for each simplified link as JSON")
+	fs.StringVar(&viamKinematicsPath, "", "viam-kinematics", "", "also write the simplified robot as a Viam arm kinematics JSON file")
+	fs.StringVar(&viamGeometriesDir, "", "viam-geometries", "", "also write each link's collision geometry as Viam spatialmath JSON, one <link>.json per link in this directory")
+	fs.StringVar(&viamGeometryFrame, "", "viam-geometry-frame", "root", "frame for --viam-geometries: root (the root link's, at the zero pose) or link (each link's own)")
+	fs.StringVar(&viamOrientation, "", "viam-orientation", "euler_angles", "orientation type of the Viam exports: euler_angles, ov_degrees, ov_radians, or quaternion")
+	fs.StringVar(&emitGoPath, "", "emit-go", "", "also write the simplified robot as typed data in this Go source file")
+	fs.StringVar(&emitGoPackage, "", "emit-go-package", "", "package of the --emit-go file (default: its directory's name, or model)")
+	fs.StringVar(&nameMapPath, "", "name-map", "", "also write the links and joints renamed by --canonical-names or --link-names as JSON, from old names to new")
+	ff.register(fs)
+	fs.BoolVar(&noProvenance, "", "no-provenance", false, "leave out the comment recording the tool version, options and input checksum (and the time, if SOURCE_DATE_EPOCH is set)")
+	fs.StringVar(&verifyPath, "", "verify-against", "", "fail unless the simplified robot's end link follows this URDF's at sampled joint positions")
+	fs.Float64Var(&verifyTolerance, "", "verify-tolerance", 1e-6, "largest distance in meters, and angle in radians, allowed by --verify-against")
+	sf.register(fs)
+	lf.register(fs)
+	pf.register(fs)
+	positional := fs.parseOrExit(args)
+
+	batch := inDir != "" || outDir != ""
+	templated := outTemplate != ""
+	if batch && (templated || inDir == "" || outDir == "" || len(positional) != 0) {
+		fs.printUsage(os.Stderr)
+		return exitUsage
+	}
+	if templated && len(positional) == 0 {
+		fs.printUsage(os.Stderr)
+		return exitUsage
+	}
+	if inPlace && (batch || templated || len(positional) == 0) {
+		fs.printUsage(os.Stderr)
+		return exitUsage
+	}
+	if !batch && !templated && !inPlace && len(positional) != 2 {
+		fs.printUsage(os.Stderr)
+		return exitUsage
+	}
+
+	logger, err := lf.logger()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+
+	if check && dryRun {
+		logger.Error("--check and --dry-run cannot be combined")
+		return exitUsage
+	}
+	if watch && (check || batch || templated || inPlace || positional[0] == "-") {
+		logger.Error("--watch needs a single input file and cannot be combined with --check, --in-place, or batch modes")
+		return exitUsage
+	}
+	if srdfPath != "" && (batch || templated || inPlace) {
+		logger.Error("--srdf needs a single input file and cannot be combined with --in-place or batch modes")
+		return exitUsage
+	}
+	if viamGeometryFrame != "root" && viamGeometryFrame != "link" {
+		logger.Error(fmt.Sprintf("invalid --viam-geometry-frame %q (want root or link)", viamGeometryFrame))
+		return exitUsage
+	}
+	viam := urdf.ViamOptions{Orientation: urdf.ViamOrientationType(viamOrientation)}
+	if err := viam.Validate(); err != nil {
+		logger.Error(err.Error())
+		return exitUsage
+	}
+	for _, side := range []struct{ flag, path string }{
+		{"--viam-frames", viamFramesPath},
+		{"--viam-kinematics", viamKinematicsPath},
+		{"--viam-geometries", viamGeometriesDir},
+		{"--emit-go", emitGoPath},
+		{"--name-map", nameMapPath},
+	} {
+		if side.path != "" && (batch || templated || inPlace) {
+			logger.Error(side.flag + " needs a single input file and cannot be combined with --in-place or batch modes")
+			return exitUsage
+		}
+	}
+	if emitGoPath != "" && emitGoPackage == "" {
+		emitGoPackage = goPackageName(emitGoPath)
+	}
+	if emitGoPackage != "" && !token.IsIdentifier(emitGoPackage) {
+		logger.Error(fmt.Sprintf("invalid --emit-go-package %q (want a Go identifier)", emitGoPackage))
+		return exitUsage
+	}
+	if err := ff.parse(fs); err != nil {
+		logger.Error(err.Error())
+		return exitUsage
+	}
+	if verifyPath != "" && (batch || templated || inPlace) {
+		logger.Error("--verify-against needs a single input file and cannot be combined with --in-place or batch modes")
+		return exitUsage
+	}
+	if verifyTolerance < 0 {
+		logger.Error(fmt.Sprintf("invalid --verify-tolerance %g (want a positive number)", verifyTolerance))
+		return exitUsage
+	}
+	if srdfSamples <= 0 {
+		logger.Error(fmt.Sprintf("invalid --srdf-samples %d (want a positive number)", srdfSamples))
+		return exitUsage
+	}
+	if inPlace && slices.Contains(positional, "-") {
+		logger.Error("--in-place needs input files, not stdin")
+		return exitUsage
+	}
+
+	cfg, err := sf.load(fs)
+	if err != nil {
+		logger.Error(err.Error())
+		return exitUsage
+	}
+	if nameMapPath != "" && !cfg.CanonicalNames && cfg.LinkNames == "" {
+		logger.Error("--name-map needs --canonical-names or --link-names (canonical_names or link_names in a config file)")
+		return exitUsage
+	}
+
+	stopProfiles, err := pf.start(logger)
+	if err != nil {
+		logger.Error(err.Error())
+		return exitFailure
+	}
+	defer stopProfiles()
+
+	run := &simplifyRun{opts: cfg.Options, packages: cfg.PackageMap, xacro: cfg.xacroInput(), lf: &lf, logger: logger,
+		check: check, dryRun: dryRun, strict: strict, force: force || inPlace, backup: backup, keepXacro: keepXacro, roundTrip: roundTrip, format: ff, noProvenance: noProvenance, options: fs.setFlags(),
+		srdf: srdfPath, srdfSamples: srdfSamples, viamFrames: viamFramesPath, viamKinematics: viamKinematicsPath,
+		viamGeometries: viamGeometriesDir, viamGeometriesInLink: viamGeometryFrame == "link", viam: viam, emitGo: emitGoPath, emitGoPackage: emitGoPackage, nameMap: nameMapPath, verify: verifyPath, verifyTolerance: verifyTolerance}
+	if inPlace {
+		return run.inPlace(positional)
+	}
+	if batch {
+		return run.batch(inDir, outDir)
+	}
+	if templated {
+		return run.templated(positional, outTemplate)
+	}
+	if watch {
+		return run.watch(positional[0], positional[1])
+	}
+	if err := run.file(positional[0], positional[1], logger); err != nil {
+		logger.Error(err.Error())
+		return exitCode(err)
+	}
+	return exitOK
+}
+
+// simplifyRun holds the settings shared by every file one simplify invocation processes.
+type simplifyRun struct {
+	opts urdf.Options
+	// packages maps ROS package names to directories for mesh resolution.
+	packages map[string]string
+	// xacro says how xacro input is read.
+	xacro  xacroInput
+	lf     *logFlags
+	logger *slog.Logger
+	check  bool
+	dryRun bool
+	// strict fails a file that produced any warning, with exitMesh if a mesh
+	// could not be read and exitInvalid otherwise.
+	strict bool
+	// force allows overwriting existing output files; backup keeps the
+	// previous version of each one as <file>.bak.
+	force  bool
+	backup bool
+	// keepXacro writes the output of xacro input as xacro; see xacro.Options.Partial.
+	keepXacro bool
+	// roundTrip checks that the output URDF parses back to the simplified
+	// robot before writing it.
+	roundTrip bool
+	// format lays the output out.
+	format formatFlags
+	// noProvenance leaves out the comment saying how the output was made,
+	// from the flags given as options.
+	noProvenance bool
+	options      []string
+	// printReports prints the change report after every file, not only in dry runs.
+	printReports bool
+	// srdf, if set, is where to write the SRDF collision matrix of the
+	// simplified robot, computed from srdfSamples configurations.
+	srdf        string
+	srdfSamples int
+	// viamFrames, if set, is where to write the Viam frame of each simplified
+	// link, and viamKinematics where to write its Viam kinematics file.
+	viamFrames     string
+	viamKinematics string
+	// viamGeometries, if set, is the directory to write each link's Viam
+	// geometries into, in the link's frame if viamGeometriesInLink is set
+	// and the root link's otherwise.
+	viamGeometries       string
+	viamGeometriesInLink bool
+	// viam says how the Viam exports are written.
+	viam urdf.ViamOptions
+	// emitGo, if set, is where to write the simplified robot as Go source in
+	// package emitGoPackage.
+	emitGo        string
+	emitGoPackage string
+	// nameMap, if set, is where to write the links and joints renamed.
+	nameMap string
+	// verify, if set, is a URDF whose kinematics the simplified robot must
+	// match to within verifyTolerance.
+	verify          string
+	verifyTolerance float64
+}
+
+// file simplifies inputPath into outputPath, or checks or previews the result
+// depending on the run mode.
+func (r *simplifyRun) file(inputPath, outputPath string, logger *slog.Logger) error {
+	if r.check && outputPath == "-" {
+		return fmt.Errorf("--check needs an output file, not stdout")
+	}
+	writes := !r.check && !r.dryRun && outputPath != "-"
+	if writes && !r.force {
+		if _, err := os.Stat(outputPath); err == nil {
+			return fmt.Errorf("%s already exists; use --force to overwrite it", outputPath)
+		}
+	}
+	if !r.check && !r.dryRun && !r.force {
+		for _, p := range r.sidePaths() {
+			if _, err := os.Stat(p); err == nil {
+				return fmt.Errorf("%s already exists; use --force to overwrite it", p)
+			}
+		}
+	}
+
+	data, err := readSource(inputPath)
+	if err != nil {
+		return err
+	}
+	source := data
+	format := r.format.format(data)
+	var expanded *xacro.Result
+	if xacro.IsXacro(inputPath, data) {
+		if expanded, err = expandXacro(inputPath, data, r.packages, r.xacro, r.keepXacro); err != nil {
+			return err
+		}
+		data = expanded.Data
+	}
+	robot, err := parseRobot(data, inputPath)
+	if err != nil {
+		return err
+	}
+
+	opts := r.opts
+	opts.Logger = logger
+	opts.Progress = r.lf.progress()
+	report := urdf.Simplify(robot, meshResolver(inputPath, r.packages), opts)
+	r.lf.clearProgress()
+	r.warnUnsupported(data, report, logger)
+
+	var finalOutput bytes.Buffer
+	if err := urdf.WriteURDFFormat(&finalOutput, robot, format); err != nil {
+		return fmt.Errorf("generating output XML: %w", err)
+	}
+	if r.roundTrip {
+		if err := urdf.CheckRoundTrip(robot, finalOutput.Bytes()); err != nil {
+			return withExitCode(exitInvalid, err)
+		}
+		logger.Debug("output parses back to the simplified robot")
+	}
+	if r.keepXacro && expanded != nil {
+		restored, err := expanded.Restore(finalOutput.Bytes())
+		if err != nil {
+			return fmt.Errorf("generating xacro output: %w", err)
+		}
+		finalOutput.Reset()
+		finalOutput.Write(restored)
+	}
+	if !r.noProvenance {
+		stamped := stampProvenance(finalOutput.Bytes(), provenance(source, inputPath, r.options))
+		finalOutput.Reset()
+		finalOutput.Write(stamped)
+	}
+
+	sides, err := r.sideOutputs(robot, report, logger)
+	if err != nil {
+		return err
+	}
+
+	if r.dryRun || r.printReports {
+		r.lf.report(logger, report)
+	}
+	if r.verify != "" {
+		if err := r.verifyKinematics(robot, logger); err != nil {
+			return err
+		}
+	}
+	if len(report.Errors) > 0 {
+		return withExitCode(exitInvalid, fmt.Errorf("the simplified robot is invalid: %s", strings.Join(report.Errors, "; ")))
+	}
+	if r.strict {
+		if err := strictError(report); err != nil {
+			return err
+		}
+	}
+	if r.dryRun {
+		logger.Info("dry run: nothing written", "bytes", finalOutput.Len(), "output", displayOutputPath(outputPath))
+		return nil
+	}
+
+	// Simplification is deterministic, so an up-to-date output file is
+	// byte-identical to what we just generated. The provenance comments are
+	// still left out of the comparison, as they differ between tool versions
+	// and with SOURCE_DATE_EPOCH, neither of which makes the output stale.
+	if r.check {
+		existing, err := os.ReadFile(outputPath)
+		if err != nil {
+			return fmt.Errorf("output is stale: %w", err)
+		}
+		if !bytes.Equal(stripProvenance(existing), stripProvenance(finalOutput.Bytes())) {
+			return fmt.Errorf("output is stale: %s does not match %s; re-run urdf-simplifier to regenerate it", outputPath, displayPath(inputPath))
+		}
+		logger.Info("output is up to date", "output", outputPath)
+		return nil
+	}
+
+	if writes && r.backup {
+		if err := backupFile(outputPath); err != nil {
+			return err
+		}
+	}
+
+	// Write output
+	if err := writeOutput(outputPath, finalOutput.Bytes()); err != nil {
+		return fmt.Errorf("writing output file: %w", err)
+	}
+	for _, side := range sides {
+		if err := os.MkdirAll(filepath.Dir(side.path), 0755); err != nil {
+			return fmt.Errorf("writing %s: %w", side.what, err)
+		}
+		if err := os.WriteFile(side.path, side.data, 0644); err != nil {
+			return fmt.Errorf("writing %s: %w", side.what, err)
+		}
+	}
+
+	logger.Info("successfully simplified URDF", "input", displayPath(inputPath), "output", displayOutputPath(outputPath))
+	return nil
+}
+
+// inPlace replaces each of paths with its simplified version.
+func (r *simplifyRun) inPlace(paths []string) int {
+	if len(paths) == 1 {
+		if err := r.file(paths[0], paths[0], r.logger); err != nil {
+			r.logger.Error(err.Error())
+			return exitCode(err)
+		}
+		return exitOK
+	}
+
+	jobs := make([]batchJob, len(paths))
+	for i, path := range paths {
+		jobs[i] = batchJob{name: path, input: path, output: path}
+	}
+	return r.runJobs(jobs)
+}
+
+// backupFile copies path to path.bak, if path exists.
+func backupFile(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("backing up %s: %w", path, err)
+	}
+	if err := os.WriteFile(path+".bak", data, 0644); err != nil {
+		return fmt.Errorf("backing up %s: %w", path, err)
+	}
+	return nil
+}
+
+// warnUnsupported adds a warning to report for each kind of input element the
+// output silently lacks. Elements inside <visual> and <inertial> are only
+// reported when those are being kept.
+func (r *simplifyRun) warnUnsupported(data []byte, report *urdf.Report, logger *slog.Logger) {
+	elements, err := urdf.UnsupportedElements(bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	for _, e := range elements {
+		if !r.opts.KeepVisuals && strings.HasPrefix(e, "robot/link/visual/") ||
+			!r.opts.KeepInertials && strings.HasPrefix(e, "robot/link/inertial/") {
+			continue
+		}
+		logger.Warn("unsupported element dropped", "element", e)
+		report.Warnings = append(report.Warnings, fmt.Sprintf("unsupported element <%s> dropped", e))
+	}
+}
+
+// verifySamples is how many joint configurations --verify-against compares.
+const verifySamples = 1000
+
+// verifyKinematics compares the end link of the simplified robot to that of
+// the --verify-against URDF at sampled joint positions.
+func (r *simplifyRun) verifyKinematics(robot *urdf.Robot, logger *slog.Logger) error {
+	reference, err := loadRobot(r.verify, r.packages, r.xacro)
+	if err != nil {
+		return err
+	}
+	diff, err := urdf.CompareKinematics(robot, reference, verifySamples)
+	if err != nil {
+		return withExitCode(exitInvalid, fmt.Errorf("verifying against %s: %w", r.verify, err))
+	}
+	if diff.Distance > r.verifyTolerance || diff.Angle > r.verifyTolerance {
+		return withExitCode(exitInvalid, fmt.Errorf("kinematics differ from %s: %s is up to %.3f mm and %.3f° from where it is there (tolerance %g)",
+			r.verify, diff.Link, diff.Distance*1000, diff.Angle*180/math.Pi, r.verifyTolerance))
+	}
+	logger.Info("kinematics match", "reference", r.verify, "link", diff.Link, "samples", diff.Samples, "distance", diff.Distance, "angle", diff.Angle)
+	return nil
+}
+
+// strictError turns the warnings of a report into an error for --strict.
+func strictError(report *urdf.Report) error {
+	switch {
+	case len(report.FailedMeshes) > 0:
+		return withExitCode(exitMesh, fmt.Errorf("--strict: %d mesh(es) could not be read", len(report.FailedMeshes)))
+	case len(report.Warnings) > 0:
+		return withExitCode(exitInvalid, fmt.Errorf("--strict: %d warning(s)", len(report.Warnings)))
+	}
+	return nil
+}
+
+// loadRobot reads and parses the URDF at path. A path of "-" reads from
+// stdin. packages maps ROS package names to directories for xacro includes,
+// and in says how xacro input is expanded.
+func loadRobot(path string, packages map[string]string, in xacroInput) (*urdf.Robot, error) {
+	data, err := readInput(path, packages, in)
+	if err != nil {
+		return nil, err
+	}
+	return parseRobot(data, path)
+}
+
+// readInput reads the file at path, or stdin if path is "-". A xacro
+// document is expanded to plain URDF, finding the packages its includes refer
+// to the same way as for meshes.
+func readInput(path string, packages map[string]string, in xacroInput) ([]byte, error) {
+	data, err := readSource(path)
+	if err != nil {
+		return nil, err
+	}
+	if xacro.IsXacro(path, data) {
+		result, err := expandXacro(path, data, packages, in, false)
+		if err != nil {
+			return nil, err
+		}
+		data = result.Data
+	}
+	return data, nil
+}
+
+// readSource reads the file at path, or stdin if path is "-", as is.
+func readSource(path string) ([]byte, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading input file: %w", err)
+	}
+	return data, nil
+}
+
+// parseRobot parses a URDF document read from path. Parse errors carry exitParse.
+func parseRobot(data []byte, path string) (*urdf.Robot, error) {
+	robot, err := urdf.ParseURDF(bytes.NewReader(data))
+	if err != nil {
+		return nil, withExitCode(exitParse, fmt.Errorf("parsing URDF %s: %w", displayPath(path), err))
+	}
+	return robot, nil
+}
+
+// writeRobot writes robot to path as a URDF document. A path of "-" writes to stdout.
+func writeRobot(path string, robot *urdf.Robot) error {
+	var buf bytes.Buffer
+	if err := urdf.WriteURDF(&buf, robot); err != nil {
+		return fmt.Errorf("generating output XML: %w", err)
+	}
+	if err := writeOutput(path, buf.Bytes()); err != nil {
+		return fmt.Errorf("writing output file: %w", err)
+	}
+	return nil
+}
+
+// writeOutput writes data to path, or to stdout if path is "-".
+func writeOutput(path string, data []byte) error {
+	if path == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// sideOutput is a file generated from the simplified robot and written
+// alongside it, such as the --srdf collision matrix.
+type sideOutput struct {
+	path string
+	// what names the file in messages.
+	what string
+	data []byte
+}
+
+// sidePaths returns the paths of the side outputs the run writes.
+func (r *simplifyRun) sidePaths() []string {
+	var paths []string
+	for _, p := range []string{r.srdf, r.viamFrames, r.viamKinematics, r.emitGo, r.nameMap} {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// sideOutputs generates the side outputs of the simplified robot, and of
+// the report of its simplification.
+func (r *simplifyRun) sideOutputs(robot *urdf.Robot, report *urdf.Report, logger *slog.Logger) ([]sideOutput, error) {
+	var sides []sideOutput
+	if r.srdf != "" {
+		var buf bytes.Buffer
+		disabled := urdf.DisableCollisions(robot, r.srdfSamples)
+		if err := urdf.WriteSRDF(&buf, robot.Name, disabled); err != nil {
+			return nil, fmt.Errorf("generating SRDF: %w", err)
+		}
+		logger.Info("computed collision matrix", "disabled_pairs", len(disabled), "samples", r.srdfSamples)
+		sides = append(sides, sideOutput{r.srdf, "SRDF", buf.Bytes()})
+	}
+	if r.viamFrames != "" {
+		frames, warnings, err := urdf.ViamFrames(robot, r.viam)
+		if err != nil {
+			return nil, fmt.Errorf("generating Viam frames: %w", err)
+		}
+		for _, w := range warnings {
+			logger.Warn(w)
+		}
+		var buf bytes.Buffer
+		if err := urdf.WriteViamFrames(&buf, frames); err != nil {
+			return nil, fmt.Errorf("generating Viam frames: %w", err)
+		}
+		sides = append(sides, sideOutput{r.viamFrames, "Viam frames", buf.Bytes()})
+	}
+	if r.viamKinematics != "" {
+		model, warnings, err := urdf.ViamKinematics(robot, r.viam)
+		if err != nil {
+			return nil, fmt.Errorf("generating Viam kinematics: %w", err)
+		}
+		for _, w := range warnings {
+			logger.Warn(w)
+		}
+		var buf bytes.Buffer
+		if err := urdf.WriteViamKinematics(&buf, model); err != nil {
+			return nil, fmt.Errorf("generating Viam kinematics: %w", err)
+		}
+		sides = append(sides, sideOutput{r.viamKinematics, "Viam kinematics", buf.Bytes()})
+	}
+	if r.viamGeometries != "" {
+		links, warnings, err := urdf.ViamGeometries(robot, !r.viamGeometriesInLink, r.viam)
+		if err != nil {
+			return nil, fmt.Errorf("generating Viam geometries: %w", err)
+		}
+		for _, w := range warnings {
+			logger.Warn(w)
+		}
+		for _, link := range links {
+			var buf bytes.Buffer
+			if err := urdf.WriteViamGeometries(&buf, link.Geometries); err != nil {
+				return nil, fmt.Errorf("generating Viam geometries: %w", err)
+			}
+			p := filepath.Join(r.viamGeometries, strings.ReplaceAll(link.Link, "/", "_")+".json")
+			if !r.check && !r.dryRun && !r.force {
+				if _, err := os.Stat(p); err == nil {
+					return nil, fmt.Errorf("%s already exists; use --force to overwrite it", p)
+				}
+			}
+			sides = append(sides, sideOutput{p, "Viam geometries", buf.Bytes()})
+		}
+	}
+	if r.emitGo != "" {
+		var buf bytes.Buffer
+		if err := urdf.WriteGo(&buf, robot, r.emitGoPackage); err != nil {
+			return nil, fmt.Errorf("generating Go source: %w", err)
+		}
+		sides = append(sides, sideOutput{r.emitGo, "Go source", buf.Bytes()})
+	}
+	if r.nameMap != "" && report.NameMap != nil {
+		data, err := json.MarshalIndent(report.NameMap, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("generating name map: %w", err)
+		}
+		sides = append(sides, sideOutput{r.nameMap, "name map", append(data, '\n')})
+	}
+	return sides, nil
+}
+
+// goPackageName returns the package a generated Go file at path belongs to
+// by convention: the name of its directory, or "model" if that is not a Go
+// identifier, as for a name with a dash.
+func goPackageName(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "model"
+	}
+	if name := filepath.Base(filepath.Dir(abs)); token.IsIdentifier(name) {
+		return name
+	}
+	return "model"
+}
+
+// meshResolver returns the resolver for meshes referenced by the URDF at
+// inputPath, with explicitly mapped packages.
+func meshResolver(inputPath string, packages map[string]string) urdf.FileResolver {
+	r := urdf.NewFileResolver(inputDir(inputPath))
+	r.Packages = packages
+	return r
+}
+
+// inputDir returns the directory mesh references are resolved against. When
+// reading from stdin that is the working directory.
+func inputDir(path string) string {
+	if path == "-" {
+		return "."
+	}
+	return filepath.Dir(path)
+}
+
+// displayPath names an input path in messages.
+func displayPath(path string) string {
+	if path == "-" {
+		return "<stdin>"
+	}
+	return path
+}
+
+// displayOutputPath names an output path in messages.
+func displayOutputPath(path string) string {
+	if path == "-" {
+		return "<stdout>"
+	}
+	return path
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// cubeSTL is an ASCII STL of a 0.1 m cube standing on the origin.
+var cubeSTL = func() string {
+	corner := func(i int) string {
+		return fmt.Sprintf("%g %g %g", float64(i&1)*0.1, float64(i>>1&1)*0.1, float64(i>>2&1)*0.1)
+	}
+	faces := [12][3]int{
+		{0, 2, 1}, {1, 2, 3}, {4, 5, 6}, {5, 7, 6}, {0, 1, 4}, {1, 5, 4},
+		{2, 6, 3}, {3, 6, 7}, {0, 4, 2}, {2, 4, 6}, {1, 3, 5}, {3, 7, 5},
+	}
+	var b strings.Builder
+	b.WriteString("solid cube\n")
+	for _, f := range faces {
+		b.WriteString("facet normal 0 0 0\nouter loop\n")
+		for _, v := range f {
+			b.WriteString("vertex " + corner(v) + "\n")
+		}
+		b.WriteString("endloop\nendfacet\n")
+	}
+	b.WriteString("endsolid cube\n")
+	return b.String()
+}()
+
+// armURDF returns a serial arm of n links, each with a visual and a
+// collision mesh at mesh.
+func armURDF(n int, mesh string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0"?>` + "\n" + `<robot name="arm">` + "\n")
+	for i := range n {
+		fmt.Fprintf(&b, `  <link name="link%d">
+    <visual><geometry><mesh filename="%s"/></geometry></visual>
+    <collision><origin xyz="0 0 %d"/><geometry><mesh filename="%s"/></geometry></collision>
+  </link>
+`, i, mesh, i, mesh)
+		if i > 0 {
+			fmt.Fprintf(&b, `  <joint name="joint%d" type="revolute">
+    <parent link="link%d"/><child link="link%d"/>
+    <origin xyz="0 0 0.2"/><axis xyz="0 0 1"/>
+    <limit lower="-3.14" upper="3.14" effort="100" velocity="2"/>
+  </joint>
+`, i, i-1, i)
+		}
+	}
+	b.WriteString("</robot>\n")
+	return b.String()
+}
+
+// writeArm writes an armURDF of n links to dir/name with its mesh in
+// dir/meshes, and returns its path.
+func writeArm(t *testing.T, dir, name string, n int) string {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, "meshes"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "meshes", "cube.stl"), []byte(cubeSTL), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(armURDF(n, "meshes/cube.stl")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func readFile(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// runArgs runs the command line args quietly and fails the test unless it
+// exits with want.
+func runArgs(t *testing.T, want int, args ...string) {
+	t.Helper()
+	quiet := append([]string{"-q"}, args...)
+	if findCommand(args[0]) != nil {
+		quiet = append([]string{args[0], "-q"}, args[1:]...)
+	}
+	if code := run(quiet); code != want {
+		t.Fatalf("urdf-simplifier %s exited %d, want %d", strings.Join(args, " "), code, want)
+	}
+}
+
+func TestSimplifyFile(t *testing.T) {
+	dir := t.TempDir()
+	in := writeArm(t, dir, "arm.urdf", 3)
+	out := filepath.Join(dir, "out.urdf")
+	runArgs(t, exitOK, in, out)
+	data := string(readFile(t, out))
+	if strings.Contains(data, "<mesh") || strings.Count(data, "<box") != 3 {
+		t.Errorf("output has meshes or the wrong number of boxes:\n%s", data)
+	}
+	if !strings.HasPrefix(data, "<?xml") || !strings.Contains(data, "urdf-simplifier") {
+		t.Errorf("output lacks the provenance comment:\n%s", data)
+	}
+
+	// An existing output is only overwritten with --force.
+	runArgs(t, exitFailure, in, out)
+	runArgs(t, exitOK, "simplify", "--force", in, out)
+}
+
+func TestSimplifyCheck(t *testing.T) {
+	dir := t.TempDir()
+	in := writeArm(t, dir, "arm.urdf", 3)
+	out := filepath.Join(dir, "out.urdf")
+	runArgs(t, exitFailure, "--check", in, out)
+
+	runArgs(t, exitOK, in, out)
+	runArgs(t, exitOK, "--check", in, out)
+	// Options that don't change the output don't make it stale.
+	runArgs(t, exitOK, "--check", "-j", "2", in, out)
+	fresh := readFile(t, out)
+
+	if err := os.WriteFile(in, []byte(strings.Replace(armURDF(3, "meshes/cube.stl"), `upper="3.14"`, `upper="3"`, 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runArgs(t, exitFailure, "--check", in, out)
+	if !bytes.Equal(readFile(t, out), fresh) {
+		t.Error("--check rewrote the output")
+	}
+	runArgs(t, exitUsage, "--check", "--dry-run", in, out)
+}
+
+func TestSimplifyDryRun(t *testing.T) {
+	dir := t.TempDir()
+	in := writeArm(t, dir, "arm.urdf", 3)
+	out := filepath.Join(dir, "out.urdf")
+	runArgs(t, exitOK, "-n", in, out)
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("dry run wrote %s", out)
+	}
+}
+
+func TestSimplifyStdin(t *testing.T) {
+	dir := t.TempDir()
+	in := writeArm(t, dir, "arm.urdf", 3)
+	want := filepath.Join(dir, "want.urdf")
+	runArgs(t, exitOK, "--no-provenance", in, want)
+
+	// From stdin, relative meshes resolve against the working directory, so
+	// give an absolute one.
+	stdin := filepath.Join(dir, "stdin.urdf")
+	if err := os.WriteFile(stdin, []byte(armURDF(3, filepath.Join(dir, "meshes", "cube.stl"))), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(stdin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	old := os.Stdin
+	os.Stdin = f
+	defer func() { os.Stdin = old }()
+
+	got := filepath.Join(dir, "got.urdf")
+	runArgs(t, exitOK, "--no-provenance", "-", got)
+	if !bytes.Equal(readFile(t, got), readFile(t, want)) {
+		t.Errorf("output from stdin differs:\n%s\nwant:\n%s", readFile(t, got), readFile(t, want))
+	}
+}
+
+func TestSimplifyInPlace(t *testing.T) {
+	dir := t.TempDir()
+	in := writeArm(t, dir, "arm.urdf", 3)
+	original := readFile(t, in)
+	want := filepath.Join(dir, "want.urdf")
+	runArgs(t, exitOK, "--no-provenance", in, want)
+
+	runArgs(t, exitOK, "--no-provenance", "--in-place", "--backup", in)
+	if !bytes.Equal(readFile(t, in), readFile(t, want)) {
+		t.Errorf("simplified in place:\n%s\nwant:\n%s", readFile(t, in), readFile(t, want))
+	}
+	if !bytes.Equal(readFile(t, in+".bak"), original) {
+		t.Error("backup differs from the original")
+	}
+	runArgs(t, exitUsage, "--in-place", "-")
+}
+
+func TestSimplifyBatch(t *testing.T) {
+	dir := t.TempDir()
+	inDir := filepath.Join(dir, "in")
+	writeArm(t, inDir, "a.urdf", 2)
+	writeArm(t, filepath.Join(inDir, "sub"), "b.urdf", 4)
+	outDir := filepath.Join(dir, "out")
+	runArgs(t, exitOK, "--no-provenance", "--in-dir", inDir, "--out-dir", outDir)
+
+	for _, name := range []string{"a.urdf", filepath.Join("sub", "b.urdf")} {
+		want := filepath.Join(dir, strings.ReplaceAll(name, string(filepath.Separator), "_"))
+		runArgs(t, exitOK, "--no-provenance", filepath.Join(inDir, name), want)
+		if !bytes.Equal(readFile(t, filepath.Join(outDir, name)), readFile(t, want)) {
+			t.Errorf("batch output %s differs from simplifying it alone", name)
+		}
+	}
+	runArgs(t, exitUsage, "--in-dir", inDir)
+}
+
+func TestSimplifyJobs(t *testing.T) {
+	dir := t.TempDir()
+	in := writeArm(t, dir, "arm.urdf", 12)
+	var outputs [][]byte
+	for _, jobs := range []string{"1", "8"} {
+		out := filepath.Join(dir, "out"+jobs+".urdf")
+		runArgs(t, exitOK, "-j", jobs, in, out)
+		outputs = append(outputs, readFile(t, out))
+	}
+	if !bytes.Equal(outputs[0], outputs[1]) {
+		t.Errorf("-j 1 and -j 8 outputs differ:\n%s\n%s", outputs[0], outputs[1])
+	}
+}
@@ -0,0 +1,73 @@
+package spatialmath
+
+// Pose is a rigid transform: a rotation followed by a translation, expressed
+// in the parent frame.
+type Pose struct {
+	Translation Vec3
+	Rotation    Quaternion
+}
+
+// Identity returns the identity pose.
+func Identity() Pose { return Pose{Rotation: IdentityQuaternion()} }
+
+// NewPose builds a pose from a URDF xyz translation and rpy rotation.
+func NewPose(xyz Vec3, rpy RPY) Pose {
+	return Pose{Translation: xyz, Rotation: rpy.Quaternion()}
+}
+
+// ParsePose builds a pose from URDF xyz and rpy attribute strings. Empty
+// attributes default to zero, as in URDF.
+func ParsePose(xyz, rpy string) (Pose, error) {
+	t, err := ParseVec3(xyz)
+	if err != nil {
+		return Pose{}, err
+	}
+	r, err := ParseVec3(rpy)
+	if err != nil {
+		return Pose{}, err
+	}
+	return NewPose(t, RPY{Roll: r.X, Pitch: r.Y, Yaw: r.Z}), nil
+}
+
+// Compose returns the pose of o expressed in p's parent frame, where o is
+// expressed in p's frame (p * o).
+func (p Pose) Compose(o Pose) Pose {
+	return Pose{
+		Translation: p.Translation.Add(p.Rotation.Rotate(o.Translation)),
+		Rotation:    p.Rotation.Mul(o.Rotation).Normalize(),
+	}
+}
+
+// Inverse returns the inverse transform of p.
+func (p Pose) Inverse() Pose {
+	inv := p.Rotation.Conjugate()
+	return Pose{
+		Translation: inv.Rotate(p.Translation).Scale(-1),
+		Rotation:    inv,
+	}
+}
+
+// Apply transforms point v from p's frame into its parent frame.
+func (p Pose) Apply(v Vec3) Vec3 {
+	return p.Translation.Add(p.Rotation.Rotate(v))
+}
+
+// RPY returns the rotation of p as URDF roll/pitch/yaw angles.
+func (p Pose) RPY() RPY { return p.Rotation.RPY() }
+
+// Matrix returns p as a 4x4 homogeneous transform in row-major order.
+func (p Pose) Matrix() [4][4]float64 {
+	r := p.Rotation.Matrix()
+	return [4][4]float64{
+		{r[0][0], r[0][1], r[0][2], p.Translation.X},
+		{r[1][0], r[1][1], r[1][2], p.Translation.Y},
+		{r[2][0], r[2][1], r[2][2], p.Translation.Z},
+		{0, 0, 0, 1},
+	}
+}
+
+// XYZRPY returns p formatted as URDF xyz and rpy attribute strings.
+func (p Pose) XYZRPY() (xyz, rpy string) {
+	r := p.RPY()
+	return FormatVec3(p.Translation), FormatVec3(Vec3{r.Roll, r.Pitch, r.Yaw})
+}
@@ -0,0 +1,119 @@
+package spatialmath
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func posesEqual(a, b Pose, tol float64) bool {
+	return vecsEqual(a.Translation, b.Translation, tol) && matricesEqual(a.Rotation.Matrix(), b.Rotation.Matrix(), tol)
+}
+
+func TestPoseCompose(t *testing.T) {
+	p := NewPose(Vec3{1, 2, 3}, RPY{0.2, -0.5, 1.3})
+	o := NewPose(Vec3{-0.4, 0.1, 0.7}, RPY{-1.1, 0.3, 0.4})
+	v := Vec3{0.3, -0.2, 0.5}
+
+	if got, want := p.Compose(o).Apply(v), p.Apply(o.Apply(v)); !vecsEqual(got, want, 1e-12) {
+		t.Errorf("(p*o)(v) = %v, want p(o(v)) = %v", got, want)
+	}
+	if got := p.Compose(p.Inverse()); !posesEqual(got, Identity(), 1e-12) {
+		t.Errorf("p * p⁻¹ = %v, want the identity", got)
+	}
+	if got := p.Inverse().Compose(p); !posesEqual(got, Identity(), 1e-12) {
+		t.Errorf("p⁻¹ * p = %v, want the identity", got)
+	}
+	if got := Identity().Compose(p); !posesEqual(got, p, 1e-15) {
+		t.Errorf("identity * p = %v, want %v", got, p)
+	}
+
+	// A joint 0.1 up whose child is turned a quarter about z puts a point
+	// 0.2 along the child's x at 0.2 along the parent's y.
+	joint := NewPose(Vec3{0, 0, 0.1}, RPY{Yaw: math.Pi / 2})
+	if got := joint.Apply(Vec3{0.2, 0, 0}); !vecsEqual(got, Vec3{0, 0.2, 0.1}, 1e-12) {
+		t.Errorf("Apply = %v, want 0 0.2 0.1", got)
+	}
+}
+
+func TestPoseMatrix(t *testing.T) {
+	p := NewPose(Vec3{1, 2, 3}, RPY{0.2, -0.5, 1.3})
+	m := p.Matrix()
+	r := p.Rotation.Matrix()
+	v := Vec3{0.3, -0.2, 0.5}
+	got := Vec3{
+		m[0][0]*v.X + m[0][1]*v.Y + m[0][2]*v.Z + m[0][3],
+		m[1][0]*v.X + m[1][1]*v.Y + m[1][2]*v.Z + m[1][3],
+		m[2][0]*v.X + m[2][1]*v.Y + m[2][2]*v.Z + m[2][3],
+	}
+	if want := p.Apply(v); !vecsEqual(got, want, 1e-12) {
+		t.Errorf("matrix applies %v, want %v", got, want)
+	}
+	if m[3] != [4]float64{0, 0, 0, 1} || m[0][0] != r[0][0] {
+		t.Errorf("matrix %v is not homogeneous", m)
+	}
+}
+
+func TestParsePose(t *testing.T) {
+	for _, tt := range []struct {
+		xyz, rpy string
+		want     Pose
+		err      string
+	}{
+		{"", "", Identity(), ""},
+		{"1 2 3", "", NewPose(Vec3{1, 2, 3}, RPY{}), ""},
+		{"  0 0\t0.5 ", "0 0 1.5707963267948966", NewPose(Vec3{0, 0, 0.5}, RPY{Yaw: math.Pi / 2}), ""},
+		{"1 2", "", Pose{}, "expected 3 values, got 2"},
+		{"1 2 x", "", Pose{}, `invalid number "x"`},
+		{"", "0 0 0 0", Pose{}, "expected 3 values, got 4"},
+	} {
+		got, err := ParsePose(tt.xyz, tt.rpy)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ParsePose(%q, %q) error %v, want %q", tt.xyz, tt.rpy, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || !posesEqual(got, tt.want, 1e-15) {
+			t.Errorf("ParsePose(%q, %q) = %v, %v, want %v", tt.xyz, tt.rpy, got, err, tt.want)
+		}
+	}
+}
+
+func TestXYZRPY(t *testing.T) {
+	for _, tt := range []struct {
+		pose     Pose
+		xyz, rpy string
+	}{
+		{Identity(), "0 0 0", "0 0 0"},
+		{NewPose(Vec3{0.1, -2, 1e-13}, RPY{Yaw: 0.25}), "0.1 -2 0", "0 0 0.25"},
+		// Composing a turn with its inverse leaves only float noise.
+		{NewPose(Vec3{}, RPY{0.3, 0.2, 0.1}).Compose(NewPose(Vec3{}, RPY{0.3, 0.2, 0.1}).Inverse()), "0 0 0", "0 0 0"},
+	} {
+		xyz, rpy := tt.pose.XYZRPY()
+		if xyz != tt.xyz || rpy != tt.rpy {
+			t.Errorf("XYZRPY() = %q, %q, want %q, %q", xyz, rpy, tt.xyz, tt.rpy)
+		}
+	}
+}
+
+func TestVec3(t *testing.T) {
+	a, b := Vec3{1, 0, 0}, Vec3{0, 1, 0}
+	if got := a.Cross(b); got != (Vec3{0, 0, 1}) {
+		t.Errorf("x × y = %v, want z", got)
+	}
+	if got := (Vec3{3, 4, 0}).Normalize(); !vecsEqual(got, Vec3{0.6, 0.8, 0}, 1e-15) {
+		t.Errorf("Normalize = %v, want 0.6 0.8 0", got)
+	}
+	if got := (Vec3{}).Normalize(); got != (Vec3{}) {
+		t.Errorf("Normalize of zero = %v, want zero", got)
+	}
+	for _, tt := range []struct {
+		f    float64
+		want string
+	}{{0.1, "0.1"}, {-1e-13, "0"}, {1e-9, "1e-09"}, {1.0 / 3, "0.3333333333333333"}} {
+		if got := FormatFloat(tt.f); got != tt.want {
+			t.Errorf("FormatFloat(%g) = %q, want %q", tt.f, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package spatialmath
+
+import "math"
+
+// Mat3 is a 3x3 matrix in row-major order, such as a rotation or an inertia
+// tensor.
+type Mat3 [3][3]float64
+
+// Identity3 returns the identity matrix.
+func Identity3() Mat3 {
+	return Mat3{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+}
+
+// Mul returns m * o.
+func (m Mat3) Mul(o Mat3) Mat3 {
+	var r Mat3
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				r[i][j] += m[i][k] * o[k][j]
+			}
+		}
+	}
+	return r
+}
+
+// MulVec returns m * v.
+func (m Mat3) MulVec(v Vec3) Vec3 {
+	return Vec3{
+		m[0][0]*v.X + m[0][1]*v.Y + m[0][2]*v.Z,
+		m[1][0]*v.X + m[1][1]*v.Y + m[1][2]*v.Z,
+		m[2][0]*v.X + m[2][1]*v.Y + m[2][2]*v.Z,
+	}
+}
+
+// Transpose returns the transpose of m, which is its inverse for rotations.
+func (m Mat3) Transpose() Mat3 {
+	var r Mat3
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			r[i][j] = m[j][i]
+		}
+	}
+	return r
+}
+
+// SymmetricEigen returns the eigenvalues of the symmetric matrix m in
+// ascending order, and a rotation whose columns are the matching unit
+// eigenvectors, so that m = vectors * diag(values) * vectorsᵀ. It uses
+// cyclic Jacobi rotations, which converge in a handful of sweeps for 3x3.
+func (m Mat3) SymmetricEigen() (values [3]float64, vectors Mat3) {
+	a, v := m, Identity3()
+	for sweep := 0; sweep < 50; sweep++ {
+		off := a[0][1]*a[0][1] + a[0][2]*a[0][2] + a[1][2]*a[1][2]
+		diag := a[0][0]*a[0][0] + a[1][1]*a[1][1] + a[2][2]*a[2][2]
+		if off <= 1e-30*diag || off == 0 {
+			break
+		}
+		for _, pq := range [][2]int{{0, 1}, {0, 2}, {1, 2}} {
+			p, q := pq[0], pq[1]
+			if a[p][q] == 0 {
+				continue
+			}
+			// Rotate in the pq plane by the angle that zeroes a[p][q].
+			theta := (a[q][q] - a[p][p]) / (2 * a[p][q])
+			t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+			if theta < 0 {
+				t = -t
+			}
+			c := 1 / math.Sqrt(t*t+1)
+			s := t * c
+			for k := 0; k < 3; k++ {
+				akp, akq := a[k][p], a[k][q]
+				a[k][p], a[k][q] = c*akp-s*akq, s*akp+c*akq
+			}
+			for k := 0; k < 3; k++ {
+				apk, aqk := a[p][k], a[q][k]
+				a[p][k], a[q][k] = c*apk-s*aqk, s*apk+c*aqk
+			}
+			for k := 0; k < 3; k++ {
+				vkp, vkq := v[k][p], v[k][q]
+				v[k][p], v[k][q] = c*vkp-s*vkq, s*vkp+c*vkq
+			}
+		}
+	}
+
+	order := [3]int{0, 1, 2}
+	for i := 1; i < 3; i++ {
+		for j := i; j > 0 && a[order[j]][order[j]] < a[order[j-1]][order[j-1]]; j-- {
+			order[j], order[j-1] = order[j-1], order[j]
+		}
+	}
+	for i, k := range order {
+		values[i] = a[k][k]
+		for r := 0; r < 3; r++ {
+			vectors[r][i] = v[r][k]
+		}
+	}
+	return values, vectors
+}
+
+// RPY holds URDF roll/pitch/yaw angles in radians.
+//
+// URDF uses extrinsic (fixed-axis) rotations: roll about X, then pitch about Y,
+// then yaw about Z, all about the parent frame's axes. The equivalent matrix
+// is Rz(yaw) * Ry(pitch) * Rx(roll).
+type RPY struct {
+	Roll, Pitch, Yaw float64
+}
+
+// Matrix returns the rotation matrix for r.
+func (r RPY) Matrix() Mat3 {
+	sr, cr := math.Sincos(r.Roll)
+	sp, cp := math.Sincos(r.Pitch)
+	sy, cy := math.Sincos(r.Yaw)
+	return Mat3{
+		{cy * cp, cy*sp*sr - sy*cr, cy*sp*cr + sy*sr},
+		{sy * cp, sy*sp*sr + cy*cr, sy*sp*cr - cy*sr},
+		{-sp, cp * sr, cp * cr},
+	}
+}
+
+// Canonical returns the angles of the same rotation as r in canonical form:
+// each in (-π, π], the pitch in [-π/2, π/2], and at a pitch of ±π/2, where
+// roll and yaw turn about the same axis, no roll, as Mat3.RPY gives them.
+// Angles within 1e-12 of zero are float noise and become zero; r is returned
+// unchanged if it is canonical already.
+func (r RPY) Canonical() RPY {
+	c := RPY{wrapAngle(r.Roll), wrapAngle(r.Pitch), wrapAngle(r.Yaw)}
+	// (roll, pitch, yaw) is the same rotation as (roll+π, π-pitch, yaw+π).
+	if c.Pitch > math.Pi/2 || c.Pitch < -math.Pi/2 {
+		c = RPY{wrapAngle(c.Roll + math.Pi), wrapAngle(math.Copysign(math.Pi, c.Pitch) - c.Pitch), wrapAngle(c.Yaw + math.Pi)}
+	}
+	if math.Abs(math.Abs(c.Pitch)-math.Pi/2) < 1e-12 && c.Roll != 0 {
+		// At a pitch of π/2 only yaw - roll matters, and at -π/2 yaw + roll.
+		yaw := c.Yaw - c.Roll
+		if c.Pitch < 0 {
+			yaw = c.Yaw + c.Roll
+		}
+		c = RPY{0, math.Copysign(math.Pi/2, c.Pitch), wrapAngle(yaw)}
+	}
+	return c
+}
+
+// wrapAngle returns a in (-π, π], or zero if it is within 1e-12 of a whole
+// turn.
+func wrapAngle(a float64) float64 {
+	a = math.Remainder(a, 2*math.Pi)
+	if a <= -math.Pi {
+		a += 2 * math.Pi
+	}
+	if math.Abs(a) < 1e-12 {
+		return 0
+	}
+	return a
+}
+
+// Quaternion returns the unit quaternion for r.
+func (r RPY) Quaternion() Quaternion {
+	sr, cr := math.Sincos(r.Roll / 2)
+	sp, cp := math.Sincos(r.Pitch / 2)
+	sy, cy := math.Sincos(r.Yaw / 2)
+	return Quaternion{
+		W: cr*cp*cy + sr*sp*sy,
+		X: sr*cp*cy - cr*sp*sy,
+		Y: cr*sp*cy + sr*cp*sy,
+		Z: cr*cp*sy - sr*sp*cy,
+	}
+}
+
+// RPY extracts URDF roll/pitch/yaw angles from m. At the pitch = ±π/2
+// singularity, within 1e-12 as for Canonical, roll is set to zero and the
+// whole rotation about Z is reported as yaw. The pitch is taken from its sine
+// and cosine together, as the sine alone changes too little near ±π/2 to
+// tell a pitch 1e-7 short of it from the singularity.
+func (m Mat3) RPY() RPY {
+	sp := -m[2][0]
+	cp := math.Hypot(m[0][0], m[1][0])
+	if cp < 1e-12 {
+		pitch := math.Copysign(math.Pi/2, sp)
+		yaw := math.Atan2(-m[0][1], m[1][1])
+		return RPY{Roll: 0, Pitch: pitch, Yaw: yaw}
+	}
+	return RPY{
+		Roll:  math.Atan2(m[2][1], m[2][2]),
+		Pitch: math.Atan2(sp, cp),
+		Yaw:   math.Atan2(m[1][0], m[0][0]),
+	}
+}
+
+// Quaternion returns the unit quaternion for rotation matrix m.
+func (m Mat3) Quaternion() Quaternion {
+	trace := m[0][0] + m[1][1] + m[2][2]
+	var q Quaternion
+	switch {
+	case trace > 0:
+		s := 0.5 / math.Sqrt(trace+1)
+		q = Quaternion{W: 0.25 / s, X: (m[2][1] - m[1][2]) * s, Y: (m[0][2] - m[2][0]) * s, Z: (m[1][0] - m[0][1]) * s}
+	case m[0][0] > m[1][1] && m[0][0] > m[2][2]:
+		s := 2 * math.Sqrt(1+m[0][0]-m[1][1]-m[2][2])
+		q = Quaternion{W: (m[2][1] - m[1][2]) / s, X: 0.25 * s, Y: (m[0][1] + m[1][0]) / s, Z: (m[0][2] + m[2][0]) / s}
+	case m[1][1] > m[2][2]:
+		s := 2 * math.Sqrt(1+m[1][1]-m[0][0]-m[2][2])
+		q = Quaternion{W: (m[0][2] - m[2][0]) / s, X: (m[0][1] + m[1][0]) / s, Y: 0.25 * s, Z: (m[1][2] + m[2][1]) / s}
+	default:
+		s := 2 * math.Sqrt(1+m[2][2]-m[0][0]-m[1][1])
+		q = Quaternion{W: (m[1][0] - m[0][1]) / s, X: (m[0][2] + m[2][0]) / s, Y: (m[1][2] + m[2][1]) / s, Z: 0.25 * s}
+	}
+	return q.Normalize()
+}
+
+// Quaternion is a rotation quaternion W + Xi + Yj + Zk.
+type Quaternion struct {
+	W, X, Y, Z float64
+}
+
+// IdentityQuaternion returns the quaternion for no rotation.
+func IdentityQuaternion() Quaternion { return Quaternion{W: 1} }
+
+// Mul returns the Hamilton product q * o, i.e. the rotation o followed by q.
+func (q Quaternion) Mul(o Quaternion) Quaternion {
+	return Quaternion{
+		W: q.W*o.W - q.X*o.X - q.Y*o.Y - q.Z*o.Z,
+		X: q.W*o.X + q.X*o.W + q.Y*o.Z - q.Z*o.Y,
+		Y: q.W*o.Y - q.X*o.Z + q.Y*o.W + q.Z*o.X,
+		Z: q.W*o.Z + q.X*o.Y - q.Y*o.X + q.Z*o.W,
+	}
+}
+
+// Conjugate returns the conjugate of q, which is its inverse for unit quaternions.
+func (q Quaternion) Conjugate() Quaternion { return Quaternion{q.W, -q.X, -q.Y, -q.Z} }
+
+// Normalize returns q scaled to unit length, with a non-negative W so that
+// equal rotations have a single representation.
+func (q Quaternion) Normalize() Quaternion {
+	n := math.Sqrt(q.W*q.W + q.X*q.X + q.Y*q.Y + q.Z*q.Z)
+	if n == 0 {
+		return IdentityQuaternion()
+	}
+	if q.W < 0 {
+		n = -n
+	}
+	return Quaternion{q.W / n, q.X / n, q.Y / n, q.Z / n}
+}
+
+// Matrix returns the rotation matrix for unit quaternion q.
+func (q Quaternion) Matrix() Mat3 {
+	w, x, y, z := q.W, q.X, q.Y, q.Z
+	return Mat3{
+		{1 - 2*(y*y+z*z), 2 * (x*y - z*w), 2 * (x*z + y*w)},
+		{2 * (x*y + z*w), 1 - 2*(x*x+z*z), 2 * (y*z - x*w)},
+		{2 * (x*z - y*w), 2 * (y*z + x*w), 1 - 2*(x*x+y*y)},
+	}
+}
+
+// Rotate returns v rotated by q.
+func (q Quaternion) Rotate(v Vec3) Vec3 { return q.Matrix().MulVec(v) }
+
+// RPY returns the URDF roll/pitch/yaw angles for q.
+func (q Quaternion) RPY() RPY { return q.Matrix().RPY() }
+
+// AxisAngle returns a rotation of angle radians about axis.
+func AxisAngle(axis Vec3, angle float64) Quaternion {
+	axis = axis.Normalize()
+	s, c := math.Sincos(angle / 2)
+	return Quaternion{W: c, X: axis.X * s, Y: axis.Y * s, Z: axis.Z * s}
+}
@@ -0,0 +1,197 @@
+package spatialmath
+
+import (
+	"math"
+	"testing"
+)
+
+func matricesEqual(a, b Mat3, tol float64) bool {
+	for i := range 3 {
+		for j := range 3 {
+			if math.Abs(a[i][j]-b[i][j]) > tol {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func vecsEqual(a, b Vec3, tol float64) bool {
+	return math.Abs(a.X-b.X) <= tol && math.Abs(a.Y-b.Y) <= tol && math.Abs(a.Z-b.Z) <= tol
+}
+
+// sameAngle reports whether a and b are the same angle, a whole turn apart
+// or not.
+func sameAngle(a, b float64) bool {
+	return math.Abs(math.Remainder(a-b, 2*math.Pi)) < 1e-9
+}
+
+var rpyCases = []struct {
+	name string
+	rpy  RPY
+}{
+	{"zero", RPY{}},
+	{"roll", RPY{Roll: 0.3}},
+	{"pitch", RPY{Pitch: -0.7}},
+	{"yaw", RPY{Yaw: 2.5}},
+	{"all", RPY{0.1, 0.2, 0.3}},
+	{"negative", RPY{-2.9, -1.1, -0.4}},
+	{"half turns", RPY{math.Pi, 0, math.Pi}},
+	{"pitch up", RPY{0, math.Pi / 2, 0}},
+	{"pitch down", RPY{0, -math.Pi / 2, 0}},
+	{"pitch up with roll and yaw", RPY{0.4, math.Pi / 2, 1.2}},
+	{"pitch down with roll and yaw", RPY{0.4, -math.Pi / 2, 1.2}},
+	{"near pitch up", RPY{0.4, math.Pi/2 - 1e-7, 1.2}},
+	{"out of range", RPY{7, 2, -4}},
+}
+
+func TestRPYRoundTrip(t *testing.T) {
+	for _, tt := range rpyCases {
+		t.Run(tt.name, func(t *testing.T) {
+			m := tt.rpy.Matrix()
+			q := tt.rpy.Quaternion()
+			if !matricesEqual(q.Matrix(), m, 1e-12) {
+				t.Errorf("quaternion matrix %v, want %v", q.Matrix(), m)
+			}
+			if !matricesEqual(m.Quaternion().Matrix(), m, 1e-12) {
+				t.Errorf("matrix -> quaternion -> matrix %v, want %v", m.Quaternion().Matrix(), m)
+			}
+			back := q.RPY()
+			if !matricesEqual(back.Matrix(), m, 1e-9) {
+				t.Errorf("RPY %v gives %v, want %v", back, back.Matrix(), m)
+			}
+			if back.Pitch < -math.Pi/2 || back.Pitch > math.Pi/2 {
+				t.Errorf("pitch %g outside [-π/2, π/2]", back.Pitch)
+			}
+			c := tt.rpy.Canonical()
+			if !matricesEqual(c.Matrix(), m, 1e-9) {
+				t.Errorf("canonical %v is another rotation", c)
+			}
+			if !sameAngle(c.Roll, back.Roll) || !sameAngle(c.Pitch, back.Pitch) || !sameAngle(c.Yaw, back.Yaw) {
+				t.Errorf("canonical %v, want %v as extracted", c, back)
+			}
+		})
+	}
+}
+
+func TestRPYSingularity(t *testing.T) {
+	// At a pitch of ±π/2 roll and yaw turn about the same axis, so only
+	// their difference, or sum, is recovered, all of it as yaw.
+	for _, tt := range []struct {
+		rpy     RPY
+		wantYaw float64
+	}{
+		{RPY{0.4, math.Pi / 2, 1.2}, 0.8},
+		{RPY{0.4, -math.Pi / 2, 1.2}, 1.6},
+	} {
+		got := tt.rpy.Matrix().RPY()
+		if got.Roll != 0 || got.Pitch != tt.rpy.Pitch || !sameAngle(got.Yaw, tt.wantYaw) {
+			t.Errorf("%v extracted as %v, want roll 0, pitch %g and yaw %g", tt.rpy, got, tt.rpy.Pitch, tt.wantYaw)
+		}
+	}
+}
+
+func TestRPYConvention(t *testing.T) {
+	x, y, z := Vec3{1, 0, 0}, Vec3{0, 1, 0}, Vec3{0, 0, 1}
+	for _, tt := range []struct {
+		name string
+		rpy  RPY
+		in   Vec3
+		want Vec3
+	}{
+		{"roll turns y to z", RPY{Roll: math.Pi / 2}, y, z},
+		{"pitch turns z to x", RPY{Pitch: math.Pi / 2}, z, x},
+		{"yaw turns x to y", RPY{Yaw: math.Pi / 2}, x, y},
+		// Roll first, then pitch, both about the fixed axes: y rolls to z,
+		// which pitches to x.
+		{"roll then pitch", RPY{Roll: math.Pi / 2, Pitch: math.Pi / 2}, y, x},
+		// Pitch first would leave y alone for the roll to turn to z.
+		{"pitch then yaw", RPY{Pitch: math.Pi / 2, Yaw: math.Pi / 2}, z, y},
+	} {
+		if got := tt.rpy.Matrix().MulVec(tt.in); !vecsEqual(got, tt.want, 1e-12) {
+			t.Errorf("%s: %v -> %v, want %v", tt.name, tt.in, got, tt.want)
+		}
+		if got := tt.rpy.Quaternion().Rotate(tt.in); !vecsEqual(got, tt.want, 1e-12) {
+			t.Errorf("%s: quaternion %v -> %v, want %v", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	for _, tt := range []struct {
+		in, want RPY
+	}{
+		{RPY{0.1, 0.2, 0.3}, RPY{0.1, 0.2, 0.3}},
+		{RPY{2 * math.Pi, 0, -2 * math.Pi}, RPY{}},
+		{RPY{0, 0, 3 * math.Pi / 2}, RPY{0, 0, -math.Pi / 2}},
+		{RPY{0, 0, -math.Pi}, RPY{0, 0, math.Pi}},
+		{RPY{0, math.Pi, 0}, RPY{math.Pi, 0, math.Pi}},
+		{RPY{0.4, math.Pi / 2, 1.2}, RPY{0, math.Pi / 2, 0.8}},
+		{RPY{1e-13, 0, 0}, RPY{}},
+	} {
+		got := tt.in.Canonical()
+		if !sameAngle(got.Roll, tt.want.Roll) || math.Abs(got.Pitch-tt.want.Pitch) > 1e-12 || !sameAngle(got.Yaw, tt.want.Yaw) {
+			t.Errorf("%v.Canonical() = %v, want %v", tt.in, got, tt.want)
+		}
+		for _, a := range []float64{got.Roll, got.Pitch, got.Yaw} {
+			if a <= -math.Pi || a > math.Pi {
+				t.Errorf("%v.Canonical() = %v, outside (-π, π]", tt.in, got)
+			}
+		}
+	}
+}
+
+func TestMatrixQuaternionBranches(t *testing.T) {
+	// Half turns about each axis have a trace of -1, which takes each of the
+	// branches for a dominant diagonal entry.
+	for _, tt := range []struct {
+		name string
+		q    Quaternion
+	}{
+		{"small", AxisAngle(Vec3{1, 2, 3}, 0.5)},
+		{"x", AxisAngle(Vec3{1, 0, 0}, math.Pi)},
+		{"y", AxisAngle(Vec3{0, 1, 0}, math.Pi)},
+		{"z", AxisAngle(Vec3{0, 0, 1}, math.Pi)},
+		{"diagonal", AxisAngle(Vec3{1, 1, 0}, 3)},
+	} {
+		m := tt.q.Matrix()
+		got := m.Quaternion()
+		if !matricesEqual(got.Matrix(), m, 1e-12) {
+			t.Errorf("%s: %v round-trips to %v", tt.name, tt.q, got)
+		}
+		if got.W < 0 || math.Abs(got.W*got.W+got.X*got.X+got.Y*got.Y+got.Z*got.Z-1) > 1e-12 {
+			t.Errorf("%s: %v is not a unit quaternion with W >= 0", tt.name, got)
+		}
+	}
+}
+
+func TestQuaternionMul(t *testing.T) {
+	a, b := RPY{0.3, -0.2, 1.1}.Quaternion(), RPY{-1.4, 0.6, 0.2}.Quaternion()
+	if got, want := a.Mul(b).Matrix(), a.Matrix().Mul(b.Matrix()); !matricesEqual(got, want, 1e-12) {
+		t.Errorf("product matrix %v, want %v", got, want)
+	}
+	if got := a.Mul(a.Conjugate()).Normalize(); !matricesEqual(got.Matrix(), Identity3(), 1e-12) {
+		t.Errorf("q * q⁻¹ = %v, want the identity", got)
+	}
+	if got := (Quaternion{W: -2}).Normalize(); got != IdentityQuaternion() {
+		t.Errorf("Normalize of -2 = %v, want the identity", got)
+	}
+}
+
+func TestSymmetricEigen(t *testing.T) {
+	r := RPY{0.3, 0.5, -0.9}.Matrix()
+	d := Mat3{{3, 0, 0}, {0, 2, 0}, {0, 0, 1}}
+	m := r.Mul(d).Mul(r.Transpose())
+	values, vectors := m.SymmetricEigen()
+	sum := 0.0
+	for i, v := range values {
+		sum += v
+		col := Vec3{vectors[0][i], vectors[1][i], vectors[2][i]}
+		if !vecsEqual(m.MulVec(col), col.Scale(v), 1e-9) {
+			t.Errorf("eigenvector %v of %g is not one", col, v)
+		}
+	}
+	if math.Abs(sum-6) > 1e-9 {
+		t.Errorf("eigenvalues %v, want 3, 2 and 1", values)
+	}
+}
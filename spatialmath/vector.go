@@ -0,0 +1,80 @@
+// Package spatialmath provides the small amount of 3D math needed to compose
+// and convert URDF origins: vectors, rotation matrices, quaternions, and rigid
+// poses.
+package spatialmath
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Vec3 is a 3D vector.
+type Vec3 struct {
+	X, Y, Z float64
+}
+
+// Add returns v + o.
+func (v Vec3) Add(o Vec3) Vec3 { return Vec3{v.X + o.X, v.Y + o.Y, v.Z + o.Z} }
+
+// Sub returns v - o.
+func (v Vec3) Sub(o Vec3) Vec3 { return Vec3{v.X - o.X, v.Y - o.Y, v.Z - o.Z} }
+
+// Scale returns v * s.
+func (v Vec3) Scale(s float64) Vec3 { return Vec3{v.X * s, v.Y * s, v.Z * s} }
+
+// Dot returns the dot product of v and o.
+func (v Vec3) Dot(o Vec3) float64 { return v.X*o.X + v.Y*o.Y + v.Z*o.Z }
+
+// Cross returns the cross product v × o.
+func (v Vec3) Cross(o Vec3) Vec3 {
+	return Vec3{v.Y*o.Z - v.Z*o.Y, v.Z*o.X - v.X*o.Z, v.X*o.Y - v.Y*o.X}
+}
+
+// Norm returns the Euclidean length of v.
+func (v Vec3) Norm() float64 { return math.Sqrt(v.Dot(v)) }
+
+// Normalize returns v scaled to unit length. The zero vector is returned unchanged.
+func (v Vec3) Normalize() Vec3 {
+	n := v.Norm()
+	if n == 0 {
+		return v
+	}
+	return v.Scale(1 / n)
+}
+
+// ParseVec3 parses a space-separated triple such as a URDF xyz or rpy
+// attribute. An empty string parses as the zero vector.
+func ParseVec3(s string) (Vec3, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return Vec3{}, nil
+	}
+	if len(fields) != 3 {
+		return Vec3{}, fmt.Errorf("expected 3 values, got %d in %q", len(fields), s)
+	}
+	var vals [3]float64
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return Vec3{}, fmt.Errorf("invalid number %q in %q", f, s)
+		}
+		vals[i] = v
+	}
+	return Vec3{vals[0], vals[1], vals[2]}, nil
+}
+
+// FormatVec3 formats v as a space-separated triple suitable for a URDF attribute.
+func FormatVec3(v Vec3) string {
+	return FormatFloat(v.X) + " " + FormatFloat(v.Y) + " " + FormatFloat(v.Z)
+}
+
+// FormatFloat formats f with the fewest digits that round-trip, printing
+// values within 1e-12 of zero as "0" so composed poses don't carry float noise.
+func FormatFloat(f float64) string {
+	if math.Abs(f) < 1e-12 {
+		return "0"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
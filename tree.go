@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nfranczak/urdf-simplifier/urdf"
+)
+
+func runTree(args []string) int {
+	var sf simplifyFlags
+	var lf logFlags
+	fs := newFlagSet("tree", "urdf-simplifier tree [flags] <robot.urdf>",
+		"Prints the kinematic tree of a URDF, each link under its parent with the\n"+
+			"joint attaching it, its type and axis, then the tree simplify would keep\n"+
+			"with the same config file and flags. Links it would remove are marked.")
+	sf.register(fs)
+	lf.register(fs)
+	positional := fs.parseOrExit(args)
+	if len(positional) != 1 {
+		fs.printUsage(os.Stderr)
+		return exitUsage
+	}
+
+	logger, err := lf.logger()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	cfg, err := sf.load(fs)
+	if err != nil {
+		logger.Error(err.Error())
+		return exitUsage
+	}
+	robot, err := loadRobot(positional[0], cfg.PackageMap, cfg.xacroInput())
+	if err != nil {
+		logger.Error(err.Error())
+		return exitCode(err)
+	}
+
+	filtered, warnings := urdf.Filter(robot, cfg.Options)
+	kept := make(map[string]bool)
+	for _, link := range filtered.Links {
+		kept[link.Name] = true
+	}
+	fmt.Printf("%s: %d links, %d joints\n", robot.Name, len(robot.Links), len(robot.Joints))
+	printTree(os.Stdout, robot, kept)
+	fmt.Printf("\nAfter filtering: %d links, %d joints\n", len(filtered.Links), len(filtered.Joints))
+	printTree(os.Stdout, filtered, nil)
+	for _, w := range warnings {
+		logger.Warn(w)
+	}
+	return exitOK
+}
+
+// printTree writes robot's links as an ASCII tree from each root, each link
+// after the joint attaching it to its parent. If kept is not nil, links not
+// in it are marked as removed.
+func printTree(w io.Writer, robot *urdf.Robot, kept map[string]bool) {
+	tree := urdf.NewKinematicTree(robot)
+	var visit func(link, prefix string, last, root bool)
+	visit = func(link, prefix string, last, root bool) {
+		line, below := link, ""
+		if !root {
+			branch := "|-- "
+			below = prefix + "|   "
+			if last {
+				branch, below = "`-- ", prefix+"    "
+			}
+			line = prefix + branch + link
+		}
+		if joint := tree.ParentJoint(link); joint != nil && !root {
+			line += "  " + describeJoint(joint)
+		}
+		if kept != nil && !kept[link] {
+			line += "  [removed]"
+		}
+		fmt.Fprintln(w, line)
+		// A malformed model can list a link under two parents; it is
+		// printed under the one the tree attaches it to.
+		var children []string
+		for _, child := range tree.Children(link) {
+			if p, _ := tree.Parent(child); p == link {
+				children = append(children, child)
+			}
+		}
+		for i, child := range children {
+			visit(child, below, i == len(children)-1, false)
+		}
+	}
+	for _, root := range tree.Roots {
+		visit(root, "", true, true)
+	}
+}
+
+// describeJoint returns the name, type and, for a moving joint, the axis of
+// joint, and the joint it mimics if any.
+func describeJoint(joint *urdf.Joint) string {
+	details := []string{joint.Type}
+	switch joint.Type {
+	case "revolute", "continuous", "prismatic":
+		if axis, err := joint.AxisVector(); err == nil {
+			details = append(details, "axis "+describeAxis(axis.X, axis.Y, axis.Z))
+		} else {
+			details = append(details, "axis "+joint.Axis.XYZ)
+		}
+	}
+	if joint.Mimic != nil {
+		details = append(details, "mimics "+joint.Mimic.Joint)
+	}
+	return joint.Name + " (" + strings.Join(details, ", ") + ")"
+}
+
+// describeAxis returns +x, -y and so on for a principal axis, and the
+// components otherwise.
+func describeAxis(x, y, z float64) string {
+	for i, c := range [3]float64{x, y, z} {
+		rest := x*x + y*y + z*z - c*c
+		if c != 0 && rest == 0 {
+			sign := "+"
+			if c < 0 {
+				sign = "-"
+			}
+			return sign + string(rune('x'+i))
+		}
+	}
+	return fmt.Sprintf("%g %g %g", x, y, z)
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+
+	"github.com/nfranczak/urdf-simplifier/urdf"
+)
+
+func runTUI(args []string) int {
+	var saveConfig string
+	var force bool
+	var sf simplifyFlags
+	var lf logFlags
+	fs := newFlagSet("tui", "urdf-simplifier tui [flags] <input.urdf> <output.urdf>",
+		"Shows the kinematic tree in the terminal so links can be kept or dropped and\n"+
+			"their collision geometry chosen interactively, then writes the simplified\n"+
+			"URDF. Any config file and flags given set the starting selection.")
+	fs.StringVar(&saveConfig, "s", "save-config", "", "also write the chosen options to this YAML file for non-interactive runs")
+	fs.BoolVar(&force, "f", "force", false, "overwrite the output and config files if they already exist")
+	sf.register(fs)
+	lf.register(fs)
+	positional := fs.parseOrExit(args)
+	if len(positional) != 2 || positional[0] == "-" {
+		fs.printUsage(os.Stderr)
+		return exitUsage
+	}
+
+	logger, err := lf.logger()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	cfg, err := sf.load(fs)
+	if err != nil {
+		logger.Error(err.Error())
+		return exitUsage
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		logger.Error("tui needs an interactive terminal; use simplify with --config instead")
+		return exitUsage
+	}
+
+	robot, err := loadRobot(positional[0], cfg.PackageMap, cfg.xacroInput())
+	if err != nil {
+		logger.Error(err.Error())
+		return exitCode(err)
+	}
+
+	sel := newLinkSelector(robot, cfg.Options)
+	save, err := sel.run(os.Stdin, os.Stdout)
+	if err != nil {
+		logger.Error(err.Error())
+		return exitFailure
+	}
+	if !save {
+		logger.Info("quit without writing")
+		return exitOK
+	}
+
+	opts := sel.options()
+	run := &simplifyRun{opts: opts, packages: cfg.PackageMap, xacro: cfg.xacroInput(), lf: &lf, logger: logger, printReports: true, force: force}
+	if err := run.file(positional[0], positional[1], logger); err != nil {
+		logger.Error(err.Error())
+		return exitCode(err)
+	}
+
+	if saveConfig != "" {
+		if _, err := os.Stat(saveConfig); err == nil && !force {
+			logger.Error(fmt.Sprintf("%s already exists; use --force to overwrite it", saveConfig))
+			return exitFailure
+		}
+		data, err := yaml.Marshal(config{PackageMap: absolutePackageMap(cfg.PackageMap), Xacro: cfg.Xacro, XacroArgs: cfg.XacroArgs, Options: opts})
+		if err != nil {
+			logger.Error(fmt.Sprintf("encoding config: %v", err))
+			return exitFailure
+		}
+		if err := writeOutput(saveConfig, data); err != nil {
+			logger.Error(fmt.Sprintf("writing config: %v", err))
+			return exitFailure
+		}
+		logger.Info("saved config", "config", saveConfig)
+	}
+	return exitOK
+}
+
+// absolutePackageMap makes every directory in packages absolute, so that a
+// saved config works regardless of where it is written.
+func absolutePackageMap(packages map[string]string) map[string]string {
+	if len(packages) == 0 {
+		return nil
+	}
+	abs := make(map[string]string)
+	for name, dir := range packages {
+		if p, err := filepath.Abs(dir); err == nil {
+			dir = p
+		}
+		abs[name] = dir
+	}
+	return abs
+}
+
+// selectorRow is one line of the tree view.
+type selectorRow struct {
+	link  string
+	depth int
+	// joint is the type of the joint attaching the link to its parent, or "" for a root.
+	joint string
+}
+
+// linkSelector is the state of the interactive tree view: which links are
+// checked and which geometry mode each one uses.
+type linkSelector struct {
+	robot *urdf.Robot
+	tree  *urdf.KinematicTree
+	rows  []selectorRow
+	base  urdf.Options
+	// defaults holds the links the chain filter keeps without keep/drop lists.
+	defaults map[string]bool
+	keep     map[string]bool
+	geometry map[string]urdf.GeometryMode
+
+	cursor, offset int
+	status         string
+}
+
+func newLinkSelector(robot *urdf.Robot, opts urdf.Options) *linkSelector {
+	chainOnly := opts
+	chainOnly.KeepLinks, chainOnly.DropLinks = nil, nil
+
+	s := &linkSelector{
+		robot:    robot,
+		tree:     urdf.NewKinematicTree(robot),
+		base:     opts,
+		defaults: urdf.KeptLinks(robot, chainOnly),
+		keep:     urdf.KeptLinks(robot, opts),
+		geometry: make(map[string]urdf.GeometryMode),
+	}
+	for _, root := range s.tree.Roots {
+		s.tree.Walk(root, func(link string, depth int) bool {
+			row := selectorRow{link: link, depth: depth}
+			if joint := s.tree.ParentJoint(link); joint != nil {
+				row.joint = joint.Type
+			}
+			s.rows = append(s.rows, row)
+			return true
+		})
+	}
+	for _, link := range robot.Links {
+		s.geometry[link.Name] = s.defaultGeometry()
+		if l, ok := opts.Links[link.Name]; ok && l.Geometry != "" {
+			s.geometry[link.Name] = l.Geometry
+		}
+	}
+	return s
+}
+
+func (s *linkSelector) defaultGeometry() urdf.GeometryMode {
+	if s.base.Geometry == "" {
+		return urdf.GeometryBox
+	}
+	return s.base.Geometry
+}
+
+// toggle flips the link under the cursor. Checking a link also checks the
+// path up to its nearest checked ancestor, as keep_links does; unchecking it
+// drops its whole subtree.
+func (s *linkSelector) toggle() {
+	link := s.rows[s.cursor].link
+	if s.keep[link] {
+		for _, l := range s.tree.DFS(link) {
+			s.keep[l] = false
+		}
+		return
+	}
+	s.keep[link] = true
+	for _, ancestor := range s.tree.Ancestors(link) {
+		if s.keep[ancestor] {
+			break
+		}
+		s.keep[ancestor] = true
+	}
+}
+
+// cycleGeometry switches the link under the cursor from box to capsule to
+// mesh to none and back to box.
+func (s *linkSelector) cycleGeometry() {
+	link := s.rows[s.cursor].link
+	switch s.geometry[link] {
+	case urdf.GeometryBox:
+		s.geometry[link] = urdf.GeometryCapsule
+	case urdf.GeometryCapsule:
+		s.geometry[link] = urdf.GeometryMesh
+	case urdf.GeometryMesh:
+		s.geometry[link] = urdf.GeometryNone
+	default:
+		s.geometry[link] = urdf.GeometryBox
+	}
+}
+
+// options returns simplification options that reproduce the current
+// selection: the chain filter of the starting options, keep_links for the
+// checked links it would drop, drop_links for the unchecked subtrees it would
+// keep, and per-link geometry wherever it differs from the default. Mass
+// overrides of the starting options are carried over for kept links.
+func (s *linkSelector) options() urdf.Options {
+	opts := s.base
+	opts.Geometry = s.defaultGeometry()
+	if opts.Chain == "" {
+		opts.Chain = urdf.ChainMain
+	}
+	opts.KeepLinks, opts.DropLinks, opts.Links = nil, nil, nil
+
+	dropped := make(map[string]bool)
+	for _, row := range s.rows {
+		parent, hasParent := s.tree.Parent(row.link)
+		switch {
+		case hasParent && dropped[parent]:
+			dropped[row.link] = true
+		case s.keep[row.link] && !s.defaults[row.link]:
+			// Only the deepest such links need listing; keep_links pulls in
+			// the rest of the path.
+			if !s.keepsDescendant(row.link) {
+				opts.KeepLinks = append(opts.KeepLinks, row.link)
+			}
+		case !s.keep[row.link] && s.defaults[row.link]:
+			opts.DropLinks = append(opts.DropLinks, row.link)
+			dropped[row.link] = true
+		}
+	}
+
+	for _, row := range s.rows {
+		if !s.keep[row.link] {
+			continue
+		}
+		l := urdf.LinkOptions{Mass: s.base.Links[row.link].Mass}
+		if s.geometry[row.link] != opts.Geometry {
+			l.Geometry = s.geometry[row.link]
+		}
+		if l != (urdf.LinkOptions{}) {
+			if opts.Links == nil {
+				opts.Links = make(map[string]urdf.LinkOptions)
+			}
+			opts.Links[row.link] = l
+		}
+	}
+	return opts
+}
+
+// keepsDescendant reports whether a link below link is checked but would be
+// dropped by the chain filter alone.
+func (s *linkSelector) keepsDescendant(link string) bool {
+	for _, l := range s.tree.DFS(link)[1:] {
+		if s.keep[l] && !s.defaults[l] {
+			return true
+		}
+	}
+	return false
+}
+
+// run puts the terminal in raw mode and handles keys until the user writes
+// (returning true) or quits (returning false).
+func (s *linkSelector) run(in, out *os.File) (bool, error) {
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return false, fmt.Errorf("setting up terminal: %w", err)
+	}
+	defer term.Restore(int(in.Fd()), state)
+
+	w := bufio.NewWriter(out)
+	// Use the alternate screen so the shell's scrollback is left untouched.
+	fmt.Fprint(w, "\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Fprint(w, "\x1b[?25h\x1b[?1049l")
+		w.Flush()
+	}()
+
+	buf := make([]byte, 16)
+	for {
+		_, height, err := term.GetSize(int(out.Fd()))
+		if err != nil || height < 6 {
+			height = 24
+		}
+		s.render(w, height)
+		if err := w.Flush(); err != nil {
+			return false, err
+		}
+
+		n, err := in.Read(buf)
+		if err != nil {
+			if err == io.EOF {
+				return false, nil
+			}
+			return false, err
+		}
+		s.status = ""
+		switch string(buf[:n]) {
+		case "\x1b[A", "k":
+			if s.cursor > 0 {
+				s.cursor--
+			}
+		case "\x1b[B", "j":
+			if s.cursor < len(s.rows)-1 {
+				s.cursor++
+			}
+		case " ", "x":
+			if len(s.rows) > 0 {
+				s.toggle()
+			}
+		case "g":
+			if len(s.rows) > 0 {
+				s.cycleGeometry()
+			}
+		case "\r", "w":
+			if s.countKept() == 0 {
+				s.status = "nothing is selected"
+				continue
+			}
+			return true, nil
+		case "q", "\x1b", "\x03":
+			return false, nil
+		}
+	}
+}
+
+func (s *linkSelector) countKept() int {
+	kept := 0
+	for _, row := range s.rows {
+		if s.keep[row.link] {
+			kept++
+		}
+	}
+	return kept
+}
+
+// render draws the tree view. Lines end in \r\n because raw mode disables
+// output newline translation.
+func (s *linkSelector) render(w io.Writer, height int) {
+	visible := height - 4
+	if s.cursor < s.offset {
+		s.offset = s.cursor
+	}
+	if s.cursor >= s.offset+visible {
+		s.offset = s.cursor - visible + 1
+	}
+
+	fmt.Fprint(w, "\x1b[H\x1b[2J")
+	fmt.Fprintf(w, "%s: %d of %d links kept\r\n\r\n", s.robot.Name, s.countKept(), len(s.rows))
+
+	width := 0
+	for _, row := range s.rows {
+		if n := 2*row.depth + len(row.link); n > width {
+			width = n
+		}
+	}
+	for i := s.offset; i < len(s.rows) && i < s.offset+visible; i++ {
+		row := s.rows[i]
+		cursor, check, geometry := "  ", "[ ]", ""
+		if i == s.cursor {
+			cursor = "> "
+		}
+		if s.keep[row.link] {
+			check = "[x]"
+			geometry = string(s.geometry[row.link])
+		}
+		name := strings.Repeat("  ", row.depth) + row.link
+		line := fmt.Sprintf("%s%s %-*s  %-10s %s", cursor, check, width, name, row.joint, geometry)
+		if i == s.cursor {
+			line = "\x1b[7m" + line + "\x1b[0m"
+		}
+		fmt.Fprint(w, line+"\r\n")
+	}
+
+	fmt.Fprint(w, "\r\n")
+	if s.status != "" {
+		fmt.Fprint(w, s.status+"  ")
+	}
+	fmt.Fprint(w, "up/down move  space keep/drop  g box/mesh  enter write  q quit")
+}
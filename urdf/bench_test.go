@@ -0,0 +1,99 @@
+package urdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// benchURDF returns a serial robot of n revolute links, each with a visual,
+// an inertial and a mesh collision, as exported from CAD.
+func benchURDF(n int) []byte {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0"?>` + "\n" + `<robot name="bench">` + "\n")
+	for i := range n {
+		fmt.Fprintf(&b, `  <link name="link%d">
+    <inertial><origin xyz="0 0 0.1" rpy="0 0 0"/><mass value="1.5"/><inertia ixx="0.01" ixy="0" ixz="0" iyy="0.01" iyz="0" izz="0.002"/></inertial>
+    <visual><origin xyz="0 0 0" rpy="0 0 0"/><geometry><mesh filename="package://bench/meshes/visual.dae"/></geometry></visual>
+    <collision><origin xyz="0 0 0" rpy="0 0 0"/><geometry><mesh filename="package://bench/meshes/link.stl"/></geometry></collision>
+  </link>
+`, i)
+		if i > 0 {
+			fmt.Fprintf(&b, `  <joint name="joint%d" type="revolute">
+    <parent link="link%d"/><child link="link%d"/>
+    <origin xyz="0 0 0.2" rpy="0 0 0"/><axis xyz="0 0 1"/>
+    <limit lower="-3.14" upper="3.14" effort="100" velocity="2"/>
+  </joint>
+`, i, i-1, i)
+		}
+	}
+	b.WriteString("</robot>\n")
+	return []byte(b.String())
+}
+
+// benchCube returns a binary STL of a 0.1 m cube standing on the origin.
+func benchCube() []byte {
+	corner := func(i int) [3]float32 {
+		return [3]float32{float32(i&1) * 0.1, float32(i>>1&1) * 0.1, float32(i>>2&1) * 0.1}
+	}
+	faces := [12][3]int{
+		{0, 2, 1}, {1, 2, 3}, {4, 5, 6}, {5, 7, 6}, {0, 1, 4}, {1, 5, 4},
+		{2, 6, 3}, {3, 6, 7}, {0, 4, 2}, {2, 4, 6}, {1, 3, 5}, {3, 7, 5},
+	}
+	data := make([]byte, 84+len(faces)*50)
+	binary.LittleEndian.PutUint32(data[80:], uint32(len(faces)))
+	for i, f := range faces {
+		for k, v := range f {
+			for c, x := range corner(v) {
+				binary.LittleEndian.PutUint32(data[84+i*50+12+k*12+c*4:], math.Float32bits(x))
+			}
+		}
+	}
+	return data
+}
+
+func BenchmarkParseURDF(b *testing.B) {
+	data := benchURDF(200)
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for range b.N {
+		if _, err := ParseURDF(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWriteURDF(b *testing.B) {
+	robot, err := ParseURDF(bytes.NewReader(benchURDF(200)))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for range b.N {
+		if err := WriteURDF(io.Discard, robot); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSimplify(b *testing.B) {
+	data := benchURDF(200)
+	resolver := FSResolver{FS: fstest.MapFS{"meshes/link.stl": {Data: benchCube()}}}
+	b.ResetTimer()
+	for range b.N {
+		b.StopTimer()
+		robot, err := ParseURDF(bytes.NewReader(data))
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+		if report := Simplify(robot, resolver, Options{}); len(report.FailedMeshes) > 0 {
+			b.Fatal(report.FailedMeshes[0].Error)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package urdf
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/nfranczak/urdf-simplifier/mesh"
+)
+
+// cacheVersion changes whenever what is cached, or how it is computed, does,
+// so that files written by another version are ignored rather than misread.
+const cacheVersion = 1
+
+// cachedBounds is what Options.CacheDir holds for one mesh.
+type cachedBounds struct {
+	Box     mesh.AABB            `json:"box"`
+	Volume  float64              `json:"volume,omitempty"`
+	Capsule *mesh.Capsule        `json:"capsule,omitempty"`
+	Mass    *mesh.MassProperties `json:"mass,omitempty"`
+	MassErr string               `json:"mass_error,omitempty"`
+}
+
+// cachePath returns the file in opts.CacheDir for the mesh whose content
+// hashes to sum, under the options that change what is computed for it.
+func cachePath(sum [sha256.Size]byte, opts Options, streamed bool) string {
+	key := sha256.Sum256(fmt.Appendf(nil, "v%d streamed=%t mass=%t capsule=%t", cacheVersion, streamed, opts.RecomputeInertia == InertiaFromMesh, opts.fitsCapsules()))
+	return filepath.Join(opts.CacheDir, hex.EncodeToString(sum[:])+"-"+hex.EncodeToString(key[:8])+".json")
+}
+
+// loadCachedBounds fills in entry from opts.CacheDir, reporting whether the
+// mesh was found there. A missing or unreadable cache file is a miss.
+func loadCachedBounds(sum [sha256.Size]byte, entry *contentEntry, opts Options, streamed bool) bool {
+	if opts.CacheDir == "" {
+		return false
+	}
+	p := cachePath(sum, opts, streamed)
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return false
+	}
+	var cached cachedBounds
+	if err := json.Unmarshal(data, &cached); err != nil {
+		opts.logger().Debug("ignoring unreadable cache file", "path", p, "error", err)
+		return false
+	}
+	entry.box, entry.volume, entry.capsule = cached.Box, cached.Volume, cached.Capsule
+	if cached.Mass != nil {
+		entry.mass = *cached.Mass
+	}
+	if cached.MassErr != "" {
+		entry.massErr = errors.New(cached.MassErr)
+	}
+	opts.logger().Log(context.Background(), LevelTrace, "reused cached mesh bounds", "path", p)
+	return true
+}
+
+// storeCachedBounds writes entry to opts.CacheDir for later runs. Meshes
+// that could not be read are not cached, and failing to write is only
+// logged, since the cache is an optimization.
+func storeCachedBounds(sum [sha256.Size]byte, entry *contentEntry, opts Options, streamed bool) {
+	if opts.CacheDir == "" || entry.err != nil {
+		return
+	}
+	cached := cachedBounds{Box: entry.box, Volume: entry.volume, Capsule: entry.capsule}
+	if opts.RecomputeInertia == InertiaFromMesh {
+		if entry.massErr != nil {
+			cached.MassErr = entry.massErr.Error()
+		} else {
+			cached.Mass = &entry.mass
+		}
+	}
+	data, err := json.Marshal(cached)
+	if err == nil {
+		err = writeCacheFile(cachePath(sum, opts, streamed), data)
+	}
+	if err != nil {
+		opts.logger().Debug("could not cache mesh bounds", "error", err)
+	}
+}
+
+// writeCacheFile writes data to p through a temporary file, so that runs
+// sharing the cache never read a partly written file.
+func writeCacheFile(p string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(p), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), p)
+}
@@ -0,0 +1,205 @@
+package urdf
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/nfranczak/urdf-simplifier/spatialmath"
+)
+
+// cleanup writes every origin rpy in canonical form, so that equivalent
+// rotations are written alike (see spatialmath.RPY.Canonical), then removes
+// what the simplified robot spells out but means nothing beyond the
+// defaults: origin xyz and rpy of all zeros, mimic multipliers of 1 and
+// offsets of 0, and axes without an xyz or on fixed joints, then the origins
+// and inertials left empty. Values that do not parse are kept, for
+// validation to report.
+func cleanup(robot *Robot, report *Report) {
+	removed := 0
+	origin := func(o **Origin) {
+		if *o == nil {
+			return
+		}
+		if canonicalRPY(*o) {
+			report.Changes.RPYNormalized++
+		}
+		if zeroVec3((*o).XYZ) {
+			(*o).XYZ = ""
+			removed++
+		}
+		if zeroVec3((*o).RPY) {
+			(*o).RPY = ""
+			removed++
+		}
+		if (*o).XYZ == "" && (*o).RPY == "" {
+			*o = nil
+			removed++
+		}
+	}
+	for i := range robot.Links {
+		link := &robot.Links[i]
+		origin(&link.Origin)
+		for j := range link.Visual {
+			origin(&link.Visual[j].Origin)
+		}
+		for j := range link.Collision {
+			origin(&link.Collision[j].Origin)
+		}
+		if in := link.Inertial; in != nil {
+			origin(&in.Origin)
+			if in.Mass == nil && in.Origin == nil && in.Inertia == nil {
+				link.Inertial = nil
+				removed++
+			}
+		}
+	}
+	for i := range robot.Joints {
+		joint := &robot.Joints[i]
+		origin(&joint.Origin)
+		if joint.Axis != nil && (joint.Axis.XYZ == "" || joint.Type == "fixed") {
+			joint.Axis = nil
+			removed++
+		}
+		if m := joint.Mimic; m != nil {
+			if v, err := strconv.ParseFloat(m.Multiplier, 64); err == nil && v == 1 {
+				m.Multiplier = ""
+				removed++
+			}
+			if v, err := strconv.ParseFloat(m.Offset, 64); err == nil && v == 0 {
+				m.Offset = ""
+				removed++
+			}
+		}
+	}
+	report.Changes.RedundantRemoved += removed
+}
+
+// canonicalRPY rewrites the origin's rpy in canonical form and reports
+// whether that changed it. An rpy already canonical is kept as written.
+func canonicalRPY(o *Origin) bool {
+	if o.RPY == "" {
+		return false
+	}
+	v, err := spatialmath.ParseVec3(o.RPY)
+	if err != nil {
+		return false
+	}
+	r := spatialmath.RPY{Roll: v.X, Pitch: v.Y, Yaw: v.Z}
+	c := r.Canonical()
+	if c == r {
+		return false
+	}
+	o.RPY = spatialmath.FormatVec3(spatialmath.Vec3{X: c.Roll, Y: c.Pitch, Z: c.Yaw})
+	return true
+}
+
+// zeroVec3 reports whether s is three numbers that are all zero.
+func zeroVec3(s string) bool {
+	if s == "" {
+		return false
+	}
+	v, err := spatialmath.ParseVec3(s)
+	return err == nil && v == spatialmath.Vec3{}
+}
+
+// snap rounds away the float noise CAD exports leave in origins and joint
+// axes: every origin xyz and rpy component within tol of a value with fewer
+// decimals, or for angles of a multiple of π/4, takes that value, and a joint
+// axis within tol of a principal axis becomes it exactly. Values that do
+// not parse are kept, for validation to report.
+func snap(robot *Robot, tol float64, report *Report) {
+	origin := func(o *Origin) {
+		if o == nil {
+			return
+		}
+		if snapVec3(&o.XYZ, tol, false) {
+			report.Changes.ValuesSnapped++
+		}
+		if snapVec3(&o.RPY, tol, true) {
+			report.Changes.ValuesSnapped++
+		}
+	}
+	for i := range robot.Links {
+		link := &robot.Links[i]
+		origin(link.Origin)
+		for j := range link.Visual {
+			origin(link.Visual[j].Origin)
+		}
+		for j := range link.Collision {
+			origin(link.Collision[j].Origin)
+		}
+		if link.Inertial != nil {
+			origin(link.Inertial.Origin)
+		}
+	}
+	for i := range robot.Joints {
+		joint := &robot.Joints[i]
+		origin(joint.Origin)
+		if joint.Axis != nil && snapAxis(&joint.Axis.XYZ, tol) {
+			report.Changes.ValuesSnapped++
+		}
+	}
+}
+
+// snapVec3 snaps each component of the vector *s, angles if angles is set,
+// and reports whether that changed it.
+func snapVec3(s *string, tol float64, angles bool) bool {
+	if *s == "" {
+		return false
+	}
+	v, err := spatialmath.ParseVec3(*s)
+	if err != nil {
+		return false
+	}
+	snapped := spatialmath.Vec3{X: snapValue(v.X, tol, angles), Y: snapValue(v.Y, tol, angles), Z: snapValue(v.Z, tol, angles)}
+	if snapped == v {
+		return false
+	}
+	*s = spatialmath.FormatVec3(snapped)
+	return true
+}
+
+// snapValue returns the multiple of π/4 within tol of v if angle is set and
+// there is one, and otherwise v rounded to the fewest decimals that keep it
+// within tol.
+func snapValue(v, tol float64, angle bool) float64 {
+	if angle {
+		if n := math.Round(v / (math.Pi / 4)); math.Abs(v-n*math.Pi/4) <= tol {
+			return n * math.Pi / 4
+		}
+	}
+	for d := 0; d <= 15; d++ {
+		p := math.Pow(10, float64(d))
+		if r := math.Round(v*p) / p; math.Abs(r-v) <= tol {
+			return r
+		}
+	}
+	return v
+}
+
+// snapAxis replaces the axis *s with the principal axis it is within tol
+// of, once normalized, and reports whether there was one it was not already.
+func snapAxis(s *string, tol float64) bool {
+	if *s == "" {
+		return false
+	}
+	v, err := spatialmath.ParseVec3(*s)
+	if err != nil || v.Norm() == 0 {
+		return false
+	}
+	n := v.Normalize()
+	for i, c := range [3]float64{n.X, n.Y, n.Z} {
+		if math.Abs(math.Abs(c)-1) > tol {
+			continue
+		}
+		var axis [3]float64
+		axis[i] = math.Copysign(1, c)
+		snapped := spatialmath.Vec3{X: axis[0], Y: axis[1], Z: axis[2]}
+		if snapped == v || math.Abs(n.X-snapped.X) > tol || math.Abs(n.Y-snapped.Y) > tol || math.Abs(n.Z-snapped.Z) > tol {
+			return false
+		}
+		*s = spatialmath.FormatVec3(snapped)
+		return true
+	}
+	return false
+}
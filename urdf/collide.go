@@ -0,0 +1,310 @@
+package urdf
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/nfranczak/urdf-simplifier/spatialmath"
+)
+
+// CollisionPair is two links whose collision boxes overlap.
+type CollisionPair struct {
+	Links [2]string `json:"links"`
+	// Geometries names the most deeply overlapping box of each link.
+	Geometries [2]string `json:"geometries"`
+	// Depth is how far the boxes would have to move apart to stop
+	// overlapping, in meters.
+	Depth float64 `json:"depth"`
+}
+
+// orientedBox is a box in the frame of a robot's root link, named as the
+// collision or obstacle it came from.
+type orientedBox struct {
+	name   string
+	center spatialmath.Vec3
+	axes   [3]spatialmath.Vec3
+	half   [3]float64
+}
+
+// linkBoxes returns the link's collision boxes placed at pose, named after
+// their collisions, or the link if a collision has no name. Collisions that
+// are not boxes, or whose box cannot be parsed, are left out.
+func linkBoxes(link *Link, pose spatialmath.Pose) []orientedBox {
+	var boxes []orientedBox
+	for _, c := range link.Collision {
+		if c.Geometry == nil || c.Geometry.Box == nil {
+			continue
+		}
+		size, err := spatialmath.ParseVec3(c.Geometry.Box.Size)
+		if err != nil {
+			continue
+		}
+		origin, err := c.Origin.Pose()
+		if err != nil {
+			continue
+		}
+		box := newOrientedBox(pose.Compose(origin), size)
+		if box.name = c.Name; box.name == "" {
+			box.name = link.Name
+		}
+		boxes = append(boxes, box)
+	}
+	return boxes
+}
+
+// newOrientedBox returns a box of the given size centered on p.
+func newOrientedBox(p spatialmath.Pose, size spatialmath.Vec3) orientedBox {
+	r := p.Rotation.Matrix()
+	return orientedBox{
+		center: p.Translation,
+		axes: [3]spatialmath.Vec3{
+			{X: r[0][0], Y: r[1][0], Z: r[2][0]},
+			{X: r[0][1], Y: r[1][1], Z: r[2][1]},
+			{X: r[0][2], Y: r[1][2], Z: r[2][2]},
+		},
+		half: [3]float64{size.X / 2, size.Y / 2, size.Z / 2},
+	}
+}
+
+// penetration returns how deeply a and b overlap, by the separating axis
+// theorem: the smallest overlap of their projections onto the face normals
+// of both boxes and the cross products of their edges. It is zero or
+// negative if they don't overlap.
+func (a orientedBox) penetration(b orientedBox) float64 {
+	axes := make([]spatialmath.Vec3, 0, 15)
+	axes = append(axes, a.axes[:]...)
+	axes = append(axes, b.axes[:]...)
+	for _, u := range a.axes {
+		for _, v := range b.axes {
+			// Parallel edges give no new axis.
+			if c := u.Cross(v); c.Norm() > 1e-9 {
+				axes = append(axes, c.Normalize())
+			}
+		}
+	}
+	d := b.center.Sub(a.center)
+	depth := math.Inf(1)
+	for _, axis := range axes {
+		overlap := a.radius(axis) + b.radius(axis) - math.Abs(d.Dot(axis))
+		depth = min(depth, overlap)
+		if depth <= 0 {
+			break
+		}
+	}
+	return depth
+}
+
+// radius returns half the length of the box's projection onto axis.
+func (a orientedBox) radius(axis spatialmath.Vec3) float64 {
+	var r float64
+	for i, u := range a.axes {
+		r += a.half[i] * math.Abs(u.Dot(axis))
+	}
+	return r
+}
+
+// minOverlap is the depth below which boxes count as touching rather than
+// colliding, so that boxes meeting face to face aren't reported.
+const minOverlap = 1e-6
+
+// collisionModel holds what checking a robot's links against each other at
+// different joint positions needs.
+type collisionModel struct {
+	robot    *Robot
+	tree     *KinematicTree
+	adjacent map[[2]string]bool
+}
+
+func newCollisionModel(robot *Robot) *collisionModel {
+	m := &collisionModel{robot: robot, tree: NewKinematicTree(robot), adjacent: make(map[[2]string]bool)}
+	for _, joint := range robot.Joints {
+		if joint.Parent != nil && joint.Child != nil {
+			m.adjacent[[2]string{joint.Parent.Link, joint.Child.Link}] = true
+			m.adjacent[[2]string{joint.Child.Link, joint.Parent.Link}] = true
+		}
+	}
+	return m
+}
+
+// pairDepth is how deeply the collision boxes of robot.Links[a] and
+// robot.Links[b] overlap, see orientedBox.penetration, and names the pair of
+// boxes overlapping the most.
+type pairDepth struct {
+	a, b  int
+	depth float64
+	boxes [2]string
+}
+
+// depths returns the overlap of every pair of links not joined to each other
+// by a joint with each joint at its position in positions, in document order.
+// Links under different roots don't share a frame and aren't paired.
+func (m *collisionModel) depths(positions map[string]float64) []pairDepth {
+	root := make(map[string]string)
+	boxes := make(map[string][]orientedBox)
+	for _, r := range m.tree.Roots {
+		for link, pose := range m.tree.Poses(r, positions) {
+			root[link] = r
+			boxes[link] = linkBoxes(m.robot.FindLink(link), pose)
+		}
+	}
+
+	var depths []pairDepth
+	links := m.robot.Links
+	for i := range links {
+		for j := i + 1; j < len(links); j++ {
+			a, b := links[i].Name, links[j].Name
+			if m.adjacent[[2]string{a, b}] || root[a] == "" || root[a] != root[b] {
+				continue
+			}
+			d := pairDepth{a: i, b: j}
+			for _, ba := range boxes[a] {
+				for _, bb := range boxes[b] {
+					if depth := ba.penetration(bb); depth > d.depth {
+						d.depth, d.boxes = depth, [2]string{ba.name, bb.name}
+					}
+				}
+			}
+			depths = append(depths, d)
+		}
+	}
+	return depths
+}
+
+// SelfCollisions returns the pairs of links whose collision boxes overlap
+// with each joint at its position in positions, at zero if not listed. Links
+// attached to each other by a joint are expected to touch and are skipped,
+// as are collisions that are not boxes. Pairs are in document order.
+func SelfCollisions(robot *Robot, positions map[string]float64) []CollisionPair {
+	var pairs []CollisionPair
+	for _, d := range newCollisionModel(robot).depths(positions) {
+		if d.depth > minOverlap {
+			pairs = append(pairs, CollisionPair{Links: [2]string{robot.Links[d.a].Name, robot.Links[d.b].Name}, Geometries: d.boxes, Depth: d.depth})
+		}
+	}
+	return pairs
+}
+
+// checkSelfCollisions warns about the non-adjacent links of the simplified
+// robot whose boxes overlap at the zero pose, which planners treat as a
+// robot in collision before it has moved.
+func checkSelfCollisions(robot *Robot, opts Options, report *Report) {
+	for _, pair := range SelfCollisions(robot, nil) {
+		report.SelfCollisions = append(report.SelfCollisions, pair)
+		a, b := pair.Geometries[0], pair.Geometries[1]
+		opts.logger().Warn("collision boxes overlap at the zero pose", "link", pair.Links[0], "other", pair.Links[1], "geometry", a, "other_geometry", b, "depth", pair.Depth)
+		report.Warnings = append(report.Warnings, fmt.Sprintf("collision boxes %s and %s overlap by %.1f mm at the zero pose", a, b, pair.Depth*1000))
+	}
+}
+
+// sweepSteps is how many evenly spaced positions, limits included, each
+// joint is checked at by SweptCollisions.
+const sweepSteps = 100
+
+// SweptCollision is a pair of links whose collision boxes overlap somewhere
+// in the travel of a joint, though not at the zero pose.
+type SweptCollision struct {
+	// Joint moves Links[0], and Links[1] stays put. Geometries names their
+	// boxes that overlap the most.
+	Joint      string    `json:"joint"`
+	Links      [2]string `json:"links"`
+	Geometries [2]string `json:"geometries"`
+	// Position is the position nearest zero, in radians or meters, at which
+	// the boxes overlap, and Depth the deepest overlap over the travel.
+	Position float64 `json:"position"`
+	Depth    float64 `json:"depth"`
+}
+
+// sweepRange returns the travel of a moving joint, or false if the joint
+// doesn't move or has no usable limits.
+func sweepRange(joint *Joint) (lower, upper float64, ok bool) {
+	switch {
+	case joint.Type == "continuous":
+		return -math.Pi, math.Pi, true
+	case joint.Type != "revolute" && joint.Type != "prismatic":
+		return 0, 0, false
+	case joint.Limit != nil && joint.Limit.Upper > joint.Limit.Lower:
+		return joint.Limit.Lower, joint.Limit.Upper, true
+	}
+	return 0, 0, false
+}
+
+// SweptCollisions moves each joint through its limits in turn, with every
+// other joint at zero, and returns the pairs of a link it moves and a link
+// it doesn't whose collision boxes overlap along the way. Pairs that already
+// overlap at the zero pose are left out, as SelfCollisions reports them;
+// links joined by a joint and collisions that are not boxes are skipped as
+// there. Pairs are in document order of the joints, then the links.
+func SweptCollisions(robot *Robot) []SweptCollision {
+	m := newCollisionModel(robot)
+	atZero := make(map[[2]int]bool)
+	for _, d := range m.depths(nil) {
+		if d.depth > minOverlap {
+			atZero[[2]int{d.a, d.b}] = true
+		}
+	}
+
+	var swept []SweptCollision
+	for _, joint := range robot.Joints {
+		lower, upper, ok := sweepRange(&joint)
+		if !ok || joint.Child == nil {
+			continue
+		}
+		moving := make(map[string]bool)
+		for _, link := range m.tree.DFS(joint.Child.Link) {
+			moving[link] = true
+		}
+
+		found := make(map[[2]int]*SweptCollision)
+		var order [][2]int
+		for i := range sweepSteps {
+			q := lower + (upper-lower)*float64(i)/(sweepSteps-1)
+			for _, d := range m.depths(map[string]float64{joint.Name: q}) {
+				key := [2]int{d.a, d.b}
+				a, b := robot.Links[d.a].Name, robot.Links[d.b].Name
+				if d.depth <= minOverlap || atZero[key] || moving[a] == moving[b] {
+					continue
+				}
+				boxes := d.boxes
+				if moving[b] {
+					a, b = b, a
+					boxes[0], boxes[1] = boxes[1], boxes[0]
+				}
+				c := found[key]
+				if c == nil {
+					c = &SweptCollision{Joint: joint.Name, Links: [2]string{a, b}, Position: q}
+					found[key] = c
+					order = append(order, key)
+				}
+				if d.depth > c.Depth {
+					c.Depth, c.Geometries = d.depth, boxes
+				}
+				if math.Abs(q) < math.Abs(c.Position) {
+					c.Position = q
+				}
+			}
+		}
+		slices.SortFunc(order, func(x, y [2]int) int {
+			if x[0] != y[0] {
+				return x[0] - y[0]
+			}
+			return x[1] - y[1]
+		})
+		for _, key := range order {
+			swept = append(swept, *found[key])
+		}
+	}
+	return swept
+}
+
+// checkSweptCollisions warns about the collision boxes that are clear at the
+// zero pose but run into each other as a joint moves.
+func checkSweptCollisions(robot *Robot, opts Options, report *Report) {
+	for _, c := range SweptCollisions(robot) {
+		report.SweptCollisions = append(report.SweptCollisions, c)
+		opts.logger().Warn("collision boxes overlap during joint travel", "joint", c.Joint, "link", c.Links[0], "other", c.Links[1],
+			"geometry", c.Geometries[0], "other_geometry", c.Geometries[1], "position", c.Position, "depth", c.Depth)
+		report.Warnings = append(report.Warnings, fmt.Sprintf("collision boxes %s and %s overlap by up to %.1f mm as %s moves, nearest zero at %.3f",
+			c.Geometries[0], c.Geometries[1], c.Depth*1000, c.Joint, c.Position))
+	}
+}
@@ -0,0 +1,94 @@
+package urdf
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Detached lists the links of a robot that are not attached to the rest of
+// it, in document order.
+type Detached struct {
+	// Root is the root link the others are measured against: the one with
+	// the most links below it, the first of them if several tie.
+	Root string `json:"root"`
+	// Orphans are the links no joint references, as left by a typo in a
+	// joint's parent or child. A robot with a single link has none.
+	Orphans []string `json:"orphans,omitempty"`
+	// Unreachable are the other links that Root's joints don't lead to.
+	Unreachable []string `json:"unreachable,omitempty"`
+}
+
+// DetachedLinks returns the orphaned and unreachable links of robot, or nil
+// if every link is attached to the main root.
+func DetachedLinks(robot *Robot) *Detached {
+	tree := NewKinematicTree(robot)
+	d := &Detached{}
+	var reachable []string
+	for _, root := range tree.Roots {
+		if below := tree.DFS(root); len(below) > len(reachable) {
+			d.Root, reachable = root, below
+		}
+	}
+
+	referenced := make(map[string]bool)
+	for _, joint := range robot.Joints {
+		if joint.Parent != nil {
+			referenced[joint.Parent.Link] = true
+		}
+		if joint.Child != nil {
+			referenced[joint.Child.Link] = true
+		}
+	}
+	for _, link := range robot.Links {
+		switch {
+		case slices.Contains(reachable, link.Name):
+		case !referenced[link.Name] && len(robot.Links) > 1:
+			d.Orphans = append(d.Orphans, link.Name)
+		default:
+			d.Unreachable = append(d.Unreachable, link.Name)
+		}
+	}
+	if len(d.Orphans) == 0 && len(d.Unreachable) == 0 {
+		return nil
+	}
+	return d
+}
+
+// checkInputDetached warns about the links detached in the input.
+func checkInputDetached(robot *Robot, opts Options, report *Report) {
+	d := DetachedLinks(robot)
+	report.DetachedBefore = d
+	if d == nil {
+		return
+	}
+	for _, link := range d.Orphans {
+		warnDetached(fmt.Sprintf("link %s is not referenced by any joint", link), opts, report)
+	}
+	for _, link := range d.Unreachable {
+		warnDetached(fmt.Sprintf("link %s is not connected to root link %s", link, d.Root), opts, report)
+	}
+}
+
+// checkFilteredDetached warns about the links that filtering detached, after
+// checkInputDetached has listed those detached to begin with.
+func checkFilteredDetached(robot *Robot, opts Options, report *Report) {
+	d := DetachedLinks(robot)
+	report.DetachedAfter = d
+	if d == nil {
+		return
+	}
+	var before []string
+	if b := report.DetachedBefore; b != nil {
+		before = append(slices.Clone(b.Orphans), b.Unreachable...)
+	}
+	for _, link := range append(slices.Clone(d.Orphans), d.Unreachable...) {
+		if !slices.Contains(before, link) {
+			warnDetached(fmt.Sprintf("filtering left link %s not connected to root link %s", link, d.Root), opts, report)
+		}
+	}
+}
+
+func warnDetached(msg string, opts Options, report *Report) {
+	opts.logger().Warn(msg)
+	report.Warnings = append(report.Warnings, msg)
+}
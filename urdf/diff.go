@@ -0,0 +1,308 @@
+package urdf
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/nfranczak/urdf-simplifier/spatialmath"
+)
+
+// Difference describes one structural difference between two robot models.
+type Difference struct {
+	// Element names the link or joint that differs, e.g. "link base_link".
+	Element string
+	// Detail describes the difference.
+	Detail string
+}
+
+func (d Difference) String() string {
+	return fmt.Sprintf("%s: %s", d.Element, d.Detail)
+}
+
+// DefaultTolerance is the tolerance Diff uses when DiffOptions.Tolerance is zero.
+const DefaultTolerance = 1e-6
+
+// DiffOptions controls how strictly Diff compares numeric values.
+type DiffOptions struct {
+	// Tolerance is the largest difference between two numbers that is still
+	// treated as equal: meters for positions and sizes, radians for
+	// rotations, and the element's own unit for limits, masses and dynamics.
+	// Zero means DefaultTolerance; use a negative value for exact comparison.
+	Tolerance float64
+}
+
+func (o DiffOptions) tolerance() float64 {
+	switch {
+	case o.Tolerance == 0:
+		return DefaultTolerance
+	case o.Tolerance < 0:
+		return 0
+	}
+	return o.Tolerance
+}
+
+// Diff compares two robot models by link and joint name and returns their
+// differences, in the document order of a followed by b. Origins are compared
+// as transforms, so "0 0 0" and an omitted attribute are equal, as are RPY
+// triples describing the same rotation.
+func Diff(a, b *Robot, opts DiffOptions) []Difference {
+	var diffs []Difference
+	add := func(element, format string, args ...any) {
+		diffs = append(diffs, Difference{element, fmt.Sprintf(format, args...)})
+	}
+	tol := opts.tolerance()
+
+	if a.Name != b.Name {
+		add("robot", "name changed from %q to %q", a.Name, b.Name)
+	}
+
+	for _, la := range a.Links {
+		lb := b.FindLink(la.Name)
+		if lb == nil {
+			add("link "+la.Name, "removed")
+			continue
+		}
+		diffLink(&la, lb, tol, func(format string, args ...any) { add("link "+la.Name, format, args...) })
+	}
+	for _, lb := range b.Links {
+		if a.FindLink(lb.Name) == nil {
+			add("link "+lb.Name, "added")
+		}
+	}
+
+	for _, ja := range a.Joints {
+		jb := b.FindJoint(ja.Name)
+		element := "joint " + ja.Name
+		if jb == nil {
+			add(element, "removed")
+			continue
+		}
+		if ja.Type != jb.Type {
+			add(element, "type changed from %s to %s", ja.Type, jb.Type)
+		}
+		if pa, pb := parentName(&ja), parentName(jb); pa != pb {
+			add(element, "parent changed from %q to %q", pa, pb)
+		}
+		if ca, cb := childName(&ja), childName(jb); ca != cb {
+			add(element, "child changed from %q to %q", ca, cb)
+		}
+		if !originsEqual(ja.Origin, jb.Origin, tol) {
+			add(element, "origin changed from %s to %s", originString(ja.Origin), originString(jb.Origin))
+		}
+		diffJointAttributes(&ja, jb, tol, func(format string, args ...any) { add(element, format, args...) })
+	}
+	for _, jb := range b.Joints {
+		if a.FindJoint(jb.Name) == nil {
+			add("joint "+jb.Name, "added")
+		}
+	}
+
+	return diffs
+}
+
+// diffLink compares the origin, collision and visual geometry, collision
+// names, and inertial mass of two links.
+func diffLink(a, b *Link, tol float64, add func(format string, args ...any)) {
+	if !originsEqual(a.Origin, b.Origin, tol) {
+		add("origin changed from %s to %s", originString(a.Origin), originString(b.Origin))
+	}
+	ca, cb := collisionGeometries(a), collisionGeometries(b)
+	diffGeometries("collision", ca, cb, tol, add)
+	for i := 0; i < len(a.Collision) && i < len(b.Collision); i++ {
+		if na, nb := a.Collision[i].Name, b.Collision[i].Name; na != nb {
+			add("collision %d renamed from %q to %q", i, na, nb)
+		}
+	}
+	va, vb := visualGeometries(a), visualGeometries(b)
+	diffGeometries("visual", va, vb, tol, add)
+
+	ma, mb := linkMass(a), linkMass(b)
+	switch {
+	case ma == nil && mb != nil:
+		add("inertial added (mass %g)", *mb)
+	case ma != nil && mb == nil:
+		add("inertial removed")
+	case ma != nil && !within(*ma, *mb, tol):
+		add("mass changed from %g to %g", *ma, *mb)
+	}
+}
+
+func collisionGeometries(l *Link) []*Geometry {
+	var gs []*Geometry
+	for _, c := range l.Collision {
+		gs = append(gs, c.Geometry)
+	}
+	return gs
+}
+
+func visualGeometries(l *Link) []*Geometry {
+	var gs []*Geometry
+	for _, v := range l.Visual {
+		gs = append(gs, v.Geometry)
+	}
+	return gs
+}
+
+// diffGeometries compares two lists of geometry elements pairwise, by position.
+func diffGeometries(kind string, a, b []*Geometry, tol float64, add func(format string, args ...any)) {
+	if len(a) != len(b) {
+		add("%s count changed from %d to %d", kind, len(a), len(b))
+	}
+	for i := 0; i < len(a) && i < len(b); i++ {
+		ta, tb := geometryType(a[i]), geometryType(b[i])
+		if ta != tb {
+			add("%s %d changed from %s to %s", kind, i, ta, tb)
+			continue
+		}
+		switch ta {
+		case "mesh":
+			if a[i].Mesh.Filename != b[i].Mesh.Filename {
+				add("%s %d mesh changed from %s to %s", kind, i, a[i].Mesh.Filename, b[i].Mesh.Filename)
+			}
+		case "box":
+			if !vectorStringsEqual(a[i].Box.Size, b[i].Box.Size, tol) {
+				add("%s %d box size changed from %q to %q", kind, i, a[i].Box.Size, b[i].Box.Size)
+			}
+		case "capsule":
+			ca, cb := a[i].Capsule, b[i].Capsule
+			if math.Abs(ca.Radius-cb.Radius) > tol || math.Abs(ca.Length-cb.Length) > tol {
+				add("%s %d capsule changed from radius %g, length %g to radius %g, length %g", kind, i, ca.Radius, ca.Length, cb.Radius, cb.Length)
+			}
+		}
+	}
+}
+
+func geometryType(g *Geometry) string {
+	switch {
+	case g == nil:
+		return "none"
+	case g.Mesh != nil:
+		return "mesh"
+	case g.Box != nil:
+		return "box"
+	case g.Capsule != nil:
+		return "capsule"
+	}
+	return "none"
+}
+
+func linkMass(l *Link) *float64 {
+	if l.Inertial == nil || l.Inertial.Mass == nil {
+		return nil
+	}
+	return &l.Inertial.Mass.Value
+}
+
+// diffJointAttributes compares the axis, limits and dynamics of two joints.
+func diffJointAttributes(a, b *Joint, tol float64, add func(format string, args ...any)) {
+	axisA, errA := a.AxisVector()
+	axisB, errB := b.AxisVector()
+	if errA != nil || errB != nil {
+		if axisString(a) != axisString(b) {
+			add("axis changed from %q to %q", axisString(a), axisString(b))
+		}
+	} else if !vectorsEqual(axisA, axisB, tol) {
+		add("axis changed from %q to %q", axisString(a), axisString(b))
+	}
+
+	switch la, lb := a.Limit, b.Limit; {
+	case la == nil && lb != nil:
+		add("limit added")
+	case la != nil && lb == nil:
+		add("limit removed")
+	case la != nil:
+		for _, f := range []struct {
+			name   string
+			va, vb float64
+		}{
+			{"lower", la.Lower, lb.Lower},
+			{"upper", la.Upper, lb.Upper},
+			{"effort", la.Effort, lb.Effort},
+			{"velocity", la.Velocity, lb.Velocity},
+		} {
+			if !within(f.va, f.vb, tol) {
+				add("%s limit changed from %g to %g", f.name, f.va, f.vb)
+			}
+		}
+	}
+
+	switch da, db := a.Dynamics, b.Dynamics; {
+	case da == nil && db != nil:
+		add("dynamics added")
+	case da != nil && db == nil:
+		add("dynamics removed")
+	case da != nil:
+		if !within(da.Damping, db.Damping, tol) {
+			add("damping changed from %g to %g", da.Damping, db.Damping)
+		}
+		if !within(da.Friction, db.Friction, tol) {
+			add("friction changed from %g to %g", da.Friction, db.Friction)
+		}
+	}
+}
+
+func axisString(j *Joint) string {
+	if j.Axis == nil {
+		return ""
+	}
+	return j.Axis.XYZ
+}
+
+// originsEqual compares two origins as transforms: translations within tol
+// meters and rotations within tol radians of each other. Origins that do not
+// parse are compared as text.
+func originsEqual(a, b *Origin, tol float64) bool {
+	pa, errA := a.Pose()
+	pb, errB := b.Pose()
+	if errA != nil || errB != nil {
+		return originString(a) == originString(b)
+	}
+	if !vectorsEqual(pa.Translation, pb.Translation, tol) {
+		return false
+	}
+	return rotationAngle(pa.Rotation, pb.Rotation) <= tol
+}
+
+// rotationAngle returns the angle in radians of the rotation taking a to b.
+func rotationAngle(a, b spatialmath.Quaternion) float64 {
+	rel := a.Conjugate().Mul(b).Normalize()
+	return 2 * math.Acos(math.Min(1, math.Abs(rel.W)))
+}
+
+func vectorStringsEqual(a, b string, tol float64) bool {
+	va, errA := spatialmath.ParseVec3(a)
+	vb, errB := spatialmath.ParseVec3(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return vectorsEqual(va, vb, tol)
+}
+
+func vectorsEqual(a, b spatialmath.Vec3, tol float64) bool {
+	return within(a.X, b.X, tol) && within(a.Y, b.Y, tol) && within(a.Z, b.Z, tol)
+}
+
+func within(a, b, tol float64) bool {
+	return math.Abs(a-b) <= tol
+}
+
+func parentName(j *Joint) string {
+	if j.Parent == nil {
+		return ""
+	}
+	return j.Parent.Link
+}
+
+func childName(j *Joint) string {
+	if j.Child == nil {
+		return ""
+	}
+	return j.Child.Link
+}
+
+func originString(o *Origin) string {
+	if o == nil {
+		return "xyz=\"\" rpy=\"\""
+	}
+	return fmt.Sprintf("xyz=%q rpy=%q", o.XYZ, o.RPY)
+}
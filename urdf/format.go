@@ -0,0 +1,239 @@
+package urdf
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Format is the layout WriteURDFFormat writes a document in.
+type Format struct {
+	// Indent is what each level of nesting is indented by.
+	Indent string
+	// Quote is the character attribute values are quoted with, '"' or '\''.
+	Quote byte
+	// SelfClose writes elements without children as <origin/> rather than
+	// <origin></origin>, and SelfCloseSpace as <origin />.
+	SelfClose      bool
+	SelfCloseSpace bool
+	// NoDeclaration leaves out the <?xml ...?> declaration.
+	NoDeclaration bool
+	// LineEnding ends each line, "\n" if empty; "\r\n" for Windows tools.
+	LineEnding string
+}
+
+// DefaultFormat is the layout of WriteURDF: two-space indentation, double
+// quotes, and explicit end tags, as xml.MarshalIndent writes.
+var DefaultFormat = Format{Indent: "  ", Quote: '"'}
+
+var (
+	leadingSpace = regexp.MustCompile(`(?m)^([ \t]+)<`)
+	quotedAttr   = regexp.MustCompile(`[\w:.-]+\s*=\s*(["'])`)
+	selfClosing  = regexp.MustCompile(`(\s?)/>`)
+	emptyElement = regexp.MustCompile(`<([\w:.-]+)(\s[^<>]*)?></([\w:.-]+)>`)
+)
+
+// DetectFormat returns the layout of the document in data, so that
+// regenerating a hand-maintained file changes as few lines as possible: the
+// shallowest indentation of any line starting a tag, the quote most
+// attributes use, whether most elements without children are written
+// self-closing, and with a space before the slash, whether it has an XML
+// declaration, and whether its lines end in CRLF. What data gives no sign
+// of is taken from DefaultFormat.
+func DetectFormat(data []byte) Format {
+	f := DefaultFormat
+	indent := ""
+	for _, m := range leadingSpace.FindAllSubmatch(data, -1) {
+		if s := string(m[1]); indent == "" || len(s) < len(indent) {
+			indent = s
+		}
+	}
+	if indent != "" {
+		f.Indent = indent
+	}
+
+	var single, double int
+	for _, m := range quotedAttr.FindAllSubmatch(data, -1) {
+		if m[1][0] == '\'' {
+			single++
+		} else {
+			double++
+		}
+	}
+	if single > double {
+		f.Quote = '\''
+	}
+
+	var spaced, tight int
+	for _, m := range selfClosing.FindAllSubmatch(data, -1) {
+		if len(m[1]) > 0 {
+			spaced++
+		} else {
+			tight++
+		}
+	}
+	empty := 0
+	for _, m := range emptyElement.FindAllSubmatch(data, -1) {
+		if bytes.Equal(m[1], m[3]) {
+			empty++
+		}
+	}
+	f.SelfClose = spaced+tight > empty
+	f.SelfCloseSpace = f.SelfClose && spaced > tight
+	f.NoDeclaration = !bytes.HasPrefix(bytes.TrimLeft(data, "\ufeff \t\r\n"), []byte("<?xml"))
+	if bytes.Contains(data, []byte("\r\n")) {
+		f.LineEnding = "\r\n"
+	}
+	return f
+}
+
+// WriteURDFFormat encodes robot to w as WriteURDF does, laid out in f, with
+// the XML declaration quoted like the attributes. Links and joints are still
+// encoded one at a time.
+func WriteURDFFormat(w io.Writer, robot *Robot, f Format) error {
+	if f == DefaultFormat {
+		return WriteURDF(w, robot)
+	}
+	bw := bufio.NewWriter(w)
+	p := &formatPrinter{w: bw, f: f}
+	if !f.NoDeclaration {
+		p.raw(strings.ReplaceAll(xml.Header, `"`, string(f.Quote)))
+	}
+	p.open(xml.StartElement{Name: xml.Name{Local: "robot"}, Attr: []xml.Attr{{Name: xml.Name{Local: "name"}, Value: robot.Name}}}, 0, false)
+	if len(robot.Links)+len(robot.Joints) == 0 {
+		p.close("robot", 0, true, false)
+		p.raw("\n")
+		if p.err != nil {
+			return p.err
+		}
+		return bw.Flush()
+	}
+	p.raw(">")
+
+	var buf bytes.Buffer
+	enc := xml.NewEncoder(&buf)
+	encode := func(v any) error {
+		buf.Reset()
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return p.element(buf.Bytes(), 1)
+	}
+	for i := range robot.Links {
+		if err := encode(&robot.Links[i]); err != nil {
+			return err
+		}
+	}
+	for i := range robot.Joints {
+		if err := encode(&robot.Joints[i]); err != nil {
+			return err
+		}
+	}
+	p.close("robot", 0, false, true)
+	p.raw("\n")
+	if p.err != nil {
+		return p.err
+	}
+	return bw.Flush()
+}
+
+// formatPrinter writes XML tokens laid out in a Format. It keeps the first
+// write error, and writes nothing after it.
+type formatPrinter struct {
+	w   *bufio.Writer
+	f   Format
+	err error
+}
+
+// raw writes s, with its newlines in the format's line ending.
+func (p *formatPrinter) raw(s string) {
+	if p.f.LineEnding != "" {
+		s = strings.ReplaceAll(s, "\n", p.f.LineEnding)
+	}
+	if p.err == nil {
+		_, p.err = p.w.WriteString(s)
+	}
+}
+
+// open writes the start tag of e at depth, without its closing '>', on a
+// new line unless it is the first tag of the document.
+func (p *formatPrinter) open(e xml.StartElement, depth int, newline bool) {
+	if newline {
+		p.raw("\n" + strings.Repeat(p.f.Indent, depth))
+	}
+	p.raw("<" + e.Name.Local)
+	q := string(p.f.Quote)
+	for _, a := range e.Attr {
+		var v strings.Builder
+		xml.EscapeText(&v, []byte(a.Value))
+		p.raw(" " + a.Name.Local + "=" + q + v.String() + q)
+	}
+}
+
+// close ends the element name at depth: as self-closing or with an end tag
+// right after its start tag if empty is set, and otherwise with an end tag
+// on a line of its own if it has children.
+func (p *formatPrinter) close(name string, depth int, empty, children bool) {
+	switch {
+	case empty && p.f.SelfClose && p.f.SelfCloseSpace:
+		p.raw(" />")
+	case empty && p.f.SelfClose:
+		p.raw("/>")
+	case empty:
+		p.raw("></" + name + ">")
+	case children:
+		p.raw("\n" + strings.Repeat(p.f.Indent, depth) + "</" + name + ">")
+	default:
+		p.raw("</" + name + ">")
+	}
+}
+
+// element re-lays out data, one element as xml.Encoder wrote it without
+// indentation, with its start tag at depth.
+func (p *formatPrinter) element(data []byte, depth int) error {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	// children records, for each open element, whether it has any; pending
+	// is set while the last start tag written still lacks its '>'.
+	var children []bool
+	pending := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return p.err
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if pending {
+				p.raw(">")
+			}
+			if len(children) > 0 {
+				children[len(children)-1] = true
+			}
+			p.open(t, depth+len(children), true)
+			children = append(children, false)
+			pending = true
+		case xml.EndElement:
+			had := children[len(children)-1]
+			children = children[:len(children)-1]
+			p.close(t.Name.Local, depth+len(children), pending, had)
+			pending = false
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) == 0 {
+				continue
+			}
+			if pending {
+				p.raw(">")
+				pending = false
+			}
+			var v strings.Builder
+			xml.EscapeText(&v, t)
+			p.raw(v.String())
+		}
+	}
+}
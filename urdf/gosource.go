@@ -0,0 +1,241 @@
+package urdf
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"strconv"
+
+	"github.com/nfranczak/urdf-simplifier/spatialmath"
+)
+
+// goTypes declares the types a generated model is made of. They are written
+// into every generated file, so that it compiles without this module.
+const goTypes = `
+// Pose is a translation in meters followed by a rotation of roll, pitch and
+// yaw in radians about the fixed x, y and z axes, as in a URDF origin.
+type Pose struct {
+	XYZ [3]float64
+	RPY [3]float64
+}
+
+// Geometry is a collision shape: a "box" of Size, a "capsule" of Radius
+// around a segment of Length along its z axis, or a "mesh" file.
+type Geometry struct {
+	Type   string
+	Size   [3]float64
+	Radius float64
+	Length float64
+	Mesh   string
+}
+
+// Collision is a collision geometry placed in its link's frame.
+type Collision struct {
+	Name     string
+	Origin   Pose
+	Geometry Geometry
+}
+
+// Inertial is a link's mass in kilograms, its center of mass, and its
+// inertia tensor about it: ixx, ixy, ixz, iyy, iyz and izz.
+type Inertial struct {
+	Mass    float64
+	Origin  Pose
+	Inertia [6]float64
+}
+
+// Link is a rigid body of the model.
+type Link struct {
+	Name       string
+	Collisions []Collision
+	Inertial   *Inertial
+}
+
+// Limit bounds a joint's position, in radians or meters, effort and
+// velocity.
+type Limit struct {
+	Lower, Upper, Effort, Velocity float64
+}
+
+// Mimic makes a joint follow another: position = Multiplier*other + Offset.
+type Mimic struct {
+	Joint              string
+	Multiplier, Offset float64
+}
+
+// Joint connects Parent to Child at Origin, moving about or along Axis.
+type Joint struct {
+	Name   string
+	Type   string
+	Parent string
+	Child  string
+	Origin Pose
+	Axis   [3]float64
+	Limit  *Limit
+	Mimic  *Mimic
+}
+
+// Model is a robot's kinematic tree, with links and joints in document
+// order and Root the link no joint is attached below.
+type Model struct {
+	Name   string
+	Root   string
+	Links  []Link
+	Joints []Joint
+}
+`
+
+// WriteGo writes robot to w as the source of a Go file in package pkg that
+// declares it as a variable, Robot, of typed data, so that a driver can
+// compile the model in instead of parsing a URDF at run time. The file
+// declares its own types and imports nothing. Numbers are written with the
+// fewest digits that round-trip, and collision meshes by file name only.
+func WriteGo(w io.Writer, robot *Robot, pkg string) error {
+	if !token.IsIdentifier(pkg) {
+		return fmt.Errorf("invalid Go package name %q", pkg)
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by urdf-simplifier from robot %s; DO NOT EDIT.\n\npackage %s\n", robot.Name, pkg)
+	b.WriteString(goTypes)
+	fmt.Fprintf(&b, "\n// Robot is the model of %s.\nvar Robot = Model{\nName: %q,\nRoot: %q,\n", robot.Name, robot.Name, NewKinematicTree(robot).Root())
+
+	b.WriteString("Links: []Link{\n")
+	for _, link := range robot.Links {
+		fmt.Fprintf(&b, "{\nName: %q,\n", link.Name)
+		if len(link.Collision) > 0 {
+			b.WriteString("Collisions: []Collision{\n")
+			for _, c := range link.Collision {
+				origin, err := goPose(c.Origin)
+				if err != nil {
+					return fmt.Errorf("link %s: collision origin: %w", link.Name, err)
+				}
+				g, err := goGeometry(c.Geometry)
+				if err != nil {
+					return fmt.Errorf("link %s: %w", link.Name, err)
+				}
+				fmt.Fprintf(&b, "{Name: %q, Origin: %s, Geometry: %s},\n", c.Name, origin, g)
+			}
+			b.WriteString("},\n")
+		}
+		if in := link.Inertial; in != nil && in.Mass != nil {
+			origin, err := goPose(in.Origin)
+			if err != nil {
+				return fmt.Errorf("link %s: inertial origin: %w", link.Name, err)
+			}
+			var tensor [6]float64
+			if i := in.Inertia; i != nil {
+				tensor = [6]float64{i.IXX, i.IXY, i.IXZ, i.IYY, i.IYZ, i.IZZ}
+			}
+			fmt.Fprintf(&b, "Inertial: &Inertial{Mass: %s, Origin: %s, Inertia: [6]float64{%s}},\n",
+				goFloat(in.Mass.Value), origin, goFloats(tensor[:]...))
+		}
+		b.WriteString("},\n")
+	}
+	b.WriteString("},\n")
+
+	b.WriteString("Joints: []Joint{\n")
+	for _, joint := range robot.Joints {
+		var parent, child string
+		if joint.Parent != nil {
+			parent = joint.Parent.Link
+		}
+		if joint.Child != nil {
+			child = joint.Child.Link
+		}
+		origin, err := goPose(joint.Origin)
+		if err != nil {
+			return fmt.Errorf("joint %s: origin: %w", joint.Name, err)
+		}
+		axis, err := joint.AxisVector()
+		if err != nil {
+			return fmt.Errorf("joint %s: axis: %w", joint.Name, err)
+		}
+		fmt.Fprintf(&b, "{\nName: %q,\nType: %q,\nParent: %q,\nChild: %q,\nOrigin: %s,\nAxis: %s,\n",
+			joint.Name, joint.Type, parent, child, origin, goVec3(axis))
+		if l := joint.Limit; l != nil {
+			fmt.Fprintf(&b, "Limit: &Limit{Lower: %s, Upper: %s, Effort: %s, Velocity: %s},\n",
+				goFloat(l.Lower), goFloat(l.Upper), goFloat(l.Effort), goFloat(l.Velocity))
+		}
+		if m := joint.Mimic; m != nil {
+			multiplier, offset := 1.0, 0.0
+			if m.Multiplier != "" {
+				if multiplier, err = strconv.ParseFloat(m.Multiplier, 64); err != nil {
+					return fmt.Errorf("joint %s: mimic multiplier: %w", joint.Name, err)
+				}
+			}
+			if m.Offset != "" {
+				if offset, err = strconv.ParseFloat(m.Offset, 64); err != nil {
+					return fmt.Errorf("joint %s: mimic offset: %w", joint.Name, err)
+				}
+			}
+			fmt.Fprintf(&b, "Mimic: &Mimic{Joint: %q, Multiplier: %s, Offset: %s},\n", m.Joint, goFloat(multiplier), goFloat(offset))
+		}
+		b.WriteString("},\n")
+	}
+	b.WriteString("},\n}\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("formatting generated Go: %w", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// goPose returns a Pose literal for the origin's xyz and rpy as written,
+// zero where they are left out.
+func goPose(o *Origin) (string, error) {
+	if o == nil {
+		return "Pose{}", nil
+	}
+	xyz, err := spatialmath.ParseVec3(o.XYZ)
+	if err != nil {
+		return "", err
+	}
+	rpy, err := spatialmath.ParseVec3(o.RPY)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Pose{XYZ: %s, RPY: %s}", goVec3(xyz), goVec3(rpy)), nil
+}
+
+// goGeometry returns a Geometry literal for g.
+func goGeometry(g *Geometry) (string, error) {
+	switch {
+	case g == nil:
+		return "Geometry{}", nil
+	case g.Box != nil:
+		size, err := spatialmath.ParseVec3(g.Box.Size)
+		if err != nil {
+			return "", fmt.Errorf("box size: %w", err)
+		}
+		return fmt.Sprintf("Geometry{Type: \"box\", Size: %s}", goVec3(size)), nil
+	case g.Capsule != nil:
+		return fmt.Sprintf("Geometry{Type: \"capsule\", Radius: %s, Length: %s}", goFloat(g.Capsule.Radius), goFloat(g.Capsule.Length)), nil
+	case g.Mesh != nil:
+		return fmt.Sprintf("Geometry{Type: \"mesh\", Mesh: %q}", g.Mesh.Filename), nil
+	}
+	return "Geometry{}", nil
+}
+
+func goVec3(v spatialmath.Vec3) string {
+	return "[3]float64{" + goFloats(v.X, v.Y, v.Z) + "}"
+}
+
+func goFloats(vs ...float64) string {
+	var b bytes.Buffer
+	for i, v := range vs {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(goFloat(v))
+	}
+	return b.String()
+}
+
+// goFloat formats v as a Go constant that converts back to exactly v.
+func goFloat(v float64) string {
+	return strconv.FormatFloat(v+0, 'g', -1, 64)
+}
@@ -0,0 +1,528 @@
+package urdf
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/nfranczak/urdf-simplifier/mesh"
+	"github.com/nfranczak/urdf-simplifier/spatialmath"
+)
+
+// Matrix returns the inertia tensor as a symmetric matrix.
+func (i *Inertia) Matrix() spatialmath.Mat3 {
+	return spatialmath.Mat3{
+		{i.IXX, i.IXY, i.IXZ},
+		{i.IXY, i.IYY, i.IYZ},
+		{i.IXZ, i.IYZ, i.IZZ},
+	}
+}
+
+// InertiaFromMatrix returns the inertia element for a symmetric tensor.
+func InertiaFromMatrix(m spatialmath.Mat3) *Inertia {
+	return &Inertia{IXX: m[0][0], IXY: m[0][1], IXZ: m[0][2], IYY: m[1][1], IYZ: m[1][2], IZZ: m[2][2]}
+}
+
+// inertiaError reports why the inertial's tensor is not physically valid:
+// its principal moments must be positive, which makes it positive-definite,
+// and none may exceed the sum of the other two. The tensor is symmetric by
+// construction, since URDF gives only six of its entries. A massless
+// inertial with an all-zero tensor, as placeholder links often carry, is
+// accepted.
+func inertiaError(in *Inertial) error {
+	if in.Inertia == nil {
+		return nil
+	}
+	m := in.Inertia.Matrix()
+	if (in.Mass == nil || in.Mass.Value == 0) && m == (spatialmath.Mat3{}) {
+		return nil
+	}
+	moments, _ := m.SymmetricEigen()
+	switch {
+	case moments[0] <= 0:
+		return fmt.Errorf("not positive-definite (principal moments %.6g, %.6g, %.6g)", moments[0], moments[1], moments[2])
+	case moments[2] > (moments[0]+moments[1])*(1+1e-9):
+		return fmt.Errorf("principal moments %.6g, %.6g, %.6g violate the triangle inequality", moments[0], moments[1], moments[2])
+	}
+	return nil
+}
+
+// nearestValidInertia returns the valid tensor nearest m that has the same
+// principal axes: moments that are not positive are raised to a floor just
+// above zero, and if the largest then exceeds the sum of the other two, the
+// three are moved the shortest distance onto the boundary of the triangle
+// inequality.
+func nearestValidInertia(m spatialmath.Mat3) spatialmath.Mat3 {
+	moments, axes := m.SymmetricEigen()
+	floor := max(1e-6*moments[2], 1e-9)
+	for i := range moments {
+		moments[i] = max(moments[i], floor)
+	}
+	if excess := moments[2] - moments[0] - moments[1]; excess > 0 {
+		moments[0] += excess / 3
+		moments[1] += excess / 3
+		moments[2] -= excess / 3
+	}
+	diag := spatialmath.Mat3{{moments[0], 0, 0}, {0, moments[1], 0}, {0, 0, moments[2]}}
+	return axes.Mul(diag).Mul(axes.Transpose())
+}
+
+// checkInertia warns about a link whose inertia tensor is not physically
+// valid, or with opts.FixInertia replaces it with the nearest valid one.
+func checkInertia(link *Link, opts Options, report *Report) {
+	if link.Inertial == nil {
+		return
+	}
+	err := inertiaError(link.Inertial)
+	if err == nil {
+		return
+	}
+	if !opts.FixInertia {
+		opts.logger().Warn("invalid inertia tensor", "link", link.Name, "error", err)
+		report.Warnings = append(report.Warnings, fmt.Sprintf("link %s has an invalid inertia tensor: %v", link.Name, err))
+		return
+	}
+	link.Inertial.Inertia = InertiaFromMatrix(nearestValidInertia(link.Inertial.Inertia.Matrix()))
+	report.FixedInertias = append(report.FixedInertias, FixedInertia{Link: link.Name, Problem: err.Error()})
+	opts.logger().Debug("fixed inertia tensor", "link", link.Name, "problem", err)
+}
+
+// ensureInertial gives a link without an inertial a placeholder of the given
+// mass, with the tensor of a solid 10 cm cube, since simulators such as
+// Gazebo and Drake reject moving links without one. A link named world is
+// left alone: by convention it is the fixed world frame, not a body.
+func ensureInertial(link *Link, mass float64, opts Options) {
+	if mass == 0 || link.Inertial != nil || link.Name == "world" {
+		return
+	}
+	size := spatialmath.Vec3{X: 0.1, Y: 0.1, Z: 0.1}
+	link.Inertial = &Inertial{Mass: &Mass{Value: mass}, Inertia: InertiaFromMatrix(BoxInertia(mass, size))}
+	opts.logger().Debug("added default inertial", "link", link.Name, "mass", mass)
+}
+
+// addPayload adds the point mass p to the inertial of the link carrying it,
+// moving the inertial to the combined center of mass.
+func addPayload(robot *Robot, p *Payload, opts Options, report *Report) {
+	fail := func(err error) {
+		opts.logger().Warn("could not add payload", "error", err)
+		report.Warnings = append(report.Warnings, fmt.Sprintf("payload not added: %v", err))
+	}
+	name := p.Link
+	if name == "" {
+		var err error
+		if name, err = endLink(robot); err != nil {
+			fail(fmt.Errorf("%w; name the one carrying the payload", err))
+			return
+		}
+	}
+	link := robot.FindLink(name)
+	if link == nil {
+		fail(fmt.Errorf("link %q does not exist", name))
+		return
+	}
+
+	payload := body{mass: p.Mass, center: spatialmath.Vec3{X: p.Offset[0], Y: p.Offset[1], Z: p.Offset[2]}}
+	parts := []body{payload}
+	if in := link.Inertial; in != nil && in.Mass != nil && in.Mass.Value > 0 {
+		own, err := inertialBody(in)
+		if err != nil {
+			fail(fmt.Errorf("link %q: %w", name, err))
+			return
+		}
+		parts = append(parts, own)
+	}
+	total := combineBodies(parts)
+	link.Inertial = &Inertial{
+		Mass:    &Mass{Value: total.mass},
+		Origin:  &Origin{XYZ: spatialmath.FormatVec3(total.center)},
+		Inertia: InertiaFromMatrix(total.inertia),
+	}
+	report.PayloadLink = name
+	opts.logger().Debug("added payload", "link", name, "mass", p.Mass, "offset", spatialmath.FormatVec3(payload.center))
+}
+
+// endLink returns the leaf at the end of the robot's longest chain, or an
+// error if several chains are equally long.
+func endLink(robot *Robot) (string, error) {
+	tree := NewKinematicTree(robot)
+	var ends []string
+	depth := -1
+	for _, leaf := range tree.Leaves() {
+		switch d := len(tree.Ancestors(leaf)); {
+		case d > depth:
+			ends, depth = []string{leaf}, d
+		case d == depth:
+			ends = append(ends, leaf)
+		}
+	}
+	switch len(ends) {
+	case 0:
+		return "", fmt.Errorf("the robot has no links")
+	case 1:
+		return ends[0], nil
+	}
+	return "", fmt.Errorf("the robot has several end links %v", ends)
+}
+
+// BoxInertia returns the inertia tensor of a solid box of uniform density
+// about its center, in the box's frame.
+func BoxInertia(mass float64, size spatialmath.Vec3) spatialmath.Mat3 {
+	x2, y2, z2 := size.X*size.X, size.Y*size.Y, size.Z*size.Z
+	return spatialmath.Mat3{
+		{mass / 12 * (y2 + z2), 0, 0},
+		{0, mass / 12 * (x2 + z2), 0},
+		{0, 0, mass / 12 * (x2 + y2)},
+	}
+}
+
+// collisionBox returns the pose and size of the link's collision boxes as
+// one box: the box itself if there is only one, or else the axis-aligned
+// box in the link frame that encloses them all. ok is false if the link has
+// no collisions or any collision is not a box.
+func collisionBox(link *Link) (pose spatialmath.Pose, size spatialmath.Vec3, ok bool, err error) {
+	if len(link.Collision) == 0 {
+		return pose, size, false, nil
+	}
+	lo := spatialmath.Vec3{X: math.Inf(1), Y: math.Inf(1), Z: math.Inf(1)}
+	hi := lo.Scale(-1)
+	for _, c := range link.Collision {
+		if c.Geometry == nil || c.Geometry.Box == nil {
+			return pose, size, false, nil
+		}
+		s, err := spatialmath.ParseVec3(c.Geometry.Box.Size)
+		if err != nil {
+			return pose, size, false, fmt.Errorf("box size: %w", err)
+		}
+		p, err := c.Origin.Pose()
+		if err != nil {
+			return pose, size, false, fmt.Errorf("collision origin: %w", err)
+		}
+		if len(link.Collision) == 1 {
+			return p, s, true, nil
+		}
+		for _, corner := range boxCorners(s) {
+			v := p.Apply(corner)
+			lo = spatialmath.Vec3{X: min(lo.X, v.X), Y: min(lo.Y, v.Y), Z: min(lo.Z, v.Z)}
+			hi = spatialmath.Vec3{X: max(hi.X, v.X), Y: max(hi.Y, v.Y), Z: max(hi.Z, v.Z)}
+		}
+	}
+	center := lo.Add(hi).Scale(0.5)
+	return spatialmath.NewPose(center, spatialmath.RPY{}), hi.Sub(lo), true, nil
+}
+
+// boxCorners returns the corners of a box of the given size centered on the
+// origin.
+func boxCorners(size spatialmath.Vec3) []spatialmath.Vec3 {
+	h := size.Scale(0.5)
+	var corners []spatialmath.Vec3
+	for _, x := range []float64{-h.X, h.X} {
+		for _, y := range []float64{-h.Y, h.Y} {
+			for _, z := range []float64{-h.Z, h.Z} {
+				corners = append(corners, spatialmath.Vec3{X: x, Y: y, Z: z})
+			}
+		}
+	}
+	return corners
+}
+
+// adjustMass applies the link's mass override, or else opts.MassScale, to
+// its inertial, scaling the inertia tensor by the same factor so that only
+// the density changes. A link without a mass gets the override only if its
+// inertia is to be recomputed, since there is no tensor to go with it
+// otherwise.
+func adjustMass(link *Link, opts Options, report *Report) {
+	override := opts.Links[link.Name].Mass
+	if override == 0 && opts.MassScale == 0 {
+		return
+	}
+	var old float64
+	if link.Inertial != nil && link.Inertial.Mass != nil {
+		old = link.Inertial.Mass.Value
+	}
+	if old <= 0 {
+		switch {
+		case override == 0:
+			return
+		case opts.RecomputeInertia == "":
+			opts.logger().Warn("mass override ignored: link has no mass to replace", "link", link.Name)
+			report.Warnings = append(report.Warnings, fmt.Sprintf("mass override for %s ignored: the link has no mass; "+
+				"recompute its inertia to give it one", link.Name))
+			return
+		}
+		if link.Inertial == nil {
+			link.Inertial = &Inertial{}
+		}
+		link.Inertial.Mass = &Mass{Value: override}
+		opts.logger().Debug("set mass", "link", link.Name, "mass", override)
+		return
+	}
+
+	mass := old * opts.MassScale
+	if override != 0 {
+		mass = override
+	}
+	link.Inertial.Mass.Value = mass
+	if in := link.Inertial.Inertia; in != nil {
+		f := mass / old
+		in.IXX, in.IXY, in.IXZ = in.IXX*f, in.IXY*f, in.IXZ*f
+		in.IYY, in.IYZ, in.IZZ = in.IYY*f, in.IYZ*f, in.IZZ*f
+	}
+	opts.logger().Debug("adjusted mass", "link", link.Name, "from", old, "to", mass)
+}
+
+// recomputeBoxInertia replaces the link's inertia tensor with that of a
+// solid box of the link's mass filling its collision box, and moves the
+// inertial origin to the box center. Links without a positive mass or
+// without box collisions are left alone.
+func recomputeBoxInertia(link *Link, opts Options, report *Report) {
+	if link.Inertial == nil || link.Inertial.Mass == nil || link.Inertial.Mass.Value <= 0 {
+		return
+	}
+	pose, size, ok, err := collisionBox(link)
+	if err != nil {
+		opts.logger().Warn("could not recompute inertia", "link", link.Name, "error", err)
+		report.Warnings = append(report.Warnings, fmt.Sprintf("could not recompute inertia of %s: %v", link.Name, err))
+		return
+	}
+	if !ok {
+		opts.logger().Debug("inertia not recomputed: collisions are not all boxes", "link", link.Name)
+		return
+	}
+
+	mass := link.Inertial.Mass.Value
+	inertia := BoxInertia(mass, size)
+	link.Inertial.Inertia = InertiaFromMatrix(inertia)
+	xyz, rpy := pose.XYZRPY()
+	link.Inertial.Origin = &Origin{XYZ: xyz, RPY: rpy}
+	if rpy == "0 0 0" {
+		link.Inertial.Origin.RPY = ""
+	}
+
+	center := pose.Translation
+	report.Inertias = append(report.Inertias, InertiaReport{
+		Link:   link.Name,
+		Mass:   mass,
+		Source: "box",
+		Center: [3]float64{center.X, center.Y, center.Z},
+		Inertia: [6]float64{inertia[0][0], inertia[0][1], inertia[0][2],
+			inertia[1][1], inertia[1][2], inertia[2][2]},
+	})
+	opts.logger().Debug("recomputed inertia from box", "link", link.Name, "mass", mass,
+		"size", fmt.Sprintf("%.5f x %.5f x %.5f", size.X, size.Y, size.Z))
+}
+
+// recomputeMeshInertia replaces the link's inertia tensor and origin with
+// those of the solids its collision meshes and boxes enclose, at uniform
+// density. The link keeps its mass; a link without one gets an inertial
+// whose mass comes from the collision volume and opts.Density. bounds holds
+// the mass properties of the collision meshes, indexed like link.Collision.
+func recomputeMeshInertia(link *Link, bounds []meshBounds, opts Options, report *Report) {
+	if len(link.Collision) == 0 {
+		return
+	}
+	fail := func(err error) {
+		opts.logger().Warn("could not compute inertia from mesh", "link", link.Name, "error", err)
+		report.Warnings = append(report.Warnings, fmt.Sprintf("could not compute inertia of %s from its collision meshes: %v", link.Name, err))
+	}
+
+	// Each part is in the link frame, at unit density.
+	var parts []body
+	for i, c := range link.Collision {
+		pose, err := c.Origin.Pose()
+		if err != nil {
+			fail(fmt.Errorf("collision origin: %w", err))
+			return
+		}
+		var part mesh.MassProperties
+		switch {
+		case c.Geometry != nil && c.Geometry.Mesh != nil:
+			switch b := bounds[i]; {
+			case b.err != nil:
+				fail(fmt.Errorf("%s: %w", c.Geometry.Mesh.Filename, b.err))
+				return
+			case b.massErr != nil:
+				fail(fmt.Errorf("%s: %w", c.Geometry.Mesh.Filename, b.massErr))
+				return
+			default:
+				part = b.mass
+			}
+		case c.Geometry != nil && c.Geometry.Box != nil:
+			size, err := spatialmath.ParseVec3(c.Geometry.Box.Size)
+			if err != nil {
+				fail(fmt.Errorf("box size: %w", err))
+				return
+			}
+			volume := size.X * size.Y * size.Z
+			part = mesh.MassProperties{Volume: volume, Inertia: BoxInertia(volume, size)}
+		default:
+			opts.logger().Debug("inertia not recomputed: collision is neither a mesh nor a box", "link", link.Name)
+			return
+		}
+		parts = append(parts, body{mass: part.Volume, center: part.Center, inertia: part.Inertia}.transform(pose))
+	}
+	total := combineBodies(parts)
+	volume := total.mass
+
+	if link.Inertial == nil {
+		link.Inertial = &Inertial{}
+	}
+	if link.Inertial.Mass == nil || link.Inertial.Mass.Value <= 0 {
+		link.Inertial.Mass = &Mass{Value: volume * opts.density()}
+	}
+	mass := link.Inertial.Mass.Value
+	density := mass / volume
+	var inertia spatialmath.Mat3
+	for i := range 3 {
+		for j := range 3 {
+			inertia[i][j] = total.inertia[i][j] * density
+		}
+	}
+	link.Inertial.Inertia = InertiaFromMatrix(inertia)
+	link.Inertial.Origin = &Origin{XYZ: spatialmath.FormatVec3(total.center)}
+
+	report.Inertias = append(report.Inertias, InertiaReport{
+		Link:   link.Name,
+		Mass:   mass,
+		Source: "mesh",
+		Center: [3]float64{total.center.X, total.center.Y, total.center.Z},
+		Inertia: [6]float64{inertia[0][0], inertia[0][1], inertia[0][2],
+			inertia[1][1], inertia[1][2], inertia[2][2]},
+	})
+	opts.logger().Debug("recomputed inertia from mesh", "link", link.Name, "mass", mass,
+		"volume", volume, "density", density)
+}
+
+// body is a rigid body's mass, center of mass, and inertia tensor about the
+// center of mass.
+type body struct {
+	mass    float64
+	center  spatialmath.Vec3
+	inertia spatialmath.Mat3
+}
+
+// transform returns b expressed in the parent frame of pose, where b is
+// expressed in pose's frame.
+func (b body) transform(pose spatialmath.Pose) body {
+	r := pose.Rotation.Matrix()
+	return body{mass: b.mass, center: pose.Apply(b.center), inertia: r.Mul(b.inertia).Mul(r.Transpose())}
+}
+
+// combineBodies returns the bodies taken together as one, shifting each
+// tensor to the common center of mass with the parallel axis theorem. The
+// bodies must be expressed in the same frame and have a positive total mass.
+func combineBodies(parts []body) body {
+	var total body
+	var moment spatialmath.Vec3
+	for _, p := range parts {
+		total.mass += p.mass
+		moment = moment.Add(p.center.Scale(p.mass))
+	}
+	total.center = moment.Scale(1 / total.mass)
+	for _, p := range parts {
+		shift := ParallelAxis(p.mass, p.center.Sub(total.center))
+		for i := range 3 {
+			for j := range 3 {
+				total.inertia[i][j] += p.inertia[i][j] + shift[i][j]
+			}
+		}
+	}
+	return total
+}
+
+// inertialBody returns the body an inertial describes, in its link's frame.
+// A missing tensor is taken as zero, as for a point mass.
+func inertialBody(in *Inertial) (body, error) {
+	b := body{mass: in.Mass.Value}
+	if in.Inertia != nil {
+		b.inertia = in.Inertia.Matrix()
+	}
+	pose, err := in.Origin.Pose()
+	if err != nil {
+		return body{}, fmt.Errorf("inertial origin: %w", err)
+	}
+	return b.transform(pose), nil
+}
+
+// lumpRemovedMass adds the mass of each link about to be removed into the
+// nearest kept ancestor it is rigidly attached to, through fixed joints
+// only, so that trimming flanges, covers and sensors doesn't take their mass
+// off the robot. The kept link's inertial moves to the combined center of
+// mass, with its tensor expressed in the link frame. Links below a moving
+// joint are removed with their mass, since it doesn't move with any kept
+// link.
+func lumpRemovedMass(robot *Robot, keep map[string]bool, opts Options, report *Report) {
+	tree := NewKinematicTree(robot)
+	for i := range robot.Links {
+		link := &robot.Links[i]
+		if keep[link.Name] || link.Inertial == nil || link.Inertial.Mass == nil || link.Inertial.Mass.Value <= 0 {
+			continue
+		}
+
+		// Find the kept ancestor, composing the pose of link in its frame.
+		pose := spatialmath.Identity()
+		into := link.Name
+		for !keep[into] {
+			joint := tree.ParentJoint(into)
+			if joint == nil || joint.Parent == nil || joint.Type != "fixed" {
+				into = ""
+				break
+			}
+			p, err := joint.Origin.Pose()
+			if err != nil {
+				into = ""
+				break
+			}
+			pose = p.Compose(pose)
+			into = joint.Parent.Link
+		}
+		if into == "" {
+			continue
+		}
+		parent := robot.FindLink(into)
+
+		fail := func(err error) {
+			opts.logger().Warn("could not move mass of removed link", "link", link.Name, "into", into, "error", err)
+			report.Warnings = append(report.Warnings, fmt.Sprintf("could not move the mass of %s into %s: %v", link.Name, into, err))
+		}
+		removed, err := inertialBody(link.Inertial)
+		if err != nil {
+			fail(err)
+			continue
+		}
+		parts := []body{removed.transform(pose)}
+		if parent.Inertial != nil && parent.Inertial.Mass != nil && parent.Inertial.Mass.Value > 0 {
+			own, err := inertialBody(parent.Inertial)
+			if err != nil {
+				fail(err)
+				continue
+			}
+			parts = append(parts, own)
+		}
+		total := combineBodies(parts)
+		parent.Inertial = &Inertial{
+			Mass:    &Mass{Value: total.mass},
+			Origin:  &Origin{XYZ: spatialmath.FormatVec3(total.center)},
+			Inertia: InertiaFromMatrix(total.inertia),
+		}
+
+		report.LumpedMasses = append(report.LumpedMasses, LumpedMass{From: link.Name, Into: into, Mass: removed.mass})
+		opts.logger().Debug("moved mass of removed link", "link", link.Name, "into", into, "mass", removed.mass)
+	}
+}
+
+// ParallelAxis returns the term the parallel axis theorem adds to the
+// inertia tensor of a body of the given mass when it is taken about a point
+// offset by -d from its center of mass: m·(|d|²·E - d dᵀ).
+func ParallelAxis(mass float64, d spatialmath.Vec3) spatialmath.Mat3 {
+	v := [3]float64{d.X, d.Y, d.Z}
+	n := d.Dot(d)
+	var m spatialmath.Mat3
+	for i := range 3 {
+		for j := range 3 {
+			m[i][j] = -mass * v[i] * v[j]
+			if i == j {
+				m[i][j] += mass * n
+			}
+		}
+	}
+	return m
+}
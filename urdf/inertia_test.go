@@ -0,0 +1,194 @@
+package urdf
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nfranczak/urdf-simplifier/spatialmath"
+)
+
+const cubeCollision = `<collision><geometry><mesh filename="package://r/meshes/cube.stl"/></geometry></collision>`
+
+func TestRecomputeBoxInertia(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		inertial string
+		want     bool
+	}{
+		{"replaced", `<inertial><origin xyz="1 2 3"/><mass value="2"/><inertia ixx="1" ixy="0" ixz="0" iyy="1" iyz="0" izz="1"/></inertial>`, true},
+		{"massless", `<inertial><mass value="0"/><inertia ixx="1" ixy="0" ixz="0" iyy="1" iyz="0" izz="1"/></inertial>`, false},
+		{"no inertial", "", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			robot, report := simplifyCubes(t, `<link name="l">`+tt.inertial+cubeCollision+`</link>`,
+				Options{Chain: ChainAll, KeepInertials: true, RecomputeInertia: InertiaFromBox})
+			if !tt.want {
+				if len(report.Inertias) != 0 {
+					t.Errorf("inertias %+v, want none", report.Inertias)
+				}
+				return
+			}
+			in := robot.Links[0].Inertial
+			if in.Origin == nil || in.Origin.XYZ != "0.05 0.05 0.05" || in.Origin.RPY != "" {
+				t.Errorf("origin %+v, want the box center", in.Origin)
+			}
+			want := 2 * (0.01 + 0.01) / 12
+			for _, got := range []float64{in.Inertia.IXX, in.Inertia.IYY, in.Inertia.IZZ} {
+				if math.Abs(got-want) > 1e-9 {
+					t.Errorf("moment %v, want %v", got, want)
+				}
+			}
+			if in.Inertia.IXY != 0 || in.Inertia.IXZ != 0 || in.Inertia.IYZ != 0 {
+				t.Errorf("inertia %+v, want a diagonal tensor", in.Inertia)
+			}
+			if len(report.Inertias) != 1 || report.Inertias[0].Source != "box" || report.Inertias[0].Mass != 2 {
+				t.Errorf("inertias %+v, want one from a box of 2 kg", report.Inertias)
+			}
+		})
+	}
+}
+
+func TestBoxInertiaOfSeveralBoxes(t *testing.T) {
+	// Two 0.1 m cubes side by side along x are treated as one 0.2 m box.
+	robot, _ := simplifyCubes(t, `<link name="l"><inertial><mass value="1"/></inertial>`+
+		`<collision><origin xyz="-0.05 0 0"/><geometry><box size="0.1 0.1 0.1"/></geometry></collision>`+
+		`<collision><origin xyz="0.05 0 0"/><geometry><box size="0.1 0.1 0.1"/></geometry></collision></link>`,
+		Options{Chain: ChainAll, KeepInertials: true, RecomputeInertia: InertiaFromBox})
+	in := robot.Links[0].Inertial.Inertia
+	for _, c := range []struct {
+		got, want float64
+	}{
+		{in.IXX, (0.01 + 0.01) / 12},
+		{in.IYY, (0.04 + 0.01) / 12},
+		{in.IZZ, (0.04 + 0.01) / 12},
+	} {
+		if math.Abs(c.got-c.want) > 1e-9 {
+			t.Errorf("moment %v, want %v", c.got, c.want)
+		}
+	}
+}
+
+func TestRecomputeMeshInertia(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		inertial string
+		density  float64
+		mass     float64
+	}{
+		{"keeps mass", `<inertial><mass value="2"/></inertial>`, 0, 2},
+		{"default density", "", 0, 1},
+		{"given density", "", 500, 0.5},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			robot, report := simplifyCubes(t, `<link name="l">`+tt.inertial+
+				`<collision><origin xyz="0 0 1"/><geometry><mesh filename="package://r/meshes/cube.stl"/></geometry></collision></link>`,
+				Options{Chain: ChainAll, KeepInertials: true, RecomputeInertia: InertiaFromMesh, Density: tt.density})
+			in := robot.Links[0].Inertial
+			if in == nil || in.Mass == nil || math.Abs(in.Mass.Value-tt.mass) > 1e-6 {
+				t.Fatalf("inertial %+v, want a mass of %v", in, tt.mass)
+			}
+			if in.Origin == nil {
+				t.Fatal("no inertial origin")
+			}
+			if c, err := spatialmath.ParseVec3(in.Origin.XYZ); err != nil || c.Sub(spatialmath.Vec3{X: 0.05, Y: 0.05, Z: 1.05}).Norm() > 1e-6 {
+				t.Errorf("origin %+v, want the cube center", in.Origin)
+			}
+			want := tt.mass * (0.01 + 0.01) / 12
+			for _, got := range []float64{in.Inertia.IXX, in.Inertia.IYY, in.Inertia.IZZ} {
+				if math.Abs(got-want) > 1e-9 {
+					t.Errorf("moment %v, want %v", got, want)
+				}
+			}
+			if len(report.Inertias) != 1 || report.Inertias[0].Source != "mesh" {
+				t.Errorf("inertias %+v, want one from a mesh", report.Inertias)
+			}
+		})
+	}
+}
+
+func TestRecomputeMeshInertiaOpenMesh(t *testing.T) {
+	// Dropping the last triangle leaves the cube open.
+	open := benchCube()
+	open = open[:len(open)-50]
+	open[80]--
+	robot, err := ParseURDF(strings.NewReader(`<robot name="r"><link name="l"><inertial><mass value="2"/>` +
+		`<inertia ixx="1" ixy="0" ixz="0" iyy="1" iyz="0" izz="1"/></inertial>` + cubeCollision + `</link></robot>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := Simplify(robot, FSResolver{FS: fstest.MapFS{"meshes/cube.stl": {Data: open}}},
+		Options{Chain: ChainAll, KeepInertials: true, RecomputeInertia: InertiaFromMesh})
+	if len(report.Inertias) != 0 || robot.Links[0].Inertial.Inertia.IXX != 1 {
+		t.Errorf("inertia %+v recomputed from an open mesh", robot.Links[0].Inertial.Inertia)
+	}
+	if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0], "could not compute inertia of l") {
+		t.Errorf("warnings %q, want one about the open mesh", report.Warnings)
+	}
+}
+
+func TestInertiaError(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		mass    float64
+		inertia Inertia
+		want    string
+	}{
+		{"valid", 1, Inertia{IXX: 1, IYY: 1, IZZ: 1}, ""},
+		{"rotated", 1, Inertia{IXX: 2, IXY: -0.5, IYY: 2, IZZ: 3}, ""},
+		{"massless placeholder", 0, Inertia{}, ""},
+		{"zero with mass", 1, Inertia{}, "not positive-definite"},
+		{"negative", 1, Inertia{IXX: 1, IYY: -1, IZZ: 1}, "not positive-definite"},
+		{"indefinite", 1, Inertia{IXX: 1, IXY: 2, IYY: 1, IZZ: 1}, "not positive-definite"},
+		{"triangle", 1, Inertia{IXX: 1, IYY: 1, IZZ: 3}, "violate the triangle inequality"},
+		{"rod", 1, Inertia{IXX: 1, IYY: 1, IZZ: 2}, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := inertiaError(&Inertial{Mass: &Mass{Value: tt.mass}, Inertia: &tt.inertia})
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("inertiaError() = %v, want nil", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("inertiaError() = %v, want %q", err, tt.want)
+			}
+			if tt.want == "" {
+				return
+			}
+			fixed := InertiaFromMatrix(nearestValidInertia(tt.inertia.Matrix()))
+			if err := inertiaError(&Inertial{Mass: &Mass{Value: tt.mass}, Inertia: fixed}); err != nil {
+				t.Errorf("nearestValidInertia() = %+v: %v", fixed, err)
+			}
+		})
+	}
+}
+
+func TestFixInertia(t *testing.T) {
+	body := `<link name="l"><inertial><mass value="1"/><inertia ixx="1" ixy="0" ixz="0" iyy="1" iyz="0" izz="3"/></inertial>` +
+		cubeCollision + `</link>`
+	for _, fix := range []bool{false, true} {
+		robot, report := simplifyCubes(t, body, Options{Chain: ChainAll, KeepInertials: true, FixInertia: fix})
+		in := robot.Links[0].Inertial.Inertia
+		if !fix {
+			if in.IZZ != 3 || len(report.FixedInertias) != 0 {
+				t.Errorf("inertia %+v changed without FixInertia", in)
+			}
+			if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0], "link l has an invalid inertia tensor") {
+				t.Errorf("warnings %q, want one about link l", report.Warnings)
+			}
+			continue
+		}
+		if len(report.FixedInertias) != 1 || report.FixedInertias[0].Link != "l" {
+			t.Errorf("fixed inertias %+v, want link l", report.FixedInertias)
+		}
+		if len(report.Warnings) != 0 {
+			t.Errorf("warnings %q with FixInertia", report.Warnings)
+		}
+		// The excess of 1 is split evenly over the three moments.
+		for _, c := range []struct{ got, want float64 }{{in.IXX, 4.0 / 3}, {in.IYY, 4.0 / 3}, {in.IZZ, 8.0 / 3}} {
+			if math.Abs(c.got-c.want) > 1e-9 {
+				t.Errorf("moment %v, want %v", c.got, c.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package urdf
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ParseURDF decodes a URDF document from r. A document with numeric
+// attributes that are not finite numbers is rejected with a *NumbersError
+// giving the line of each, rather than carrying NaN or garbage through. The
+// document is checked and decoded in one pass as it is read, so it is never
+// held in memory whole.
+func ParseURDF(r io.Reader) (*Robot, error) {
+	src := &checkedTokens{dec: xml.NewDecoder(r)}
+	var robot Robot
+	err := xml.NewTokenDecoder(src).Decode(&robot)
+	if src.err != nil && !errors.Is(src.err, io.EOF) {
+		return nil, src.err
+	}
+	if err != nil {
+		// Report every malformed number, including any past the element
+		// that failed to decode.
+		src.drain()
+	}
+	if errs := src.numbers.found; len(errs) > 0 {
+		return nil, &NumbersError{Errors: errs}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &robot, nil
+}
+
+// WriteURDF encodes robot to w as an indented URDF document with an XML
+// header. Links and joints are encoded one at a time through a buffer, so
+// the document is never held in memory whole; the output is the same as
+// xml.MarshalIndent's with two-space indentation.
+func WriteURDF(w io.Writer, robot *Robot) error {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(bw)
+	enc.Indent("", "  ")
+	start := xml.StartElement{Name: xml.Name{Local: "robot"}, Attr: []xml.Attr{{Name: xml.Name{Local: "name"}, Value: robot.Name}}}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	for i := range robot.Links {
+		if err := enc.Encode(&robot.Links[i]); err != nil {
+			return err
+		}
+	}
+	for i := range robot.Joints {
+		if err := enc.Encode(&robot.Joints[i]); err != nil {
+			return err
+		}
+	}
+	if err := enc.EncodeToken(start.End()); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	if _, err := bw.WriteString("\n"); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// modeledElements lists the element paths the Robot model represents. Any
+// other element is dropped when a parsed robot is written back out.
+var modeledElements = map[string]bool{
+	"robot":                                 true,
+	"robot/link":                            true,
+	"robot/link/origin":                     true,
+	"robot/link/inertial":                   true,
+	"robot/link/inertial/origin":            true,
+	"robot/link/inertial/mass":              true,
+	"robot/link/inertial/inertia":           true,
+	"robot/link/visual":                     true,
+	"robot/link/visual/origin":              true,
+	"robot/link/visual/geometry":            true,
+	"robot/link/visual/geometry/mesh":       true,
+	"robot/link/visual/geometry/box":        true,
+	"robot/link/visual/geometry/capsule":    true,
+	"robot/link/visual/material":            true,
+	"robot/link/visual/material/color":      true,
+	"robot/link/collision":                  true,
+	"robot/link/collision/origin":           true,
+	"robot/link/collision/geometry":         true,
+	"robot/link/collision/geometry/mesh":    true,
+	"robot/link/collision/geometry/box":     true,
+	"robot/link/collision/geometry/capsule": true,
+	"robot/joint":                           true,
+	"robot/joint/parent":                    true,
+	"robot/joint/child":                     true,
+	"robot/joint/origin":                    true,
+	"robot/joint/axis":                      true,
+	"robot/joint/limit":                     true,
+	"robot/joint/dynamics":                  true,
+	"robot/joint/mimic":                     true,
+}
+
+// UnsupportedElements scans a URDF document and returns the paths of the
+// elements that ParseURDF does not represent, such as "robot/transmission" or
+// "robot/link/collision/geometry/cylinder", each once in document order.
+// Writing a parsed robot drops these elements.
+func UnsupportedElements(r io.Reader) ([]string, error) {
+	var found []string
+	seen := make(map[string]bool)
+	var path []string
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return found, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			p := strings.Join(append(path, t.Name.Local), "/")
+			if !modeledElements[p] {
+				if !seen[p] {
+					seen[p] = true
+					found = append(found, p)
+				}
+				if err := dec.Skip(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			path = append(path, t.Name.Local)
+		case xml.EndElement:
+			path = path[:len(path)-1]
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package urdf
+
+import "github.com/nfranczak/urdf-simplifier/spatialmath"
+
+// MassReport summarizes how a robot's mass is distributed with every joint
+// at zero.
+type MassReport struct {
+	// Total is the sum of the link masses.
+	Total float64 `json:"total"`
+	// Frame is the link in whose frame Center is expressed.
+	Frame string `json:"frame"`
+	// Center is the robot's center of mass. Links not connected to Frame
+	// count towards Total but not towards Center.
+	Center [3]float64 `json:"center"`
+	// Links lists the links with a positive mass, in document order.
+	Links []LinkMass `json:"links"`
+}
+
+// LinkMass is the mass of one link.
+type LinkMass struct {
+	Link string  `json:"link"`
+	Mass float64 `json:"mass"`
+	// Center is the link's center of mass in its own frame.
+	Center [3]float64 `json:"center"`
+}
+
+// Masses returns the mass report of robot, with the center of mass in the
+// frame of the named link, or of the first root link if frame is "" or not
+// a link of robot.
+func Masses(robot *Robot, frame string) *MassReport {
+	tree := NewKinematicTree(robot)
+	if frame == "" || robot.FindLink(frame) == nil {
+		frame = tree.Root()
+	}
+	report := &MassReport{Frame: frame}
+	if frame == "" {
+		return report
+	}
+
+	root := frame
+	if ancestors := tree.Ancestors(frame); len(ancestors) > 0 {
+		root = ancestors[len(ancestors)-1]
+	}
+	poses := tree.ZeroPoses(root)
+	inFrame := poses[frame].Inverse()
+
+	var moment spatialmath.Vec3
+	var connected float64
+	for _, link := range robot.Links {
+		in := link.Inertial
+		if in == nil || in.Mass == nil || in.Mass.Value <= 0 {
+			continue
+		}
+		var center spatialmath.Vec3
+		if pose, err := in.Origin.Pose(); err == nil {
+			center = pose.Translation
+		}
+		m := in.Mass.Value
+		report.Total += m
+		report.Links = append(report.Links, LinkMass{Link: link.Name, Mass: m, Center: [3]float64{center.X, center.Y, center.Z}})
+		if pose, ok := poses[link.Name]; ok {
+			moment = moment.Add(inFrame.Compose(pose).Apply(center).Scale(m))
+			connected += m
+		}
+	}
+	if connected > 0 {
+		c := moment.Scale(1 / connected)
+		report.Center = [3]float64{c.X, c.Y, c.Z}
+	}
+	return report
+}
+
+// massModel returns a copy of robot holding only what Masses and
+// sampleWorkspace read, the links' inertials and the joints, sharing nothing
+// Simplify modifies.
+func massModel(robot *Robot) *Robot {
+	model := &Robot{Name: robot.Name, Joints: make([]Joint, len(robot.Joints))}
+	for i, joint := range robot.Joints {
+		model.Joints[i] = joint
+		if joint.Origin != nil {
+			origin := *joint.Origin
+			model.Joints[i].Origin = &origin
+		}
+	}
+	for _, link := range robot.Links {
+		l := Link{Name: link.Name}
+		if in := link.Inertial; in != nil {
+			l.Inertial = &Inertial{}
+			if in.Mass != nil {
+				l.Inertial.Mass = &Mass{Value: in.Mass.Value}
+			}
+			if in.Origin != nil {
+				origin := *in.Origin
+				l.Inertial.Origin = &origin
+			}
+		}
+		model.Links = append(model.Links, l)
+	}
+	return model
+}
@@ -0,0 +1,62 @@
+package urdf
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Attach merges a copy of other into the robot, connecting other's root link
+// to parentLink with a fixed joint at origin. Every link and joint name from
+// other is prefixed with prefix, which can be used to avoid name clashes, as
+// are the names of its collisions.
+func (r *Robot) Attach(other *Robot, parentLink, prefix string, origin *Origin) error {
+	if r.FindLink(parentLink) == nil {
+		return fmt.Errorf("parent link %q does not exist", parentLink)
+	}
+
+	tree := NewKinematicTree(other)
+	if len(tree.Roots) != 1 {
+		return fmt.Errorf("robot %q must have exactly one root link to be attached, found %d", other.Name, len(tree.Roots))
+	}
+
+	for _, link := range other.Links {
+		if r.FindLink(prefix+link.Name) != nil {
+			return fmt.Errorf("link %q already exists; use a prefix to disambiguate", prefix+link.Name)
+		}
+	}
+	for _, joint := range other.Joints {
+		if r.FindJoint(prefix+joint.Name) != nil {
+			return fmt.Errorf("joint %q already exists; use a prefix to disambiguate", prefix+joint.Name)
+		}
+	}
+
+	for _, link := range other.Links {
+		link.Name = prefix + link.Name
+		link.Collision = slices.Clone(link.Collision)
+		for i := range link.Collision {
+			if c := &link.Collision[i]; c.Name != "" {
+				c.Name = prefix + c.Name
+			}
+		}
+		r.Links = append(r.Links, link)
+	}
+	for _, joint := range other.Joints {
+		joint.Name = prefix + joint.Name
+		if joint.Parent != nil {
+			joint.Parent = &Parent{Link: prefix + joint.Parent.Link}
+		}
+		if joint.Child != nil {
+			joint.Child = &Child{Link: prefix + joint.Child.Link}
+		}
+		r.Joints = append(r.Joints, joint)
+	}
+
+	root := prefix + tree.Root()
+	return r.AddJoint(Joint{
+		Name:   fmt.Sprintf("%s_to_%s", parentLink, root),
+		Type:   "fixed",
+		Parent: &Parent{Link: parentLink},
+		Child:  &Child{Link: root},
+		Origin: origin,
+	})
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package urdf
+
+import (
+	"errors"
+	"os"
+)
+
+// mapFile is not supported on this platform; mesh files are read into
+// memory instead.
+func mapFile(f *os.File, size int64) ([]byte, func(), error) {
+	return nil, nil, errors.ErrUnsupported
+}
@@ -0,0 +1,19 @@
+//go:build unix
+
+package urdf
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps the first size bytes of f into memory read-only, so a large
+// mesh is parsed from the page cache instead of being copied into a buffer
+// first. The returned function unmaps it; the bytes must not be used after.
+func mapFile(f *os.File, size int64) ([]byte, func(), error) {
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() { syscall.Munmap(data) }, nil
+}
@@ -0,0 +1,157 @@
+package urdf
+
+import "encoding/xml"
+
+// URDF XML structures
+type Robot struct {
+	XMLName xml.Name `xml:"robot"`
+	Name    string   `xml:"name,attr"`
+	Links   []Link   `xml:"link"`
+	Joints  []Joint  `xml:"joint"`
+}
+
+type Link struct {
+	XMLName   xml.Name    `xml:"link"`
+	Name      string      `xml:"name,attr"`
+	Visual    []Visual    `xml:"visual"`
+	Collision []Collision `xml:"collision"`
+	Inertial  *Inertial   `xml:"inertial"`
+	Origin    *Origin     `xml:"origin"`
+}
+
+type Visual struct {
+	XMLName  xml.Name  `xml:"visual"`
+	Name     string    `xml:"name,attr,omitempty"`
+	Origin   *Origin   `xml:"origin"`
+	Geometry *Geometry `xml:"geometry"`
+	Material *Material `xml:"material"`
+}
+
+// Material colors a visual, inline or by the name of a material the
+// document defines.
+type Material struct {
+	XMLName xml.Name `xml:"material"`
+	Name    string   `xml:"name,attr"`
+	Color   *Color   `xml:"color"`
+}
+
+// Color is an RGBA color, four numbers from 0 to 1.
+type Color struct {
+	XMLName xml.Name `xml:"color"`
+	RGBA    string   `xml:"rgba,attr"`
+}
+
+type Collision struct {
+	XMLName xml.Name `xml:"collision"`
+	// Name labels the collision geometry for planners' collision reports.
+	// Simplify names every collision that has none; see nameCollisions.
+	Name     string    `xml:"name,attr,omitempty"`
+	Origin   *Origin   `xml:"origin"`
+	Geometry *Geometry `xml:"geometry"`
+}
+
+type Inertial struct {
+	XMLName xml.Name `xml:"inertial"`
+	Mass    *Mass    `xml:"mass"`
+	Origin  *Origin  `xml:"origin"`
+	Inertia *Inertia `xml:"inertia"`
+}
+
+type Mass struct {
+	XMLName xml.Name `xml:"mass"`
+	Value   float64  `xml:"value,attr"`
+}
+
+type Origin struct {
+	XMLName xml.Name `xml:"origin"`
+	RPY     string   `xml:"rpy,attr,omitempty"`
+	XYZ     string   `xml:"xyz,attr,omitempty"`
+}
+
+type Inertia struct {
+	XMLName xml.Name `xml:"inertia"`
+	IXX     float64  `xml:"ixx,attr"`
+	IXY     float64  `xml:"ixy,attr"`
+	IXZ     float64  `xml:"ixz,attr"`
+	IYY     float64  `xml:"iyy,attr"`
+	IYZ     float64  `xml:"iyz,attr"`
+	IZZ     float64  `xml:"izz,attr"`
+}
+
+type Geometry struct {
+	XMLName xml.Name `xml:"geometry"`
+	Mesh    *Mesh    `xml:"mesh"`
+	Box     *Box     `xml:"box"`
+	Capsule *Capsule `xml:"capsule"`
+}
+
+type Mesh struct {
+	XMLName  xml.Name `xml:"mesh"`
+	Filename string   `xml:"filename,attr"`
+}
+
+type Box struct {
+	XMLName xml.Name `xml:"box"`
+	Size    string   `xml:"size,attr"`
+}
+
+// Capsule is the capsule geometry Drake, SDFormat and Viam read in URDF: a
+// cylinder of Length along the z axis, capped with hemispheres of Radius,
+// so that it is Length + 2*Radius long in all.
+type Capsule struct {
+	XMLName xml.Name `xml:"capsule"`
+	Radius  float64  `xml:"radius,attr"`
+	Length  float64  `xml:"length,attr"`
+}
+
+type Joint struct {
+	XMLName  xml.Name  `xml:"joint"`
+	Name     string    `xml:"name,attr"`
+	Type     string    `xml:"type,attr"`
+	Parent   *Parent   `xml:"parent"`
+	Child    *Child    `xml:"child"`
+	Origin   *Origin   `xml:"origin"`
+	Axis     *Axis     `xml:"axis"`
+	Limit    *Limit    `xml:"limit"`
+	Dynamics *Dynamics `xml:"dynamics"`
+	Mimic    *Mimic    `xml:"mimic"`
+}
+
+type Parent struct {
+	XMLName xml.Name `xml:"parent"`
+	Link    string   `xml:"link,attr"`
+}
+
+type Child struct {
+	XMLName xml.Name `xml:"child"`
+	Link    string   `xml:"link,attr"`
+}
+
+type Axis struct {
+	XMLName xml.Name `xml:"axis"`
+	XYZ     string   `xml:"xyz,attr"`
+}
+
+type Limit struct {
+	XMLName  xml.Name `xml:"limit"`
+	Effort   float64  `xml:"effort,attr"`
+	Lower    float64  `xml:"lower,attr"`
+	Upper    float64  `xml:"upper,attr"`
+	Velocity float64  `xml:"velocity,attr"`
+}
+
+// Mimic makes a joint follow another: position = multiplier * other +
+// offset. Multiplier and Offset are kept as written, since leaving them out
+// means 1 and 0.
+type Mimic struct {
+	XMLName    xml.Name `xml:"mimic"`
+	Joint      string   `xml:"joint,attr"`
+	Multiplier string   `xml:"multiplier,attr,omitempty"`
+	Offset     string   `xml:"offset,attr,omitempty"`
+}
+
+type Dynamics struct {
+	XMLName  xml.Name `xml:"dynamics"`
+	Damping  float64  `xml:"damping,attr"`
+	Friction float64  `xml:"friction,attr"`
+}
@@ -0,0 +1,145 @@
+package urdf
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// NameMap records the links and joints a simplification renamed, from
+// their names in the input to those in the output.
+type NameMap struct {
+	Links  map[string]string `json:"links"`
+	Joints map[string]string `json:"joints"`
+}
+
+// renameRobot renames the robot's links and joints as Options.CanonicalNames
+// and Options.LinkNames say. The moving joints are numbered from base to
+// tip, depth first in document order, and the link moved by the i-th is
+// link_i in either link scheme. Other links and joints keep their names. If
+// a new name is taken by one of them, the robot is left as it is and the
+// clash recorded as an error.
+func renameRobot(robot *Robot, opts Options, report *Report) {
+	scheme := opts.LinkNames
+	if scheme == "" && opts.CanonicalNames {
+		scheme = LinkNamesNumbered
+	}
+	tree := NewKinematicTree(robot)
+	names := &NameMap{Links: make(map[string]string), Joints: make(map[string]string)}
+	rename := func(renames map[string]string, from, to string) {
+		if from != to {
+			renames[from] = to
+		}
+	}
+	root := tree.Root()
+	if root == "" {
+		return
+	}
+	moved := make(map[string]bool)
+	n := 0
+	tree.Walk(root, func(link string, depth int) bool {
+		j := tree.ParentJoint(link)
+		if j == nil || !moves(j) {
+			return true
+		}
+		n++
+		moved[link] = true
+		if opts.CanonicalNames {
+			rename(names.Joints, j.Name, fmt.Sprintf("joint_%d", n))
+		}
+		if scheme != "" {
+			rename(names.Links, link, fmt.Sprintf("link_%d", n))
+		}
+		return true
+	})
+	switch scheme {
+	case LinkNamesNumbered:
+		rename(names.Links, root, "link_0")
+	case LinkNamesStandard:
+		rename(names.Links, root, "base_link")
+		end, err := endLink(robot)
+		switch {
+		case err != nil:
+			opts.logger().Warn("no tool link to name", "error", err)
+			report.Warnings = append(report.Warnings, fmt.Sprintf("link names: no link named tool: %v", err))
+		case end != root && !moved[end]:
+			rename(names.Links, end, "tool")
+		}
+	}
+	if err := renameAll(robot, names); err != nil {
+		msg := "renaming: " + err.Error()
+		opts.logger().Error("could not rename", "error", msg)
+		report.Errors = append(report.Errors, msg)
+		return
+	}
+	report.NameMap = names
+}
+
+// renameAll renames the links and joints in names, and the references to
+// them: joints' parent, child and mimicked joint, and the names of
+// collisions and visuals that start with their link's, as Simplify gives
+// them. It renames nothing and returns an error if a new name is already
+// that of a link or joint that keeps its own.
+func renameAll(robot *Robot, names *NameMap) error {
+	for _, link := range robot.Links {
+		if _, ok := names.Links[link.Name]; !ok && taken(names.Links, link.Name) {
+			return fmt.Errorf("link %q already exists", link.Name)
+		}
+	}
+	for _, joint := range robot.Joints {
+		if _, ok := names.Joints[joint.Name]; !ok && taken(names.Joints, joint.Name) {
+			return fmt.Errorf("joint %q already exists", joint.Name)
+		}
+	}
+
+	for i := range robot.Links {
+		l := &robot.Links[i]
+		to, ok := names.Links[l.Name]
+		if !ok {
+			continue
+		}
+		prefix := l.Name + ":"
+		for j := range l.Collision {
+			if rest, ok := strings.CutPrefix(l.Collision[j].Name, prefix); ok {
+				l.Collision[j].Name = to + ":" + rest
+			}
+		}
+		for j := range l.Visual {
+			if rest, ok := strings.CutPrefix(l.Visual[j].Name, prefix); ok {
+				l.Visual[j].Name = to + ":" + rest
+			}
+		}
+	}
+	if err := renameEach(names.Links, robot.RenameLink); err != nil {
+		return err
+	}
+	return renameEach(names.Joints, robot.RenameJoint)
+}
+
+// renameEach applies renames with rename, by way of names no link or joint
+// can have, so that one may take the old name of another, as when two swap.
+func renameEach(renames map[string]string, rename func(oldName, newName string) error) error {
+	from := slices.Sorted(maps.Keys(renames))
+	for i, name := range from {
+		if err := rename(name, fmt.Sprintf("\x00%d", i)); err != nil {
+			return err
+		}
+	}
+	for i, name := range from {
+		if err := rename(fmt.Sprintf("\x00%d", i), renames[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// taken reports whether name is one that renames map to.
+func taken(renames map[string]string, name string) bool {
+	for _, to := range renames {
+		if to == name {
+			return true
+		}
+	}
+	return false
+}
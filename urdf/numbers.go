@@ -0,0 +1,169 @@
+package urdf
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// numericAttrs lists the numeric attributes of the elements the Robot model
+// represents, with how many numbers each holds.
+var numericAttrs = map[string]map[string]int{
+	"origin":   {"xyz": 3, "rpy": 3},
+	"axis":     {"xyz": 3},
+	"box":      {"size": 3},
+	"capsule":  {"radius": 1, "length": 1},
+	"mass":     {"value": 1},
+	"inertia":  {"ixx": 1, "ixy": 1, "ixz": 1, "iyy": 1, "iyz": 1, "izz": 1},
+	"limit":    {"lower": 1, "upper": 1, "effort": 1, "velocity": 1},
+	"dynamics": {"damping": 1, "friction": 1},
+	"mimic":    {"multiplier": 1, "offset": 1},
+	"color":    {"rgba": 4},
+}
+
+// NumberError is a numeric attribute that does not hold the finite numbers
+// it should.
+type NumberError struct {
+	// Line is the line of the document on which the element's start tag
+	// ends.
+	Line    int
+	Element string
+	Attr    string
+	Value   string
+	// Problem says what is wrong with Value.
+	Problem string
+}
+
+func (e *NumberError) Error() string {
+	return fmt.Sprintf("line %d: <%s %s=%q>: %s", e.Line, e.Element, e.Attr, e.Value, e.Problem)
+}
+
+// CheckNumbers scans a URDF document and returns an error for each numeric
+// attribute of a modeled element that is not a number, is NaN or infinite,
+// or holds the wrong count of numbers, in document order. An empty
+// attribute reads as zero and is allowed.
+func CheckNumbers(r io.Reader) ([]*NumberError, error) {
+	var c numberChecker
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return c.found, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := dec.InputPos()
+		c.token(tok, line)
+	}
+}
+
+// numberChecker checks the numeric attributes in a stream of tokens the way
+// CheckNumbers does, so that ParseURDF can check a document as it decodes
+// it.
+type numberChecker struct {
+	path []string
+	// skip is how deep the stream is inside an element the Robot model does
+	// not represent, whose attributes are not checked.
+	skip  int
+	found []*NumberError
+}
+
+// token checks tok, which ends on line.
+func (c *numberChecker) token(tok xml.Token, line int) {
+	switch t := tok.(type) {
+	case xml.StartElement:
+		if c.skip > 0 || !modeledElements[strings.Join(append(c.path, t.Name.Local), "/")] {
+			c.skip++
+			return
+		}
+		c.path = append(c.path, t.Name.Local)
+		attrs := numericAttrs[t.Name.Local]
+		for _, attr := range t.Attr {
+			if n, ok := attrs[attr.Name.Local]; ok {
+				if problem := numberProblem(attr.Value, n); problem != "" {
+					c.found = append(c.found, &NumberError{Line: line, Element: t.Name.Local, Attr: attr.Name.Local, Value: attr.Value, Problem: problem})
+				}
+			}
+		}
+	case xml.EndElement:
+		if c.skip > 0 {
+			c.skip--
+		} else if len(c.path) > 0 {
+			c.path = c.path[:len(c.path)-1]
+		}
+	}
+}
+
+// checkedTokens passes the tokens of a document on to another decoder,
+// checking their numbers along the way. It stops at the first error, which
+// it keeps.
+type checkedTokens struct {
+	dec     *xml.Decoder
+	numbers numberChecker
+	err     error
+}
+
+func (s *checkedTokens) Token() (xml.Token, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	tok, err := s.dec.Token()
+	if err != nil {
+		s.err = err
+		return nil, err
+	}
+	line, _ := s.dec.InputPos()
+	s.numbers.token(tok, line)
+	return tok, nil
+}
+
+// drain reads the rest of the document, so that its numbers are checked
+// too.
+func (s *checkedTokens) drain() {
+	for s.err == nil {
+		s.Token()
+	}
+}
+
+// numberProblem says what is wrong with s as an attribute of n numbers, or
+// returns "" if nothing is.
+func numberProblem(s string, n int) string {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return ""
+	}
+	for _, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		switch {
+		case err != nil && !errors.Is(err, strconv.ErrRange):
+			return fmt.Sprintf("%q is not a number", f)
+		case math.IsNaN(v):
+			return fmt.Sprintf("%q is not a number", f)
+		case math.IsInf(v, 0):
+			return fmt.Sprintf("%q is infinite", f)
+		}
+	}
+	if len(fields) != n {
+		return fmt.Sprintf("want %d numbers, got %d", n, len(fields))
+	}
+	return ""
+}
+
+// NumbersError is returned by ParseURDF for a document with malformed
+// numeric attributes.
+type NumbersError struct {
+	Errors []*NumberError
+}
+
+func (e *NumbersError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d malformed numeric attribute(s):\n  %s", len(msgs), strings.Join(msgs, "\n  "))
+}
@@ -0,0 +1,86 @@
+package urdf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNumberProblem(t *testing.T) {
+	for _, tt := range []struct {
+		value string
+		n     int
+		want  string
+	}{
+		{"1 2 3", 3, ""},
+		{"  -1.5e-3\t0  2 ", 3, ""},
+		{"", 3, ""},
+		{"1e999", 1, `"1e999" is infinite`},
+		{"1 inf 0", 3, `"inf" is infinite`},
+		{"-Infinity", 1, `"-Infinity" is infinite`},
+		{"NaN", 1, `"NaN" is not a number`},
+		{"1 2 three", 3, `"three" is not a number`},
+		{"1,2,3", 3, `"1,2,3" is not a number`},
+		{"1 2", 3, "want 3 numbers, got 2"},
+		{"1 2 3 4", 3, "want 3 numbers, got 4"},
+		{"0.5 0.5", 1, "want 1 numbers, got 2"},
+	} {
+		if got := numberProblem(tt.value, tt.n); got != tt.want {
+			t.Errorf("numberProblem(%q, %d) = %q, want %q", tt.value, tt.n, got, tt.want)
+		}
+	}
+}
+
+const malformedURDF = `<robot name="r">
+  <link name="a">
+    <inertial>
+      <mass value="nan"/>
+    </inertial>
+    <visual>
+      <origin xyz="0 0"/>
+      <material name="m"><color rgba="1 0 0 1"/></material>
+    </visual>
+  </link>
+  <gazebo reference="a"><origin xyz="not checked"/></gazebo>
+  <link name="b"/>
+  <joint name="j" type="revolute">
+    <parent link="a"/><child link="b"/>
+    <limit lower="-1" upper="inf" effort="1" velocity="1"/>
+  </joint>
+</robot>
+`
+
+func TestCheckNumbers(t *testing.T) {
+	found, err := CheckNumbers(strings.NewReader(malformedURDF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`line 4: <mass value="nan">: "nan" is not a number`,
+		`line 7: <origin xyz="0 0">: want 3 numbers, got 2`,
+		`line 15: <limit upper="inf">: "inf" is infinite`,
+	}
+	if len(found) != len(want) {
+		t.Fatalf("CheckNumbers() found %v, want %d errors", found, len(want))
+	}
+	for i, err := range found {
+		if err.Error() != want[i] {
+			t.Errorf("error %d = %q, want %q", i, err, want[i])
+		}
+	}
+}
+
+func TestParseURDFNumbers(t *testing.T) {
+	_, err := ParseURDF(strings.NewReader(malformedURDF))
+	var numbers *NumbersError
+	if !errors.As(err, &numbers) {
+		t.Fatalf("ParseURDF() error = %v, want a *NumbersError", err)
+	}
+	if len(numbers.Errors) != 3 || !strings.HasPrefix(err.Error(), "3 malformed numeric attribute(s):\n  line 4: ") {
+		t.Errorf("ParseURDF() error = %v", err)
+	}
+
+	if _, err := ParseURDF(strings.NewReader(`<robot name="r"><link name="a"><visual><origin xyz="" rpy="0 0 1"/></visual></link></robot>`)); err != nil {
+		t.Errorf("ParseURDF() of valid numbers: %v", err)
+	}
+}
@@ -0,0 +1,690 @@
+package urdf
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nfranczak/urdf-simplifier/spatialmath"
+)
+
+// GeometryMode selects how collision meshes are simplified.
+type GeometryMode string
+
+const (
+	// GeometryBox replaces each collision mesh with its axis-aligned bounding box.
+	GeometryBox GeometryMode = "box"
+	// GeometryMesh keeps collision meshes unchanged.
+	GeometryMesh GeometryMode = "mesh"
+	// GeometryCapsule replaces each collision mesh with a capsule enclosing
+	// it, which planners check faster than a box, unless the capsule is much
+	// larger than the box would be.
+	GeometryCapsule GeometryMode = "capsule"
+	// GeometryNone removes collision geometry, leaving links with none, for
+	// consumers that only need the kinematics.
+	GeometryNone GeometryMode = "none"
+)
+
+// ChainMode selects which links and joints survive filtering.
+type ChainMode string
+
+const (
+	// ChainMain keeps only links attached by revolute or prismatic joints.
+	ChainMain ChainMode = "main"
+	// ChainAll keeps every link and joint.
+	ChainAll ChainMode = "all"
+)
+
+// DynamicsMode selects what happens to the <dynamics> elements of joints.
+type DynamicsMode string
+
+const (
+	// DynamicsKeep leaves damping and friction as written.
+	DynamicsKeep DynamicsMode = "keep"
+	// DynamicsZero sets damping and friction to zero, keeping the elements.
+	DynamicsZero DynamicsMode = "zero"
+	// DynamicsRemove removes the elements.
+	DynamicsRemove DynamicsMode = "remove"
+)
+
+// InertiaSource selects what recomputed inertia tensors are derived from.
+type InertiaSource string
+
+const (
+	// InertiaFromBox treats each link as a solid box filling its collision boxes.
+	InertiaFromBox InertiaSource = "box"
+	// InertiaFromMesh integrates the solid each closed collision mesh encloses.
+	InertiaFromMesh InertiaSource = "mesh"
+)
+
+// DefaultVisualColor is the color of the visuals Options.VisualFromCollision
+// adds: a translucent gray, which reads as an approximation of the robot.
+const DefaultVisualColor = "0.6 0.6 0.6 0.5"
+
+// LinkNaming selects the names links are renamed to, base to tip.
+type LinkNaming string
+
+const (
+	// LinkNamesNumbered names the root link link_0 and the links moved by
+	// the moving joints link_1 to link_n.
+	LinkNamesNumbered LinkNaming = "numbered"
+	// LinkNamesStandard names the root link base_link, the links moved by
+	// the moving joints link_1 to link_n, and the end link, if fixed below
+	// the last of them, tool.
+	LinkNamesStandard LinkNaming = "standard"
+)
+
+// Options controls the simplification pipeline. The zero value reproduces the
+// default behavior: boxes for every collision mesh, visuals and inertials
+// removed, and only the main kinematic chain kept.
+type Options struct {
+	// Name is the name of the simplified robot. Empty means the original
+	// name with "_simplified" appended, unless it ends in it already, so that
+	// the model does not collide with the original when both are loaded.
+	Name string `yaml:"name,omitempty"`
+	// BaseTransform, if set, is a pose "x y z roll pitch yaw", in meters and
+	// radians, that the simplified robot's root link frame is moved by, so
+	// that the model comes out in another frame, such as a work cell's: it
+	// pre-multiplies the origins of the joints out of the root link and of
+	// the root link's own visuals, collisions and inertial.
+	BaseTransform string `yaml:"base_transform,omitempty"`
+	// GeometryOnly turns off everything but the replacement of collision
+	// meshes: every link, joint, visual and inertial is kept as the input
+	// has them, nothing is cleaned up, and the robot keeps its name unless
+	// Name gives one. Options that would change anything else cannot be
+	// combined with it.
+	GeometryOnly bool `yaml:"geometry_only,omitempty"`
+	// Geometry is the default geometry mode for every link.
+	Geometry GeometryMode `yaml:"geometry"`
+	// Chain selects which part of the kinematic tree is kept.
+	Chain ChainMode `yaml:"chain"`
+	// KeepLinks lists links to keep even if the chain filter would drop them,
+	// together with the joints connecting them to the kept tree.
+	KeepLinks []string `yaml:"keep_links,omitempty"`
+	// DropLinks lists links to remove along with everything below them.
+	DropLinks []string `yaml:"drop_links,omitempty"`
+	// KeepTipFrames keeps the links hanging off the kept tree by fixed
+	// joints down to a leaf, such as tool0 or flange frames.
+	KeepTipFrames bool `yaml:"keep_tip_frames,omitempty"`
+	// KeepVisuals keeps <visual> elements instead of removing them.
+	KeepVisuals bool `yaml:"keep_visuals"`
+	// VisualFromCollision gives each kept link a <visual> for each of its
+	// simplified collision geometries, in VisualColor, in place of its own
+	// visuals, so that the model can still be viewed in RViz or Foxglove.
+	// It cannot be combined with KeepVisuals.
+	VisualFromCollision bool `yaml:"visual_from_collision,omitempty"`
+	// VisualColor is the RGBA color of the visuals VisualFromCollision adds,
+	// as four numbers from 0 to 1. Empty means DefaultVisualColor, as does
+	// an invalid color, with a warning.
+	VisualColor string `yaml:"visual_color,omitempty"`
+	// VisualPalette colors the visuals VisualFromCollision adds by link
+	// instead, each kept link taking the next color of a palette of ten, at
+	// the alpha of VisualColor.
+	VisualPalette bool `yaml:"visual_palette,omitempty"`
+	// KeepInertials keeps <inertial> elements instead of removing them.
+	KeepInertials bool `yaml:"keep_inertials"`
+	// RecomputeInertia, if set, replaces the inertia tensor and origin of each
+	// kept inertial with those of the link's collision geometry, keeping the
+	// link's mass. With InertiaFromMesh a link without a mass is given one
+	// from the mesh volume and Density. It requires KeepInertials.
+	RecomputeInertia InertiaSource `yaml:"recompute_inertia,omitempty"`
+	// Density is the uniform density in kg/m³ assumed for links without a
+	// mass. Zero means 1000, the density of water.
+	Density float64 `yaml:"density,omitempty"`
+	// MaxVolumeRatio is how many times the volume of the closed mesh it
+	// replaces a collision box may be before Simplify warns that the link
+	// needs a better geometry mode. Zero means 3.
+	MaxVolumeRatio float64 `yaml:"max_volume_ratio,omitempty"`
+	// MaxCapsuleRatio is how many times the volume of a mesh's bounding box
+	// the capsule fit to it under GeometryCapsule may be before the box is
+	// used instead. Zero means 1.5.
+	MaxCapsuleRatio float64 `yaml:"max_capsule_ratio,omitempty"`
+	// MassScale, if not zero, multiplies the mass and inertia tensor of each
+	// kept inertial whose link has no mass override. It is applied before
+	// inertia is recomputed and requires KeepInertials.
+	MassScale float64 `yaml:"mass_scale,omitempty"`
+	// EnsureInertials, if not zero, is the mass in kg of the placeholder
+	// inertial given to each link that still has none after any inertia
+	// recomputation. It requires KeepInertials.
+	EnsureInertials float64 `yaml:"ensure_inertials,omitempty"`
+	// Payload, if set, is a point mass added to the inertial of the end link
+	// after filtering, for payload-loaded models. It requires KeepInertials.
+	Payload *Payload `yaml:"payload,omitempty"`
+	// FixInertia replaces each kept inertia tensor that is not physically
+	// valid with the nearest valid one, instead of only warning about it.
+	FixInertia bool `yaml:"fix_inertia,omitempty"`
+	// LumpMass adds the mass and inertia of each removed link into the kept
+	// link it is attached to by fixed joints, if any, instead of dropping
+	// them. It only has an effect with KeepInertials.
+	LumpMass bool `yaml:"lump_mass,omitempty"`
+	// Dynamics says what to do with joint damping and friction. Empty means
+	// DynamicsKeep.
+	Dynamics DynamicsMode `yaml:"dynamics,omitempty"`
+	// SkipCollisionCheck turns off the check for collision boxes of links not
+	// joined to each other that overlap at the zero pose.
+	SkipCollisionCheck bool `yaml:"skip_collision_check,omitempty"`
+	// CanonicalNames renames the simplified robot's moving joints joint_1
+	// to joint_n from base to tip, and its links as LinkNames says, for
+	// consumers that require that convention, and records the renames in
+	// Report.NameMap. Everything else in the options, and the report, names
+	// links and joints as the input does.
+	CanonicalNames bool `yaml:"canonical_names,omitempty"`
+	// LinkNames renames the simplified robot's links to a scheme, recording
+	// the renames in Report.NameMap like CanonicalNames. Empty means
+	// LinkNamesNumbered with CanonicalNames and no renaming otherwise.
+	LinkNames LinkNaming `yaml:"link_names,omitempty"`
+	// SkipCleanup keeps attributes and elements that only restate the
+	// defaults, such as rpy="0 0 0", instead of removing them, and rpy
+	// angles as written instead of in canonical form: each in (-π, π], the
+	// pitch in [-π/2, π/2].
+	SkipCleanup bool `yaml:"skip_cleanup,omitempty"`
+	// SnapTolerance, if not zero, snaps origin and joint axis values within
+	// it of round ones before the cleanup: joint axes to the principal axis
+	// they are nearly along, and origin components to the value with the
+	// fewest decimals, or for angles a multiple of π/4, so that the float
+	// noise of CAD exports, such as 0.3000001, comes out as 0.3.
+	SnapTolerance float64 `yaml:"snap_tolerance,omitempty"`
+	// SweepJoints also moves each joint through its limits, one at a time,
+	// and warns about collision boxes that are clear at the zero pose but
+	// overlap somewhere along the way.
+	SweepJoints bool `yaml:"sweep_joints,omitempty"`
+	// WorkspaceSamples, if not zero, is how many random configurations to
+	// sample for an estimate of the end link's workspace, which is checked
+	// against the original robot's.
+	WorkspaceSamples int `yaml:"workspace_samples,omitempty"`
+	// Links holds per-link overrides keyed by link name.
+	Links map[string]LinkOptions `yaml:"links,omitempty"`
+	// DefaultEffort and DefaultVelocity, if not zero, fill in the effort and
+	// velocity of joint limits that leave them out (or give them as zero),
+	// which downstream loaders reject.
+	DefaultEffort   float64 `yaml:"default_effort,omitempty"`
+	DefaultVelocity float64 `yaml:"default_velocity,omitempty"`
+	// Joints holds per-joint overrides keyed by joint name or by a glob
+	// pattern such as "finger_*". An exact name wins over any pattern, and a
+	// longer pattern over a shorter one.
+	Joints map[string]JointOptions `yaml:"joints,omitempty"`
+
+	// Logger receives progress messages: warnings at Warn, a summary at Info,
+	// per-mesh results at Debug, and path resolution traces below Debug. Nil
+	// discards them.
+	Logger *slog.Logger `yaml:"-"`
+
+	// Progress, if set, is called after each collision mesh file has been
+	// read, in the order they finish, on the goroutine running Simplify. A file
+	// referenced by several links is read once.
+	Progress func(Progress) `yaml:"-"`
+
+	// Jobs is the number of collision meshes read and bounded, and then of
+	// links processed, at once. Zero or one does one at a time; anything more
+	// requires the MeshResolver to be safe for concurrent use, as FileResolver
+	// and FSResolver are. Log messages come out in the same order either way.
+	Jobs int `yaml:"-"`
+
+	// CacheDir, if set, is a directory where the bounds of each mesh are kept
+	// between runs, keyed by the hash of its content and of the options that
+	// change them, so unchanged meshes are not parsed again. Files larger
+	// than 64 MiB that cannot be memory-mapped are not looked up, since
+	// their hash is only known once they have been read.
+	CacheDir string `yaml:"-"`
+}
+
+// Progress reports how far Simplify has got through the collision meshes.
+type Progress struct {
+	// Done counts the mesh files read so far, including this one, out of Total.
+	Done, Total int
+	Link        string
+	Mesh        string
+	// Elapsed is the time spent reading and bounding this mesh.
+	Elapsed time.Duration
+	// Err is set if the mesh could not be read.
+	Err error
+}
+
+// Payload is a point mass carried by a link.
+type Payload struct {
+	// Mass is the payload mass in kg.
+	Mass float64 `yaml:"mass"`
+	// Offset is the position of the payload in the link frame.
+	Offset [3]float64 `yaml:"offset,flow"`
+	// Link names the link carrying the payload. Empty means the end of the
+	// longest chain, which must be unique.
+	Link string `yaml:"link,omitempty"`
+}
+
+// LinkOptions overrides Options for a single link.
+type LinkOptions struct {
+	// Geometry overrides Options.Geometry for this link.
+	Geometry GeometryMode `yaml:"geometry"`
+	// Mass, if not zero, replaces the link's mass in kg, scaling its inertia
+	// tensor to match. It requires Options.KeepInertials.
+	Mass float64 `yaml:"mass,omitempty"`
+}
+
+// JointOptions overrides Options for the joints matching a name or pattern.
+type JointOptions struct {
+	// DefaultEffort and DefaultVelocity override Options.DefaultEffort and
+	// Options.DefaultVelocity.
+	DefaultEffort   float64 `yaml:"default_effort,omitempty"`
+	DefaultVelocity float64 `yaml:"default_velocity,omitempty"`
+	// Lower and Upper, if set, replace the lower and upper limits of
+	// revolute and prismatic joints.
+	Lower JointPosition `yaml:"lower,omitempty"`
+	Upper JointPosition `yaml:"upper,omitempty"`
+	// Lock, if set, turns a moving joint into a fixed one at this position,
+	// folding the motion into its origin.
+	Lock JointPosition `yaml:"lock,omitempty"`
+}
+
+// JointPosition is a joint position as a config file gives it: a number,
+// in radians for a revolute or continuous joint and meters for a prismatic
+// one, or degrees with a deg suffix, such as 150deg.
+type JointPosition string
+
+// value returns the position in radians or meters, and whether it was
+// given in degrees.
+func (p JointPosition) value() (v float64, degrees bool, err error) {
+	s := strings.TrimSpace(string(p))
+	if n, ok := strings.CutSuffix(s, "deg"); ok {
+		s, degrees = strings.TrimSpace(n), true
+	}
+	v, err = strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(v, 0) || math.IsNaN(v) {
+		return 0, false, fmt.Errorf("invalid joint position %q (want a number, or degrees such as 150deg)", string(p))
+	}
+	if degrees {
+		v *= math.Pi / 180
+	}
+	return v, degrees, nil
+}
+
+// valueFor returns the position for joint, which must turn if it was given
+// in degrees.
+func (p JointPosition) valueFor(joint *Joint) (float64, error) {
+	v, degrees, err := p.value()
+	if err != nil {
+		return 0, err
+	}
+	if degrees && joint.Type == "prismatic" {
+		return 0, fmt.Errorf("%s is in degrees, but joint %q is prismatic and moves in meters", string(p), joint.Name)
+	}
+	return v, nil
+}
+
+// Validate reports option values that are not recognized.
+func (o Options) Validate() error {
+	if err := o.Geometry.validate(); err != nil {
+		return err
+	}
+	if o.BaseTransform != "" {
+		if _, err := parseBaseTransform(o.BaseTransform); err != nil {
+			return err
+		}
+	}
+	if o.GeometryOnly {
+		if conflict := o.geometryOnlyConflict(); conflict != "" {
+			return fmt.Errorf("geometry-only mode keeps everything but collision geometry as it is, and cannot be combined with %s", conflict)
+		}
+	}
+	switch o.Chain {
+	case "", ChainMain, ChainAll:
+	default:
+		return fmt.Errorf("unknown chain mode %q (want %q or %q)", o.Chain, ChainMain, ChainAll)
+	}
+	switch o.LinkNames {
+	case "", LinkNamesNumbered, LinkNamesStandard:
+	default:
+		return fmt.Errorf("unknown link naming %q (want %q or %q)", o.LinkNames, LinkNamesNumbered, LinkNamesStandard)
+	}
+	switch o.Dynamics {
+	case "", DynamicsKeep, DynamicsZero, DynamicsRemove:
+	default:
+		return fmt.Errorf("unknown dynamics mode %q (want %q, %q or %q)", o.Dynamics, DynamicsKeep, DynamicsZero, DynamicsRemove)
+	}
+	if o.VisualFromCollision && o.KeepVisuals {
+		return fmt.Errorf("visuals from collision geometry replace the links' own, which cannot also be kept (keep_visuals or --keep-visuals)")
+	}
+	if o.VisualFromCollision && o.Geometry == GeometryNone {
+		return fmt.Errorf("visuals from collision geometry need collision geometry to be kept (geometry other than %q)", GeometryNone)
+	}
+	if o.VisualPalette && !o.VisualFromCollision {
+		return fmt.Errorf("a visual palette needs visuals from collision geometry (visual_from_collision or --visual-from-collision)")
+	}
+	if o.VisualColor != "" {
+		if !o.VisualFromCollision {
+			return fmt.Errorf("a visual color needs visuals from collision geometry (visual_from_collision or --visual-from-collision)")
+		}
+		if err := validateRGBA(o.VisualColor); err != nil {
+			return err
+		}
+	}
+	switch o.RecomputeInertia {
+	case "":
+	case InertiaFromBox, InertiaFromMesh:
+		if !o.KeepInertials {
+			return fmt.Errorf("recomputing inertia needs inertials to be kept (keep_inertials or --keep-inertials)")
+		}
+		if o.RecomputeInertia == InertiaFromBox && o.Geometry == GeometryNone {
+			return fmt.Errorf("recomputing inertia from boxes needs collision geometry to be kept (geometry other than %q)", GeometryNone)
+		}
+	default:
+		return fmt.Errorf("unknown inertia source %q (want %q or %q)", o.RecomputeInertia, InertiaFromBox, InertiaFromMesh)
+	}
+	if o.Density < 0 {
+		return fmt.Errorf("invalid density %g (want a positive number)", o.Density)
+	}
+	if o.MaxVolumeRatio != 0 && o.MaxVolumeRatio < 1 {
+		return fmt.Errorf("invalid max volume ratio %g (want 1 or more)", o.MaxVolumeRatio)
+	}
+	if o.SnapTolerance < 0 || o.SnapTolerance > 0.01 {
+		return fmt.Errorf("invalid snap tolerance %g (want a positive number up to 0.01)", o.SnapTolerance)
+	}
+	if o.MaxCapsuleRatio < 0 {
+		return fmt.Errorf("invalid max capsule ratio %g (want a positive number)", o.MaxCapsuleRatio)
+	}
+	if o.WorkspaceSamples < 0 {
+		return fmt.Errorf("invalid workspace samples %d (want a positive number)", o.WorkspaceSamples)
+	}
+	if o.EnsureInertials < 0 {
+		return fmt.Errorf("invalid default inertial mass %g (want a positive number)", o.EnsureInertials)
+	}
+	if o.EnsureInertials != 0 && !o.KeepInertials {
+		return fmt.Errorf("adding default inertials needs inertials to be kept (keep_inertials or --keep-inertials)")
+	}
+	if o.Payload != nil {
+		if o.Payload.Mass <= 0 {
+			return fmt.Errorf("invalid payload mass %g (want a positive number)", o.Payload.Mass)
+		}
+		if !o.KeepInertials {
+			return fmt.Errorf("adding a payload needs inertials to be kept (keep_inertials or --keep-inertials)")
+		}
+	}
+	if o.MassScale < 0 {
+		return fmt.Errorf("invalid mass scale %g (want a positive number)", o.MassScale)
+	}
+	if o.MassScale != 0 && !o.KeepInertials {
+		return fmt.Errorf("scaling masses needs inertials to be kept (keep_inertials or --keep-inertials)")
+	}
+	if o.DefaultEffort < 0 || o.DefaultVelocity < 0 {
+		return fmt.Errorf("invalid default limits (effort %g, velocity %g; want positive numbers)", o.DefaultEffort, o.DefaultVelocity)
+	}
+	// Entries are checked in sorted order, so that of several invalid ones
+	// the same is reported on every run.
+	for _, pattern := range slices.Sorted(maps.Keys(o.Joints)) {
+		joint := o.Joints[pattern]
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("joints: invalid pattern %q", pattern)
+		}
+		if joint.DefaultEffort < 0 || joint.DefaultVelocity < 0 {
+			return fmt.Errorf("joints: %q: invalid default limits (effort %g, velocity %g; want positive numbers)",
+				pattern, joint.DefaultEffort, joint.DefaultVelocity)
+		}
+		for _, p := range []JointPosition{joint.Lower, joint.Upper, joint.Lock} {
+			if p == "" {
+				continue
+			}
+			if _, _, err := p.value(); err != nil {
+				return fmt.Errorf("joints: %q: %w", pattern, err)
+			}
+		}
+		if joint.Lock != "" && (joint.Lower != "" || joint.Upper != "") {
+			return fmt.Errorf("joints: %q: a locked joint has no limits to override", pattern)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(o.Links)) {
+		link := o.Links[name]
+		if err := link.Geometry.validate(); err != nil {
+			return fmt.Errorf("link %q: %w", name, err)
+		}
+		if link.Mass < 0 {
+			return fmt.Errorf("link %q: invalid mass %g (want a positive number)", name, link.Mass)
+		}
+		if link.Mass != 0 && !o.KeepInertials {
+			return fmt.Errorf("link %q: overriding its mass needs inertials to be kept (keep_inertials or --keep-inertials)", name)
+		}
+	}
+	return nil
+}
+
+func (g GeometryMode) validate() error {
+	switch g {
+	case "", GeometryBox, GeometryMesh, GeometryCapsule, GeometryNone:
+		return nil
+	}
+	return fmt.Errorf("unknown geometry mode %q (want %q, %q, %q or %q)", g, GeometryBox, GeometryCapsule, GeometryMesh, GeometryNone)
+}
+
+// geometryOnlyConflict returns the first option set that GeometryOnly cannot
+// be combined with, as the config key and flag that set it, or "" if none is.
+func (o Options) geometryOnlyConflict() string {
+	mass := false
+	for _, l := range o.Links {
+		mass = mass || l.Mass != 0
+	}
+	for _, c := range []struct {
+		set  bool
+		what string
+	}{
+		{o.Chain == ChainMain, "chain main (chain or --chain)"},
+		{len(o.KeepLinks) > 0, "kept links (keep_links or --keep-link)"},
+		{len(o.DropLinks) > 0, "dropped links (drop_links or --drop-link)"},
+		{o.VisualFromCollision, "visuals from collision geometry (visual_from_collision or --visual-from-collision)"},
+		{o.RecomputeInertia != "", "recomputed inertia (recompute_inertia or --recompute-inertia)"},
+		{o.MassScale != 0, "a mass scale (mass_scale or --mass-scale)"},
+		{mass, "mass overrides (mass under links)"},
+		{o.EnsureInertials != 0, "default inertials (ensure_inertials or --ensure-inertials)"},
+		{o.Payload != nil, "a payload (payload or --payload)"},
+		{o.FixInertia, "fixed inertia (fix_inertia or --fix-inertia)"},
+		{o.Dynamics != "" && o.Dynamics != DynamicsKeep, "changed dynamics (dynamics or --dynamics)"},
+		{o.DefaultEffort != 0 || o.DefaultVelocity != 0, "default limits (default_effort, default_velocity or --default-effort, --default-velocity)"},
+		{len(o.Joints) > 0, "joint overrides (joints)"},
+		{o.BaseTransform != "", "a base transform (base_transform or --base-transform)"},
+		{o.SnapTolerance != 0, "snapping (snap_tolerance or --snap)"},
+		{o.CanonicalNames || o.LinkNames != "", "renaming (canonical_names, link_names or --canonical-names, --link-names)"},
+	} {
+		if c.set {
+			return c.what
+		}
+	}
+	return ""
+}
+
+// geometryOnly returns o with the rest of the pipeline turned off for
+// GeometryOnly, for a robot named name.
+func (o Options) geometryOnly(name string) Options {
+	o.Chain = ChainAll
+	o.KeepVisuals, o.KeepInertials, o.SkipCleanup = true, true, true
+	o.Name = cmp.Or(o.Name, name)
+	return o
+}
+
+// parseBaseTransform parses Options.BaseTransform.
+func parseBaseTransform(s string) (spatialmath.Pose, error) {
+	f := strings.Fields(s)
+	if len(f) == 6 {
+		if p, err := spatialmath.ParsePose(strings.Join(f[:3], " "), strings.Join(f[3:], " ")); err == nil {
+			return p, nil
+		}
+	}
+	return spatialmath.Pose{}, fmt.Errorf("invalid base transform %q (want \"x y z roll pitch yaw\" in meters and radians)", s)
+}
+
+// validateRGBA checks that s is a color as a URDF writes it: red, green,
+// blue and alpha, each from 0 to 1.
+func validateRGBA(s string) error {
+	fields := strings.Fields(s)
+	valid := len(fields) == 4
+	for _, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		valid = valid && err == nil && v >= 0 && v <= 1
+	}
+	if !valid {
+		return fmt.Errorf("invalid visual color %q (want red, green, blue and alpha from 0 to 1, such as %q)", s, DefaultVisualColor)
+	}
+	return nil
+}
+
+// nameFor returns the name of the simplified robot for the original one.
+func (o Options) nameFor(original string) string {
+	if o.Name != "" || original == "" || strings.HasSuffix(original, "_simplified") {
+		return cmp.Or(o.Name, original)
+	}
+	return original + "_simplified"
+}
+
+// geometryFor returns the geometry mode that applies to the named link.
+func (o Options) geometryFor(link string) GeometryMode {
+	if l, ok := o.Links[link]; ok && l.Geometry != "" {
+		return l.Geometry
+	}
+	if o.Geometry == "" {
+		return GeometryBox
+	}
+	return o.Geometry
+}
+
+// fitsCapsules reports whether any link uses GeometryCapsule, so that meshes
+// need capsules fit to them.
+func (o Options) fitsCapsules() bool {
+	if o.Geometry == GeometryCapsule {
+		return true
+	}
+	for _, l := range o.Links {
+		if l.Geometry == GeometryCapsule {
+			return true
+		}
+	}
+	return false
+}
+
+// jointFor returns the options that apply to the named joint: those given
+// for its exact name, or else for the longest pattern matching it, with
+// unset defaults taken from o.
+func (o Options) jointFor(name string) JointOptions {
+	j, ok := o.Joints[name]
+	if !ok {
+		best := ""
+		for pattern, p := range o.Joints {
+			if matched, _ := path.Match(pattern, name); !matched {
+				continue
+			}
+			if !ok || len(pattern) > len(best) || len(pattern) == len(best) && pattern < best {
+				j, best, ok = p, pattern, true
+			}
+		}
+	}
+	if j.DefaultEffort == 0 {
+		j.DefaultEffort = o.DefaultEffort
+	}
+	if j.DefaultVelocity == 0 {
+		j.DefaultVelocity = o.DefaultVelocity
+	}
+	return j
+}
+
+// density returns the density for links without a mass.
+func (o Options) density() float64 {
+	if o.Density == 0 {
+		return 1000
+	}
+	return o.Density
+}
+
+// visualColor returns VisualColor, or DefaultVisualColor if it is empty or,
+// as Validate would report, not a valid color.
+func (o Options) visualColor() string {
+	if o.VisualColor == "" || validateRGBA(o.VisualColor) != nil {
+		return DefaultVisualColor
+	}
+	return o.VisualColor
+}
+
+// maxVolumeRatio returns the box to mesh volume ratio above which Simplify
+// warns.
+func (o Options) maxVolumeRatio() float64 {
+	if o.MaxVolumeRatio == 0 {
+		return 3
+	}
+	return o.MaxVolumeRatio
+}
+
+// maxCapsuleRatio returns the capsule to box volume ratio above which
+// GeometryCapsule falls back to the box.
+func (o Options) maxCapsuleRatio() float64 {
+	if o.MaxCapsuleRatio == 0 {
+		return 1.5
+	}
+	return o.MaxCapsuleRatio
+}
+
+// LevelTrace is the log level of the most detailed messages, such as how each
+// mesh URI was resolved.
+const LevelTrace = slog.LevelDebug - 4
+
+func (o Options) logger() *slog.Logger {
+	if o.Logger == nil {
+		return slog.New(discardHandler{})
+	}
+	return o.Logger
+}
+
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (d discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return d }
+func (d discardHandler) WithGroup(string) slog.Handler           { return d }
+
+// logBuffer is a slog.Handler that holds records until flush passes them on
+// to the handler it wraps, so that work done concurrently logs in a fixed
+// order rather than the order it happens to run in. It is not safe for
+// concurrent use; each goroutine gets its own.
+type logBuffer struct {
+	handler slog.Handler
+	records *[]bufferedRecord
+}
+
+type bufferedRecord struct {
+	handler slog.Handler
+	record  slog.Record
+}
+
+// buffered returns a copy of o whose logger writes to a new logBuffer in
+// front of o's.
+func (o Options) buffered() (Options, *logBuffer) {
+	b := &logBuffer{handler: o.logger().Handler(), records: new([]bufferedRecord)}
+	o.Logger = slog.New(b)
+	return o, b
+}
+
+func (b *logBuffer) Enabled(ctx context.Context, level slog.Level) bool {
+	return b.handler.Enabled(ctx, level)
+}
+
+func (b *logBuffer) Handle(_ context.Context, r slog.Record) error {
+	*b.records = append(*b.records, bufferedRecord{b.handler, r.Clone()})
+	return nil
+}
+
+func (b *logBuffer) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &logBuffer{handler: b.handler.WithAttrs(attrs), records: b.records}
+}
+
+func (b *logBuffer) WithGroup(name string) slog.Handler {
+	return &logBuffer{handler: b.handler.WithGroup(name), records: b.records}
+}
+
+// flush passes the held records on, in the order they were logged.
+func (b *logBuffer) flush() {
+	for _, r := range *b.records {
+		r.handler.Handle(context.Background(), r.record)
+	}
+	*b.records = nil
+}
@@ -0,0 +1,51 @@
+package urdf
+
+import "github.com/nfranczak/urdf-simplifier/spatialmath"
+
+// Pose parses the origin into a rigid transform. A nil origin is the identity.
+func (o *Origin) Pose() (spatialmath.Pose, error) {
+	if o == nil {
+		return spatialmath.Identity(), nil
+	}
+	return spatialmath.ParsePose(o.XYZ, o.RPY)
+}
+
+// OriginFromPose returns an origin element describing p.
+func OriginFromPose(p spatialmath.Pose) *Origin {
+	xyz, rpy := p.XYZRPY()
+	return &Origin{XYZ: xyz, RPY: rpy}
+}
+
+// Motion returns the transform a joint at position q adds after its origin:
+// a rotation of q radians about the axis for revolute and continuous joints,
+// a translation of q meters along it for prismatic ones, and the identity
+// otherwise.
+func (j *Joint) Motion(q float64) (spatialmath.Pose, error) {
+	if q == 0 {
+		return spatialmath.Identity(), nil
+	}
+	switch j.Type {
+	case "revolute", "continuous", "prismatic":
+	default:
+		return spatialmath.Identity(), nil
+	}
+	axis, err := j.AxisVector()
+	if err != nil {
+		return spatialmath.Pose{}, err
+	}
+	if axis.Norm() == 0 {
+		return spatialmath.Identity(), nil
+	}
+	if j.Type == "prismatic" {
+		return spatialmath.Pose{Translation: axis.Normalize().Scale(q), Rotation: spatialmath.IdentityQuaternion()}, nil
+	}
+	return spatialmath.Pose{Rotation: spatialmath.AxisAngle(axis, q)}, nil
+}
+
+// AxisVector parses the joint axis. URDF defaults a missing axis to (1, 0, 0).
+func (j *Joint) AxisVector() (spatialmath.Vec3, error) {
+	if j.Axis == nil || j.Axis.XYZ == "" {
+		return spatialmath.Vec3{X: 1}, nil
+	}
+	return spatialmath.ParseVec3(j.Axis.XYZ)
+}
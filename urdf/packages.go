@@ -0,0 +1,142 @@
+package urdf
+
+import (
+	"encoding/xml"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// splitPackageURI splits package://pkg/rest into its package name and the
+// slash-separated path inside the package.
+func splitPackageURI(uri string) (pkg, rest string, ok bool) {
+	return splitURI(uri, "package://")
+}
+
+// splitModelURI splits a Gazebo model://model/rest URI the same way.
+func splitModelURI(uri string) (model, rest string, ok bool) {
+	return splitURI(uri, "model://")
+}
+
+func splitURI(uri, scheme string) (name, rest string, ok bool) {
+	trimmed, ok := strings.CutPrefix(uri, scheme)
+	if !ok {
+		return "", "", false
+	}
+	name, rest, _ = strings.Cut(trimmed, "/")
+	return name, rest, name != ""
+}
+
+// PackageDir returns the root directory of the named ROS package: its entry
+// in Packages if it has one, and otherwise the directory found the same way
+// as for package:// mesh URIs.
+func (r FileResolver) PackageDir(name string) (string, bool) {
+	if dir, ok := r.Packages[name]; ok {
+		return dir, true
+	}
+	return r.findPackage(name)
+}
+
+// findPackage looks up the root directory of a ROS package: the nearest
+// directory at or above BaseDir whose package.xml names it, then share/<pkg>
+// under each ament prefix, then each ROS_PACKAGE_PATH entry, which may be the
+// package itself or a tree of packages.
+func (r FileResolver) findPackage(pkg string) (string, bool) {
+	if dir, ok := enclosingPackage(r.BaseDir, pkg); ok {
+		return dir, true
+	}
+	for _, prefix := range r.AmentPrefixPath {
+		dir := filepath.Join(prefix, "share", pkg)
+		if isDir(dir) {
+			return dir, true
+		}
+	}
+	for _, root := range r.PackagePath {
+		if dir, ok := packageIndex(root)[pkg]; ok {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// enclosingPackage walks up from dir looking for a package.xml that names
+// pkg, as when a URDF refers to meshes of the package it belongs to. The
+// result is relative if dir is.
+func enclosingPackage(dir, pkg string) (string, bool) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	for {
+		if name, ok := packageName(abs); ok && name == pkg {
+			return dir, true
+		}
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return "", false
+		}
+		abs, dir = parent, filepath.Join(dir, "..")
+	}
+}
+
+var (
+	packageIndexMu sync.Mutex
+	packageIndexes = make(map[string]map[string]string)
+)
+
+// packageIndex maps the name of every package under root to its directory.
+// Like rospack, it does not look inside packages for further packages. The
+// index for a root is built once per process.
+func packageIndex(root string) map[string]string {
+	packageIndexMu.Lock()
+	defer packageIndexMu.Unlock()
+	if index, ok := packageIndexes[root]; ok {
+		return index
+	}
+
+	index := make(map[string]string)
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path != root && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if name, ok := packageName(path); ok {
+			if _, dup := index[name]; !dup {
+				index[name] = path
+			}
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	packageIndexes[root] = index
+	return index
+}
+
+// packageName reads the package name from dir/package.xml.
+func packageName(dir string) (string, bool) {
+	data, err := os.ReadFile(filepath.Join(dir, "package.xml"))
+	if err != nil {
+		return "", false
+	}
+	var manifest struct {
+		Name string `xml:"name"`
+	}
+	if err := xml.Unmarshal(data, &manifest); err != nil || manifest.Name == "" {
+		return "", false
+	}
+	return strings.TrimSpace(manifest.Name), true
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,62 @@
+package urdf
+
+import (
+	"fmt"
+	"sort"
+)
+
+// presets are the built-in option sets selectable by name.
+var presets = map[string]Options{
+	// Collision checking and planning: boxes for every link, only the
+	// actuated chain plus tool frames, nothing else.
+	"motion-planning": {
+		Geometry:      GeometryBox,
+		Chain:         ChainMain,
+		KeepTipFrames: true,
+	},
+	// Viam's motion planner: capsules where they fit, which it checks
+	// faster than boxes, and boxes elsewhere, for the actuated chain plus
+	// tool frames.
+	"viam": {
+		Geometry:      GeometryCapsule,
+		Chain:         ChainMain,
+		KeepTipFrames: true,
+	},
+	// Physics simulation: every link with box collisions, and the visuals
+	// and inertials the simulator needs, the inertia tensors recomputed to
+	// match the boxes.
+	"gazebo": {
+		Geometry:         GeometryBox,
+		Chain:            ChainAll,
+		KeepVisuals:      true,
+		KeepInertials:    true,
+		RecomputeInertia: InertiaFromBox,
+		LumpMass:         true,
+	},
+	// Viewing the robot: the full tree with its visuals and the original
+	// collision meshes.
+	"visualization": {
+		Geometry:    GeometryMesh,
+		Chain:       ChainAll,
+		KeepVisuals: true,
+	},
+}
+
+// Preset returns the built-in options with the given name.
+func Preset(name string) (Options, error) {
+	opts, ok := presets[name]
+	if !ok {
+		return Options{}, fmt.Errorf("unknown preset %q (want one of %v)", name, PresetNames())
+	}
+	return opts, nil
+}
+
+// PresetNames returns the names of the built-in presets, sorted.
+func PresetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
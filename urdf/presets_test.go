@@ -0,0 +1,21 @@
+package urdf
+
+import "testing"
+
+func TestPresets(t *testing.T) {
+	for _, name := range PresetNames() {
+		opts, err := Preset(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := opts.Validate(); err != nil {
+			t.Errorf("preset %s: %v", name, err)
+		}
+	}
+	if opts, _ := Preset("gazebo"); !opts.KeepInertials || opts.RecomputeInertia != InertiaFromBox {
+		t.Errorf("gazebo preset %+v, want inertials kept and recomputed from boxes", opts)
+	}
+	if _, err := Preset("nosuch"); err == nil {
+		t.Error("unknown preset accepted")
+	}
+}
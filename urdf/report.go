@@ -0,0 +1,241 @@
+package urdf
+
+import "github.com/nfranczak/urdf-simplifier/mesh"
+
+// Report summarizes the changes made while simplifying a URDF.
+type Report struct {
+	// Robot is the name of the input robot, and Name that of the
+	// simplified one; see Options.Name.
+	Robot         string       `json:"robot"`
+	Name          string       `json:"name"`
+	Links         int          `json:"links"`
+	Joints        int          `json:"joints"`
+	Meshes        []MeshReport `json:"meshes"`
+	RemovedLinks  []string     `json:"removed_links"`
+	RemovedJoints []string     `json:"removed_joints"`
+	Warnings      []string     `json:"warnings"`
+	// DetachedBefore and DetachedAfter list the links not attached to the
+	// rest of the robot before and after filtering, if any. Each detached link
+	// also has an entry in Warnings, after filtering only if filtering
+	// detached it.
+	DetachedBefore *Detached `json:"detached_before,omitempty"`
+	DetachedAfter  *Detached `json:"detached_after,omitempty"`
+	// Errors lists the problems that make the simplified robot invalid, such
+	// as a joint mimicking a removed joint. A robot with errors should not
+	// be written.
+	Errors []string `json:"errors,omitempty"`
+	// NameMap holds the links and joints renamed under
+	// Options.CanonicalNames or Options.LinkNames, if any.
+	NameMap *NameMap `json:"name_map,omitempty"`
+	// Changes counts what was removed or altered, by category.
+	Changes Changes `json:"changes"`
+	// FailedMeshes lists the collision meshes that could not be read and were
+	// left in place. Each also has an entry in Warnings.
+	FailedMeshes []FailedMesh `json:"failed_meshes,omitempty"`
+	// DuplicateMeshes counts the entries of Meshes whose box was reused from
+	// an earlier reference to the same file or to a file with the same content.
+	DuplicateMeshes int `json:"duplicate_meshes,omitempty"`
+	// Inertias lists the links whose inertia tensor was recomputed.
+	Inertias []InertiaReport `json:"inertias,omitempty"`
+	// LumpedMasses lists the removed links whose mass was added to a kept
+	// link they were fixed to.
+	LumpedMasses []LumpedMass `json:"lumped_masses,omitempty"`
+	// FixedInertias lists the links whose invalid inertia tensor was replaced
+	// with the nearest valid one.
+	FixedInertias []FixedInertia `json:"fixed_inertias,omitempty"`
+	// SelfCollisions lists the pairs of links not joined to each other whose
+	// collision boxes overlap at the zero pose. Each also has an entry in
+	// Warnings.
+	SelfCollisions []CollisionPair `json:"self_collisions,omitempty"`
+	// SweptCollisions lists the pairs of links whose collision boxes overlap
+	// as a joint moves through its limits, with SweepJoints set. Each also has
+	// an entry in Warnings.
+	SweptCollisions []SweptCollision `json:"swept_collisions,omitempty"`
+	// FilledLimits lists the joints whose missing effort or velocity limit was
+	// filled in with a default.
+	FilledLimits []string `json:"filled_limits,omitempty"`
+	// PayloadLink names the link the payload was added to, if any.
+	PayloadLink string `json:"payload_link,omitempty"`
+	// CollisionVolume compares the volume of the replaced meshes to that of
+	// the boxes and capsules replacing them.
+	CollisionVolume *VolumeReport `json:"collision_volume,omitempty"`
+	// Workspace estimates the workspace of the simplified robot's end link,
+	// with WorkspaceSamples set.
+	Workspace *Workspace `json:"workspace,omitempty"`
+	// MassBefore and MassAfter describe the robot's mass before and after
+	// simplification, both with the center of mass in the frame of the
+	// simplified robot's root link.
+	MassBefore *MassReport `json:"mass_before"`
+	MassAfter  *MassReport `json:"mass_after"`
+}
+
+// Changes groups the removals and alterations of a simplification by
+// category. Visuals and inertials of removed links are counted too.
+type Changes struct {
+	VisualsRemoved   int `json:"visuals_removed"`
+	InertialsRemoved int `json:"inertials_removed"`
+	// VisualsAdded counts the visuals made from collision geometry under
+	// Options.VisualFromCollision.
+	VisualsAdded int `json:"visuals_added,omitempty"`
+	// MeshesReplaced counts the collision meshes replaced with boxes or
+	// capsules, which Report.Meshes lists with their sizes. Those merged
+	// into another collision are counted in CollisionsMerged instead.
+	MeshesReplaced int `json:"meshes_replaced"`
+	LinksRemoved   int `json:"links_removed"`
+	// CollisionsRemoved counts the collisions of links under GeometryNone.
+	CollisionsRemoved int `json:"collisions_removed,omitempty"`
+	// CollisionsMerged counts the collisions removed for having the same
+	// geometry and origin as another of their link's.
+	CollisionsMerged int `json:"collisions_merged,omitempty"`
+	// FixedJointsRemoved and MovingJointsRemoved split Report.RemovedJoints
+	// by whether the joint was fixed.
+	FixedJointsRemoved  []string `json:"fixed_joints_removed,omitempty"`
+	MovingJointsRemoved []string `json:"moving_joints_removed,omitempty"`
+	// RPYNormalized counts the origins whose rpy was rewritten in canonical
+	// form; see Options.SkipCleanup.
+	RPYNormalized int `json:"rpy_normalized,omitempty"`
+	// ValuesSnapped counts the origin xyz and rpy and the joint axes that
+	// were snapped to nearby round values; see Options.SnapTolerance.
+	ValuesSnapped int `json:"values_snapped,omitempty"`
+	// RedundantRemoved counts the attributes and elements removed for only
+	// restating the defaults; see Options.SkipCleanup.
+	RedundantRemoved int `json:"redundant_removed,omitempty"`
+	// LimitsOverridden lists the joints whose lower and upper limits were
+	// replaced, and JointsLocked those made fixed, from JointOptions.
+	LimitsOverridden []string `json:"limits_overridden,omitempty"`
+	JointsLocked     []string `json:"joints_locked,omitempty"`
+	// DynamicsChanged counts the kept joints whose damping and friction were
+	// zeroed or removed.
+	DynamicsChanged int `json:"dynamics_changed,omitempty"`
+	// InertiasChanged counts the kept links whose inertial was recomputed,
+	// fixed, or added to.
+	InertiasChanged int `json:"inertias_changed,omitempty"`
+}
+
+// FixedInertia records an inertia tensor replaced because it was invalid.
+type FixedInertia struct {
+	Link string `json:"link"`
+	// Problem says what was wrong with the original tensor.
+	Problem string `json:"problem"`
+}
+
+// LumpedMass records the mass of a removed link moving into a kept one.
+type LumpedMass struct {
+	From string  `json:"from"`
+	Into string  `json:"into"`
+	Mass float64 `json:"mass"`
+}
+
+// InertiaReport describes a recomputed inertial.
+type InertiaReport struct {
+	Link string  `json:"link"`
+	Mass float64 `json:"mass"`
+	// Source is what the tensor was computed from: "box" or "mesh".
+	Source string `json:"source"`
+	// Center is the new inertial origin in the link frame.
+	Center [3]float64 `json:"center"`
+	// Inertia holds ixx, ixy, ixz, iyy, iyz and izz.
+	Inertia [6]float64 `json:"inertia"`
+}
+
+// MeshReport describes a single collision mesh that was replaced with a box
+// or a capsule.
+type MeshReport struct {
+	Link string `json:"link"`
+	Mesh string `json:"mesh"`
+	// Name is that of the collision the mesh belonged to; see
+	// Collision.Name.
+	Name string `json:"name,omitempty"`
+	// Size and Center give the mesh's bounding box, which replaced it unless
+	// Capsule is set.
+	Size   [3]float64 `json:"size"`
+	Center [3]float64 `json:"center"`
+	// Capsule is the capsule that replaced the mesh, if one did.
+	Capsule *CapsuleReport `json:"capsule,omitempty"`
+	// Volume is the volume the mesh encloses, or zero if it is not closed
+	// and so encloses none.
+	Volume float64 `json:"volume,omitempty"`
+}
+
+// CapsuleReport describes a capsule fit to a collision mesh, in the mesh's
+// frame: the segment of Length through Center along Axis, "x", "y" or "z",
+// and the Radius around it.
+type CapsuleReport struct {
+	Center [3]float64 `json:"center"`
+	Axis   string     `json:"axis"`
+	Radius float64    `json:"radius"`
+	Length float64    `json:"length"`
+}
+
+// BoxVolume returns the volume of the mesh's bounding box.
+func (m MeshReport) BoxVolume() float64 {
+	return m.Size[0] * m.Size[1] * m.Size[2]
+}
+
+// PrimitiveVolume returns the volume of the capsule or box that replaced
+// the mesh.
+func (m MeshReport) PrimitiveVolume() float64 {
+	if c := m.Capsule; c != nil {
+		return mesh.Capsule{Radius: c.Radius, Length: c.Length}.Volume()
+	}
+	return m.BoxVolume()
+}
+
+// VolumeReport compares the volume of the collision meshes replaced with
+// boxes or capsules to that of the primitives, which is what
+// over-approximating them costs.
+// Meshes that are not closed have no volume and are left out.
+type VolumeReport struct {
+	// Mesh and Box are the total volumes of the meshes and of the boxes and
+	// capsules replacing them.
+	Mesh float64 `json:"mesh"`
+	Box  float64 `json:"box"`
+	// Open counts the replaced meshes left out because they are not closed.
+	Open int `json:"open,omitempty"`
+	// Links holds the totals of each link, in the order of Report.Meshes.
+	Links []LinkVolume `json:"links"`
+}
+
+// LinkVolume is the volume of one link's replaced collision meshes and of
+// their boxes and capsules. Primitives that overlap each other are counted
+// in full.
+type LinkVolume struct {
+	Link string  `json:"link"`
+	Mesh float64 `json:"mesh"`
+	Box  float64 `json:"box"`
+}
+
+// collisionVolume totals the volumes of meshes, or returns nil if there are
+// none.
+func collisionVolume(meshes []MeshReport) *VolumeReport {
+	if len(meshes) == 0 {
+		return nil
+	}
+	v := &VolumeReport{}
+	index := make(map[string]int)
+	for _, m := range meshes {
+		if m.Volume == 0 {
+			v.Open++
+			continue
+		}
+		i, ok := index[m.Link]
+		if !ok {
+			i = len(v.Links)
+			index[m.Link] = i
+			v.Links = append(v.Links, LinkVolume{Link: m.Link})
+		}
+		v.Links[i].Mesh += m.Volume
+		v.Links[i].Box += m.PrimitiveVolume()
+		v.Mesh += m.Volume
+		v.Box += m.PrimitiveVolume()
+	}
+	return v
+}
+
+// FailedMesh describes a collision mesh that could not be replaced because it
+// could not be read.
+type FailedMesh struct {
+	Link  string `json:"link"`
+	Mesh  string `json:"mesh"`
+	Error string `json:"error"`
+}
@@ -0,0 +1,235 @@
+package urdf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// MeshResolver opens the mesh referenced by a URDF filename attribute.
+type MeshResolver interface {
+	Open(uri string) (io.ReadCloser, error)
+}
+
+// FileResolver resolves mesh URIs against the local filesystem, relative to
+// BaseDir (usually the directory containing the URDF).
+type FileResolver struct {
+	BaseDir string
+
+	// Packages maps ROS package names to their root directories. A mapped
+	// package is resolved only there, never by searching.
+	Packages map[string]string
+	// AmentPrefixPath lists ROS 2 install prefixes; package://pkg/... is
+	// looked up as <prefix>/share/pkg/... in each.
+	AmentPrefixPath []string
+	// PackagePath lists ROS 1 package roots, as in ROS_PACKAGE_PATH. Each is
+	// searched for a package.xml naming the package.
+	PackagePath []string
+	// ModelPath lists Gazebo model directories, as in GZ_SIM_RESOURCE_PATH;
+	// model://model/... is looked up as <dir>/model/... in each.
+	ModelPath []string
+
+	// index lists the files under BaseDir for meshes that must be searched
+	// for. A resolver without one walks BaseDir for each such mesh.
+	index *fileIndex
+}
+
+// NewFileResolver returns a resolver for URDFs in baseDir that also finds
+// installed ROS packages through the AMENT_PREFIX_PATH and ROS_PACKAGE_PATH
+// environment variables, and Gazebo models through GZ_SIM_RESOURCE_PATH and
+// GAZEBO_MODEL_PATH. Meshes that have to be searched for under baseDir are
+// found in a list of its files made the first time one is needed, so files
+// added after that are not found by searching.
+func NewFileResolver(baseDir string) FileResolver {
+	return FileResolver{
+		BaseDir:         baseDir,
+		index:           newFileIndex(baseDir),
+		AmentPrefixPath: splitPathList(os.Getenv("AMENT_PREFIX_PATH")),
+		PackagePath:     splitPathList(os.Getenv("ROS_PACKAGE_PATH")),
+		ModelPath: append(splitPathList(os.Getenv("GZ_SIM_RESOURCE_PATH")),
+			splitPathList(os.Getenv("GAZEBO_MODEL_PATH"))...),
+	}
+}
+
+func splitPathList(list string) []string {
+	var paths []string
+	for _, p := range filepath.SplitList(list) {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// Open resolves uri to a file path and opens it.
+func (r FileResolver) Open(uri string) (io.ReadCloser, error) {
+	p := r.Resolve(uri)
+	f, err := os.Open(p)
+	if err != nil {
+		if other, ok := unreadableVariant(p); ok && errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%s does not exist; only an unreadable %s does (meshes must be STL)", p, filepath.Base(other))
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// Resolve returns the file path uri refers to. A package:// URI is looked up
+// in Packages if its package is mapped there, and otherwise in the installed
+// ROS packages; if the package or file isn't found
+// there, the package name is stripped and the rest is looked up under BaseDir.
+// A model:// URI is looked up in ModelPath and then under BaseDir the same
+// way. A file that is missing as named is matched ignoring case, then by
+// the STL extension.
+func (r FileResolver) Resolve(uri string) string {
+	if model, rest, ok := splitModelURI(uri); ok {
+		for _, dir := range r.ModelPath {
+			if p, ok := meshVariant(filepath.Join(dir, model, filepath.FromSlash(rest))); ok {
+				return p
+			}
+		}
+		uri = "package://" + model + "/" + rest
+	}
+	if pkg, rest, ok := splitPackageURI(uri); ok {
+		if dir, ok := r.Packages[pkg]; ok {
+			return variantOr(filepath.Join(dir, filepath.FromSlash(rest)))
+		}
+		if dir, ok := r.findPackage(pkg); ok {
+			if p, ok := meshVariant(filepath.Join(dir, filepath.FromSlash(rest))); ok {
+				return p
+			}
+		}
+	}
+	return variantOr(resolvePackageURI(uri, r.BaseDir, r.index))
+}
+
+// variantOr returns the meshVariant of path, or path itself if there is none.
+func variantOr(path string) string {
+	if p, ok := meshVariant(path); ok {
+		return p
+	}
+	return path
+}
+
+// FSResolver resolves mesh URIs against a virtual filesystem such as an
+// fstest.MapFS or an uploaded zip archive. package:// URIs have their package
+// name stripped and are looked up relative to the root of FS, as are model://
+// URIs with their model name stripped.
+type FSResolver struct {
+	FS fs.FS
+}
+
+// Open resolves uri within the filesystem and opens it.
+func (r FSResolver) Open(uri string) (io.ReadCloser, error) {
+	name := strings.TrimPrefix(uri, "/")
+	for _, scheme := range []string{"package://", "model://"} {
+		if strings.HasPrefix(uri, scheme) {
+			name = strings.TrimPrefix(uri, scheme)
+			if parts := strings.SplitN(name, "/", 2); len(parts) == 2 {
+				name = parts[1]
+			}
+		}
+	}
+	name = path.Clean(name)
+
+	if f, err := r.FS.Open(name); err == nil {
+		return f, nil
+	}
+
+	// Fall back to the first file whose path ends with the requested one, the
+	// same way the file-based resolver searches under its base directory.
+	var foundPath string
+	fs.WalkDir(r.FS, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !d.IsDir() && (p == name || strings.HasSuffix(p, "/"+name)) {
+			foundPath = p
+			return fs.SkipAll
+		}
+		return nil
+	})
+	if foundPath == "" {
+		return nil, &fs.PathError{Op: "open", Path: uri, Err: fs.ErrNotExist}
+	}
+	return r.FS.Open(foundPath)
+}
+
+// resolvePackageURI resolves mesh file paths, handling both package:// URIs and regular paths
+// Supports:
+//   - package://ur_description/meshes/ur20/collision/shoulder.stl
+//   - file:///absolute/path/to/shoulder%20link.stl (percent-encoded)
+//   - meshes/shoulder.stl (relative path)
+//   - /absolute/path/to/shoulder.stl
+func resolvePackageURI(uri string, baseDir string, index *fileIndex) string {
+	// Handle file:// URIs by decoding them to a plain path
+	if strings.HasPrefix(uri, "file://") {
+		uri = fileURIPath(uri)
+	}
+
+	// Handle package:// URIs
+	if strings.HasPrefix(uri, "package://") {
+		// Remove "package://" prefix
+		relativePath := strings.TrimPrefix(uri, "package://")
+
+		// Strip the package name (first component) from the path
+		// e.g., "ur_description/meshes/ur20/collision/base.stl" -> "meshes/ur20/collision/base.stl"
+		parts := strings.SplitN(relativePath, "/", 2)
+		if len(parts) == 2 {
+			relativePath = parts[1]
+		}
+
+		standardPath := filepath.Join(baseDir, relativePath)
+
+		// Check if standard path exists
+		if _, err := os.Stat(standardPath); err == nil {
+			return standardPath
+		}
+
+		// If not found, search for a file matching the relative path suffix
+		var foundPath string
+		if index != nil {
+			foundPath = index.find(relativePath)
+		} else {
+			foundPath = searchTree(baseDir, func(path string) bool {
+				return strings.HasSuffix(path, relativePath)
+			})
+		}
+
+		if foundPath != "" {
+			return foundPath
+		}
+
+		// Return standard path even if it doesn't exist (will fail later with clear error)
+		return standardPath
+	}
+
+	// Handle absolute paths - use as-is
+	if filepath.IsAbs(uri) {
+		return uri
+	}
+
+	// Handle relative paths - resolve relative to baseDir
+	return filepath.Join(baseDir, uri)
+}
+
+// fileURIPath returns the file path a file:// URI names. file:///a/b and
+// file://localhost/a/b are absolute; a non-standard file://a/b is taken as
+// the relative path a/b. A URI that does not parse is returned without its
+// scheme.
+func fileURIPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return strings.TrimPrefix(uri, "file://")
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return filepath.FromSlash(u.Host + u.Path)
+	}
+	return filepath.FromSlash(u.Path)
+}
@@ -0,0 +1,258 @@
+package urdf
+
+import "fmt"
+
+// FindLink returns the link with the given name, or nil if there is none.
+func (r *Robot) FindLink(name string) *Link {
+	for i := range r.Links {
+		if r.Links[i].Name == name {
+			return &r.Links[i]
+		}
+	}
+	return nil
+}
+
+// FindJoint returns the joint with the given name, or nil if there is none.
+func (r *Robot) FindJoint(name string) *Joint {
+	for i := range r.Joints {
+		if r.Joints[i].Name == name {
+			return &r.Joints[i]
+		}
+	}
+	return nil
+}
+
+// ParentJoint returns the joint whose child is the named link, or nil if the
+// link is a root.
+func (r *Robot) ParentJoint(link string) *Joint {
+	for i := range r.Joints {
+		if r.Joints[i].Child != nil && r.Joints[i].Child.Link == link {
+			return &r.Joints[i]
+		}
+	}
+	return nil
+}
+
+// ChildJoints returns the joints whose parent is the named link, in document order.
+func (r *Robot) ChildJoints(link string) []*Joint {
+	var joints []*Joint
+	for i := range r.Joints {
+		if r.Joints[i].Parent != nil && r.Joints[i].Parent.Link == link {
+			joints = append(joints, &r.Joints[i])
+		}
+	}
+	return joints
+}
+
+// AddLink appends link to the robot. Link names must be unique.
+func (r *Robot) AddLink(link Link) error {
+	if link.Name == "" {
+		return fmt.Errorf("link has no name")
+	}
+	if r.FindLink(link.Name) != nil {
+		return fmt.Errorf("link %q already exists", link.Name)
+	}
+	r.Links = append(r.Links, link)
+	return nil
+}
+
+// AddJoint appends joint to the robot. The joint name must be unique, both its
+// parent and child links must already exist, the child must not already have
+// a parent joint, and the joint must not introduce a cycle.
+func (r *Robot) AddJoint(joint Joint) error {
+	if joint.Name == "" {
+		return fmt.Errorf("joint has no name")
+	}
+	if r.FindJoint(joint.Name) != nil {
+		return fmt.Errorf("joint %q already exists", joint.Name)
+	}
+	if joint.Parent == nil || joint.Child == nil {
+		return fmt.Errorf("joint %q must have both a parent and a child link", joint.Name)
+	}
+	if r.FindLink(joint.Parent.Link) == nil {
+		return fmt.Errorf("joint %q: parent link %q does not exist", joint.Name, joint.Parent.Link)
+	}
+	if r.FindLink(joint.Child.Link) == nil {
+		return fmt.Errorf("joint %q: child link %q does not exist", joint.Name, joint.Child.Link)
+	}
+	if existing := r.ParentJoint(joint.Child.Link); existing != nil {
+		return fmt.Errorf("joint %q: link %q already has parent joint %q", joint.Name, joint.Child.Link, existing.Name)
+	}
+	if r.isAncestor(joint.Child.Link, joint.Parent.Link) {
+		return fmt.Errorf("joint %q would create a cycle through link %q", joint.Name, joint.Child.Link)
+	}
+	r.Joints = append(r.Joints, joint)
+	return nil
+}
+
+// RemoveLink removes the named link together with the joint attaching it to
+// its parent. Links that still have children cannot be removed; remove or
+// reparent the children first. Nor can a link whose parent joint another
+// joint mimics.
+func (r *Robot) RemoveLink(name string) error {
+	if r.FindLink(name) == nil {
+		return fmt.Errorf("link %q does not exist", name)
+	}
+	if children := r.ChildJoints(name); len(children) > 0 {
+		return fmt.Errorf("link %q still has %d child joint(s), starting with %q", name, len(children), children[0].Name)
+	}
+	if parent := r.ParentJoint(name); parent != nil {
+		if mimic := r.mimicOf(parent.Name); mimic != nil {
+			return fmt.Errorf("link %q: its parent joint %q is still mimicked by joint %q", name, parent.Name, mimic.Name)
+		}
+		r.removeJointAt(r.jointIndex(parent.Name))
+	}
+	for i := range r.Links {
+		if r.Links[i].Name == name {
+			r.Links = append(r.Links[:i], r.Links[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// RemoveJoint removes the named joint. Its child link stays in the model as a
+// new root until it is reparented or removed. A joint that another joint
+// mimics cannot be removed; remove the mimic or the other joint first.
+func (r *Robot) RemoveJoint(name string) error {
+	i := r.jointIndex(name)
+	if i < 0 {
+		return fmt.Errorf("joint %q does not exist", name)
+	}
+	if mimic := r.mimicOf(name); mimic != nil {
+		return fmt.Errorf("joint %q is still mimicked by joint %q", name, mimic.Name)
+	}
+	r.removeJointAt(i)
+	return nil
+}
+
+// ReparentLink moves the named link (and the subtree below it) under
+// newParent by rewriting the parent of the joint that attaches it. The joint
+// origin is left unchanged, so it is now interpreted relative to newParent.
+func (r *Robot) ReparentLink(name, newParent string) error {
+	if r.FindLink(name) == nil {
+		return fmt.Errorf("link %q does not exist", name)
+	}
+	if r.FindLink(newParent) == nil {
+		return fmt.Errorf("link %q does not exist", newParent)
+	}
+	joint := r.ParentJoint(name)
+	if joint == nil {
+		return fmt.Errorf("link %q has no parent joint to reparent", name)
+	}
+	if name == newParent || r.isAncestor(name, newParent) {
+		return fmt.Errorf("cannot reparent link %q under its own descendant %q", name, newParent)
+	}
+	joint.Parent = &Parent{Link: newParent}
+	return nil
+}
+
+// RenameLink renames a link and updates every joint that references it. The
+// joints are given new parent and child elements rather than having theirs
+// changed, as copies of the robot may share them.
+func (r *Robot) RenameLink(oldName, newName string) error {
+	link := r.FindLink(oldName)
+	if link == nil {
+		return fmt.Errorf("link %q does not exist", oldName)
+	}
+	if oldName == newName {
+		return nil
+	}
+	if r.FindLink(newName) != nil {
+		return fmt.Errorf("link %q already exists", newName)
+	}
+	link.Name = newName
+	for i := range r.Joints {
+		if r.Joints[i].Parent != nil && r.Joints[i].Parent.Link == oldName {
+			r.Joints[i].Parent = &Parent{Link: newName}
+		}
+		if r.Joints[i].Child != nil && r.Joints[i].Child.Link == oldName {
+			r.Joints[i].Child = &Child{Link: newName}
+		}
+	}
+	return nil
+}
+
+// RenameJoint renames a joint and updates the joints that mimic it, giving
+// them new mimic elements as RenameLink does parent and child ones.
+func (r *Robot) RenameJoint(oldName, newName string) error {
+	joint := r.FindJoint(oldName)
+	if joint == nil {
+		return fmt.Errorf("joint %q does not exist", oldName)
+	}
+	if oldName == newName {
+		return nil
+	}
+	if r.FindJoint(newName) != nil {
+		return fmt.Errorf("joint %q already exists", newName)
+	}
+	joint.Name = newName
+	for i := range r.Joints {
+		if m := r.Joints[i].Mimic; m != nil && m.Joint == oldName {
+			mimic := *m
+			mimic.Joint = newName
+			r.Joints[i].Mimic = &mimic
+		}
+	}
+	return nil
+}
+
+// mimicOf returns the first joint that mimics the named one, or nil if none does.
+func (r *Robot) mimicOf(name string) *Joint {
+	for i := range r.Joints {
+		if m := r.Joints[i].Mimic; m != nil && m.Joint == name && r.Joints[i].Name != name {
+			return &r.Joints[i]
+		}
+	}
+	return nil
+}
+
+// isAncestor reports whether ancestor appears on the path from link up to its root.
+func (r *Robot) isAncestor(ancestor, link string) bool {
+	seen := make(map[string]bool)
+	for current := link; !seen[current]; {
+		seen[current] = true
+		joint := r.ParentJoint(current)
+		if joint == nil || joint.Parent == nil {
+			return false
+		}
+		current = joint.Parent.Link
+		if current == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Robot) jointIndex(name string) int {
+	for i := range r.Joints {
+		if r.Joints[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *Robot) removeJointAt(i int) {
+	r.Joints = append(r.Joints[:i], r.Joints[i+1:]...)
+}
+
+// MeshRefs returns every mesh element referenced by the robot's visual and
+// collision geometry, in document order.
+func (r *Robot) MeshRefs() []*Mesh {
+	var meshes []*Mesh
+	for i := range r.Links {
+		link := &r.Links[i]
+		for j := range link.Visual {
+			if g := link.Visual[j].Geometry; g != nil && g.Mesh != nil {
+				meshes = append(meshes, g.Mesh)
+			}
+		}
+		for j := range link.Collision {
+			if g := link.Collision[j].Geometry; g != nil && g.Mesh != nil {
+				meshes = append(meshes, g.Mesh)
+			}
+		}
+	}
+	return meshes
+}
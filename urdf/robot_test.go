@@ -0,0 +1,113 @@
+package urdf
+
+import (
+	"strings"
+	"testing"
+)
+
+// mimicRobot returns a base with two fingers, the second mimicking the first.
+func mimicRobot(t *testing.T) *Robot {
+	t.Helper()
+	robot, err := ParseURDF(strings.NewReader(`<robot name="r">
+  <link name="base"/><link name="left"/><link name="right"/>
+  <joint name="j_left" type="prismatic"><parent link="base"/><child link="left"/><axis xyz="1 0 0"/>
+    <limit lower="0" upper="0.04" effort="10" velocity="0.1"/></joint>
+  <joint name="j_right" type="prismatic"><parent link="base"/><child link="right"/><axis xyz="-1 0 0"/>
+    <limit lower="0" upper="0.04" effort="10" velocity="0.1"/><mimic joint="j_left" multiplier="1"/></joint>
+</robot>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return robot
+}
+
+func TestRenameJointUpdatesMimics(t *testing.T) {
+	robot := mimicRobot(t)
+	shared := robot.Joints[1].Mimic
+	if err := robot.RenameJoint("j_left", "finger"); err != nil {
+		t.Fatal(err)
+	}
+	if got := robot.FindJoint("j_right").Mimic.Joint; got != "finger" {
+		t.Errorf("j_right mimics %q, want finger", got)
+	}
+	if shared.Joint != "j_left" {
+		t.Errorf("the old mimic element was changed to %q", shared.Joint)
+	}
+}
+
+func TestRenameLinkLeavesSharedElements(t *testing.T) {
+	robot := mimicRobot(t)
+	parent, child := robot.Joints[0].Parent, robot.Joints[0].Child
+	if err := robot.RenameLink("base", "body"); err != nil {
+		t.Fatal(err)
+	}
+	if err := robot.RenameLink("left", "finger"); err != nil {
+		t.Fatal(err)
+	}
+	if j := robot.Joints[0]; j.Parent.Link != "body" || j.Child.Link != "finger" {
+		t.Errorf("j_left joins %s to %s, want body to finger", j.Parent.Link, j.Child.Link)
+	}
+	if parent.Link != "base" || child.Link != "left" {
+		t.Errorf("the old parent and child elements were changed to %s and %s", parent.Link, child.Link)
+	}
+}
+
+func TestRemoveMimickedJoint(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		remove func(*Robot) error
+		want   string
+	}{
+		{"joint", func(r *Robot) error { return r.RemoveJoint("j_left") }, `joint "j_left" is still mimicked by joint "j_right"`},
+		{"link", func(r *Robot) error { return r.RemoveLink("left") }, `parent joint "j_left" is still mimicked by joint "j_right"`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			robot := mimicRobot(t)
+			err := tt.remove(robot)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("error %v, want %q", err, tt.want)
+			}
+			if len(robot.Joints) != 2 || len(robot.Links) != 3 {
+				t.Errorf("%d joints and %d links left, want the robot unchanged", len(robot.Joints), len(robot.Links))
+			}
+		})
+	}
+
+	robot := mimicRobot(t)
+	if err := robot.RemoveJoint("j_right"); err != nil {
+		t.Fatalf("removing the mimic: %v", err)
+	}
+	if err := robot.RemoveJoint("j_left"); err != nil {
+		t.Errorf("removing the joint once nothing mimics it: %v", err)
+	}
+}
+
+func TestRenameAll(t *testing.T) {
+	robot := mimicRobot(t)
+	robot.Links[1].Collision = []Collision{{Name: "left:box0"}}
+	names := &NameMap{
+		Links:  map[string]string{"left": "right", "right": "left"},
+		Joints: map[string]string{"j_left": "joint_1", "j_right": "joint_2"},
+	}
+	if err := renameAll(robot, names); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, j := range robot.Joints {
+		got = append(got, j.Name+":"+j.Parent.Link+">"+j.Child.Link)
+	}
+	if want := "joint_1:base>right joint_2:base>left"; strings.Join(got, " ") != want {
+		t.Errorf("joints %s, want %s", strings.Join(got, " "), want)
+	}
+	if m := robot.Joints[1].Mimic.Joint; m != "joint_1" {
+		t.Errorf("joint_2 mimics %q, want joint_1", m)
+	}
+	if l := robot.Links[1]; l.Name != "right" || l.Collision[0].Name != "right:box0" {
+		t.Errorf("link %s has collision %s, want right with right:box0", l.Name, l.Collision[0].Name)
+	}
+
+	taken := &NameMap{Links: map[string]string{"left": "base"}}
+	if err := renameAll(mimicRobot(t), taken); err == nil {
+		t.Error("renaming onto a kept link's name succeeded")
+	}
+}
@@ -0,0 +1,92 @@
+package urdf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// maxRoundTripDiffs is how many differences a RoundTripError lists.
+const maxRoundTripDiffs = 10
+
+// RoundTripError lists the fields of a robot that were lost or changed by
+// writing it out and parsing it back.
+type RoundTripError struct {
+	// Diffs describe the first differences found, by field path, such as
+	// Links[2].Collision[0].Origin.XYZ.
+	Diffs []string
+	// More counts the differences left out of Diffs.
+	More int
+}
+
+func (e *RoundTripError) Error() string {
+	msg := fmt.Sprintf("output does not parse back to the simplified robot:\n  %s", strings.Join(e.Diffs, "\n  "))
+	if e.More > 0 {
+		msg += fmt.Sprintf("\n  and %d more", e.More)
+	}
+	return msg
+}
+
+// CheckRoundTrip parses data, robot as written by WriteURDF, and returns a
+// *RoundTripError if the result differs from robot in any field. Element
+// names are ignored, and an empty list equals a missing one, since neither
+// survives writing.
+func CheckRoundTrip(robot *Robot, data []byte) error {
+	parsed, err := ParseURDF(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("output does not parse: %w", err)
+	}
+	e := &RoundTripError{}
+	diffValues("robot", reflect.ValueOf(robot).Elem(), reflect.ValueOf(parsed).Elem(), func(d string) {
+		if len(e.Diffs) < maxRoundTripDiffs {
+			e.Diffs = append(e.Diffs, d)
+		} else {
+			e.More++
+		}
+	})
+	if len(e.Diffs) > 0 {
+		return e
+	}
+	return nil
+}
+
+var xmlNameType = reflect.TypeFor[xml.Name]()
+
+// diffValues reports each field in which want and got, values of the same
+// model type, differ.
+func diffValues(path string, want, got reflect.Value, add func(string)) {
+	switch want.Kind() {
+	case reflect.Pointer:
+		switch {
+		case want.IsNil() && got.IsNil():
+		case want.IsNil():
+			add(path + ": added")
+		case got.IsNil():
+			add(path + ": lost")
+		default:
+			diffValues(path, want.Elem(), got.Elem(), add)
+		}
+	case reflect.Struct:
+		for i := range want.NumField() {
+			f := want.Type().Field(i)
+			if f.Type == xmlNameType || !f.IsExported() {
+				continue
+			}
+			diffValues(path+"."+f.Name, want.Field(i), got.Field(i), add)
+		}
+	case reflect.Slice:
+		if want.Len() != got.Len() {
+			add(fmt.Sprintf("%s: %d elements became %d", path, want.Len(), got.Len()))
+			return
+		}
+		for i := range want.Len() {
+			diffValues(fmt.Sprintf("%s[%d]", path, i), want.Index(i), got.Index(i), add)
+		}
+	default:
+		if !reflect.DeepEqual(want.Interface(), got.Interface()) {
+			add(fmt.Sprintf("%s: %#v became %#v", path, want.Interface(), got.Interface()))
+		}
+	}
+}
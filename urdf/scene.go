@@ -0,0 +1,319 @@
+package urdf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+
+	"github.com/nfranczak/urdf-simplifier/spatialmath"
+)
+
+// sceneGoalTries is how many random configurations NewScene tries before
+// giving up on finding a collision-free goal.
+const sceneGoalTries = 1000
+
+// SceneObstacle is a box fixed in the frame of the robot's root link, with
+// lengths in meters and angles in radians.
+type SceneObstacle struct {
+	Name string     `yaml:"name" json:"name"`
+	Size [3]float64 `yaml:"size" json:"size"`
+	XYZ  [3]float64 `yaml:"xyz,omitempty" json:"xyz"`
+	RPY  [3]float64 `yaml:"rpy,omitempty" json:"rpy"`
+}
+
+func (o *SceneObstacle) box() orientedBox {
+	pose := spatialmath.NewPose(
+		spatialmath.Vec3{X: o.XYZ[0], Y: o.XYZ[1], Z: o.XYZ[2]},
+		spatialmath.RPY{Roll: o.RPY[0], Pitch: o.RPY[1], Yaw: o.RPY[2]})
+	box := newOrientedBox(pose, spatialmath.Vec3{X: o.Size[0], Y: o.Size[1], Z: o.Size[2]})
+	box.name = o.Name
+	return box
+}
+
+// SceneSpec is what a planning scene is built from, as read from a scene
+// file:
+//
+//	obstacles:
+//	  - name: table
+//	    size: [1.2, 0.8, 0.05]
+//	    xyz: [0.5, 0, -0.025]
+//	start:
+//	  shoulder_lift_joint: -1.57
+//	goal:
+//	  shoulder_pan_joint: 1.0
+//	seed: 7
+type SceneSpec struct {
+	Obstacles []SceneObstacle `yaml:"obstacles,omitempty"`
+	// Start and Goal set joint positions in radians or meters. Joints left
+	// out of either are at zero, or the limit nearest it. If Goal is left
+	// out entirely, a configuration within the limits is picked at random,
+	// seeded by Seed, with no collisions between links, or with obstacles,
+	// that aren't already there at the start.
+	Start map[string]float64 `yaml:"start,omitempty"`
+	Goal  map[string]float64 `yaml:"goal,omitempty"`
+	Seed  uint64             `yaml:"seed,omitempty"`
+}
+
+// Scene is a motion-planning problem: a robot, the obstacles around it, and
+// the joint positions to plan between.
+type Scene struct {
+	Robot string `json:"robot"`
+	// URDF is the robot's URDF document. Frame is its root link, in whose
+	// frame the obstacles are placed.
+	URDF      string          `json:"urdf"`
+	Frame     string          `json:"frame"`
+	Obstacles []SceneObstacle `json:"obstacles"`
+	// Start and Goal give the position of every moving joint that doesn't
+	// mimic another.
+	Start map[string]float64 `json:"start"`
+	Goal  map[string]float64 `json:"goal"`
+}
+
+// NewScene places the obstacles of spec around robot and resolves its start
+// and goal configurations. Joints that are unknown, fixed, or mimic another
+// joint, and positions outside a joint's limits, are errors. A start or goal
+// configuration in collision is returned as a warning, since a scene may be
+// meant to test that; collision geometry other than boxes is not checked.
+func NewScene(robot *Robot, spec SceneSpec) (*Scene, []string, error) {
+	s := &Scene{Robot: robot.Name, Frame: NewKinematicTree(robot).Root(), Obstacles: spec.Obstacles}
+	if s.Frame == "" {
+		return nil, nil, fmt.Errorf("robot has no links")
+	}
+	if s.Obstacles == nil {
+		s.Obstacles = []SceneObstacle{}
+	}
+	seen := make(map[string]bool)
+	for _, o := range s.Obstacles {
+		if o.Name == "" {
+			return nil, nil, fmt.Errorf("an obstacle has no name")
+		}
+		if seen[o.Name] {
+			return nil, nil, fmt.Errorf("obstacle %q appears more than once", o.Name)
+		}
+		seen[o.Name] = true
+		for _, v := range o.Size {
+			if !(v > 0) || math.IsInf(v, 0) {
+				return nil, nil, fmt.Errorf("obstacle %q: invalid size %v (want three positive lengths)", o.Name, o.Size)
+			}
+		}
+	}
+
+	var active []*Joint
+	for i := range robot.Joints {
+		j := &robot.Joints[i]
+		if moves(j) && j.Mimic == nil {
+			active = append(active, j)
+		}
+	}
+
+	var err error
+	if s.Start, err = scenePositions(robot, active, spec.Start, "start"); err != nil {
+		return nil, nil, err
+	}
+	checker := newSceneChecker(robot, s.Frame, s.Obstacles)
+	var warnings []string
+	// Overlaps already present at the start, such as a base sunk into the
+	// table it stands on, are ones a planner has to be told to ignore, so a
+	// random goal may have them too.
+	allowed := make(map[collisionKey]bool)
+	for _, c := range checker.collisions(s.Start) {
+		warnings = append(warnings, "start: "+c.String())
+		allowed[c.collisionKey] = true
+	}
+
+	if spec.Goal != nil {
+		if s.Goal, err = scenePositions(robot, active, spec.Goal, "goal"); err != nil {
+			return nil, nil, err
+		}
+		for _, c := range checker.collisions(s.Goal) {
+			warnings = append(warnings, "goal: "+c.String())
+		}
+	} else if s.Goal = sampleGoal(active, checker, allowed, spec.Seed); s.Goal == nil {
+		return nil, nil, fmt.Errorf("no collision-free goal found in %d random configurations", sceneGoalTries)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteURDF(&buf, robot); err != nil {
+		return nil, nil, err
+	}
+	s.URDF = buf.String()
+	return s, warnings, nil
+}
+
+// moves reports whether the joint has a position to plan for.
+func moves(j *Joint) bool {
+	return j.Type == "revolute" || j.Type == "continuous" || j.Type == "prismatic"
+}
+
+// scenePositions checks the positions given for a scene's start or goal,
+// named what in errors, and fills in the active joints not given.
+func scenePositions(robot *Robot, active []*Joint, given map[string]float64, what string) (map[string]float64, error) {
+	for _, name := range slices.Sorted(maps.Keys(given)) {
+		q := given[name]
+		j := robot.FindJoint(name)
+		switch {
+		case j == nil:
+			return nil, fmt.Errorf("%s: no joint %q", what, name)
+		case !moves(j):
+			return nil, fmt.Errorf("%s: joint %q is %s and has no position", what, name, j.Type)
+		case j.Mimic != nil:
+			return nil, fmt.Errorf("%s: joint %q mimics %s; give the position of %s instead", what, name, j.Mimic.Joint, j.Mimic.Joint)
+		case math.IsNaN(q) || math.IsInf(q, 0):
+			return nil, fmt.Errorf("%s: joint %q: invalid position %v", what, name, q)
+		}
+		if lower, upper, ok := sweepRange(j); ok && j.Type != "continuous" && (q < lower || q > upper) {
+			return nil, fmt.Errorf("%s: joint %q: position %g is outside its limits [%g, %g]", what, name, q, lower, upper)
+		}
+	}
+
+	positions := make(map[string]float64, len(active))
+	for _, j := range active {
+		q, ok := given[j.Name]
+		if !ok {
+			if lower, upper, ok := sweepRange(j); ok && j.Type != "continuous" {
+				q = min(max(0, lower), upper)
+			}
+		}
+		positions[j.Name] = q
+	}
+	return positions, nil
+}
+
+// sampleGoal returns a random configuration of the active joints within
+// their limits in which checker finds no collisions other than those in
+// allowed, or nil if none of sceneGoalTries is.
+func sampleGoal(active []*Joint, checker *sceneChecker, allowed map[collisionKey]bool, seed uint64) map[string]float64 {
+	rng := rand.New(rand.NewPCG(seed, 2))
+	for range sceneGoalTries {
+		positions := make(map[string]float64, len(active))
+		for _, j := range active {
+			if lower, upper, ok := sweepRange(j); ok {
+				positions[j.Name] = lower + rng.Float64()*(upper-lower)
+			} else {
+				positions[j.Name] = 0
+			}
+		}
+		free := true
+		for _, c := range checker.collisions(positions) {
+			if !allowed[c.collisionKey] {
+				free = false
+				break
+			}
+		}
+		if free {
+			return positions
+		}
+	}
+	return nil
+}
+
+// sceneChecker finds the collisions of a robot's links with each other and
+// with a scene's obstacles.
+type sceneChecker struct {
+	robot     *Robot
+	model     *collisionModel
+	frame     string
+	obstacles []SceneObstacle
+	boxes     []orientedBox
+}
+
+func newSceneChecker(robot *Robot, frame string, obstacles []SceneObstacle) *sceneChecker {
+	c := &sceneChecker{robot: robot, model: newCollisionModel(robot), frame: frame, obstacles: obstacles}
+	for i := range obstacles {
+		c.boxes = append(c.boxes, obstacles[i].box())
+	}
+	return c
+}
+
+// collisionKey names two links, or a link and an obstacle.
+type collisionKey struct {
+	link, other string
+	obstacle    bool
+}
+
+// sceneCollision is an overlap of two links' collision boxes, or of a
+// link's and an obstacle, naming the boxes overlapping the most.
+type sceneCollision struct {
+	collisionKey
+	depth float64
+	boxes [2]string
+}
+
+func (c sceneCollision) String() string {
+	if c.obstacle {
+		return fmt.Sprintf("collision box %s overlaps obstacle %s by %.1f mm", c.boxes[0], c.other, c.depth*1000)
+	}
+	return fmt.Sprintf("collision boxes %s and %s overlap by %.1f mm", c.boxes[0], c.boxes[1], c.depth*1000)
+}
+
+// collisions returns the overlaps of two links, or a link and an obstacle,
+// with the active joints at positions and mimic joints following them. Only
+// links below the scene's frame are checked against obstacles.
+func (c *sceneChecker) collisions(positions map[string]float64) []sceneCollision {
+	positions = withMimics(c.robot, positions)
+	var found []sceneCollision
+	for _, d := range c.model.depths(positions) {
+		if d.depth > minOverlap {
+			found = append(found, sceneCollision{collisionKey{link: c.robot.Links[d.a].Name, other: c.robot.Links[d.b].Name}, d.depth, d.boxes})
+		}
+	}
+	poses := c.model.tree.Poses(c.frame, positions)
+	for _, link := range c.robot.Links {
+		pose, ok := poses[link.Name]
+		if !ok {
+			continue
+		}
+		// One overlap per obstacle, with its deepest box.
+		boxes := linkBoxes(&link, pose)
+		for i, obstacle := range c.boxes {
+			hit := sceneCollision{collisionKey: collisionKey{link: link.Name, other: c.obstacles[i].Name, obstacle: true}}
+			for _, box := range boxes {
+				if depth := box.penetration(obstacle); depth > hit.depth {
+					hit.depth, hit.boxes = depth, [2]string{box.name, obstacle.name}
+				}
+			}
+			if hit.depth > minOverlap {
+				found = append(found, hit)
+			}
+		}
+	}
+	return found
+}
+
+// withMimics returns positions with the position of every mimic joint of
+// robot added, following the joint it mimics.
+func withMimics(robot *Robot, positions map[string]float64) map[string]float64 {
+	all := make(map[string]float64, len(positions))
+	for name, q := range positions {
+		all[name] = q
+	}
+	for _, j := range robot.Joints {
+		if j.Mimic == nil || !moves(&j) {
+			continue
+		}
+		multiplier, offset := 1.0, 0.0
+		if v, err := strconv.ParseFloat(j.Mimic.Multiplier, 64); err == nil {
+			multiplier = v
+		}
+		if v, err := strconv.ParseFloat(j.Mimic.Offset, 64); err == nil {
+			offset = v
+		}
+		all[j.Name] = multiplier*positions[j.Mimic.Joint] + offset
+	}
+	return all
+}
+
+// WriteScene encodes the scene to w as indented JSON, leaving the markup
+// of its URDF unescaped.
+func WriteScene(w io.Writer, scene *Scene) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(scene)
+}
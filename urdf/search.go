@@ -0,0 +1,191 @@
+package urdf
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// maxSearchDepth bounds how many directories deep searchTree descends, so a
+// search of a large install tree cannot wander indefinitely.
+const maxSearchDepth = 16
+
+// searchTree walks the files under root depth-first in lexical order, the
+// same order as filepath.Walk, and returns the first path for which match
+// returns true, or "" if there is none. Unlike filepath.Walk it follows
+// symlinks, as ROS install trees are often made of them, and visits each real
+// directory at most once so symlink cycles are harmless.
+func searchTree(root string, match func(path string) bool) string {
+	visited := make(map[string]bool)
+	var search func(dir string, depth int) string
+	search = func(dir string, depth int) string {
+		real, err := filepath.EvalSymlinks(dir)
+		if err != nil || visited[real] || depth > maxSearchDepth {
+			return ""
+		}
+		visited[real] = true
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return ""
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			isDir := entry.IsDir()
+			if entry.Type()&os.ModeSymlink != 0 {
+				info, err := os.Stat(path)
+				if err != nil {
+					continue
+				}
+				isDir = info.IsDir()
+			}
+			if isDir {
+				if found := search(path, depth+1); found != "" {
+					return found
+				}
+			} else if match(path) {
+				return path
+			}
+		}
+		return ""
+	}
+	return search(root, 0)
+}
+
+// fileIndex lists the files under a directory once, so that resolving many
+// meshes by path suffix costs one walk of the tree rather than one per mesh.
+// It is safe for concurrent use.
+type fileIndex struct {
+	root  string
+	once  sync.Once
+	files []string
+	// byName maps each file name to the indexes in files of the files with
+	// that name.
+	byName map[string][]int
+}
+
+func newFileIndex(root string) *fileIndex {
+	return &fileIndex{root: root}
+}
+
+// find returns the first file, in searchTree's order, whose path ends with
+// suffix, or "" if there is none. The tree is walked on the first call.
+func (x *fileIndex) find(suffix string) string {
+	x.once.Do(func() {
+		x.byName = make(map[string][]int)
+		searchTree(x.root, func(path string) bool {
+			x.byName[filepath.Base(path)] = append(x.byName[filepath.Base(path)], len(x.files))
+			x.files = append(x.files, path)
+			return false
+		})
+	})
+
+	// A suffix with a directory in it ends in a whole file name, which
+	// narrows the candidates; a bare name may also end a longer one.
+	candidates := x.files
+	if dir, name := path.Split(suffix); dir != "" {
+		candidates = nil
+		for _, i := range x.byName[name] {
+			candidates = append(candidates, x.files[i])
+		}
+	}
+	for _, p := range candidates {
+		if strings.HasSuffix(p, suffix) {
+			return p
+		}
+	}
+	return ""
+}
+
+// readableExtensions lists the mesh formats the mesh package can parse,
+// which meshVariant substitutes for a missing file, and otherExtensions
+// those it cannot, which unreadableVariant only reports.
+var (
+	readableExtensions = []string{".stl"}
+	otherExtensions    = []string{".dae", ".obj", ".ply"}
+)
+
+// meshVariant returns path if the file exists. Otherwise it looks for the
+// same file with different casing, in the name or in any missing directory
+// along the way, and then for a sibling with the same name but a readable
+// mesh extension, as vendor packages often ship base.STL or base.stl where
+// the URDF says base.dae.
+func meshVariant(path string) (string, bool) {
+	if fileExists(path) {
+		return path, true
+	}
+	name := filepath.Base(path)
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+	candidates := []string{name}
+	for _, ext := range readableExtensions {
+		candidates = append(candidates, stem+ext)
+	}
+	return findFolded(filepath.Dir(path), candidates)
+}
+
+// unreadableVariant returns the sibling of the missing file path with the
+// same name but a mesh extension the mesh package cannot parse, such as the
+// base.dae a vendor package ships where the URDF says base.stl, so that the
+// error can say so instead of the file being read as STL.
+func unreadableVariant(path string) (string, bool) {
+	name := filepath.Base(path)
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+	var candidates []string
+	for _, ext := range otherExtensions {
+		candidates = append(candidates, stem+ext)
+	}
+	return findFolded(filepath.Dir(path), candidates)
+}
+
+// findFolded returns the first of candidates, in order, that is a file in
+// dir when case is ignored in both, as foldDir and the names compare.
+func findFolded(dir string, candidates []string) (string, bool) {
+	dir, ok := foldDir(dir)
+	if !ok {
+		return "", false
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+	for _, candidate := range candidates {
+		for _, entry := range entries {
+			if strings.EqualFold(entry.Name(), candidate) {
+				if p := filepath.Join(dir, entry.Name()); fileExists(p) {
+					return p, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// foldDir returns dir if it exists, and otherwise the directory whose path
+// matches it when the case of its missing components is ignored.
+func foldDir(dir string) (string, bool) {
+	if isDir(dir) {
+		return dir, true
+	}
+	parent := filepath.Dir(dir)
+	if parent == dir {
+		return "", false
+	}
+	parent, ok := foldDir(parent)
+	if !ok {
+		return "", false
+	}
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		return "", false
+	}
+	for _, entry := range entries {
+		if strings.EqualFold(entry.Name(), filepath.Base(dir)) {
+			if p := filepath.Join(parent, entry.Name()); isDir(p) {
+				return p, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,89 @@
+package urdf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// touch creates each of the slash-separated files under dir, with their
+// directories.
+func touch(t *testing.T, dir string, files ...string) {
+	t.Helper()
+	for _, name := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMeshVariant(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		files []string
+		path  string
+		want  string // "" if meshVariant should fail
+	}{
+		{"exact", []string{"meshes/base.stl"}, "meshes/base.stl", "meshes/base.stl"},
+		{"exact preferred", []string{"meshes/base.dae", "meshes/base.stl"}, "meshes/base.dae", "meshes/base.dae"},
+		{"folded name", []string{"meshes/Base.STL"}, "meshes/base.stl", "meshes/Base.STL"},
+		{"folded directory", []string{"Meshes/Visual/base.stl"}, "meshes/visual/base.stl", "Meshes/Visual/base.stl"},
+		{"stl sibling", []string{"meshes/base.stl"}, "meshes/base.dae", "meshes/base.stl"},
+		{"folded stl sibling", []string{"Meshes/BASE.STL"}, "meshes/base.obj", "Meshes/BASE.STL"},
+		{"unreadable sibling", []string{"meshes/base.dae"}, "meshes/base.stl", ""},
+		{"missing", []string{"meshes/arm.stl"}, "meshes/base.stl", ""},
+		{"missing directory", []string{"meshes/base.stl"}, "other/base.stl", ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			touch(t, dir, tt.files...)
+			got, ok := meshVariant(filepath.Join(dir, filepath.FromSlash(tt.path)))
+			if tt.want == "" {
+				if ok {
+					t.Errorf("found %s, want none", got)
+				}
+				return
+			}
+			if want := filepath.Join(dir, filepath.FromSlash(tt.want)); !ok || got != want {
+				t.Errorf("got %q, %v, want %q", got, ok, want)
+			}
+		})
+	}
+}
+
+func TestFileResolver(t *testing.T) {
+	dir := t.TempDir()
+	touch(t, dir, "meshes/Base.STL", "meshes/visual/link1.stl", "other/robot_description/meshes/hand.dae")
+	r := NewFileResolver(dir)
+	for _, tt := range []struct {
+		uri  string
+		want string
+	}{
+		{"package://robot_description/meshes/base.dae", "meshes/Base.STL"},
+		{"meshes/base.stl", "meshes/Base.STL"},
+		{"package://robot_description/visual/link1.stl", "meshes/visual/link1.stl"},
+		{"file://" + filepath.ToSlash(filepath.Join(dir, "meshes", "visual", "link1.stl")), "meshes/visual/link1.stl"},
+	} {
+		if got, want := r.Resolve(tt.uri), filepath.Join(dir, filepath.FromSlash(tt.want)); got != want {
+			t.Errorf("Resolve(%q) = %q, want %q", tt.uri, got, want)
+		}
+	}
+}
+
+func TestFileResolverUnreadable(t *testing.T) {
+	dir := t.TempDir()
+	touch(t, dir, "meshes/base.dae")
+	_, err := NewFileResolver(dir).Open("package://robot_description/meshes/base.stl")
+	if err == nil || !strings.Contains(err.Error(), "only an unreadable base.dae") {
+		t.Errorf("error %v, want it to name base.dae", err)
+	}
+	_, err = NewFileResolver(dir).Open("meshes/arm.stl")
+	if err == nil || strings.Contains(err.Error(), "unreadable") {
+		t.Errorf("error %v, want a plain missing file", err)
+	}
+}
@@ -0,0 +1,1172 @@
+package urdf
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nfranczak/urdf-simplifier/mesh"
+	"github.com/nfranczak/urdf-simplifier/spatialmath"
+)
+
+// Simplify runs the full simplification pipeline on robot, opening collision
+// meshes through resolver, and returns a report of what was changed. The
+// result depends only on the input model and mesh contents, so identical inputs
+// always produce byte-identical output.
+func Simplify(robot *Robot, resolver MeshResolver, opts Options) *Report {
+	if opts.GeometryOnly {
+		opts = opts.geometryOnly(robot.Name)
+	}
+	report := &Report{Robot: robot.Name}
+	original := massModel(robot)
+	if opts.VisualFromCollision && opts.VisualColor != "" {
+		if err := validateRGBA(opts.VisualColor); err != nil {
+			opts.logger().Warn("using the default visual color", "error", err)
+			report.Warnings = append(report.Warnings, fmt.Sprintf("%v: using %q", err, DefaultVisualColor))
+		}
+	}
+	checkInputDetached(robot, opts, report)
+	bounds, duplicates := boundMeshes(robot, resolver, opts)
+	report.DuplicateMeshes = duplicates
+
+	processLinks(robot, bounds, opts, report)
+
+	// Filter to keep only the requested part of the kinematic tree
+	filterLinks(robot, opts, report)
+	checkFilteredDetached(robot, opts, report)
+	checkReferences(robot, opts, report)
+	if opts.VisualFromCollision && opts.VisualPalette {
+		paletteVisuals(robot, opts)
+	}
+
+	for i := range robot.Joints {
+		processJoint(&robot.Joints[i], opts, report)
+	}
+	lockJoints(robot, original, opts, report)
+	if opts.BaseTransform != "" {
+		transformBase(robot, original, opts, report)
+	}
+	if opts.Payload != nil && opts.KeepInertials {
+		addPayload(robot, opts.Payload, opts, report)
+	}
+	if !opts.SkipCollisionCheck {
+		checkSelfCollisions(robot, opts, report)
+	}
+	if opts.SweepJoints {
+		checkSweptCollisions(robot, opts, report)
+	}
+	if opts.WorkspaceSamples > 0 {
+		checkWorkspace(robot, original, opts, report)
+	}
+
+	if opts.SnapTolerance > 0 {
+		snap(robot, opts.SnapTolerance, report)
+	}
+	if !opts.SkipCleanup {
+		cleanup(robot, report)
+	}
+	if opts.CanonicalNames || opts.LinkNames != "" {
+		renameRobot(robot, opts, report)
+	}
+	robot.Name = opts.nameFor(robot.Name)
+	report.Name = robot.Name
+	report.CollisionVolume = collisionVolume(report.Meshes)
+	countChanges(robot, original, report)
+	report.MassAfter = Masses(robot, "")
+	report.MassBefore = Masses(original, report.MassAfter.Frame)
+	return report
+}
+
+// countChanges fills in the parts of report.Changes that summarize the rest
+// of the report, looking removed joints up in original.
+func countChanges(robot, original *Robot, report *Report) {
+	c := &report.Changes
+	c.MeshesReplaced = len(report.Meshes)
+	c.LinksRemoved = len(report.RemovedLinks)
+	for _, name := range report.RemovedJoints {
+		if joint := original.FindJoint(name); joint != nil && joint.Type == "fixed" {
+			c.FixedJointsRemoved = append(c.FixedJointsRemoved, name)
+		} else {
+			c.MovingJointsRemoved = append(c.MovingJointsRemoved, name)
+		}
+	}
+
+	changed := make(map[string]bool)
+	for _, in := range report.Inertias {
+		changed[in.Link] = true
+	}
+	for _, f := range report.FixedInertias {
+		changed[f.Link] = true
+	}
+	for _, m := range report.LumpedMasses {
+		changed[m.Into] = true
+	}
+	if report.PayloadLink != "" {
+		changed[report.PayloadLink] = true
+	}
+	for _, link := range robot.Links {
+		if changed[link.Name] {
+			c.InertiasChanged++
+		}
+	}
+}
+
+// filterLinks applies the chain mode and the explicit keep/drop lists.
+func filterLinks(robot *Robot, opts Options, report *Report) {
+	if opts.Chain == ChainAll && len(opts.DropLinks) == 0 {
+		report.Links = len(robot.Links)
+		report.Joints = len(robot.Joints)
+		return
+	}
+
+	keep, pulled, warnings := selectLinks(robot, opts)
+	report.Warnings = append(report.Warnings, warnings...)
+
+	// The main chain keeps only actuated joints, plus whatever joints attach
+	// explicitly kept links.
+	keepJoint := func(joint *Joint) bool {
+		return opts.Chain == ChainAll || isActuated(joint) || pulled[joint.Child.Link]
+	}
+	applyLinkFilter(robot, keep, keepJoint, opts, report)
+}
+
+// checkReferences records an error for each kept joint that names a link,
+// or mimics a joint, that the simplified robot does not have, since
+// downstream parsers reject such a URDF outright.
+func checkReferences(robot *Robot, opts Options, report *Report) {
+	links := make(map[string]bool)
+	for _, link := range robot.Links {
+		links[link.Name] = true
+	}
+	joints := make(map[string]bool)
+	for _, joint := range robot.Joints {
+		joints[joint.Name] = true
+	}
+	fail := func(msg string) {
+		opts.logger().Error("invalid reference", "error", msg)
+		report.Errors = append(report.Errors, msg)
+	}
+	describe := func(kind, name string, removed []string) string {
+		if slices.Contains(removed, name) {
+			return fmt.Sprintf("%s %q, which was removed", kind, name)
+		}
+		return fmt.Sprintf("missing %s %q", kind, name)
+	}
+
+	for _, joint := range robot.Joints {
+		if joint.Parent == nil || joint.Child == nil {
+			fail(fmt.Sprintf("joint %q has no parent or child link", joint.Name))
+			continue
+		}
+		for _, link := range []string{joint.Parent.Link, joint.Child.Link} {
+			if !links[link] {
+				fail(fmt.Sprintf("joint %q references %s", joint.Name, describe("link", link, report.RemovedLinks)))
+			}
+		}
+		if m := joint.Mimic; m != nil && !joints[m.Joint] {
+			fail(fmt.Sprintf("joint %q mimics %s", joint.Name, describe("joint", m.Joint, report.RemovedJoints)))
+		}
+	}
+}
+
+// KeptLinks reports which links Simplify would keep under opts, without
+// modifying robot.
+func KeptLinks(robot *Robot, opts Options) map[string]bool {
+	keep, _, _ := selectLinks(robot, opts)
+	return keep
+}
+
+// Filter returns a copy of robot holding only the links and joints Simplify
+// would keep under opts, with the warnings the keep and drop lists give,
+// without reading meshes or changing anything else. The copy shares the
+// links' and joints' elements with robot.
+func Filter(robot *Robot, opts Options) (*Robot, []string) {
+	if opts.GeometryOnly {
+		opts = opts.geometryOnly(robot.Name)
+	}
+	opts.LumpMass, opts.Logger = false, nil
+	filtered := &Robot{Name: robot.Name, Links: slices.Clone(robot.Links), Joints: slices.Clone(robot.Joints)}
+	report := &Report{}
+	filterLinks(filtered, opts, report)
+	return filtered, report.Warnings
+}
+
+// selectLinks marks the links kept by the chain mode and the keep/drop lists.
+// pulled holds the links kept only because of keep_links, whose parent joints
+// survive even when they are not actuated. Both are only looked up, never
+// ranged over, so that what is kept stays in document order.
+func selectLinks(robot *Robot, opts Options) (keep, pulled map[string]bool, warnings []string) {
+	tree := NewKinematicTree(robot)
+	keep = make(map[string]bool)
+	for _, link := range robot.Links {
+		keep[link.Name] = opts.Chain == ChainAll || inMainChain(tree, link.Name)
+	}
+
+	pulled = make(map[string]bool)
+	if opts.KeepTipFrames {
+		for _, link := range tipFrames(tree, keep) {
+			keep[link], pulled[link] = true, true
+		}
+	}
+
+	// Explicitly kept links pull in the path up to the nearest kept ancestor.
+	for _, name := range opts.KeepLinks {
+		if robot.FindLink(name) == nil {
+			warnings = append(warnings, fmt.Sprintf("keep_links: link %q does not exist", name))
+			continue
+		}
+		keep[name], pulled[name] = true, true
+		for _, ancestor := range tree.Ancestors(name) {
+			if keep[ancestor] {
+				break
+			}
+			keep[ancestor], pulled[ancestor] = true, true
+		}
+	}
+
+	for _, name := range opts.DropLinks {
+		if robot.FindLink(name) == nil {
+			warnings = append(warnings, fmt.Sprintf("drop_links: link %q does not exist", name))
+			continue
+		}
+		for _, link := range tree.DFS(name) {
+			keep[link] = false
+		}
+	}
+	return keep, pulled, warnings
+}
+
+// applyLinkFilter removes every link not marked in keep, and every joint that
+// does not connect two kept links or is rejected by keepJoint.
+func applyLinkFilter(robot *Robot, keep map[string]bool, keepJoint func(*Joint) bool, opts Options, report *Report) {
+	if opts.KeepInertials && opts.LumpMass {
+		lumpRemovedMass(robot, keep, opts, report)
+	}
+
+	var links []Link
+	for _, link := range robot.Links {
+		if keep[link.Name] {
+			links = append(links, link)
+		} else {
+			report.RemovedLinks = append(report.RemovedLinks, link.Name)
+		}
+	}
+
+	var joints []Joint
+	for _, joint := range robot.Joints {
+		if joint.Parent != nil && joint.Child != nil && keep[joint.Parent.Link] && keep[joint.Child.Link] && keepJoint(&joint) {
+			joints = append(joints, joint)
+		} else {
+			report.RemovedJoints = append(report.RemovedJoints, joint.Name)
+		}
+	}
+
+	robot.Links = links
+	robot.Joints = joints
+	report.Links = len(robot.Links)
+	report.Joints = len(robot.Joints)
+
+	opts.logger().Info("filtered kinematic chain", "links", len(robot.Links), "joints", len(robot.Joints))
+}
+
+// tipFrames returns the links that are not in keep but hang off a kept link
+// by fixed joints only, down to a leaf.
+func tipFrames(tree *KinematicTree, keep map[string]bool) []string {
+	var frames []string
+	for _, leaf := range tree.Leaves() {
+		var path []string
+		for link := leaf; !keep[link]; {
+			joint := tree.ParentJoint(link)
+			if joint == nil || joint.Parent == nil || joint.Type != "fixed" || slices.Contains(path, link) {
+				path = nil
+				break
+			}
+			path = append(path, link)
+			link = joint.Parent.Link
+		}
+		frames = append(frames, path...)
+	}
+	return frames
+}
+
+// inMainChain reports whether link is the parent or child of a revolute or prismatic joint.
+func inMainChain(tree *KinematicTree, link string) bool {
+	if joint := tree.ParentJoint(link); joint != nil && isActuated(joint) {
+		return true
+	}
+	for _, joint := range tree.ChildJoints(link) {
+		if isActuated(joint) {
+			return true
+		}
+	}
+	return false
+}
+
+func isActuated(joint *Joint) bool {
+	return joint.Type == "revolute" || joint.Type == "prismatic"
+}
+
+// meshBounds is the bounding box of one collision mesh, or the error that
+// prevented reading it, and the volume it encloses, zero if it is not
+// closed. With InertiaFromMesh it also holds the mesh's mass properties, or
+// why they could not be computed, and with GeometryCapsule its bounding
+// capsule, unless the mesh was too large to fit one to.
+type meshBounds struct {
+	box     mesh.AABB
+	volume  float64
+	capsule *mesh.Capsule
+	err     error
+	elapsed time.Duration
+	mass    mesh.MassProperties
+	massErr error
+}
+
+// meshTask identifies a collision mesh by link and collision index.
+type meshTask struct {
+	link, collision int
+}
+
+// meshRead is one mesh file to be read, with every reference to it.
+type meshRead struct {
+	index     int
+	link, uri string
+	refs      []meshTask
+}
+
+// boundMeshes reads every collision mesh that processLink will replace and
+// returns their bounding boxes indexed by link and collision, along with the
+// number of references whose box was reused from an identical mesh. Each
+// file is read once however many links refer to it, and files with the same
+// content are bounded once. Up to opts.Jobs files are read at once; the
+// results, and so the output, do not depend on the order in which they
+// finish, and what each logs is passed on in the order the files were listed.
+func boundMeshes(robot *Robot, resolver MeshResolver, opts Options) ([][]meshBounds, int) {
+	bounds := make([][]meshBounds, len(robot.Links))
+	var reads []*meshRead
+	byFile := make(map[string]*meshRead)
+	for i, link := range robot.Links {
+		bounds[i] = make([]meshBounds, len(link.Collision))
+		if mode := opts.geometryFor(link.Name); (mode == GeometryMesh || mode == GeometryNone) && opts.RecomputeInertia != InertiaFromMesh {
+			continue
+		}
+		for j, c := range link.Collision {
+			if c.Geometry == nil || c.Geometry.Mesh == nil {
+				continue
+			}
+			uri := c.Geometry.Mesh.Filename
+			key := meshFileKey(resolver, uri)
+			read := byFile[key]
+			if read == nil {
+				read = &meshRead{index: len(reads), link: link.Name, uri: uri}
+				byFile[key] = read
+				reads = append(reads, read)
+			}
+			read.refs = append(read.refs, meshTask{link: i, collision: j})
+		}
+	}
+
+	type result struct {
+		*meshRead
+		meshBounds
+		reused bool
+		log    *logBuffer
+	}
+	cache := &contentCache{entries: make(map[[sha256.Size]byte]*contentEntry)}
+	queue := make(chan *meshRead)
+	results := make(chan result)
+	for range min(max(opts.Jobs, 1), len(reads)) {
+		go func() {
+			for read := range queue {
+				start := time.Now()
+				readOpts, log := opts.buffered()
+				b, reused := cache.bound(resolver, read.uri, readOpts)
+				b.elapsed = time.Since(start)
+				results <- result{read, b, reused, log}
+			}
+		}()
+	}
+	go func() {
+		for _, read := range reads {
+			queue <- read
+		}
+		close(queue)
+	}()
+
+	// Each file's log is held until those before it have been flushed.
+	logs := make([]*logBuffer, len(reads))
+	flushed := 0
+	duplicates := 0
+	for done := 1; done <= len(reads); done++ {
+		r := <-results
+		logs[r.index] = r.log
+		for ; flushed < len(reads) && logs[flushed] != nil; flushed++ {
+			logs[flushed].flush()
+		}
+		for _, ref := range r.refs {
+			bounds[ref.link][ref.collision] = r.meshBounds
+		}
+		if r.err == nil {
+			duplicates += len(r.refs) - 1
+			if r.reused {
+				duplicates++
+			}
+		}
+		if opts.Progress != nil {
+			opts.Progress(Progress{Done: done, Total: len(reads), Link: r.link, Mesh: r.uri, Elapsed: r.elapsed, Err: r.err})
+		}
+	}
+	return bounds, duplicates
+}
+
+// meshFileKey identifies the file uri refers to, so that different URIs for
+// the same file are read once.
+func meshFileKey(resolver MeshResolver, uri string) string {
+	if r, ok := resolver.(FileResolver); ok {
+		if p, err := filepath.Abs(r.Resolve(uri)); err == nil {
+			return p
+		}
+	}
+	return uri
+}
+
+// streamMeshSize is the size in bytes above which a mesh file is bounded as
+// it is read rather than loaded whole.
+const streamMeshSize = 64 << 20
+
+// contentCache shares bounding boxes between mesh files with identical
+// content, such as left and right finger meshes exported separately. It is
+// safe for concurrent use.
+type contentCache struct {
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]*contentEntry
+}
+
+type contentEntry struct {
+	ready   chan struct{}
+	box     mesh.AABB
+	volume  float64
+	capsule *mesh.Capsule
+	err     error
+	mass    mesh.MassProperties
+	massErr error
+}
+
+// bound reads the mesh at uri and returns its bounding box, reporting
+// whether it was reused from a file with the same content. Local files are
+// memory-mapped where the platform allows, rather than read into a buffer.
+// Files larger than streamMeshSize are bounded as they are read instead of
+// parsed whole, unless mesh inertia needs the whole mesh; their volume is not
+// measured, nor a capsule fit to them.
+func (c *contentCache) bound(resolver MeshResolver, uri string, opts Options) (meshBounds, bool) {
+	f, size, err := openMeshFile(resolver, uri, opts)
+	if err != nil {
+		return meshBounds{err: err}, false
+	}
+	defer f.Close()
+
+	var data []byte
+	if file, ok := f.(*os.File); ok && size > 0 {
+		if mapped, unmap, err := mapFile(file, size); err == nil {
+			defer unmap()
+			data = mapped
+		}
+	}
+	streamed := size > streamMeshSize && opts.RecomputeInertia != InertiaFromMesh
+	if streamed && data == nil {
+		return c.stream(f, size, opts)
+	}
+	if data == nil {
+		if data, err = io.ReadAll(f); err != nil {
+			return meshBounds{err: fmt.Errorf("error reading STL: %w", err)}, false
+		}
+	}
+
+	sum := sha256.Sum256(data)
+	c.mu.Lock()
+	entry, reused := c.entries[sum]
+	if !reused {
+		entry = &contentEntry{ready: make(chan struct{})}
+		c.entries[sum] = entry
+	}
+	c.mu.Unlock()
+
+	if reused {
+		<-entry.ready
+	} else {
+		if !loadCachedBounds(sum, entry, opts, streamed) {
+			if streamed {
+				if s, err := mesh.BoundSTL(bytes.NewReader(data), size, false); err != nil {
+					entry.err = err
+				} else {
+					entry.box = s.Box
+				}
+			} else if m, err := mesh.ParseSTL(data); err != nil {
+				entry.err = err
+			} else {
+				entry.box = m.Bounds()
+				if m.Closed() {
+					entry.volume = m.Volume()
+				}
+				if opts.RecomputeInertia == InertiaFromMesh {
+					entry.mass, entry.massErr = m.MassProperties()
+				}
+				if opts.fitsCapsules() {
+					c := m.BoundingCapsule()
+					entry.capsule = &c
+				}
+			}
+			storeCachedBounds(sum, entry, opts, streamed)
+		}
+		close(entry.ready)
+	}
+	return entry.bounds(), reused
+}
+
+// stream bounds a large mesh file while hashing it, so that neither needs
+// the file in memory. The content is only known once it has been read, so
+// a duplicate is recognized afterwards and reported as reused.
+func (c *contentCache) stream(f io.Reader, size int64, opts Options) (meshBounds, bool) {
+	h := sha256.New()
+	s, err := mesh.BoundSTL(io.TeeReader(f, h), size, false)
+	entry := &contentEntry{ready: make(chan struct{}), err: err}
+	if err == nil {
+		entry.box = s.Box
+	}
+	close(entry.ready)
+
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	c.mu.Lock()
+	existing, reused := c.entries[sum]
+	if !reused {
+		c.entries[sum] = entry
+	}
+	c.mu.Unlock()
+	if reused {
+		<-existing.ready
+		return existing.bounds(), true
+	}
+	storeCachedBounds(sum, entry, opts, true)
+	return entry.bounds(), false
+}
+
+func (e *contentEntry) bounds() meshBounds {
+	return meshBounds{box: e.box, volume: e.volume, capsule: e.capsule, err: e.err, mass: e.mass, massErr: e.massErr}
+}
+
+// processLinks runs processLink on every link, up to opts.Jobs at once. Each
+// link is processed into a report and log of its own, which are merged into
+// report and flushed in link order, so both read the same for any opts.Jobs.
+func processLinks(robot *Robot, bounds [][]meshBounds, opts Options, report *Report) {
+	parts := make([]*Report, len(robot.Links))
+	logs := make([]*logBuffer, len(robot.Links))
+	done := make([]chan struct{}, len(robot.Links))
+	slots := make(chan struct{}, max(opts.Jobs, 1))
+	for i := range robot.Links {
+		done[i] = make(chan struct{})
+		go func() {
+			slots <- struct{}{}
+			defer func() { <-slots; close(done[i]) }()
+			var linkOpts Options
+			linkOpts, logs[i] = opts.buffered()
+			parts[i] = &Report{}
+			processLink(&robot.Links[i], bounds[i], linkOpts, parts[i])
+		}()
+	}
+	for i := range robot.Links {
+		<-done[i]
+		logs[i].flush()
+		mergeLinkReport(report, parts[i])
+	}
+}
+
+// mergeLinkReport adds what processLink recorded in part to report.
+func mergeLinkReport(report, part *Report) {
+	report.Warnings = append(report.Warnings, part.Warnings...)
+	report.FailedMeshes = append(report.FailedMeshes, part.FailedMeshes...)
+	report.Meshes = append(report.Meshes, part.Meshes...)
+	report.Inertias = append(report.Inertias, part.Inertias...)
+	report.FixedInertias = append(report.FixedInertias, part.FixedInertias...)
+	report.Changes.VisualsRemoved += part.Changes.VisualsRemoved
+	report.Changes.VisualsAdded += part.Changes.VisualsAdded
+	report.Changes.CollisionsMerged += part.Changes.CollisionsMerged
+	report.Changes.CollisionsRemoved += part.Changes.CollisionsRemoved
+	report.Changes.InertialsRemoved += part.Changes.InertialsRemoved
+}
+
+// processLink simplifies one link, using bounds for the bounding boxes of its
+// collision meshes, indexed like link.Collision.
+func processLink(link *Link, bounds []meshBounds, opts Options, report *Report) {
+	if !opts.KeepInertials {
+		if link.Inertial != nil {
+			report.Changes.InertialsRemoved++
+		}
+		// Step 1.3: Move origin from inertial to link level
+		if link.Inertial != nil && link.Inertial.Origin != nil {
+			link.Origin = link.Inertial.Origin
+		}
+
+		// Step 1.3: Remove inertial entirely
+		link.Inertial = nil
+	}
+
+	// Step 1.4: Remove visual elements
+	if !opts.KeepVisuals {
+		report.Changes.VisualsRemoved += len(link.Visual)
+		link.Visual = nil
+	}
+
+	if opts.KeepInertials {
+		adjustMass(link, opts, report)
+	}
+
+	// Mesh inertia needs the collision origins as written, before boxes
+	// replace them; box inertia needs the boxes.
+	if opts.RecomputeInertia == InertiaFromMesh {
+		recomputeMeshInertia(link, bounds, opts, report)
+	}
+	switch mode := opts.geometryFor(link.Name); mode {
+	case GeometryMesh:
+		mergeDuplicateCollisions(link, opts, report)
+		nameCollisions(link)
+	case GeometryNone:
+		report.Changes.CollisionsRemoved += len(link.Collision)
+		link.Collision = nil
+	default:
+		boxCollisions(link, bounds, mode == GeometryCapsule, opts, report)
+	}
+	if opts.VisualFromCollision {
+		visualsFromCollisions(link, opts, report)
+	}
+	if opts.RecomputeInertia == InertiaFromBox {
+		recomputeBoxInertia(link, opts, report)
+	}
+	if opts.KeepInertials {
+		ensureInertial(link, opts.EnsureInertials, opts)
+		checkInertia(link, opts, report)
+	}
+}
+
+// processJoint applies the joint options to a kept joint.
+func processJoint(joint *Joint, opts Options, report *Report) {
+	j := opts.jointFor(joint.Name)
+	if j.Lower != "" || j.Upper != "" {
+		overrideLimits(joint, j, opts, report)
+	}
+	if limit := joint.Limit; limit != nil {
+		filled := false
+		if limit.Effort == 0 && j.DefaultEffort != 0 {
+			limit.Effort, filled = j.DefaultEffort, true
+		}
+		if limit.Velocity == 0 && j.DefaultVelocity != 0 {
+			limit.Velocity, filled = j.DefaultVelocity, true
+		}
+		if filled {
+			report.FilledLimits = append(report.FilledLimits, joint.Name)
+			opts.logger().Debug("filled in joint limits", "joint", joint.Name, "effort", limit.Effort, "velocity", limit.Velocity)
+		}
+	}
+
+	if joint.Dynamics != nil {
+		switch opts.Dynamics {
+		case DynamicsZero:
+			joint.Dynamics.Damping, joint.Dynamics.Friction = 0, 0
+			report.Changes.DynamicsChanged++
+		case DynamicsRemove:
+			joint.Dynamics = nil
+			report.Changes.DynamicsChanged++
+		}
+	}
+}
+
+// overrideLimits replaces the lower and upper limits of a revolute or
+// prismatic joint with those in j.
+func overrideLimits(joint *Joint, j JointOptions, opts Options, report *Report) {
+	if joint.Type != "revolute" && joint.Type != "prismatic" {
+		opts.logger().Warn("joint has no limits to override", "joint", joint.Name, "type", joint.Type)
+		report.Warnings = append(report.Warnings, fmt.Sprintf("joint limits: %s is %s and has no lower and upper limits to override", joint.Name, joint.Type))
+		return
+	}
+	// The limit is replaced rather than changed, since the original robot
+	// kept for the checks shares it.
+	var limit Limit
+	if joint.Limit != nil {
+		limit = *joint.Limit
+	}
+	for _, l := range []struct {
+		p JointPosition
+		v *float64
+	}{{j.Lower, &limit.Lower}, {j.Upper, &limit.Upper}} {
+		if l.p == "" {
+			continue
+		}
+		v, err := l.p.valueFor(joint)
+		if err != nil {
+			msg := "joint limits: " + err.Error()
+			opts.logger().Error("could not override joint limits", "error", msg)
+			report.Errors = append(report.Errors, msg)
+			return
+		}
+		*l.v = v
+	}
+	if limit.Lower > limit.Upper {
+		msg := fmt.Sprintf("joint limits: joint %q would have its lower limit %g above its upper limit %g", joint.Name, limit.Lower, limit.Upper)
+		opts.logger().Error("could not override joint limits", "error", msg)
+		report.Errors = append(report.Errors, msg)
+		return
+	}
+	joint.Limit = &limit
+	report.Changes.LimitsOverridden = append(report.Changes.LimitsOverridden, joint.Name)
+	opts.logger().Debug("overrode joint limits", "joint", joint.Name, "lower", limit.Lower, "upper", limit.Upper)
+}
+
+// lockJoints turns the kept moving joints with a lock position into fixed
+// joints at that position, in robot and in original alike, so that the
+// checks comparing the two move the original's joints to where the
+// simplified robot has them.
+func lockJoints(robot, original *Robot, opts Options, report *Report) {
+	locked := make(map[string]bool)
+	for i := range robot.Joints {
+		joint := &robot.Joints[i]
+		p := opts.jointFor(joint.Name).Lock
+		if p == "" {
+			continue
+		}
+		if !moves(joint) {
+			opts.logger().Warn("joint does not move and cannot be locked", "joint", joint.Name, "type", joint.Type)
+			report.Warnings = append(report.Warnings, fmt.Sprintf("joint lock: %s is %s and does not move", joint.Name, joint.Type))
+			continue
+		}
+		q, err := p.valueFor(joint)
+		if err == nil {
+			err = lockJoint(joint, q)
+		}
+		if err == nil {
+			if j := original.FindJoint(joint.Name); j != nil {
+				err = lockJoint(j, q)
+			}
+		}
+		if err != nil {
+			msg := "joint lock: " + err.Error()
+			opts.logger().Error("could not lock joint", "joint", joint.Name, "error", msg)
+			report.Errors = append(report.Errors, msg)
+			continue
+		}
+		locked[joint.Name] = true
+		report.Changes.JointsLocked = append(report.Changes.JointsLocked, joint.Name)
+		opts.logger().Debug("locked joint", "joint", joint.Name, "position", q)
+	}
+	for _, joint := range robot.Joints {
+		if joint.Mimic != nil && locked[joint.Mimic.Joint] {
+			opts.logger().Warn("joint mimics a locked joint", "joint", joint.Name, "mimic", joint.Mimic.Joint)
+			report.Warnings = append(report.Warnings, fmt.Sprintf("joint lock: %s mimics %s, which is now fixed", joint.Name, joint.Mimic.Joint))
+		}
+	}
+}
+
+// lockJoint makes joint fixed at position q: its origin becomes the pose of
+// its child at q, and its axis, limits, dynamics and mimic go.
+func lockJoint(joint *Joint, q float64) error {
+	origin, err := joint.Origin.Pose()
+	if err != nil {
+		return fmt.Errorf("joint %q: %w", joint.Name, err)
+	}
+	motion, err := joint.Motion(q)
+	if err != nil {
+		return fmt.Errorf("joint %q: %w", joint.Name, err)
+	}
+	joint.Origin = OriginFromPose(origin.Compose(motion))
+	joint.Type = "fixed"
+	joint.Axis, joint.Limit, joint.Dynamics, joint.Mimic = nil, nil, nil, nil
+	return nil
+}
+
+// transformBase moves the root link frame of robot by Options.BaseTransform,
+// and that of original with it, so that the checks comparing the two see
+// the same motion. It pre-multiplies the origins of the joints out of the
+// root and of what the root link itself holds.
+func transformBase(robot, original *Robot, opts Options, report *Report) {
+	base, err := parseBaseTransform(opts.BaseTransform)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return
+	}
+	root := NewKinematicTree(robot).Root()
+	if root == "" {
+		return
+	}
+	move := func(o **Origin) error {
+		pose, err := (*o).Pose()
+		if err != nil {
+			return err
+		}
+		*o = OriginFromPose(base.Compose(pose))
+		return nil
+	}
+	var errs []error
+	for _, r := range []*Robot{robot, original} {
+		for i := range r.Joints {
+			if j := &r.Joints[i]; j.Parent != nil && j.Parent.Link == root {
+				if err := move(&j.Origin); err != nil {
+					errs = append(errs, fmt.Errorf("joint %q: %w", j.Name, err))
+				}
+			}
+		}
+	}
+	if link := robot.FindLink(root); link != nil {
+		for i := range link.Visual {
+			if err := move(&link.Visual[i].Origin); err != nil {
+				errs = append(errs, fmt.Errorf("link %q: visual: %w", root, err))
+			}
+		}
+		for i := range link.Collision {
+			if err := move(&link.Collision[i].Origin); err != nil {
+				errs = append(errs, fmt.Errorf("link %q: collision: %w", root, err))
+			}
+		}
+		if in := link.Inertial; in != nil {
+			if err := move(&in.Origin); err != nil {
+				errs = append(errs, fmt.Errorf("link %q: inertial: %w", root, err))
+			}
+		}
+	}
+	if link := original.FindLink(root); link != nil && link.Inertial != nil {
+		if err := move(&link.Inertial.Origin); err != nil {
+			errs = append(errs, fmt.Errorf("link %q: inertial: %w", root, err))
+		}
+	}
+	for _, err := range errs {
+		msg := "base transform: " + err.Error()
+		opts.logger().Error("could not apply the base transform", "error", msg)
+		report.Errors = append(report.Errors, msg)
+	}
+	opts.logger().Debug("moved the root link frame", "link", root, "transform", opts.BaseTransform)
+}
+
+// formatFixed formats a box's size, or the xyz or rpy of its origin, with
+// six decimals, dropping the sign of a value that rounds to zero.
+func formatFixed(x, y, z float64) string {
+	f := make([]string, 3)
+	for i, v := range []float64{x, y, z} {
+		f[i] = fmt.Sprintf("%f", v)
+		if f[i] == "-0.000000" {
+			f[i] = "0.000000"
+		}
+	}
+	return strings.Join(f, " ")
+}
+
+// boxCollisions replaces the link's collision meshes with their bounding
+// boxes, taken from bounds, or with capsules if capsules is set and the
+// capsule fit to a mesh is not too much larger than its box, then names the
+// collisions.
+func boxCollisions(link *Link, bounds []meshBounds, capsules bool, opts Options, report *Report) {
+	// The report entry of each replaced mesh, by collision index, to be
+	// given the collision's name once they all have their final shape.
+	replaced := make(map[int]int)
+
+	// Step 2: Replace collision meshes with bounding boxes
+	for i := range link.Collision {
+		if link.Collision[i].Geometry != nil && link.Collision[i].Geometry.Mesh != nil {
+			meshRef := link.Collision[i].Geometry.Mesh
+
+			// Calculate bounding box
+			b := bounds[i]
+			if err := b.err; err != nil {
+				opts.logger().Warn("could not calculate bounding box", "link", link.Name, "mesh", meshRef.Filename, "error", err)
+				report.Warnings = append(report.Warnings, fmt.Sprintf("could not calculate bounding box for %s: %v", meshRef.Filename, err))
+				report.FailedMeshes = append(report.FailedMeshes, FailedMesh{Link: link.Name, Mesh: meshRef.Filename, Error: err.Error()})
+				continue
+			}
+
+			// Get dimensions
+			size := b.box.Size()
+			width, height, depth := size.X, size.Y, size.Z
+
+			// Get center coordinates
+			center := b.box.Center()
+
+			r := MeshReport{
+				Link:   link.Name,
+				Mesh:   meshRef.Filename,
+				Size:   [3]float64{width, height, depth},
+				Center: [3]float64{center.X, center.Y, center.Z},
+				Volume: b.volume,
+			}
+			if capsules && capsuleCollision(&link.Collision[i], b, link.Name, opts) {
+				c := b.capsule
+				r.Capsule = &CapsuleReport{Center: [3]float64{c.Center.X, c.Center.Y, c.Center.Z}, Axis: string(rune('x' + c.Axis)),
+					Radius: link.Collision[i].Geometry.Capsule.Radius, Length: link.Collision[i].Geometry.Capsule.Length}
+			} else {
+				// The box center is in the mesh's frame, which the collision
+				// origin places in the link's, as for capsules.
+				origin, err := link.Collision[i].Origin.Pose()
+				if err != nil {
+					opts.logger().Warn("could not place bounding box", "link", link.Name, "mesh", meshRef.Filename, "error", err)
+					report.Warnings = append(report.Warnings, fmt.Sprintf("could not place the bounding box of %s on link %s: invalid collision origin: %v", meshRef.Filename, link.Name, err))
+					continue
+				}
+
+				// Replace mesh with box
+				link.Collision[i].Geometry.Mesh = nil
+				link.Collision[i].Geometry.Box = &Box{
+					Size: formatFixed(width, height, depth),
+				}
+
+				// Set the collision origin to the bounding box center, rounded
+				// like the box size
+				placed := origin.Compose(spatialmath.NewPose(center, spatialmath.RPY{}))
+				newOrigin := &Origin{XYZ: formatFixed(placed.Translation.X, placed.Translation.Y, placed.Translation.Z)}
+				if o := link.Collision[i].Origin; o != nil && o.RPY != "" {
+					rpy := placed.RPY()
+					newOrigin.RPY = formatFixed(rpy.Roll, rpy.Pitch, rpy.Yaw)
+				}
+				link.Collision[i].Origin = newOrigin
+			}
+
+			replaced[i] = len(report.Meshes)
+			report.Meshes = append(report.Meshes, r)
+			shape := "box"
+			if r.Capsule != nil {
+				shape = "capsule"
+			}
+			if ratio := r.PrimitiveVolume() / b.volume; b.volume > 0 && ratio > opts.maxVolumeRatio() {
+				opts.logger().Warn(shape+" over-approximates mesh", "link", link.Name, "mesh", meshRef.Filename, "ratio", ratio)
+				report.Warnings = append(report.Warnings, fmt.Sprintf("%s for %s on link %s is %.1fx the volume of the mesh (more than %gx): the link may need a better geometry mode",
+					shape, path.Base(meshRef.Filename), link.Name, ratio, opts.maxVolumeRatio()))
+			}
+
+			if r.Capsule != nil {
+				opts.logger().Debug("replaced mesh with capsule", "link", link.Name, "mesh", path.Base(meshRef.Filename),
+					"radius", fmt.Sprintf("%.5f", r.Capsule.Radius), "length", fmt.Sprintf("%.5f", r.Capsule.Length), "axis", r.Capsule.Axis,
+					"elapsed", b.elapsed.Round(time.Microsecond))
+				continue
+			}
+			opts.logger().Debug("replaced mesh with box", "link", link.Name, "mesh", path.Base(meshRef.Filename),
+				"size", fmt.Sprintf("%.5f x %.5f x %.5f", width, height, depth),
+				"center", fmt.Sprintf("%.5f %.5f %.5f", center.X, center.Y, center.Z),
+				"elapsed", b.elapsed.Round(time.Microsecond))
+		}
+	}
+
+	at := mergeDuplicateCollisions(link, opts, report)
+	nameCollisions(link)
+
+	// A collision merged into an earlier one is not in the output, so its
+	// report entry is dropped to keep it out of the volumes and counts.
+	merged := make(map[int]bool)
+	seen := make(map[int]bool)
+	for i := range at {
+		k, ok := replaced[i]
+		if ok && seen[at[i]] {
+			merged[k] = true
+		} else if ok {
+			report.Meshes[k].Name = link.Collision[at[i]].Name
+		}
+		seen[at[i]] = true
+	}
+	if len(merged) > 0 {
+		kept := report.Meshes[:0]
+		for k, m := range report.Meshes {
+			if !merged[k] {
+				kept = append(kept, m)
+			}
+		}
+		report.Meshes = kept
+	}
+}
+
+// mergeDuplicateCollisions removes each of the link's collisions with the
+// same geometry and origin as an earlier one, as the meshes of a link made
+// of several often collapse to the same box, unless it is named otherwise.
+// It returns the index each collision ended up at: for one removed, that of
+// the earlier one.
+func mergeDuplicateCollisions(link *Link, opts Options, report *Report) []int {
+	at := make([]int, len(link.Collision))
+	first := make(map[string]int)
+	kept := link.Collision[:0]
+	for i, c := range link.Collision {
+		key := duplicateKey(c)
+		if j, ok := first[key]; ok && (c.Name == "" || c.Name == kept[j].Name) {
+			at[i] = j
+			report.Changes.CollisionsMerged++
+			opts.logger().Debug("merged duplicate collision", "link", link.Name, "collision", i, "into", j)
+			continue
+		}
+		if _, ok := first[key]; !ok {
+			first[key] = len(kept)
+		}
+		at[i] = len(kept)
+		kept = append(kept, c)
+	}
+	link.Collision = kept
+	return at
+}
+
+// duplicateKey returns the collision's geometry and origin as XML, which two
+// collisions share exactly when they are the same but for their names.
+func duplicateKey(c Collision) string {
+	c.Name = ""
+	data, _ := xml.Marshal(c)
+	return string(data)
+}
+
+// nameCollisions names each of the link's collisions that has no name after
+// the link and its shape, numbered from zero for each shape in document
+// order: base_link:box0, base_link:box1, base_link:capsule0. Names already
+// given are kept, and skipped when numbering.
+func nameCollisions(link *Link) {
+	taken := make(map[string]bool)
+	for _, c := range link.Collision {
+		taken[c.Name] = true
+	}
+	next := make(map[string]int)
+	for i := range link.Collision {
+		c := &link.Collision[i]
+		if c.Name != "" {
+			continue
+		}
+		shape := geometryType(c.Geometry)
+		if shape == "none" {
+			shape = "collision"
+		}
+		for {
+			name := fmt.Sprintf("%s:%s%d", link.Name, shape, next[shape])
+			next[shape]++
+			if !taken[name] {
+				c.Name, taken[name] = name, true
+				break
+			}
+		}
+	}
+}
+
+// visualMaterial names the material of the visuals visualsFromCollisions
+// adds. Each gives its color inline, so that no <material> needs defining at
+// the top of the document.
+const visualMaterial = "collision"
+
+// visualsFromCollisions gives the link a visual for each of its collisions,
+// named and placed like it, with the same geometry, in opts.visualColor.
+func visualsFromCollisions(link *Link, opts Options, report *Report) {
+	color := opts.visualColor()
+	for _, c := range link.Collision {
+		v := Visual{Name: c.Name, Material: &Material{Name: visualMaterial, Color: &Color{RGBA: color}}}
+		if c.Origin != nil {
+			origin := *c.Origin
+			v.Origin = &origin
+		}
+		if g := c.Geometry; g != nil {
+			v.Geometry = &Geometry{}
+			if g.Box != nil {
+				box := *g.Box
+				v.Geometry.Box = &box
+			}
+			if g.Capsule != nil {
+				capsule := *g.Capsule
+				v.Geometry.Capsule = &capsule
+			}
+			if g.Mesh != nil {
+				m := *g.Mesh
+				v.Geometry.Mesh = &m
+			}
+		}
+		link.Visual = append(link.Visual, v)
+	}
+	report.Changes.VisualsAdded += len(link.Collision)
+}
+
+// visualPalette is the Tableau 10 palette, whose colors stay distinct from
+// each other in a viewer, named as the materials of the visuals
+// paletteVisuals colors.
+var visualPalette = []struct{ name, rgb string }{
+	{"collision_blue", "0.306 0.475 0.655"},
+	{"collision_orange", "0.949 0.557 0.169"},
+	{"collision_red", "0.882 0.341 0.349"},
+	{"collision_teal", "0.463 0.718 0.698"},
+	{"collision_green", "0.349 0.631 0.31"},
+	{"collision_yellow", "0.929 0.788 0.282"},
+	{"collision_purple", "0.69 0.478 0.631"},
+	{"collision_pink", "1 0.616 0.655"},
+	{"collision_brown", "0.612 0.459 0.373"},
+	{"collision_gray", "0.729 0.69 0.675"},
+}
+
+// paletteVisuals gives the visuals of each link that has any the next color
+// of visualPalette, in document order, so that neighboring links differ. The
+// alpha is that of opts.visualColor.
+func paletteVisuals(robot *Robot, opts Options) {
+	alpha := strings.Fields(opts.visualColor())[3]
+	n := 0
+	for i := range robot.Links {
+		link := &robot.Links[i]
+		if len(link.Visual) == 0 {
+			continue
+		}
+		p := visualPalette[n%len(visualPalette)]
+		for j := range link.Visual {
+			link.Visual[j].Material = &Material{Name: p.name, Color: &Color{RGBA: p.rgb + " " + alpha}}
+		}
+		n++
+	}
+}
+
+// capsuleAxes rotates the z axis of a URDF capsule onto the x, y or z axis.
+var capsuleAxes = [3]spatialmath.RPY{{Pitch: math.Pi / 2}, {Roll: -math.Pi / 2}, {}}
+
+// capsuleCollision replaces the collision's mesh with the capsule in b,
+// placed in the collision's frame, and reports whether it did. It does not
+// if the mesh was too large to fit a capsule to, or the capsule has more
+// than opts.maxCapsuleRatio times the volume of the mesh's bounding box.
+// The radius and length are rounded to the micrometer, as box sizes are.
+func capsuleCollision(c *Collision, b meshBounds, link string, opts Options) bool {
+	if b.capsule == nil {
+		opts.logger().Debug("mesh too large to fit a capsule to; using its box", "link", link, "mesh", path.Base(c.Geometry.Mesh.Filename))
+		return false
+	}
+	if ratio := b.capsule.Volume() / b.box.Volume(); !(ratio <= opts.maxCapsuleRatio()) {
+		opts.logger().Debug("capsule fits loosely; using the box", "link", link, "mesh", path.Base(c.Geometry.Mesh.Filename), "ratio", ratio)
+		return false
+	}
+	origin, err := c.Origin.Pose()
+	if err != nil {
+		return false
+	}
+	um := func(v float64) float64 { return math.Round(v*1e6) / 1e6 }
+	c.Geometry.Mesh = nil
+	c.Geometry.Capsule = &Capsule{Radius: um(b.capsule.Radius), Length: um(b.capsule.Length)}
+	c.Origin = OriginFromPose(origin.Compose(spatialmath.NewPose(b.capsule.Center, capsuleAxes[b.capsule.Axis])))
+	return true
+}
+
+// openMeshFile opens the mesh file uri refers to and returns its size, or
+// -1 if the resolver cannot tell.
+func openMeshFile(resolver MeshResolver, uri string, opts Options) (io.ReadCloser, int64, error) {
+	if r, ok := resolver.(FileResolver); ok {
+		opts.logger().Log(context.Background(), LevelTrace, "resolved mesh", "uri", uri, "path", r.Resolve(uri))
+	}
+
+	f, err := resolver.Open(uri)
+	if err != nil {
+		return nil, 0, err
+	}
+	size := int64(-1)
+	if s, ok := f.(interface{ Stat() (fs.FileInfo, error) }); ok {
+		if info, err := s.Stat(); err == nil {
+			size = info.Size()
+		}
+	}
+	return f, size, nil
+}
@@ -0,0 +1,94 @@
+package urdf
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// simplifyCubes parses a robot whose links are given as the inside of
+// <robot>, with meshes/cube.stl a benchCube, and simplifies it.
+func simplifyCubes(t *testing.T, body string, opts Options) (*Robot, *Report) {
+	t.Helper()
+	robot, err := ParseURDF(strings.NewReader(`<robot name="r">` + body + `</robot>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver := FSResolver{FS: fstest.MapFS{"meshes/cube.stl": {Data: benchCube()}}}
+	return robot, Simplify(robot, resolver, opts)
+}
+
+func TestBoxCollisionOrigin(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		origin   string
+		xyz, rpy string
+	}{
+		{"no origin", "", "0.050000 0.050000 0.050000", ""},
+		{"translated", `<origin xyz="0 0 0.06"/>`, "0.050000 0.050000 0.110000", ""},
+		{"zero rpy", `<origin xyz="0 0 0.06" rpy="0 0 0"/>`, "0.050000 0.050000 0.110000", "0.000000 0.000000 0.000000"},
+		{"rotated", `<origin xyz="0 0 0.06" rpy="0 0 1.5707963267948966"/>`, "-0.050000 0.050000 0.110000", "0.000000 0.000000 1.570796"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			robot, _ := simplifyCubes(t, `<link name="l"><collision>`+tt.origin+
+				`<geometry><mesh filename="package://r/meshes/cube.stl"/></geometry></collision></link>`,
+				Options{Chain: ChainAll, SkipCleanup: true})
+			c := robot.Links[0].Collision[0]
+			if c.Geometry.Box == nil || c.Geometry.Box.Size != "0.100000 0.100000 0.100000" {
+				t.Fatalf("geometry %+v, want a 0.1 m box", c.Geometry)
+			}
+			if c.Origin == nil || c.Origin.XYZ != tt.xyz || c.Origin.RPY != tt.rpy {
+				t.Errorf("origin %+v, want xyz %q rpy %q", c.Origin, tt.xyz, tt.rpy)
+			}
+		})
+	}
+}
+
+func TestMergedCollisionsReported(t *testing.T) {
+	collision := `<collision><geometry><mesh filename="package://r/meshes/cube.stl"/></geometry></collision>`
+	robot, report := simplifyCubes(t, `<link name="l">`+collision+collision+`</link>`, Options{Chain: ChainAll})
+	if n := len(robot.Links[0].Collision); n != 1 {
+		t.Fatalf("%d collisions, want the duplicate merged", n)
+	}
+	if len(report.Meshes) != 1 || report.Meshes[0].Name != "l:box0" {
+		t.Errorf("meshes %+v, want only l:box0", report.Meshes)
+	}
+	if c := report.Changes; c.MeshesReplaced != 1 || c.CollisionsMerged != 1 {
+		t.Errorf("%d meshes replaced and %d merged, want 1 and 1", c.MeshesReplaced, c.CollisionsMerged)
+	}
+	if v := report.CollisionVolume; v == nil || math.Abs(v.Box-0.001) > 1e-9 {
+		t.Errorf("collision volume %+v, want one 0.001 m³ box", v)
+	}
+}
+
+func TestVisualColor(t *testing.T) {
+	for _, tt := range []struct {
+		name, color string
+		palette     bool
+		want        string
+		warns       bool
+	}{
+		{"default", "", false, DefaultVisualColor, false},
+		{"given", "1 0 0 1", false, "1 0 0 1", false},
+		{"short", "1 0 0", false, DefaultVisualColor, true},
+		{"out of range", "2 0 0 1", false, DefaultVisualColor, true},
+		{"palette alpha", "1 0 0 0.25", true, "0.306 0.475 0.655 0.25", false},
+		{"palette short", "1 0", true, "0.306 0.475 0.655 0.5", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			robot, report := simplifyCubes(t, `<link name="l"><collision><geometry><mesh filename="package://r/meshes/cube.stl"/></geometry></collision></link>`,
+				Options{Chain: ChainAll, VisualFromCollision: true, VisualColor: tt.color, VisualPalette: tt.palette})
+			v := robot.Links[0].Visual
+			if len(v) != 1 || v[0].Material == nil || v[0].Material.Color == nil {
+				t.Fatalf("visuals %+v, want one with a color", v)
+			}
+			if got := v[0].Material.Color.RGBA; got != tt.want {
+				t.Errorf("color %q, want %q", got, tt.want)
+			}
+			if warned := len(report.Warnings) > 0; warned != tt.warns {
+				t.Errorf("warnings %q, want some: %v", report.Warnings, tt.warns)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package urdf
+
+import (
+	"encoding/xml"
+	"io"
+	"math"
+	"math/rand/v2"
+)
+
+// The reasons MoveIt's setup assistant records for not checking a pair of
+// links for collisions.
+const (
+	// ReasonAdjacent marks links joined to each other by a joint.
+	ReasonAdjacent = "Adjacent"
+	// ReasonDefault marks links that collide at the zero pose.
+	ReasonDefault = "Default"
+	// ReasonAlways marks links that collide in every sampled configuration.
+	ReasonAlways = "Always"
+	// ReasonNever marks links that collide in no sampled configuration.
+	ReasonNever = "Never"
+)
+
+// DisabledCollision is an SRDF <disable_collisions> entry.
+type DisabledCollision struct {
+	XMLName xml.Name `xml:"disable_collisions"`
+	Link1   string   `xml:"link1,attr"`
+	Link2   string   `xml:"link2,attr"`
+	Reason  string   `xml:"reason,attr"`
+}
+
+// DisableCollisions computes the pairs of links a planner need not check
+// against each other, the way MoveIt's setup assistant does: links joined
+// by a joint, links whose collision boxes overlap at the zero pose, and
+// links whose boxes overlap in every one, or none, of samples random
+// configurations within the joint limits. The samples are seeded, so the
+// result depends only on the robot. Pairs involving a link with collision
+// geometry other than boxes are never disabled, since they cannot be
+// checked here. The result is in document order of the first link, then the
+// second.
+func DisableCollisions(robot *Robot, samples int) []DisabledCollision {
+	m := newCollisionModel(robot)
+	checkable := make(map[string]bool)
+	for _, link := range robot.Links {
+		checkable[link.Name] = true
+		for _, c := range link.Collision {
+			if c.Geometry == nil || c.Geometry.Box == nil {
+				checkable[link.Name] = false
+			}
+		}
+	}
+
+	reasons := make(map[[2]int]string)
+	for i, a := range robot.Links {
+		for j := i + 1; j < len(robot.Links); j++ {
+			if m.adjacent[[2]string{a.Name, robot.Links[j].Name}] {
+				reasons[[2]int{i, j}] = ReasonAdjacent
+			}
+		}
+	}
+
+	// Every pair that could be disabled starts out as a candidate; each
+	// sample rules out the ones it contradicts.
+	zero := m.depths(nil)
+	hits := make(map[[2]int]int)
+	for _, d := range zero {
+		if key := [2]int{d.a, d.b}; checkable[robot.Links[d.a].Name] && checkable[robot.Links[d.b].Name] {
+			if d.depth > minOverlap {
+				reasons[key] = ReasonDefault
+			} else {
+				hits[key] = 0
+			}
+		}
+	}
+	rng := rand.New(rand.NewPCG(1, 2))
+	for range samples {
+		for _, d := range m.depths(samplePositions(robot, rng)) {
+			if _, ok := hits[[2]int{d.a, d.b}]; ok && d.depth > minOverlap {
+				hits[[2]int{d.a, d.b}]++
+			}
+		}
+	}
+	for key, n := range hits {
+		switch n {
+		case 0:
+			reasons[key] = ReasonNever
+		case samples:
+			reasons[key] = ReasonAlways
+		}
+	}
+
+	var disabled []DisabledCollision
+	for i, a := range robot.Links {
+		for j := i + 1; j < len(robot.Links); j++ {
+			if reason, ok := reasons[[2]int{i, j}]; ok {
+				disabled = append(disabled, DisabledCollision{Link1: a.Name, Link2: robot.Links[j].Name, Reason: reason})
+			}
+		}
+	}
+	return disabled
+}
+
+// samplePositions returns a uniformly random position within its limits for
+// every moving joint: continuous joints anywhere in [-π, π), and joints
+// without usable limits at zero.
+func samplePositions(robot *Robot, rng *rand.Rand) map[string]float64 {
+	positions := make(map[string]float64)
+	for _, joint := range robot.Joints {
+		switch {
+		case joint.Type == "continuous":
+			positions[joint.Name] = (rng.Float64()*2 - 1) * math.Pi
+		case joint.Type != "revolute" && joint.Type != "prismatic":
+		case joint.Limit != nil && joint.Limit.Upper > joint.Limit.Lower:
+			positions[joint.Name] = joint.Limit.Lower + rng.Float64()*(joint.Limit.Upper-joint.Limit.Lower)
+		}
+	}
+	return positions
+}
+
+// srdf is the part of the SRDF format WriteSRDF produces.
+type srdf struct {
+	XMLName  xml.Name            `xml:"robot"`
+	Name     string              `xml:"name,attr"`
+	Disabled []DisabledCollision `xml:"disable_collisions"`
+}
+
+// WriteSRDF encodes an SRDF document for the named robot listing disabled to
+// w, for MoveIt to load alongside the URDF.
+func WriteSRDF(w io.Writer, robot string, disabled []DisabledCollision) error {
+	output, err := xml.MarshalIndent(srdf{Name: robot, Disabled: disabled}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	if _, err := w.Write(output); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,228 @@
+package urdf
+
+import (
+	"fmt"
+
+	"github.com/nfranczak/urdf-simplifier/spatialmath"
+)
+
+// KinematicTree is an indexed view of the link/joint graph of a Robot. It is
+// a snapshot: rebuild it with NewKinematicTree after mutating the robot.
+type KinematicTree struct {
+	robot       *Robot
+	parentJoint map[string]*Joint
+	childJoints map[string][]*Joint
+
+	// Roots lists the links that are not the child of any joint, in document order.
+	Roots []string
+}
+
+// NewKinematicTree indexes the links and joints of robot.
+func NewKinematicTree(robot *Robot) *KinematicTree {
+	t := &KinematicTree{
+		robot:       robot,
+		parentJoint: make(map[string]*Joint),
+		childJoints: make(map[string][]*Joint),
+	}
+	for i := range robot.Joints {
+		joint := &robot.Joints[i]
+		if joint.Child != nil {
+			if _, ok := t.parentJoint[joint.Child.Link]; !ok {
+				t.parentJoint[joint.Child.Link] = joint
+			}
+		}
+		if joint.Parent != nil {
+			t.childJoints[joint.Parent.Link] = append(t.childJoints[joint.Parent.Link], joint)
+		}
+	}
+	for _, link := range robot.Links {
+		if _, ok := t.parentJoint[link.Name]; !ok {
+			t.Roots = append(t.Roots, link.Name)
+		}
+	}
+	return t
+}
+
+// Root returns the first root link, or "" if the robot has no links.
+func (t *KinematicTree) Root() string {
+	if len(t.Roots) == 0 {
+		return ""
+	}
+	return t.Roots[0]
+}
+
+// ParentJoint returns the joint attaching link to its parent, or nil for a root.
+func (t *KinematicTree) ParentJoint(link string) *Joint {
+	return t.parentJoint[link]
+}
+
+// Parent returns the name of link's parent link and whether it has one.
+func (t *KinematicTree) Parent(link string) (string, bool) {
+	joint := t.parentJoint[link]
+	if joint == nil || joint.Parent == nil {
+		return "", false
+	}
+	return joint.Parent.Link, true
+}
+
+// ChildJoints returns the joints whose parent is link, in document order.
+func (t *KinematicTree) ChildJoints(link string) []*Joint {
+	return t.childJoints[link]
+}
+
+// Children returns the names of link's child links, in document order.
+func (t *KinematicTree) Children(link string) []string {
+	var children []string
+	for _, joint := range t.childJoints[link] {
+		if joint.Child != nil {
+			children = append(children, joint.Child.Link)
+		}
+	}
+	return children
+}
+
+// Leaves returns the links with no children, in document order.
+func (t *KinematicTree) Leaves() []string {
+	var leaves []string
+	for _, link := range t.robot.Links {
+		if len(t.childJoints[link.Name]) == 0 {
+			leaves = append(leaves, link.Name)
+		}
+	}
+	return leaves
+}
+
+// DFS returns the links reachable from start in depth-first pre-order.
+func (t *KinematicTree) DFS(start string) []string {
+	var order []string
+	t.Walk(start, func(link string, depth int) bool {
+		order = append(order, link)
+		return true
+	})
+	return order
+}
+
+// Walk visits start and its descendants depth-first, calling fn with each
+// link and its depth below start. Returning false from fn skips the subtree
+// below that link. Each link is visited at most once, even in malformed
+// models with cycles.
+func (t *KinematicTree) Walk(start string, fn func(link string, depth int) bool) {
+	visited := make(map[string]bool)
+	var visit func(link string, depth int)
+	visit = func(link string, depth int) {
+		if visited[link] {
+			return
+		}
+		visited[link] = true
+		if !fn(link, depth) {
+			return
+		}
+		for _, child := range t.Children(link) {
+			visit(child, depth+1)
+		}
+	}
+	visit(start, 0)
+}
+
+// ZeroPoses returns the pose in root's frame of root and every link below
+// it, with every joint at zero.
+func (t *KinematicTree) ZeroPoses(root string) map[string]spatialmath.Pose {
+	return t.Poses(root, nil)
+}
+
+// Poses returns the pose in root's frame of root and every link below it,
+// with each joint at its position in positions, in radians or meters, and
+// joints not listed at zero. Floating and planar joints are always at zero.
+// Links below a joint whose origin or axis cannot be parsed are left out.
+func (t *KinematicTree) Poses(root string, positions map[string]float64) map[string]spatialmath.Pose {
+	poses := map[string]spatialmath.Pose{root: spatialmath.Identity()}
+	t.Walk(root, func(link string, depth int) bool {
+		pose, ok := poses[link]
+		if !ok {
+			return false
+		}
+		for _, joint := range t.childJoints[link] {
+			if joint.Child == nil {
+				continue
+			}
+			origin, err := joint.Origin.Pose()
+			if err != nil {
+				continue
+			}
+			motion, err := joint.Motion(positions[joint.Name])
+			if err != nil {
+				continue
+			}
+			poses[joint.Child.Link] = pose.Compose(origin).Compose(motion)
+		}
+		return true
+	})
+	return poses
+}
+
+// BFS returns the links reachable from start in breadth-first order.
+func (t *KinematicTree) BFS(start string) []string {
+	visited := map[string]bool{start: true}
+	order := []string{start}
+	for i := 0; i < len(order); i++ {
+		for _, child := range t.Children(order[i]) {
+			if !visited[child] {
+				visited[child] = true
+				order = append(order, child)
+			}
+		}
+	}
+	return order
+}
+
+// Depth returns the number of joints on the longest path from a root to a leaf.
+func (t *KinematicTree) Depth() int {
+	maxDepth := 0
+	for _, root := range t.Roots {
+		t.Walk(root, func(link string, depth int) bool {
+			if depth > maxDepth {
+				maxDepth = depth
+			}
+			return true
+		})
+	}
+	return maxDepth
+}
+
+// Ancestors returns the links above link, nearest first.
+func (t *KinematicTree) Ancestors(link string) []string {
+	var ancestors []string
+	seen := map[string]bool{link: true}
+	for current := link; ; {
+		parent, ok := t.Parent(current)
+		if !ok || seen[parent] {
+			return ancestors
+		}
+		seen[parent] = true
+		ancestors = append(ancestors, parent)
+		current = parent
+	}
+}
+
+// Chain returns the joints on the path from link from down to link to, in
+// order from base to tip. to must be from itself or one of its descendants.
+func (t *KinematicTree) Chain(from, to string) ([]*Joint, error) {
+	var chain []*Joint
+	seen := make(map[string]bool)
+	for current := to; current != from; {
+		if seen[current] {
+			return nil, fmt.Errorf("cycle detected at link %q", current)
+		}
+		seen[current] = true
+		joint := t.parentJoint[current]
+		if joint == nil || joint.Parent == nil {
+			return nil, fmt.Errorf("link %q is not a descendant of %q", to, from)
+		}
+		chain = append(chain, joint)
+		current = joint.Parent.Link
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain, nil
+}
@@ -0,0 +1,325 @@
+package urdf
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Severity classifies an Issue.
+type Severity int
+
+const (
+	// Warning marks issues that downstream tools usually tolerate.
+	Warning Severity = iota
+	// Error marks issues that make the model invalid.
+	Error
+	// Off turns a rule off in a Policy. No Issue has it.
+	Off
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Error:
+		return "error"
+	case Off:
+		return "off"
+	}
+	return "warning"
+}
+
+// ParseSeverity parses the name of a severity: "error", "warn" (or
+// "warning"), or "off".
+func ParseSeverity(s string) (Severity, error) {
+	switch s {
+	case "error":
+		return Error, nil
+	case "warn", "warning":
+		return Warning, nil
+	case "off":
+		return Off, nil
+	}
+	return 0, fmt.Errorf("unknown severity %q (want error, warn or off)", s)
+}
+
+// Issue is a problem found while validating a robot model.
+type Issue struct {
+	Severity Severity
+	// Rule names the check that found the issue; see Rules.
+	Rule    string
+	Message string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s [%s]", i.Severity, i.Message, i.Rule)
+}
+
+// Rule is a named check made by Validate or ValidateMeshes.
+type Rule struct {
+	Name string
+	// Severity is the severity of the rule's issues unless a Policy says
+	// otherwise.
+	Severity    Severity
+	Description string
+}
+
+// Rules lists every rule, in the order Validate first applies them.
+var Rules = []Rule{
+	{"robot-name", Warning, "the robot has a name"},
+	{"link-name", Error, "every link has a name, unique among links"},
+	{"inertia", Error, "inertia tensors are positive-definite and satisfy the triangle inequality"},
+	{"joint-name", Error, "every joint has a name, unique among joints"},
+	{"joint-type", Error, "joint types are ones the URDF specification defines"},
+	{"joint-origin", Error, "joint origins parse"},
+	{"joint-axis", Error, "joint axes parse, and are not zero on moving joints"},
+	{"limit-missing", Error, "revolute and prismatic joints have a <limit>"},
+	{"limit-invalid", Error, "limits are not negative and the lower is not above the upper"},
+	{"limit-range", Warning, "revolute and prismatic joints can move: the lower limit is below the upper"},
+	{"limit-velocity", Warning, "revolute and prismatic joints have a velocity limit"},
+	{"joint-links", Error, "joints name a parent and child link the robot has, and no link is the child of two joints"},
+	{"mimic", Error, "<mimic> names another joint the robot has, with numeric multiplier and offset"},
+	{"mimic-fixed", Warning, "fixed joints have no <mimic>, which would have no effect"},
+	{"cycle", Error, "the joints form a tree, without cycles"},
+	{"single-root", Warning, "the robot has exactly one root link"},
+	{"orphan-link", Warning, "every link is referenced by a joint"},
+	{"mesh", Error, "every mesh has a filename that can be opened"},
+}
+
+// ruleSeverity returns the default severity of the named rule.
+func ruleSeverity(name string) Severity {
+	for _, r := range Rules {
+		if r.Name == name {
+			return r.Severity
+		}
+	}
+	panic("urdf: unknown rule " + name)
+}
+
+// Policy overrides the severity of rules by name, as a team's lint
+// configuration does.
+type Policy map[string]Severity
+
+// Check returns an error naming the first rule in p that does not exist.
+func (p Policy) Check() error {
+	for _, name := range slices.Sorted(maps.Keys(p)) {
+		if !slices.ContainsFunc(Rules, func(r Rule) bool { return r.Name == name }) {
+			return fmt.Errorf("unknown lint rule %q", name)
+		}
+	}
+	return nil
+}
+
+// Apply returns issues with the severities of p, leaving out those of rules
+// p turns off.
+func (p Policy) Apply(issues []Issue) []Issue {
+	var kept []Issue
+	for _, issue := range issues {
+		if s, ok := p[issue.Rule]; ok {
+			issue.Severity = s
+		}
+		if issue.Severity != Off {
+			kept = append(kept, issue)
+		}
+	}
+	return kept
+}
+
+// jointTypes lists the joint types defined by the URDF specification.
+var jointTypes = map[string]bool{
+	"revolute":   true,
+	"continuous": true,
+	"prismatic":  true,
+	"fixed":      true,
+	"floating":   true,
+	"planar":     true,
+}
+
+// Validate runs structural checks against robot and returns the issues found,
+// in document order.
+func Validate(robot *Robot) []Issue {
+	var issues []Issue
+	issuef := func(rule, format string, args ...any) {
+		issues = append(issues, Issue{ruleSeverity(rule), rule, fmt.Sprintf(format, args...)})
+	}
+
+	if robot.Name == "" {
+		issuef("robot-name", "robot has no name")
+	}
+
+	links := make(map[string]bool)
+	for _, link := range robot.Links {
+		if link.Name == "" {
+			issuef("link-name", "link with no name")
+			continue
+		}
+		if links[link.Name] {
+			issuef("link-name", "duplicate link name %q", link.Name)
+		}
+		links[link.Name] = true
+		if link.Inertial != nil {
+			if err := inertiaError(link.Inertial); err != nil {
+				issuef("inertia", "link %q has an invalid inertia tensor: %v", link.Name, err)
+			}
+		}
+	}
+
+	joints := make(map[string]bool)
+	parentOf := make(map[string]string)
+	for _, joint := range robot.Joints {
+		if joint.Name == "" {
+			issuef("joint-name", "joint with no name")
+		} else if joints[joint.Name] {
+			issuef("joint-name", "duplicate joint name %q", joint.Name)
+		}
+		joints[joint.Name] = true
+
+		if !jointTypes[joint.Type] {
+			issuef("joint-type", "joint %q has unknown type %q", joint.Name, joint.Type)
+		}
+		if _, err := joint.Origin.Pose(); err != nil {
+			issuef("joint-origin", "joint %q has an invalid origin: %v", joint.Name, err)
+		}
+		if axis, err := joint.AxisVector(); err != nil {
+			issuef("joint-axis", "joint %q has an invalid axis: %v", joint.Name, err)
+		} else if axis.Norm() == 0 && joint.Type != "fixed" {
+			issuef("joint-axis", "joint %q has a zero axis", joint.Name)
+		}
+		validateLimit(&joint, issuef)
+		if joint.Parent == nil || joint.Parent.Link == "" {
+			issuef("joint-links", "joint %q has no parent link", joint.Name)
+		} else if !links[joint.Parent.Link] {
+			issuef("joint-links", "joint %q references missing parent link %q", joint.Name, joint.Parent.Link)
+		}
+		if joint.Child == nil || joint.Child.Link == "" {
+			issuef("joint-links", "joint %q has no child link", joint.Name)
+			continue
+		} else if !links[joint.Child.Link] {
+			issuef("joint-links", "joint %q references missing child link %q", joint.Name, joint.Child.Link)
+		}
+		if other, ok := parentOf[joint.Child.Link]; ok {
+			issuef("joint-links", "link %q is the child of both joint %q and joint %q", joint.Child.Link, other, joint.Name)
+		} else {
+			parentOf[joint.Child.Link] = joint.Name
+		}
+	}
+
+	for _, joint := range robot.Joints {
+		validateMimic(&joint, joints, issuef)
+	}
+
+	tree := NewKinematicTree(robot)
+	if len(robot.Links) > 0 && len(tree.Roots) == 0 {
+		issuef("cycle", "robot has no root link; the joints form a cycle")
+	}
+	if len(tree.Roots) > 1 {
+		roots := make([]string, len(tree.Roots))
+		for i, root := range tree.Roots {
+			roots[i] = strconv.Quote(root)
+		}
+		issuef("single-root", "robot has %d root links (%s); expected exactly one", len(tree.Roots), strings.Join(roots, ", "))
+	}
+	if d := DetachedLinks(robot); d != nil {
+		for _, link := range d.Orphans {
+			issuef("orphan-link", "link %q is not referenced by any joint", link)
+		}
+	}
+	for _, link := range robot.Links {
+		if inCycle(tree, link.Name) {
+			issuef("cycle", "link %q is part of a joint cycle", link.Name)
+		}
+	}
+
+	return issues
+}
+
+// validateLimit checks the <limit> element of a joint. The specification
+// requires one on revolute and prismatic joints; on other types it is allowed
+// but its range is ignored.
+func validateLimit(joint *Joint, issuef func(rule, format string, args ...any)) {
+	limit := joint.Limit
+	if limit == nil {
+		if joint.Type == "revolute" || joint.Type == "prismatic" {
+			issuef("limit-missing", "%s joint %q has no <limit> element", joint.Type, joint.Name)
+		}
+		return
+	}
+	if limit.Effort < 0 {
+		issuef("limit-invalid", "joint %q has negative effort limit %g", joint.Name, limit.Effort)
+	}
+	if limit.Velocity < 0 {
+		issuef("limit-invalid", "joint %q has negative velocity limit %g", joint.Name, limit.Velocity)
+	}
+	if joint.Type != "revolute" && joint.Type != "prismatic" {
+		return
+	}
+	switch {
+	case limit.Lower > limit.Upper:
+		issuef("limit-invalid", "joint %q has lower limit %g above upper limit %g", joint.Name, limit.Lower, limit.Upper)
+	case limit.Lower == limit.Upper:
+		issuef("limit-range", "joint %q has an empty range (lower = upper = %g)", joint.Name, limit.Lower)
+	}
+	if limit.Velocity == 0 {
+		issuef("limit-velocity", "joint %q has a zero velocity limit", joint.Name)
+	}
+}
+
+// validateMimic checks the <mimic> element of a joint against the names of
+// the robot's joints.
+func validateMimic(joint *Joint, joints map[string]bool, issuef func(rule, format string, args ...any)) {
+	mimic := joint.Mimic
+	if mimic == nil {
+		return
+	}
+	switch {
+	case mimic.Joint == "":
+		issuef("mimic", "joint %q has a <mimic> with no joint", joint.Name)
+	case mimic.Joint == joint.Name:
+		issuef("mimic", "joint %q mimics itself", joint.Name)
+	case !joints[mimic.Joint]:
+		issuef("mimic", "joint %q mimics missing joint %q", joint.Name, mimic.Joint)
+	}
+	for _, attr := range [][2]string{{"multiplier", mimic.Multiplier}, {"offset", mimic.Offset}} {
+		if _, err := strconv.ParseFloat(attr[1], 64); attr[1] != "" && err != nil {
+			issuef("mimic", "joint %q has an invalid mimic %s %q", joint.Name, attr[0], attr[1])
+		}
+	}
+	if joint.Type == "fixed" {
+		issuef("mimic-fixed", "fixed joint %q has a <mimic>, which has no effect", joint.Name)
+	}
+}
+
+// ValidateMeshes checks that every mesh the robot references can be opened
+// through resolver. Each file is reported once, however often it is used.
+func ValidateMeshes(robot *Robot, resolver MeshResolver) []Issue {
+	var issues []Issue
+	checked := make(map[string]bool)
+	for _, ref := range robot.MeshRefs() {
+		if checked[ref.Filename] {
+			continue
+		}
+		checked[ref.Filename] = true
+		if ref.Filename == "" {
+			issues = append(issues, Issue{Error, "mesh", "mesh with no filename"})
+			continue
+		}
+		f, err := resolver.Open(ref.Filename)
+		if err != nil {
+			issues = append(issues, Issue{Error, "mesh", fmt.Sprintf("mesh %s cannot be opened: %v", ref.Filename, err)})
+			continue
+		}
+		f.Close()
+	}
+	return issues
+}
+
+// inCycle reports whether following parent joints up from link leads back to it.
+func inCycle(tree *KinematicTree, link string) bool {
+	ancestors := tree.Ancestors(link)
+	if len(ancestors) == 0 {
+		return false
+	}
+	parent, ok := tree.Parent(ancestors[len(ancestors)-1])
+	return ok && parent == link
+}
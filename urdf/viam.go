@@ -0,0 +1,512 @@
+package urdf
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/nfranczak/urdf-simplifier/spatialmath"
+)
+
+// ViamWorld is the parent Viam gives frames attached to nothing else.
+const ViamWorld = "world"
+
+// ViamVector is a translation, in millimeters, or a direction in a Viam
+// config.
+type ViamVector struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Z float64 `json:"z"`
+}
+
+// viamTranslation converts a URDF translation in meters to millimeters,
+// rounded to the nanometer so that float32 mesh noise does not show.
+func viamTranslation(v spatialmath.Vec3) ViamVector {
+	mm := func(m float64) float64 { return math.Round(m*1e6)/1e3 + 0 }
+	return ViamVector{X: mm(v.X), Y: mm(v.Y), Z: mm(v.Z)}
+}
+
+// ViamOrientation is an orientation in a Viam config: Type names the
+// representation and Value holds its fields.
+type ViamOrientation struct {
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+}
+
+// ViamEulerAngles is the value of a "euler_angles" orientation: the same
+// roll, pitch and yaw in radians as a URDF rpy.
+type ViamEulerAngles struct {
+	Roll  float64 `json:"roll"`
+	Pitch float64 `json:"pitch"`
+	Yaw   float64 `json:"yaw"`
+}
+
+// ViamOrientationType names a representation of orientations in Viam
+// configs.
+type ViamOrientationType string
+
+const (
+	// ViamEulerAnglesType writes orientations as ViamEulerAngles.
+	ViamEulerAnglesType ViamOrientationType = "euler_angles"
+	// ViamOVDegreesType and ViamOVRadiansType write them as
+	// ViamOrientationVector, with Theta in degrees or radians.
+	ViamOVDegreesType ViamOrientationType = "ov_degrees"
+	ViamOVRadiansType ViamOrientationType = "ov_radians"
+	// ViamQuaternionType writes them as ViamQuaternion.
+	ViamQuaternionType ViamOrientationType = "quaternion"
+)
+
+// ViamOrientationVector is the value of an "ov_degrees" or "ov_radians"
+// orientation: the direction the frame's z axis points in, and Theta, the
+// rotation of the frame about it.
+type ViamOrientationVector struct {
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+	Z     float64 `json:"z"`
+	Theta float64 `json:"th"`
+}
+
+// ViamQuaternion is the value of a "quaternion" orientation: the unit
+// quaternion of the rotation, with W not negative.
+type ViamQuaternion struct {
+	W float64 `json:"W"`
+	X float64 `json:"X"`
+	Y float64 `json:"Y"`
+	Z float64 `json:"Z"`
+}
+
+// viamPoleEpsilon is how close to 1 the z component of an orientation
+// vector has to be for Viam to treat it as pointing straight up or down,
+// where the vector's longitude is undefined and taken to be zero.
+const viamPoleEpsilon = 1e-4
+
+// ViamOptions says how the Viam exports write what they convert.
+type ViamOptions struct {
+	// Orientation is the representation orientations are given in, by
+	// default ViamEulerAnglesType.
+	Orientation ViamOrientationType
+}
+
+// Validate reports an unknown orientation type.
+func (o ViamOptions) Validate() error {
+	switch o.Orientation {
+	case "", ViamEulerAnglesType, ViamOVDegreesType, ViamOVRadiansType, ViamQuaternionType:
+		return nil
+	}
+	return fmt.Errorf("unknown Viam orientation type %q (want %s, %s, %s or %s)", o.Orientation, ViamEulerAnglesType, ViamOVDegreesType, ViamOVRadiansType, ViamQuaternionType)
+}
+
+// orientation returns q in the representation o selects.
+func (o ViamOptions) orientation(q spatialmath.Quaternion) ViamOrientation {
+	switch o.Orientation {
+	case ViamOVDegreesType, ViamOVRadiansType:
+		ov := orientationVector(q)
+		if o.Orientation == ViamOVDegreesType {
+			ov.Theta = degrees(ov.Theta)
+		}
+		return ViamOrientation{Type: string(o.Orientation), Value: ov}
+	case ViamQuaternionType:
+		return ViamOrientation{Type: string(o.Orientation), Value: viamQuaternion(q)}
+	}
+	r := q.RPY()
+	return ViamOrientation{Type: string(ViamEulerAnglesType), Value: ViamEulerAngles{Roll: r.Roll + 0, Pitch: r.Pitch + 0, Yaw: r.Yaw + 0}}
+}
+
+// orientationVector converts q to the orientation vector Viam decodes as
+// the rotation Rz(lon)·Ry(lat)·Rz(theta), where the vector's latitude and
+// longitude are lat and lon, with Theta in radians. Float noise below
+// 1e-12 is rounded away so that right angles come out exact.
+func orientationVector(q spatialmath.Quaternion) ViamOrientationVector {
+	m := q.Matrix()
+	clean := func(v float64) float64 { return math.Round(v*1e12)/1e12 + 0 }
+	ov := ViamOrientationVector{X: clean(m[0][2]), Y: clean(m[1][2]), Z: clean(m[2][2])}
+	if 1-math.Abs(ov.Z) > viamPoleEpsilon {
+		// The bottom row of the rotation is (-sin lat cos theta, sin lat
+		// sin theta, cos lat).
+		ov.Theta = math.Atan2(m[2][1], -m[2][0])
+	} else {
+		// Viam takes a vector this close to a pole to have no longitude,
+		// leaving Rz(theta) on its own, or Ry(pi)·Rz(theta) below.
+		ov.Theta = math.Atan2(m[1][0], m[1][1])
+	}
+	ov.Theta = clean(ov.Theta)
+	return ov
+}
+
+// viamQuaternion converts q, which is exact where angles would lose
+// precision near a pitch of ±90°, to a ViamQuaternion, at full precision.
+// Components within 1e-12 of zero are float noise and written as zero.
+func viamQuaternion(q spatialmath.Quaternion) ViamQuaternion {
+	q = q.Normalize()
+	clean := func(v float64) float64 {
+		if math.Abs(v) < 1e-12 {
+			return 0
+		}
+		return v
+	}
+	return ViamQuaternion{W: clean(q.W), X: clean(q.X), Y: clean(q.Y), Z: clean(q.Z)}
+}
+
+// isIdentity reports whether q is the identity rotation, to within float
+// noise.
+func isIdentity(q spatialmath.Quaternion) bool {
+	return 1-math.Abs(q.W) < 1e-12
+}
+
+// ViamGeometry is a collision geometry as Viam's machine configs,
+// kinematics files and spatialmath accept it, with lengths in millimeters.
+// A box has sides X, Y and Z; a capsule has radius R and length L from end
+// to end, along its z axis. Translation and Orientation place it in its
+// frame. Label is the name of the collision, which Viam uses for it in
+// collision reports.
+type ViamGeometry struct {
+	Type        string           `json:"type"`
+	X           float64          `json:"x,omitempty"`
+	Y           float64          `json:"y,omitempty"`
+	Z           float64          `json:"z,omitempty"`
+	R           float64          `json:"r,omitempty"`
+	L           float64          `json:"l,omitempty"`
+	Translation ViamVector       `json:"translation"`
+	Orientation *ViamOrientation `json:"orientation_offset,omitempty"`
+	Label       string           `json:"label,omitempty"`
+}
+
+// geometry converts a collision box or capsule of link to a Viam geometry at
+// pose, labeled with the collision's name, or the link's if it has none. It
+// returns nil if the collision is neither, since Viam configs take
+// primitives only.
+func (o ViamOptions) geometry(link *Link, c *Collision, pose spatialmath.Pose) (*ViamGeometry, error) {
+	var g *ViamGeometry
+	switch {
+	case c.Geometry == nil:
+		return nil, nil
+	case c.Geometry.Box != nil:
+		size, err := spatialmath.ParseVec3(c.Geometry.Box.Size)
+		if err != nil {
+			return nil, fmt.Errorf("box size: %w", err)
+		}
+		s := viamTranslation(size)
+		g = &ViamGeometry{Type: "box", X: s.X, Y: s.Y, Z: s.Z}
+	case c.Geometry.Capsule != nil:
+		capsule := c.Geometry.Capsule
+		s := viamTranslation(spatialmath.Vec3{X: capsule.Radius, Y: capsule.Length + 2*capsule.Radius})
+		g = &ViamGeometry{Type: "capsule", R: s.X, L: s.Y}
+	default:
+		return nil, nil
+	}
+	if g.Label = c.Name; g.Label == "" {
+		g.Label = link.Name
+	}
+	g.Translation = viamTranslation(pose.Translation)
+	if !isIdentity(pose.Rotation) {
+		orientation := o.orientation(pose.Rotation)
+		g.Orientation = &orientation
+	}
+	return g, nil
+}
+
+// linkGeometry returns the first collision box or capsule of link as a Viam
+// geometry in the link's frame, or nil if it has none. Viam frames hold one
+// geometry each, so other primitives, and collisions that are neither, are
+// returned as warnings.
+func (o ViamOptions) linkGeometry(link *Link) (*ViamGeometry, []string, error) {
+	var first *ViamGeometry
+	var warnings []string
+	primitives := 0
+	for j := range link.Collision {
+		c := &link.Collision[j]
+		pose, err := c.Origin.Pose()
+		if err != nil {
+			return nil, nil, fmt.Errorf("link %s: collision origin: %w", link.Name, err)
+		}
+		g, err := o.geometry(link, c, pose)
+		if err != nil {
+			return nil, nil, fmt.Errorf("link %s: %w", link.Name, err)
+		}
+		if g == nil {
+			warnings = append(warnings, fmt.Sprintf("link %s has a collision that is neither a box nor a capsule, which Viam cannot take", link.Name))
+			continue
+		}
+		if primitives++; primitives == 1 {
+			first = g
+		}
+	}
+	if primitives > 1 {
+		warnings = append(warnings, fmt.Sprintf("link %s has %d collision primitives, but a Viam frame holds one: only the first is kept", link.Name, primitives))
+	}
+	return first, warnings, nil
+}
+
+// ViamFrame is the "frame" of a component in a Viam machine config: its
+// pose in its parent's frame, and its geometry.
+type ViamFrame struct {
+	Parent      string          `json:"parent"`
+	Translation ViamVector      `json:"translation"`
+	Orientation ViamOrientation `json:"orientation"`
+	Geometry    *ViamGeometry   `json:"geometry,omitempty"`
+}
+
+// ViamLinkFrame is the Viam frame of one link.
+type ViamLinkFrame struct {
+	Name  string    `json:"name"`
+	Frame ViamFrame `json:"frame"`
+}
+
+// ViamFrames returns a Viam frame for each link of robot, in document order:
+// the pose of the link at the zero position of its parent joint, in the
+// frame of its parent link, or of ViamWorld for a root link, with its
+// collision box or capsule as the geometry. A Viam frame holds one
+// geometry, so a link with several gets its first, and one whose collision
+// is a mesh gets none; both are returned as warnings.
+func ViamFrames(robot *Robot, opts ViamOptions) ([]ViamLinkFrame, []string, error) {
+	var frames []ViamLinkFrame
+	var warnings []string
+	for i := range robot.Links {
+		link := &robot.Links[i]
+		frame := ViamFrame{Parent: ViamWorld, Orientation: opts.orientation(spatialmath.IdentityQuaternion())}
+		if joint := robot.ParentJoint(link.Name); joint != nil && joint.Parent != nil {
+			pose, err := joint.Origin.Pose()
+			if err != nil {
+				return nil, nil, fmt.Errorf("joint %s: origin: %w", joint.Name, err)
+			}
+			frame.Parent = joint.Parent.Link
+			frame.Translation = viamTranslation(pose.Translation)
+			frame.Orientation = opts.orientation(pose.Rotation)
+		}
+
+		g, warns, err := opts.linkGeometry(link)
+		if err != nil {
+			return nil, nil, err
+		}
+		frame.Geometry = g
+		warnings = append(warnings, warns...)
+		frames = append(frames, ViamLinkFrame{Name: link.Name, Frame: frame})
+	}
+	return frames, warnings, nil
+}
+
+// writeViamJSON writes v to w as JSON indented by two spaces, like the URDF
+// output.
+func writeViamJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// WriteViamFrames writes frames to w as an indented JSON array.
+func WriteViamFrames(w io.Writer, frames []ViamLinkFrame) error {
+	return writeViamJSON(w, frames)
+}
+
+// ViamLinkGeometries is the collision geometry of one link.
+type ViamLinkGeometries struct {
+	Link       string         `json:"link"`
+	Geometries []ViamGeometry `json:"geometries"`
+}
+
+// ViamGeometries returns every collision box and capsule of each link of
+// robot that has any, in document order, labeled with their collisions'
+// names. With inRoot set they are placed in the frame of the root link with
+// every joint at zero, ready to use as static obstacles; otherwise each is
+// in its link's frame, as a component geometry. Collisions that are neither
+// are left out and returned as warnings.
+func ViamGeometries(robot *Robot, inRoot bool, opts ViamOptions) ([]ViamLinkGeometries, []string, error) {
+	tree := NewKinematicTree(robot)
+	poses := make(map[string]spatialmath.Pose)
+	if inRoot {
+		for _, root := range tree.Roots {
+			for link, pose := range tree.ZeroPoses(root) {
+				poses[link] = pose
+			}
+		}
+	}
+
+	var out []ViamLinkGeometries
+	var warnings []string
+	for i := range robot.Links {
+		link := &robot.Links[i]
+		linkPose, ok := poses[link.Name]
+		if !ok {
+			linkPose = spatialmath.Identity()
+		}
+		var geoms []ViamGeometry
+		for j := range link.Collision {
+			c := &link.Collision[j]
+			pose, err := c.Origin.Pose()
+			if err != nil {
+				return nil, nil, fmt.Errorf("link %s: collision origin: %w", link.Name, err)
+			}
+			g, err := opts.geometry(link, c, linkPose.Compose(pose))
+			if err != nil {
+				return nil, nil, fmt.Errorf("link %s: %w", link.Name, err)
+			}
+			if g == nil {
+				warnings = append(warnings, fmt.Sprintf("link %s has a collision that is neither a box nor a capsule, which Viam cannot take", link.Name))
+				continue
+			}
+			geoms = append(geoms, *g)
+		}
+		if len(geoms) > 0 {
+			out = append(out, ViamLinkGeometries{Link: link.Name, Geometries: geoms})
+		}
+	}
+	return out, warnings, nil
+}
+
+// WriteViamGeometries writes geometries to w as an indented JSON array of
+// spatialmath geometry configs.
+func WriteViamGeometries(w io.Writer, geometries []ViamGeometry) error {
+	return writeViamJSON(w, geometries)
+}
+
+// ViamModel is a Viam kinematics file, the JSON model arm components load.
+// It describes the robot as frames of two kinds: links, fixed transforms
+// from their parent that may carry a geometry, and joints, which move along
+// or about an axis in their parent's frame.
+type ViamModel struct {
+	Name         string      `json:"name"`
+	KinParamType string      `json:"kinematic_param_type"`
+	Links        []ViamLink  `json:"links"`
+	Joints       []ViamJoint `json:"joints"`
+}
+
+// ViamLink is a fixed frame of a ViamModel.
+type ViamLink struct {
+	ID          string          `json:"id"`
+	Parent      string          `json:"parent"`
+	Translation ViamVector      `json:"translation"`
+	Orientation ViamOrientation `json:"orientation"`
+	Geometry    *ViamGeometry   `json:"geometry,omitempty"`
+}
+
+// ViamJoint is a moving frame of a ViamModel. Min and Max are in degrees
+// for revolute joints and millimeters for prismatic ones.
+type ViamJoint struct {
+	ID     string     `json:"id"`
+	Type   string     `json:"type"`
+	Parent string     `json:"parent"`
+	Axis   ViamVector `json:"axis"`
+	Min    float64    `json:"min"`
+	Max    float64    `json:"max"`
+}
+
+// ViamKinematics converts robot to a Viam kinematics model. Links are
+// visited depth-first from the root. A link attached by a fixed joint
+// becomes a Viam link at the joint's origin. One attached by a moving joint
+// becomes three frames, since Viam joints have no origin of their own: a
+// link named <joint>_origin at the joint's origin, the joint itself, and the
+// link, carrying its geometry. Continuous joints become revolute joints
+// limited to a full turn either way, and mimic joints become independent
+// ones, with a warning. Geometries are taken as ViamFrames takes them, with
+// the same warnings.
+func ViamKinematics(robot *Robot, opts ViamOptions) (*ViamModel, []string, error) {
+	tree := NewKinematicTree(robot)
+	model := &ViamModel{Name: robot.Name, KinParamType: "SVA", Links: []ViamLink{}, Joints: []ViamJoint{}}
+	var warnings []string
+	names := make(map[string]string)
+	claim := func(name, what string) error {
+		if other, ok := names[name]; ok {
+			return fmt.Errorf("%s %s has the same name as %s, but Viam frames share one namespace", what, name, other)
+		}
+		names[name] = what + " " + name
+		return nil
+	}
+	for _, link := range robot.Links {
+		if err := claim(link.Name, "link"); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	identity := opts.orientation(spatialmath.IdentityQuaternion())
+	for _, root := range tree.Roots {
+		for _, name := range tree.DFS(root) {
+			link := robot.FindLink(name)
+			out := ViamLink{ID: name, Parent: ViamWorld, Orientation: identity}
+			g, warns, err := opts.linkGeometry(link)
+			if err != nil {
+				return nil, nil, err
+			}
+			out.Geometry = g
+			warnings = append(warnings, warns...)
+
+			joint := tree.ParentJoint(name)
+			if joint == nil || joint.Parent == nil {
+				model.Links = append(model.Links, out)
+				continue
+			}
+			pose, err := joint.Origin.Pose()
+			if err != nil {
+				return nil, nil, fmt.Errorf("joint %s: origin: %w", joint.Name, err)
+			}
+			if joint.Type == "fixed" {
+				out.Parent = joint.Parent.Link
+				out.Translation, out.Orientation = viamTranslation(pose.Translation), opts.orientation(pose.Rotation)
+				model.Links = append(model.Links, out)
+				continue
+			}
+
+			j, err := viamJoint(joint)
+			if err != nil {
+				return nil, nil, err
+			}
+			origin := joint.Name + "_origin"
+			if err := claim(j.ID, "joint"); err != nil {
+				return nil, nil, err
+			}
+			if err := claim(origin, "the origin of joint"); err != nil {
+				return nil, nil, err
+			}
+			j.Parent = origin
+			if joint.Mimic != nil {
+				warnings = append(warnings, fmt.Sprintf("joint %s mimics %s, which Viam kinematics cannot express: it becomes an independent joint", joint.Name, joint.Mimic.Joint))
+			}
+			model.Links = append(model.Links, ViamLink{ID: origin, Parent: joint.Parent.Link,
+				Translation: viamTranslation(pose.Translation), Orientation: opts.orientation(pose.Rotation)})
+			model.Joints = append(model.Joints, j)
+			out.Parent = j.ID
+			model.Links = append(model.Links, out)
+		}
+	}
+	return model, warnings, nil
+}
+
+// viamJoint converts the motion of a moving joint to a Viam joint, without
+// its parent.
+func viamJoint(joint *Joint) (ViamJoint, error) {
+	axis, err := joint.AxisVector()
+	if err != nil {
+		return ViamJoint{}, fmt.Errorf("joint %s: axis: %w", joint.Name, err)
+	}
+	if axis.Norm() == 0 {
+		return ViamJoint{}, fmt.Errorf("joint %s has a zero axis", joint.Name)
+	}
+	axis = axis.Normalize()
+	j := ViamJoint{ID: joint.Name, Axis: ViamVector{X: axis.X + 0, Y: axis.Y + 0, Z: axis.Z + 0}}
+	lower, upper := 0.0, 0.0
+	if joint.Limit != nil {
+		lower, upper = joint.Limit.Lower, joint.Limit.Upper
+	}
+	switch joint.Type {
+	case "revolute":
+		j.Type, j.Min, j.Max = "revolute", degrees(lower), degrees(upper)
+	case "continuous":
+		j.Type, j.Min, j.Max = "revolute", -360, 360
+	case "prismatic":
+		j.Type, j.Min, j.Max = "prismatic", viamTranslation(spatialmath.Vec3{X: lower}).X, viamTranslation(spatialmath.Vec3{X: upper}).X
+	default:
+		return ViamJoint{}, fmt.Errorf("joint %s is %s, which a Viam kinematics model cannot express", joint.Name, joint.Type)
+	}
+	return j, nil
+}
+
+// degrees converts radians to degrees, rounded to a millionth of a degree
+// so that limits written as multiples of pi come out whole.
+func degrees(rad float64) float64 {
+	return math.Round(rad*180/math.Pi*1e6)/1e6 + 0
+}
+
+// WriteViamKinematics writes model to w as an indented JSON kinematics file.
+func WriteViamKinematics(w io.Writer, model *ViamModel) error {
+	return writeViamJSON(w, model)
+}
@@ -0,0 +1,156 @@
+package urdf
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+
+	"github.com/nfranczak/urdf-simplifier/spatialmath"
+)
+
+// maxDeviation is how far the end link may move, in meters, between the
+// original and simplified robot at the same joint positions before Simplify
+// warns about it.
+const maxDeviation = 1e-6
+
+// Workspace approximates where the end link of a robot can be, from random
+// configurations within the joint limits.
+type Workspace struct {
+	// Link is the end link, and Frame the link in whose frame its positions
+	// are given.
+	Link    string `json:"link"`
+	Frame   string `json:"frame"`
+	Samples int    `json:"samples"`
+	// Reach is the largest distance of the end link's origin from Frame's.
+	Reach float64 `json:"reach"`
+	// Min and Max bound the end link's origins.
+	Min [3]float64 `json:"min"`
+	Max [3]float64 `json:"max"`
+	// Deviation is the largest distance between the end link in the original
+	// and simplified robot at the same joint positions.
+	Deviation float64 `json:"deviation"`
+}
+
+// Volume returns the volume of the box between Min and Max, in m³.
+func (w *Workspace) Volume() float64 {
+	return (w.Max[0] - w.Min[0]) * (w.Max[1] - w.Min[1]) * (w.Max[2] - w.Min[2])
+}
+
+// endPose returns the pose of link in frame's frame with each joint at its
+// position in positions, or false if link is not connected to frame.
+func endPose(tree *KinematicTree, frame, link string, positions map[string]float64) (spatialmath.Pose, bool) {
+	root := frame
+	if ancestors := tree.Ancestors(frame); len(ancestors) > 0 {
+		root = ancestors[len(ancestors)-1]
+	}
+	poses := tree.Poses(root, positions)
+	end, ok := poses[link]
+	if !ok {
+		return spatialmath.Pose{}, false
+	}
+	return poses[frame].Inverse().Compose(end), true
+}
+
+// sampleWorkspace samples the joint space of the simplified robot, moving
+// the same joints of the original robot alongside it, and measures the
+// workspace of the simplified robot's end link in its root's frame. Joints
+// removed by simplification stay at zero in the original. Sampling is
+// seeded, so the result depends only on the robots.
+func sampleWorkspace(robot, original *Robot, samples int) (*Workspace, error) {
+	link, err := endLink(robot)
+	if err != nil {
+		return nil, err
+	}
+	tree, before := NewKinematicTree(robot), NewKinematicTree(original)
+	w := &Workspace{Link: link, Frame: tree.Root(), Samples: samples}
+	for i := range 3 {
+		w.Min[i], w.Max[i] = math.Inf(1), math.Inf(-1)
+	}
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	for range samples {
+		positions := samplePositions(robot, rng)
+		pose, ok := endPose(tree, w.Frame, link, positions)
+		if !ok {
+			return nil, fmt.Errorf("the pose of %s cannot be computed", link)
+		}
+		p := pose.Translation
+		w.Reach = max(w.Reach, p.Norm())
+		for i, v := range [3]float64{p.X, p.Y, p.Z} {
+			w.Min[i], w.Max[i] = min(w.Min[i], v), max(w.Max[i], v)
+		}
+		if was, ok := endPose(before, w.Frame, link, positions); ok {
+			w.Deviation = max(w.Deviation, was.Translation.Sub(p).Norm())
+		}
+	}
+	return w, nil
+}
+
+// checkWorkspace reports the workspace of the simplified robot and warns if
+// its end link no longer follows the original's, which means filtering
+// changed the kinematics.
+func checkWorkspace(robot, original *Robot, opts Options, report *Report) {
+	w, err := sampleWorkspace(robot, original, opts.WorkspaceSamples)
+	if err != nil {
+		opts.logger().Warn("could not estimate the workspace", "error", err)
+		report.Warnings = append(report.Warnings, fmt.Sprintf("could not estimate the workspace: %v", err))
+		return
+	}
+	report.Workspace = w
+	if w.Deviation > maxDeviation {
+		opts.logger().Warn("end link moved by simplification", "link", w.Link, "deviation", w.Deviation)
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%s is up to %.1f mm from where the original robot puts it at the same joint positions", w.Link, w.Deviation*1000))
+	}
+	opts.logger().Debug("estimated workspace", "link", w.Link, "reach", w.Reach, "samples", w.Samples)
+}
+
+// KinematicsDiff is how far apart the end link of two robots ends up at the
+// same joint positions.
+type KinematicsDiff struct {
+	// Link is the end link, and Frame the link in whose frame its poses are
+	// compared.
+	Link    string `json:"link"`
+	Frame   string `json:"frame"`
+	Samples int    `json:"samples"`
+	// Distance is the largest distance between the end link's origins in
+	// the two robots, in meters, and Angle the largest angle between its
+	// orientations, in radians.
+	Distance float64 `json:"distance"`
+	Angle    float64 `json:"angle"`
+}
+
+// CompareKinematics samples the joint space of robot, moving the same joints
+// of reference alongside it, and measures how far apart the end link of
+// robot is in the two, in the frame of robot's root link. Joints of
+// reference that robot lacks stay at zero. Sampling is seeded like
+// sampleWorkspace's.
+func CompareKinematics(robot, reference *Robot, samples int) (*KinematicsDiff, error) {
+	link, err := endLink(robot)
+	if err != nil {
+		return nil, err
+	}
+	tree, ref := NewKinematicTree(robot), NewKinematicTree(reference)
+	d := &KinematicsDiff{Link: link, Frame: tree.Root(), Samples: samples}
+	for _, name := range []string{d.Frame, d.Link} {
+		if reference.FindLink(name) == nil {
+			return nil, fmt.Errorf("the reference robot has no link %q", name)
+		}
+	}
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	for range samples {
+		positions := samplePositions(robot, rng)
+		pose, ok := endPose(tree, d.Frame, link, positions)
+		if !ok {
+			return nil, fmt.Errorf("the pose of %s cannot be computed", link)
+		}
+		want, ok := endPose(ref, d.Frame, link, positions)
+		if !ok {
+			return nil, fmt.Errorf("%s is not connected to %s in the reference robot", link, d.Frame)
+		}
+		d.Distance = max(d.Distance, want.Translation.Sub(pose.Translation).Norm())
+		q := want.Rotation.Conjugate().Mul(pose.Rotation)
+		d.Angle = max(d.Angle, 2*math.Atan2(math.Sqrt(q.X*q.X+q.Y*q.Y+q.Z*q.Z), math.Abs(q.W)))
+	}
+	return d, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nfranczak/urdf-simplifier/urdf"
+)
+
+func runValidate(args []string) int {
+	var skipMeshes, strict, listRules bool
+	var configPath string
+	var packageMap, rules []string
+	var xf xacroFlags
+	fs := newFlagSet("validate", "urdf-simplifier validate [flags] <robot.urdf>",
+		"Checks a URDF for structural problems (names, references, joint limits,\n"+
+			"origins, inertia tensors, and mesh files) and exits 5 if any errors are found.\n"+
+			"Each check is a named rule whose severity can be changed with --rule or\n"+
+			"the lint section of a config file.")
+	fs.BoolVar(&skipMeshes, "", "skip-meshes", false, "do not check that referenced mesh files exist")
+	fs.BoolVar(&strict, "", "strict", false, "treat warnings as errors")
+	fs.StringVar(&configPath, "", "config", "", "YAML file whose lint section sets rule severities")
+	fs.StringsVar(&rules, "", "rule", "set a rule's severity, given as NAME=error, NAME=warn or NAME=off")
+	fs.BoolVar(&listRules, "", "list-rules", false, "list the rules and their default severities, then exit")
+	registerPackageMap(fs, &packageMap)
+	xf.register(fs)
+	positional := fs.parseOrExit(args)
+	if listRules {
+		for _, r := range urdf.Rules {
+			fmt.Printf("%-15s %-8s %s\n", r.Name, r.Severity, r.Description)
+		}
+		return exitOK
+	}
+	if len(positional) != 1 {
+		fs.printUsage(os.Stderr)
+		return exitUsage
+	}
+
+	packages, err := parsePackageMap(packageMap)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	xacroIn, err := xf.parse()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	policy, err := lintPolicy(configPath, rules)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+
+	robot, err := loadRobot(positional[0], packages, xacroIn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitCode(err)
+	}
+
+	issues := urdf.Validate(robot)
+	if !skipMeshes {
+		issues = append(issues, urdf.ValidateMeshes(robot, meshResolver(positional[0], packages))...)
+	}
+	issues = policy.Apply(issues)
+	errors := 0
+	for _, issue := range issues {
+		fmt.Println(issue)
+		if issue.Severity == urdf.Error {
+			errors++
+		}
+	}
+
+	if errors > 0 || strict && len(issues) > 0 {
+		fmt.Printf("%s: %d error(s), %d warning(s)\n", positional[0], errors, len(issues)-errors)
+		return exitInvalid
+	}
+	fmt.Printf("%s: valid (%d warning(s))\n", positional[0], len(issues))
+	return exitOK
+}
+
+// lintPolicy combines the lint section of the config file at path, if any,
+// with the NAME=SEVERITY pairs given with --rule, which take precedence.
+func lintPolicy(path string, rules []string) (urdf.Policy, error) {
+	policy := make(urdf.Policy)
+	if path != "" {
+		cfg, err := loadConfig(path, "")
+		if err != nil {
+			return nil, err
+		}
+		if policy, err = cfg.lintPolicy(); err != nil {
+			return nil, err
+		}
+	}
+	for _, rule := range rules {
+		name, severity, ok := strings.Cut(rule, "=")
+		s, err := urdf.ParseSeverity(severity)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("invalid --rule %q (want NAME=error, NAME=warn or NAME=off)", rule)
+		}
+		policy[name] = s
+	}
+	return policy, policy.Check()
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/nfranczak/urdf-simplifier/xacro"
+)
+
+// watchInterval is how often watched files are polled for changes.
+const watchInterval = 500 * time.Millisecond
+
+// watch simplifies inputPath into outputPath, then re-runs every time the
+// input or one of the meshes it references changes, until interrupted.
+// Failures are reported and watching continues, so a half-saved edit doesn't
+// end the session.
+func (r *simplifyRun) watch(inputPath, outputPath string) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	r.printReports = true
+	for {
+		if err := r.file(inputPath, outputPath, r.logger); err != nil {
+			r.logger.Error(err.Error())
+		}
+		// Later runs regenerate the file this session wrote, so they may
+		// overwrite it even without --force.
+		r.force = true
+
+		paths := watchedPaths(inputPath, r.packages, r.xacro)
+		r.logger.Info("watching for changes", "files", len(paths))
+		if !waitForChange(ctx, paths) {
+			return exitOK
+		}
+		r.logger.Info("change detected, re-running")
+	}
+}
+
+// watchedPaths returns the input file, the files it includes if it is a
+// xacro document, and every mesh file it references. If the input can't
+// currently be parsed only the input itself is watched.
+func watchedPaths(inputPath string, packages map[string]string, in xacroInput) []string {
+	paths := []string{inputPath}
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return paths
+	}
+	if xacro.IsXacro(inputPath, data) {
+		result, err := expandXacro(inputPath, data, packages, in, false)
+		if err != nil {
+			return paths
+		}
+		data = result.Data
+		paths = append(paths, result.Includes...)
+	}
+	robot, err := parseRobot(data, inputPath)
+	if err != nil {
+		return paths
+	}
+	resolver := meshResolver(inputPath, packages)
+	seen := make(map[string]bool)
+	for _, p := range paths {
+		seen[p] = true
+	}
+	for _, m := range robot.MeshRefs() {
+		p := resolver.Resolve(m.Filename)
+		if !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// fileState is what waitForChange compares between polls.
+type fileState struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+func statAll(paths []string) []fileState {
+	states := make([]fileState, len(paths))
+	for i, p := range paths {
+		if info, err := os.Stat(p); err == nil {
+			states[i] = fileState{info.ModTime(), info.Size(), true}
+		}
+	}
+	return states
+}
+
+// waitForChange blocks until any of paths is modified, created, or removed,
+// returning false if ctx is cancelled first. Once a change is seen it waits
+// for one more quiet interval so that editors finish writing.
+func waitForChange(ctx context.Context, paths []string) bool {
+	before := statAll(paths)
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	changed := false
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+
+		now := statAll(paths)
+		same := true
+		for i := range now {
+			if now[i] != before[i] {
+				same = false
+				break
+			}
+		}
+		switch {
+		case !same:
+			changed = true
+			before = now
+		case changed:
+			return true
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package xacro
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// node is one piece of an XML document: an *element, a declaration, or a
+// character data, comment, processing instruction, or directive token.
+type node any
+
+// declaration is a top-level xacro:property or xacro:arg kept by partial
+// expansion. It is written only by Result.Restore.
+type declaration struct{ e *element }
+
+// element is an XML element with its prefix kept as written, so that xacro
+// elements can be recognized by prefix and the output keeps the input's
+// spelling.
+type element struct {
+	prefix, local string
+	attrs         []xml.Attr
+	children      []node
+	// kept holds the attributes as partial expansion writes them, with
+	// expressions on top-level declarations left unevaluated, or nil if
+	// they are the same as attrs.
+	kept []xml.Attr
+}
+
+func (e *element) name() string {
+	if e.prefix == "" {
+		return e.local
+	}
+	return e.prefix + ":" + e.local
+}
+
+// attr returns the value of the unprefixed attribute name and whether it is set.
+func (e *element) attr(name string) (string, bool) {
+	for _, a := range e.attrs {
+		if a.Name.Space == "" && a.Name.Local == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// clone returns a deep copy of e, so that macro bodies can be expanded more
+// than once.
+func (e *element) clone() *element {
+	c := &element{prefix: e.prefix, local: e.local, attrs: append([]xml.Attr(nil), e.attrs...), kept: e.kept}
+	c.children = cloneNodes(e.children)
+	return c
+}
+
+func cloneNodes(nodes []node) []node {
+	out := make([]node, len(nodes))
+	for i, n := range nodes {
+		if e, ok := n.(*element); ok {
+			out[i] = e.clone()
+		} else {
+			out[i] = n
+		}
+	}
+	return out
+}
+
+// document is a parsed XML file: the nodes before the root element, such as
+// the XML declaration and comments, and the root itself.
+type document struct {
+	prolog []node
+	root   *element
+}
+
+// parse reads an XML document without resolving namespaces.
+func parse(data []byte) (*document, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	doc := &document{}
+	var stack []*element
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			e := &element{prefix: t.Name.Space, local: t.Name.Local, attrs: t.Attr}
+			switch {
+			case len(stack) > 0:
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, e)
+			case doc.root != nil:
+				return nil, fmt.Errorf("line %d: more than one root element", line(dec, data))
+			default:
+				doc.root = e
+			}
+			stack = append(stack, e)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("line %d: unexpected </%s>", line(dec, data), rawName(t.Name))
+			}
+			if e := stack[len(stack)-1]; rawName(t.Name) != e.name() {
+				return nil, fmt.Errorf("line %d: element <%s> closed by </%s>", line(dec, data), e.name(), rawName(t.Name))
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			tok = xml.CopyToken(tok)
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, tok)
+			} else if doc.root == nil {
+				doc.prolog = append(doc.prolog, tok)
+			}
+		}
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("unexpected end of file: <%s> is not closed", stack[len(stack)-1].name())
+	}
+	if doc.root == nil {
+		return nil, fmt.Errorf("no root element")
+	}
+	return doc, nil
+}
+
+func rawName(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
+
+// line returns the line number the decoder has reached in data.
+func line(dec *xml.Decoder, data []byte) int {
+	return 1 + bytes.Count(data[:dec.InputOffset()], []byte("\n"))
+}
+
+// write serializes doc, writing elements without children as empty tags.
+func (doc *document) write(w io.Writer) error {
+	var buf bytes.Buffer
+	for _, n := range doc.prolog {
+		writeNode(&buf, n)
+	}
+	writeNode(&buf, doc.root)
+	buf.WriteString("\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeNode(buf *bytes.Buffer, n node) {
+	switch t := n.(type) {
+	case *element:
+		buf.WriteString("<" + t.name())
+		for _, a := range t.attrs {
+			buf.WriteString(" " + rawName(a.Name) + `="` + attrEscaper.Replace(a.Value) + `"`)
+		}
+		if len(t.children) == 0 {
+			buf.WriteString("/>")
+			return
+		}
+		buf.WriteString(">")
+		for _, c := range t.children {
+			writeNode(buf, c)
+		}
+		buf.WriteString("</" + t.name() + ">")
+	case xml.CharData:
+		buf.WriteString(textEscaper.Replace(string(t)))
+	case xml.Comment:
+		buf.WriteString("<!--")
+		buf.Write(t)
+		buf.WriteString("-->")
+	case xml.ProcInst:
+		buf.WriteString("<?" + t.Target)
+		if len(t.Inst) > 0 {
+			buf.WriteString(" ")
+			buf.Write(t.Inst)
+		}
+		buf.WriteString("?>")
+	case xml.Directive:
+		buf.WriteString("<!")
+		buf.Write(t)
+		buf.WriteString(">")
+	}
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\n", "&#xA;", "\t", "&#x9;", "\r", "&#xD;")
+)
+
+// isBlank reports whether n is whitespace-only character data or a comment,
+// which xacro skips when matching block parameters.
+func isBlank(n node) bool {
+	switch t := n.(type) {
+	case xml.CharData:
+		return strings.TrimSpace(string(t)) == ""
+	case xml.Comment:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,298 @@
+package xacro
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// text substitutes the expressions in t and returns the result as a string.
+func (x *expander) text(t string, s *scope) (string, error) {
+	v, err := x.eval(t, s)
+	if err != nil {
+		return "", err
+	}
+	return format(v), nil
+}
+
+// eval substitutes the ${...} expressions and $(...) substitution arguments
+// in t. Text that is exactly one expression yields the expression's value
+// unconverted; anything else yields a string. $${ and $$( stand for a literal
+// ${ and $(.
+func (x *expander) eval(t string, s *scope) (any, error) {
+	if !strings.Contains(t, "$") {
+		return t, nil
+	}
+	if expr, ok := singleExpr(t); ok {
+		return x.evalExpr(expr, s)
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(t); {
+		switch {
+		case strings.HasPrefix(t[i:], "$${"), strings.HasPrefix(t[i:], "$$("):
+			out.WriteString(t[i+1 : i+3])
+			i += 3
+		case strings.HasPrefix(t[i:], "$("):
+			end := strings.IndexByte(t[i:], ')')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated substitution in %q", t)
+			}
+			v, err := x.substitute(t[i+2 : i+end])
+			if err != nil {
+				return nil, err
+			}
+			out.WriteString(v)
+			i += end + 1
+		case strings.HasPrefix(t[i:], "${"):
+			end := exprEnd(t, i+2)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated expression in %q", t)
+			}
+			v, err := x.evalExpr(t[i+2:end], s)
+			if err != nil {
+				return nil, err
+			}
+			out.WriteString(format(v))
+			i = end + 1
+		default:
+			out.WriteByte(t[i])
+			i++
+		}
+	}
+	return out.String(), nil
+}
+
+// written returns t as partial expansion writes it, or ok false if it
+// cannot be written other than as its value. A ${...} expression that uses
+// only top-level declarations is kept; one naming a macro parameter with a
+// written form becomes that form; and one that uses neither is replaced by
+// its value, as is $(dirname). Without Options.Partial, or for text holding
+// no expressions, written returns "".
+func (x *expander) written(t string, s *scope) (_ string, ok bool, _ error) {
+	if !x.opts.Partial || !strings.Contains(t, "$") {
+		return "", false, nil
+	}
+	literal := strings.NewReplacer("${", "$${", "$(", "$$(")
+	var out strings.Builder
+	for i := 0; i < len(t); {
+		switch {
+		case strings.HasPrefix(t[i:], "$${"), strings.HasPrefix(t[i:], "$$("):
+			out.WriteString(t[i : i+3])
+			i += 3
+		case strings.HasPrefix(t[i:], "$("):
+			end := strings.IndexByte(t[i:], ')')
+			if end < 0 {
+				return "", false, fmt.Errorf("unterminated substitution in %q", t)
+			}
+			if fields := strings.Fields(t[i+2 : i+end]); len(fields) > 0 && fields[0] == "dirname" {
+				v, err := x.substitute(t[i+2 : i+end])
+				if err != nil {
+					return "", false, err
+				}
+				out.WriteString(literal.Replace(v))
+			} else {
+				out.WriteString(t[i : i+end+1])
+			}
+			i += end + 1
+		case strings.HasPrefix(t[i:], "${"):
+			end := exprEnd(t, i+2)
+			if end < 0 {
+				return "", false, fmt.Errorf("unterminated expression in %q", t)
+			}
+			expr := t[i+2 : end]
+			outer := x.refs
+			x.refs = &refs{}
+			v, err := x.evalExpr(expr, s)
+			r := x.refs
+			x.refs = outer
+			if err != nil {
+				return "", false, err
+			}
+			switch p := s.property(strings.TrimSpace(expr)); {
+			case !r.local:
+				out.WriteString(t[i : end+1])
+			case !r.written:
+				out.WriteString(literal.Replace(format(v)))
+			case p != nil && p.written != "":
+				out.WriteString(p.written)
+			default:
+				return "", false, nil
+			}
+			i = end + 1
+		default:
+			out.WriteByte(t[i])
+			i++
+		}
+	}
+	return out.String(), true, nil
+}
+
+// missingPackageError reports a package that $(find) or a package:// include
+// could not locate.
+type missingPackageError struct{ name string }
+
+func (e *missingPackageError) Error() string {
+	return fmt.Sprintf("package %q not found", e.name)
+}
+
+// findPackage returns the absolute directory of a ROS package, as $(find)
+// does in ROS.
+func (x *expander) findPackage(name string) (string, error) {
+	if x.opts.FindPackage != nil {
+		if dir, ok := x.opts.FindPackage(name); ok {
+			return filepath.Abs(dir)
+		}
+	}
+	return "", &missingPackageError{name}
+}
+
+// substitute evaluates the inside of a $(...) substitution argument.
+func (x *expander) substitute(s string) (string, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty substitution $()")
+	}
+	switch cmd, args := fields[0], fields[1:]; {
+	case cmd == "find" && len(args) == 1:
+		return x.findPackage(args[0])
+	case cmd == "arg" && len(args) == 1:
+		if v, ok := x.args[args[0]]; ok {
+			return v, nil
+		}
+		return "", fmt.Errorf("$(arg %s): argument has no value; pass one or give its xacro:arg a default", args[0])
+	case cmd == "dirname" && len(args) == 0:
+		return filepath.Abs(filepath.Dir(x.files[len(x.files)-1]))
+	case cmd == "env" && len(args) == 1:
+		if v, ok := os.LookupEnv(args[0]); ok {
+			return v, nil
+		}
+		return "", fmt.Errorf("$(env %s): environment variable is not set", args[0])
+	case cmd == "optenv" && len(args) >= 1:
+		if v, ok := os.LookupEnv(args[0]); ok {
+			return v, nil
+		}
+		return strings.Join(args[1:], " "), nil
+	}
+	return "", fmt.Errorf("unsupported substitution $(%s)", s)
+}
+
+// singleExpr reports whether t consists of exactly one ${...} expression.
+func singleExpr(t string) (string, bool) {
+	if !strings.HasPrefix(t, "${") || exprEnd(t, 2) != len(t)-1 {
+		return "", false
+	}
+	return t[2 : len(t)-1], true
+}
+
+// exprEnd returns the index of the brace closing an expression that starts at
+// i, skipping braces nested inside it or inside quoted strings, or -1.
+func exprEnd(t string, i int) int {
+	depth := 0
+	var quote byte
+	for ; i < len(t); i++ {
+		c := t[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '{':
+			depth++
+		case c == '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// propertyValue evaluates a text property on first use, in the scope that
+// defined it, converting text that spells a number or boolean to one.
+func (x *expander) propertyValue(name string, p *property) (any, error) {
+	switch {
+	case p.isBlock:
+		return nil, fmt.Errorf("property %q is a block; use insert_block", name)
+	case p.evaluated:
+		return p.value, nil
+	case p.evaluating:
+		return nil, fmt.Errorf("property %q is defined in terms of itself", name)
+	}
+	p.evaluating = true
+	// A declared property is kept as written, along with whatever it
+	// refers to.
+	refs := x.refs
+	if p.declared {
+		x.refs = nil
+	}
+	defer func() { p.evaluating, x.refs = false, refs }()
+	v, err := x.eval(p.text, p.scope)
+	if err != nil {
+		return nil, fmt.Errorf("property %q: %w", name, err)
+	}
+	p.value, p.evaluated = literal(v), true
+	return p.value, nil
+}
+
+// truth interprets a condition value the way xacro does: booleans as is,
+// numbers as true unless zero, and the strings true, True, false and False,
+// or a number written as a string.
+func truth(v any) (bool, error) {
+	switch t := v.(type) {
+	case bool:
+		return t, nil
+	case float64:
+		return t != 0, nil
+	case int64:
+		return t != 0, nil
+	case string:
+		switch s := strings.TrimSpace(t); s {
+		case "true", "True":
+			return true, nil
+		case "false", "False":
+			return false, nil
+		default:
+			if f, err := strconv.ParseFloat(s, 64); err == nil {
+				return f != 0, nil
+			}
+		}
+	}
+	return false, fmt.Errorf("%q is not a boolean", format(v))
+}
+
+// format converts an evaluated value to text as Python's str does, so that
+// ${0.5*2} gives 1.0 and ${1 == 1} gives True, matching xacro's output.
+func format(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case bool:
+		if t {
+			return "True"
+		}
+		return "False"
+	case nil:
+		return "None"
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case float64:
+		return formatFloat(t)
+	case []any:
+		items := make([]string, len(t))
+		for i, item := range t {
+			if s, ok := item.(string); ok {
+				items[i] = "'" + s + "'"
+			} else {
+				items[i] = format(item)
+			}
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return fmt.Sprint(v)
+}
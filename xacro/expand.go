@@ -0,0 +1,718 @@
+package xacro
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// maxMacroDepth bounds nested macro calls, so that a macro that calls itself
+// unconditionally fails instead of exhausting the stack.
+const maxMacroDepth = 100
+
+// scope holds the properties and macros visible at one point of the
+// expansion. Macro calls and namespaced includes open a child scope; lookups
+// fall back to the parent.
+type scope struct {
+	parent     *scope
+	properties map[string]*property
+	macros     map[string]*macro
+	// namespaces holds the scopes of includes given an ns attribute, whose
+	// names are reached as ns.name.
+	namespaces map[string]*scope
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{
+		parent:     parent,
+		properties: make(map[string]*property),
+		macros:     make(map[string]*macro),
+		namespaces: make(map[string]*scope),
+	}
+}
+
+// resolve finds the scope holding a possibly namespaced name and the name
+// within it.
+func (s *scope) resolve(name string) (*scope, string) {
+	ns, rest, ok := strings.Cut(name, ".")
+	if !ok {
+		return s, name
+	}
+	for c := s; c != nil; c = c.parent {
+		if child, ok := c.namespaces[ns]; ok {
+			return child.resolve(rest)
+		}
+	}
+	return s, name
+}
+
+func (s *scope) property(name string) *property {
+	s, name = s.resolve(name)
+	for c := s; c != nil; c = c.parent {
+		if p, ok := c.properties[name]; ok {
+			return p
+		}
+	}
+	return nil
+}
+
+func (s *scope) macro(name string) *macro {
+	s, name = s.resolve(name)
+	for c := s; c != nil; c = c.parent {
+		if m, ok := c.macros[name]; ok {
+			return m
+		}
+	}
+	return nil
+}
+
+// property is a xacro property. Text properties are evaluated lazily, in the
+// scope that defined them, the first time they are used; block properties
+// hold XML to be inserted with xacro:insert_block.
+type property struct {
+	text  string
+	scope *scope
+
+	evaluated, evaluating bool
+	value                 any
+	// declared marks a top-level property kept in partial output; written
+	// holds a macro parameter's value as partial output writes it, if its
+	// value used top-level declarations.
+	declared bool
+	written  string
+
+	block   []node
+	isBlock bool
+}
+
+// macro is a xacro macro definition.
+type macro struct {
+	name   string
+	params []param
+	body   []node
+}
+
+// param is one entry of a macro's params attribute: name, name:=default,
+// name:=^ or name:=^|default to inherit the caller's property of the same
+// name, *name for a block, or **name for a block's contents.
+type param struct {
+	name             string
+	block, contents  bool
+	def              string
+	hasDef, inherits bool
+}
+
+// expander holds the state of one Expand call.
+type expander struct {
+	opts Options
+	// files is the stack of files being expanded, innermost last, for
+	// resolving relative includes.
+	files []string
+	// included lists every file read by xacro:include, in order.
+	included []string
+	// missing describes the includes that could not be found.
+	missing []string
+	// args holds the values of xacro arguments: those from Options.Args,
+	// then the defaults of xacro:arg elements not given there.
+	args map[string]string
+	// prefixes holds the element prefixes bound to the xacro namespace.
+	prefixes map[string]bool
+	// calls lists the macros whose calls are being expanded, outermost
+	// first.
+	calls []string
+	// global is the scope of the main document; conditionals counts the
+	// xacro:if and xacro:unless elements being expanded. Together with calls
+	// they tell top-level declarations apart for partial expansion.
+	global       *scope
+	conditionals int
+	// refs, while an expression is evaluated for partial expansion, records
+	// what it refers to.
+	refs *refs
+}
+
+// refs records what an expression refers to.
+type refs struct {
+	// local is set by a reference to anything but a top-level declaration,
+	// which partial output cannot keep as written; written is set by a
+	// reference to a macro parameter that has a written form.
+	local, written bool
+}
+
+// addPrefixes records the prefixes that e's xmlns attributes bind to the
+// xacro namespace.
+func (x *expander) addPrefixes(e *element) {
+	for _, a := range e.attrs {
+		if a.Name.Space == "xmlns" && isNamespace(a.Value) {
+			x.prefixes[a.Name.Local] = true
+		}
+	}
+}
+
+// xacroName returns the local name of e if it is a xacro element.
+func (x *expander) xacroName(e *element) (string, bool) {
+	if e.prefix != "" && x.prefixes[e.prefix] {
+		return e.local, true
+	}
+	return "", false
+}
+
+// root expands the root element of the main document.
+func (x *expander) root(e *element, s *scope) (*element, error) {
+	x.addPrefixes(e)
+	if _, ok := x.xacroName(e); ok {
+		return nil, fmt.Errorf("root element <%s> is a xacro element", e.name())
+	}
+	out, err := x.plain(e, s)
+	if err != nil {
+		return nil, err
+	}
+	if x.opts.Partial && out.kept == nil {
+		out.kept = out.attrs
+	}
+	// The xacro namespace declarations have nothing left to refer to.
+	var attrs []xml.Attr
+	for _, a := range out.attrs {
+		if !(a.Name.Space == "xmlns" && isNamespace(a.Value)) {
+			attrs = append(attrs, a)
+		}
+	}
+	out.attrs = attrs
+	return out, nil
+}
+
+// nodes expands a list of sibling nodes in scope s.
+func (x *expander) nodes(in []node, s *scope) ([]node, error) {
+	var out []node
+	for _, n := range in {
+		switch t := n.(type) {
+		case *element:
+			expanded, err := x.element(t, s)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, expanded...)
+		case xml.CharData:
+			text, err := x.text(string(t), s)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, xml.CharData(text))
+		default:
+			out = append(out, n)
+		}
+	}
+	return out, nil
+}
+
+// element expands e, which yields no nodes for a definition, the expansion
+// for a macro call or include, and a copy with substituted attributes and
+// expanded children for any other element.
+func (x *expander) element(e *element, s *scope) ([]node, error) {
+	name, ok := x.xacroName(e)
+	if !ok {
+		out, err := x.plain(e, s)
+		if err != nil {
+			return nil, err
+		}
+		return []node{out}, nil
+	}
+
+	switch name {
+	case "property":
+		if err := x.defineProperty(e, s); err != nil {
+			return nil, err
+		}
+		return x.declare(e, s), nil
+	case "macro":
+		return nil, x.defineMacro(e, s)
+	case "include":
+		return x.include(e, s)
+	case "insert_block":
+		return x.insertBlock(e, s)
+	case "arg":
+		if err := x.defineArg(e, s); err != nil {
+			return nil, err
+		}
+		return x.declare(e, s), nil
+	case "if", "unless":
+		return x.conditional(e, name == "if", s)
+	case "call":
+		macroName, ok := e.attr("macro")
+		if !ok {
+			return nil, fmt.Errorf("<%s> needs a macro attribute", e.name())
+		}
+		macroName, err := x.text(macroName, s)
+		if err != nil {
+			return nil, err
+		}
+		return x.call(e, macroName, s)
+	}
+	if s.macro(name) != nil {
+		return x.call(e, name, s)
+	}
+	return nil, fmt.Errorf("unknown xacro element <%s>: no macro named %q is defined", e.name(), name)
+}
+
+// conditional expands the children of a xacro:if whose value is true, or of
+// a xacro:unless whose value is false, in the enclosing scope.
+func (x *expander) conditional(e *element, want bool, s *scope) ([]node, error) {
+	value, ok := e.attr("value")
+	if !ok {
+		return nil, fmt.Errorf("<%s> needs a value", e.name())
+	}
+	v, err := x.eval(value, s)
+	if err != nil {
+		return nil, fmt.Errorf("<%s value=%q>: %w", e.name(), value, err)
+	}
+	cond, err := truth(v)
+	if err != nil {
+		return nil, fmt.Errorf("<%s value=%q>: %w", e.name(), value, err)
+	}
+	if cond != want {
+		return nil, nil
+	}
+	x.conditionals++
+	defer func() { x.conditionals-- }()
+	return x.nodes(e.children, s)
+}
+
+// declare returns the property or argument definition e as a declaration
+// to keep in partial output, if it is made at the top level of the main
+// document or a file it includes without a namespace, and is not a block.
+func (x *expander) declare(e *element, s *scope) []node {
+	if !x.opts.Partial || s != x.global || len(x.calls) > 0 || x.conditionals > 0 {
+		return nil
+	}
+	if e.local == "property" {
+		name, _ := e.attr("name")
+		p := s.properties[name]
+		if p == nil || p.isBlock {
+			return nil
+		}
+		p.declared = true
+	}
+	return []node{declaration{e.clone()}}
+}
+
+// plain copies an ordinary element, substituting its attribute values and
+// expanding its children.
+func (x *expander) plain(e *element, s *scope) (*element, error) {
+	out := &element{prefix: e.prefix, local: e.local}
+	partial := false
+	for _, a := range e.attrs {
+		written := a
+		if a.Name.Space != "xmlns" {
+			value, err := x.text(a.Value, s)
+			if err != nil {
+				return nil, fmt.Errorf("<%s %s>: %w", e.name(), rawName(a.Name), err)
+			}
+			a.Value = value
+			w, ok, err := x.written(written.Value, s)
+			if err != nil {
+				return nil, fmt.Errorf("<%s %s>: %w", e.name(), rawName(a.Name), err)
+			}
+			if ok && w != value {
+				written.Value = w
+				partial = true
+			} else {
+				written = a
+			}
+		}
+		out.attrs = append(out.attrs, a)
+		out.kept = append(out.kept, written)
+	}
+	if !partial {
+		out.kept = nil
+	}
+	children, err := x.nodes(e.children, s)
+	if err != nil {
+		return nil, err
+	}
+	out.children = children
+	return out, nil
+}
+
+func (x *expander) defineProperty(e *element, s *scope) error {
+	name, ok := e.attr("name")
+	if !ok || name == "" {
+		return fmt.Errorf("<%s> needs a name", e.name())
+	}
+	target := s
+	if where, ok := e.attr("scope"); ok {
+		switch where {
+		case "parent":
+			if s.parent != nil {
+				target = s.parent
+			}
+		case "global":
+			for target.parent != nil {
+				target = target.parent
+			}
+		default:
+			return fmt.Errorf("property %q: unknown scope %q (want parent or global)", name, where)
+		}
+	}
+
+	if def, ok := e.attr("default"); ok {
+		if target.property(name) != nil {
+			return nil
+		}
+		target.properties[name] = &property{text: def, scope: s}
+		return nil
+	}
+	if value, ok := e.attr("value"); ok {
+		target.properties[name] = &property{text: value, scope: s}
+		return nil
+	}
+	// A property without a value holds its children as a block.
+	target.properties[name] = &property{block: cloneNodes(e.children), isBlock: true}
+	return nil
+}
+
+// defineArg declares a xacro argument. Its default applies only if the
+// argument has no value yet, so values given in Options.Args win.
+func (x *expander) defineArg(e *element, s *scope) error {
+	name, ok := e.attr("name")
+	if !ok || name == "" {
+		return fmt.Errorf("<%s> needs a name", e.name())
+	}
+	if _, ok := x.args[name]; ok {
+		return nil
+	}
+	def, ok := e.attr("default")
+	if !ok {
+		return nil
+	}
+	value, err := x.text(def, s)
+	if err != nil {
+		return fmt.Errorf("arg %q default: %w", name, err)
+	}
+	x.args[name] = value
+	return nil
+}
+
+func (x *expander) defineMacro(e *element, s *scope) error {
+	name, ok := e.attr("name")
+	if !ok || name == "" {
+		return fmt.Errorf("<%s> needs a name", e.name())
+	}
+	params, err := parseParams(e.attrOr("params"))
+	if err != nil {
+		return fmt.Errorf("macro %q: %w", name, err)
+	}
+	s.macros[name] = &macro{name: name, params: params, body: cloneNodes(e.children)}
+	return nil
+}
+
+func (e *element) attrOr(name string) string {
+	v, _ := e.attr(name)
+	return v
+}
+
+// parseParams parses a macro's params attribute. Defaults may be quoted to
+// include spaces.
+func parseParams(spec string) ([]param, error) {
+	fields, err := splitFields(spec)
+	if err != nil {
+		return nil, err
+	}
+	var params []param
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		var p param
+		switch {
+		case strings.HasPrefix(field, "**"):
+			p.block, p.contents, field = true, true, field[2:]
+		case strings.HasPrefix(field, "*"):
+			p.block, field = true, field[1:]
+		}
+		name, def, hasDef := strings.Cut(field, ":=")
+		if hasDef {
+			if p.block {
+				return nil, fmt.Errorf("block parameter %q cannot have a default", name)
+			}
+			if rest, ok := strings.CutPrefix(def, "^"); ok {
+				p.inherits = true
+				def, hasDef = strings.CutPrefix(rest, "|")
+			}
+			p.def, p.hasDef = def, hasDef
+		}
+		if name == "" {
+			return nil, fmt.Errorf("empty parameter name in %q", spec)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("parameter %q given twice", name)
+		}
+		seen[name] = true
+		p.name = name
+		params = append(params, p)
+	}
+	return params, nil
+}
+
+// splitFields splits s at whitespace outside single or double quotes and
+// removes the quotes.
+func splitFields(s string) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	inField := false
+	var quote rune
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				field.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inField = r, true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteRune(r)
+			inField = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", s)
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
+
+// depthError reports macro calls nested more than maxMacroDepth deep. It is
+// passed up through the calls it ran out of depth in unwrapped, as each
+// would otherwise add its own prefix, and describes the calls instead.
+type depthError struct {
+	calls []string
+}
+
+func (e *depthError) Error() string {
+	// The start of the chain is enough to see the recursion.
+	shown, more := e.calls, ""
+	if len(shown) > 5 {
+		shown, more = shown[:5], " → ..."
+	}
+	return fmt.Sprintf("macro %q: calls nested more than %d deep: %s%s", e.calls[0], maxMacroDepth, strings.Join(shown, " → "), more)
+}
+
+// call expands a call of the named macro. Parameter values and block
+// arguments are evaluated in the caller's scope; the body is then expanded
+// in a child scope holding the parameters.
+func (x *expander) call(e *element, name string, s *scope) ([]node, error) {
+	m := s.macro(name)
+	if m == nil {
+		return nil, fmt.Errorf("<%s>: no macro named %q is defined", e.name(), name)
+	}
+	if len(x.calls) >= maxMacroDepth {
+		return nil, &depthError{calls: append(slices.Clone(x.calls), name)}
+	}
+	x.calls = append(x.calls, name)
+	defer func() { x.calls = x.calls[:len(x.calls)-1] }()
+
+	out, err := x.expandCall(e, m, name, s)
+	var deep *depthError
+	if errors.As(err, &deep) {
+		return nil, deep
+	}
+	return out, err
+}
+
+// expandCall expands the call e of the macro m, named name, for call.
+func (x *expander) expandCall(e *element, m *macro, name string, s *scope) ([]node, error) {
+	ms := newScope(s)
+	params := make(map[string]param)
+	for _, p := range m.params {
+		params[p.name] = p
+	}
+	given := make(map[string]bool)
+	tag, _ := x.xacroName(e)
+	for _, a := range e.attrs {
+		if a.Name.Space != "" || tag == "call" && a.Name.Local == "macro" {
+			continue
+		}
+		p, ok := params[a.Name.Local]
+		if !ok || p.block {
+			return nil, fmt.Errorf("macro %q has no parameter %q", name, a.Name.Local)
+		}
+		value, err := x.eval(a.Value, s)
+		if err != nil {
+			return nil, fmt.Errorf("macro %q parameter %q: %w", name, p.name, err)
+		}
+		w, err := x.paramWritten(a.Value, s)
+		if err != nil {
+			return nil, fmt.Errorf("macro %q parameter %q: %w", name, p.name, err)
+		}
+		ms.properties[p.name] = &property{evaluated: true, value: literal(value), written: w}
+		given[p.name] = true
+	}
+
+	// Block arguments are the element children of the call, in order.
+	children, err := x.nodes(e.children, s)
+	if err != nil {
+		return nil, fmt.Errorf("macro %q: %w", name, err)
+	}
+	var blocks []*element
+	for _, c := range children {
+		if b, ok := c.(*element); ok {
+			blocks = append(blocks, b)
+		} else if !isBlank(c) {
+			return nil, fmt.Errorf("macro %q: unexpected text %q in call", name, strings.TrimSpace(fmt.Sprint(c)))
+		}
+	}
+
+	for _, p := range m.params {
+		switch {
+		case p.block:
+			if len(blocks) == 0 {
+				return nil, fmt.Errorf("macro %q: missing block parameter %q", name, p.name)
+			}
+			block := []node{blocks[0]}
+			if p.contents {
+				block = blocks[0].children
+			}
+			ms.properties[p.name] = &property{block: block, isBlock: true}
+			blocks = blocks[1:]
+		case given[p.name]:
+		case p.inherits && s.property(p.name) != nil:
+			ms.properties[p.name] = s.property(p.name)
+		case p.hasDef:
+			value, err := x.eval(p.def, s)
+			if err != nil {
+				return nil, fmt.Errorf("macro %q parameter %q default: %w", name, p.name, err)
+			}
+			w, err := x.paramWritten(p.def, s)
+			if err != nil {
+				return nil, fmt.Errorf("macro %q parameter %q default: %w", name, p.name, err)
+			}
+			ms.properties[p.name] = &property{evaluated: true, value: literal(value), written: w}
+		default:
+			return nil, fmt.Errorf("macro %q: missing parameter %q", name, p.name)
+		}
+	}
+	if len(blocks) > 0 {
+		return nil, fmt.Errorf("macro %q: unused block <%s>", name, blocks[0].name())
+	}
+
+	out, err := x.nodes(cloneNodes(m.body), ms)
+	if err != nil {
+		return nil, fmt.Errorf("macro %q: %w", name, err)
+	}
+	return out, nil
+}
+
+// paramWritten returns the written form of a macro parameter's value t, or
+// "" if it has none left to write once partially expanded.
+func (x *expander) paramWritten(t string, s *scope) (string, error) {
+	w, ok, err := x.written(t, s)
+	if err != nil || !ok || !strings.Contains(w, "$") {
+		return "", err
+	}
+	return w, nil
+}
+
+func (x *expander) insertBlock(e *element, s *scope) ([]node, error) {
+	name, ok := e.attr("name")
+	if !ok {
+		return nil, fmt.Errorf("<%s> needs a name", e.name())
+	}
+	name, err := x.text(name, s)
+	if err != nil {
+		return nil, err
+	}
+	p := s.property(name)
+	if p == nil || !p.isBlock {
+		return nil, fmt.Errorf("insert_block: no block named %q", name)
+	}
+	return x.nodes(cloneNodes(p.block), s)
+}
+
+// include expands another xacro file in place. Its definitions go into the
+// current scope, or into a namespace if the include has an ns attribute. An
+// include whose file cannot be found is recorded in x.missing and skipped.
+func (x *expander) include(e *element, s *scope) ([]node, error) {
+	written, ok := e.attr("filename")
+	if !ok {
+		return nil, fmt.Errorf("<%s> needs a filename", e.name())
+	}
+	current := x.files[len(x.files)-1]
+	missing := func(err error) ([]node, error) {
+		x.missing = append(x.missing, fmt.Sprintf("%s (in %s): %v", written, current, err))
+		return nil, nil
+	}
+
+	filename, err := x.text(written, s)
+	var notFound *missingPackageError
+	if errors.As(err, &notFound) {
+		return missing(err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("include %q: %w", written, err)
+	}
+	path, err := x.includePath(filename, current)
+	if err != nil {
+		return missing(err)
+	}
+	for _, f := range x.files {
+		if f == path {
+			return nil, fmt.Errorf("include %q: %s includes itself", written, path)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return missing(fmt.Errorf("%s does not exist", path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("include %q: %w", written, err)
+	}
+	doc, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("include %s: %w", path, err)
+	}
+	x.included = append(x.included, path)
+	x.addPrefixes(doc.root)
+
+	if ns, ok := e.attr("ns"); ok && ns != "" {
+		child := newScope(s)
+		s.namespaces[ns] = child
+		s = child
+	}
+	x.files = append(x.files, path)
+	defer func() { x.files = x.files[:len(x.files)-1] }()
+	out, err := x.nodes(doc.root.children, s)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return out, nil
+}
+
+// includePath returns the file an include filename names: a package:// URI
+// is looked up with FindPackage, and a relative path is relative to the file
+// containing the include.
+func (x *expander) includePath(filename, current string) (string, error) {
+	if rest, ok := strings.CutPrefix(filename, "package://"); ok {
+		pkg, rest, _ := strings.Cut(rest, "/")
+		dir, err := x.findPackage(pkg)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, filepath.FromSlash(rest)), nil
+	}
+	if filepath.IsAbs(filename) {
+		return filename, nil
+	}
+	return filepath.Join(filepath.Dir(current), filename), nil
+}
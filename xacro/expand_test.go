@@ -0,0 +1,199 @@
+package xacro
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const header = `<robot xmlns:xacro="http://www.ros.org/wiki/xacro" name="r">`
+
+// writeFiles writes each named document to a new temporary directory and
+// returns the directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestExpand(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		body string
+		args map[string]string
+		want string
+	}{
+		{
+			name: "macro params",
+			body: `<xacro:macro name="m" params="a b:=2"><link name="${a}_${b}"/></xacro:macro><xacro:m a="x"/><xacro:m a="y" b="${1+2}"/>`,
+			want: `<link name="x_2"/><link name="y_3"/>`,
+		},
+		{
+			name: "inherited param",
+			body: `<xacro:property name="side" value="left"/><xacro:macro name="m" params="side:=^"><link name="${side}"/></xacro:macro><xacro:m/>`,
+			want: `<link name="left"/>`,
+		},
+		{
+			name: "inherited param default",
+			body: `<xacro:macro name="m" params="side:=^|right"><link name="${side}"/></xacro:macro><xacro:m/>`,
+			want: `<link name="right"/>`,
+		},
+		{
+			name: "params are local",
+			body: `<xacro:property name="a" value="outer"/><xacro:macro name="m" params="a"><link name="${a}"/></xacro:macro><xacro:m a="inner"/><link name="${a}"/>`,
+			want: `<link name="inner"/><link name="outer"/>`,
+		},
+		{
+			name: "block",
+			body: `<xacro:macro name="m" params="*origin"><joint name="j"><xacro:insert_block name="origin"/></joint></xacro:macro><xacro:m><origin xyz="0 0 1"/></xacro:m>`,
+			want: `<joint name="j"><origin xyz="0 0 1"/></joint>`,
+		},
+		{
+			name: "block contents",
+			body: `<xacro:macro name="m" params="**parts"><link name="l"><xacro:insert_block name="parts"/></link></xacro:macro><xacro:m><parts><visual/><collision/></parts></xacro:m>`,
+			want: `<link name="l"><visual/><collision/></link>`,
+		},
+		{
+			name: "nested macros",
+			body: `<xacro:macro name="inner" params="n"><link name="${n}"/></xacro:macro><xacro:macro name="outer" params="n"><xacro:inner n="${n}_a"/><xacro:inner n="${n}_b"/></xacro:macro><xacro:outer n="arm"/>`,
+			want: `<link name="arm_a"/><link name="arm_b"/>`,
+		},
+		{
+			name: "conditionals",
+			body: `<xacro:property name="gripper" value="true"/><xacro:if value="${gripper}"><link name="yes"/></xacro:if><xacro:unless value="${gripper}"><link name="no"/></xacro:unless>`,
+			want: `<link name="yes"/>`,
+		},
+		{
+			name: "args",
+			body: `<xacro:arg name="prefix" default="left_"/><link name="$(arg prefix)base"/>`,
+			args: map[string]string{"prefix": "right_"},
+			want: `<link name="right_base"/>`,
+		},
+		{
+			name: "arg default",
+			body: `<xacro:arg name="prefix" default="left_"/><link name="$(arg prefix)base"/>`,
+			want: `<link name="left_base"/>`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Expand([]byte(header+tt.body+`</robot>`), "r.urdf.xacro", Options{Args: tt.args})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimSpace(string(r.Data)); got != `<robot name="r">`+tt.want+`</robot>` {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		body string
+		want string
+	}{
+		{"missing param", `<xacro:macro name="m" params="a"><link name="${a}"/></xacro:macro><xacro:m/>`, `missing parameter "a"`},
+		{"unknown param", `<xacro:macro name="m" params="a"/><xacro:m a="1" b="2"/>`, `no parameter "b"`},
+		{"missing block", `<xacro:macro name="m" params="*o"/><xacro:m/>`, `missing block parameter "o"`},
+		{"unused block", `<xacro:macro name="m"/><xacro:m><origin/></xacro:m>`, "unused block <origin>"},
+		{"undefined macro", `<xacro:nosuch/>`, `no macro named "nosuch"`},
+		{"recursion", `<xacro:macro name="a"><xacro:b/></xacro:macro><xacro:macro name="b"><xacro:a/></xacro:macro><xacro:a/>`, "calls nested more than 100 deep: a → b → a"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Expand([]byte(header+tt.body+`</robot>`), "r.urdf.xacro", Options{})
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q does not mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecursionErrorNotRepeated(t *testing.T) {
+	_, err := Expand([]byte(header+`<xacro:macro name="m"><xacro:m/></xacro:macro><xacro:m/></robot>`), "r.urdf.xacro", Options{})
+	if err == nil {
+		t.Fatal("no error")
+	}
+	if n := strings.Count(err.Error(), `macro "m"`); n != 1 {
+		t.Errorf("error %q names the macro %d times, want once", err, n)
+	}
+}
+
+func TestIncludes(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"robot.urdf.xacro": header +
+			`<xacro:include filename="parts/arm.xacro" ns="arm"/>` +
+			`<xacro:include filename="$(find grippers)/hand.xacro"/>` +
+			`<xacro:arm.segment n="upper" length="${arm.length}"/><xacro:hand/></robot>`,
+		"parts/arm.xacro": header +
+			`<xacro:property name="length" value="0.5"/>` +
+			`<xacro:macro name="segment" params="n length"><link name="${n}_${length}"/></xacro:macro></robot>`,
+		"grippers/hand.xacro": header + `<xacro:macro name="hand"><link name="hand"/></xacro:macro></robot>`,
+	})
+	find := func(name string) (string, bool) {
+		if name == "grippers" {
+			return filepath.Join(dir, "grippers"), true
+		}
+		return "", false
+	}
+	r, err := ExpandFile(filepath.Join(dir, "robot.urdf.xacro"), Options{FindPackage: find})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.TrimSpace(string(r.Data)), `<robot name="r"><link name="upper_0.5"/><link name="hand"/></robot>`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	want := []string{filepath.Join(dir, "parts", "arm.xacro"), filepath.Join(dir, "grippers", "hand.xacro")}
+	if strings.Join(r.Includes, ",") != strings.Join(want, ",") {
+		t.Errorf("includes %v, want %v", r.Includes, want)
+	}
+}
+
+func TestNamespacesAreSeparate(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"robot.urdf.xacro": header + `<xacro:include filename="arm.xacro" ns="arm"/><link name="${length}"/></robot>`,
+		"arm.xacro":        header + `<xacro:property name="length" value="0.5"/></robot>`,
+	})
+	if _, err := ExpandFile(filepath.Join(dir, "robot.urdf.xacro"), Options{}); err == nil {
+		t.Error("a namespaced include's property is visible without its namespace")
+	}
+}
+
+func TestIncludeCycle(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.xacro": header + `<xacro:include filename="b.xacro"/></robot>`,
+		"b.xacro": header + `<xacro:include filename="a.xacro"/></robot>`,
+	})
+	_, err := ExpandFile(filepath.Join(dir, "a.xacro"), Options{})
+	if err == nil || !strings.Contains(err.Error(), "includes itself") {
+		t.Errorf("error %v, want an include cycle", err)
+	}
+}
+
+func TestMissingInclude(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"robot.urdf.xacro": header + `<xacro:include filename="nosuch.xacro"/><xacro:fromnosuch/></robot>`,
+	})
+	_, err := ExpandFile(filepath.Join(dir, "robot.urdf.xacro"), Options{})
+	var missing *IncludeError
+	if !errors.As(err, &missing) {
+		t.Fatalf("error %v, want an *IncludeError", err)
+	}
+	if len(missing.Missing) != 1 || !strings.Contains(missing.Missing[0], "nosuch.xacro") {
+		t.Errorf("missing %v, want nosuch.xacro", missing.Missing)
+	}
+}
@@ -0,0 +1,1175 @@
+package xacro
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Expressions inside ${...} follow Python, as in xacro: int and float
+// arithmetic with + - * / // % **, comparisons, and, or, not, in, the
+// conditional x if c else y, string and list literals, indexing, and the
+// functions and constants of Python's math module, referred to with or
+// without a math. prefix. Property values are ints, floats, booleans or
+// strings; a property whose text looks like a number or boolean is one.
+
+// evalExpr evaluates the inside of a ${...} expression in scope s.
+func (x *expander) evalExpr(src string, s *scope) (any, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, fmt.Errorf("${%s}: %w", src, err)
+	}
+	p := &exprParser{x: x, s: s, toks: toks}
+	e, err := p.parseExpr()
+	if err == nil && p.pos < len(p.toks) {
+		err = fmt.Errorf("unexpected %q", p.toks[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("${%s}: %w", src, err)
+	}
+	v, err := e()
+	if err != nil {
+		return nil, fmt.Errorf("${%s}: %w", src, err)
+	}
+	return v, nil
+}
+
+// literal converts a property value written as text into the number or
+// boolean it spells, as xacro does. Text in single quotes is taken as a
+// string without them, and text containing an underscore is never converted.
+func literal(v any) any {
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return s[1 : len(s)-1]
+	}
+	if strings.Contains(s, "_") {
+		return s
+	}
+	t := strings.TrimSpace(s)
+	if i, err := strconv.ParseInt(t, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(t, 64); err == nil && !strings.HasPrefix(strings.ToLower(strings.TrimLeft(t, "+-")), "0x") {
+		return f
+	}
+	switch t {
+	case "true", "True":
+		return true
+	case "false", "False":
+		return false
+	}
+	return s
+}
+
+type tokenKind int
+
+const (
+	tokNumber tokenKind = iota
+	tokString
+	tokName
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// value holds the parsed number or unquoted string.
+	value any
+}
+
+func tokenize(src string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isDigit(c) || c == '.' && i+1 < len(src) && isDigit(src[i+1]):
+			j := i
+			isFloat := false
+			for j < len(src) && (isDigit(src[j]) || src[j] == '.') {
+				isFloat = isFloat || src[j] == '.'
+				j++
+			}
+			if j < len(src) && (src[j] == 'e' || src[j] == 'E') {
+				k := j + 1
+				if k < len(src) && (src[k] == '+' || src[k] == '-') {
+					k++
+				}
+				if k < len(src) && isDigit(src[k]) {
+					for k < len(src) && isDigit(src[k]) {
+						k++
+					}
+					j, isFloat = k, true
+				}
+			}
+			text := src[i:j]
+			var value any
+			var err error
+			if isFloat {
+				value, err = strconv.ParseFloat(text, 64)
+			} else {
+				value, err = strconv.ParseInt(text, 10, 64)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", text)
+			}
+			toks = append(toks, token{tokNumber, text, value})
+			i = j
+		case c == '\'' || c == '"':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(src) && src[j] != c; j++ {
+				if src[j] == '\\' && j+1 < len(src) {
+					j++
+					switch src[j] {
+					case 'n':
+						b.WriteByte('\n')
+					case 't':
+						b.WriteByte('\t')
+					default:
+						b.WriteByte(src[j])
+					}
+					continue
+				}
+				b.WriteByte(src[j])
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			toks = append(toks, token{tokString, src[i : j+1], b.String()})
+			i = j + 1
+		case c == '_' || isLetter(c):
+			j := i
+			for j < len(src) && (src[j] == '_' || isLetter(src[j]) || isDigit(src[j])) {
+				j++
+			}
+			toks = append(toks, token{kind: tokName, text: src[i:j]})
+			i = j
+		default:
+			op := ""
+			for _, candidate := range []string{"**", "//", "==", "!=", "<=", ">=", "+", "-", "*", "/", "%", "<", ">", "(", ")", "[", "]", ",", "."} {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q", c)
+			}
+			toks = append(toks, token{kind: tokOp, text: op})
+			i += len(op)
+		}
+	}
+	return toks, nil
+}
+
+func isDigit(c byte) bool  { return '0' <= c && c <= '9' }
+func isLetter(c byte) bool { return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' }
+
+// thunk is a parsed expression, evaluated when called. Parsing everything
+// before evaluating lets the untaken side of a conditional, and the right
+// side of a short-circuited and/or, go unevaluated as in Python.
+type thunk func() (any, error)
+
+// exprParser is a recursive-descent parser over Python's expression grammar.
+type exprParser struct {
+	x    *expander
+	s    *scope
+	toks []token
+	pos  int
+}
+
+func (p *exprParser) peek(texts ...string) bool {
+	if p.pos >= len(p.toks) {
+		return false
+	}
+	t := p.toks[p.pos]
+	if t.kind != tokOp && t.kind != tokName {
+		return false
+	}
+	for _, text := range texts {
+		if t.text == text {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *exprParser) next() token {
+	t := p.toks[p.pos]
+	p.pos++
+	return t
+}
+
+func (p *exprParser) expect(text string) error {
+	if !p.peek(text) {
+		if p.pos >= len(p.toks) {
+			return fmt.Errorf("expected %q at end of expression", text)
+		}
+		return fmt.Errorf("expected %q, got %q", text, p.toks[p.pos].text)
+	}
+	p.pos++
+	return nil
+}
+
+// parseExpr parses a conditional expression: or_test [if or_test else expr].
+func (p *exprParser) parseExpr() (thunk, error) {
+	then, err := p.parseOr()
+	if err != nil || !p.peek("if") {
+		return then, err
+	}
+	p.pos++
+	cond, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("else"); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return func() (any, error) {
+		c, err := cond()
+		if err != nil {
+			return nil, err
+		}
+		if pyTruth(c) {
+			return then()
+		}
+		return otherwise()
+	}, nil
+}
+
+func (p *exprParser) parseOr() (thunk, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.peek("or") {
+		p.pos++
+		var right thunk
+		if right, err = p.parseAnd(); err == nil {
+			left = shortCircuit(left, right, true)
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseAnd() (thunk, error) {
+	left, err := p.parseNot()
+	for err == nil && p.peek("and") {
+		p.pos++
+		var right thunk
+		if right, err = p.parseNot(); err == nil {
+			left = shortCircuit(left, right, false)
+		}
+	}
+	return left, err
+}
+
+// shortCircuit returns left if its truth is stop, else right, like Python's
+// or (stop true) and and (stop false).
+func shortCircuit(left, right thunk, stop bool) thunk {
+	return func() (any, error) {
+		l, err := left()
+		if err != nil || pyTruth(l) == stop {
+			return l, err
+		}
+		return right()
+	}
+}
+
+func (p *exprParser) parseNot() (thunk, error) {
+	if !p.peek("not") {
+		return p.parseComparison()
+	}
+	p.pos++
+	operand, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	return func() (any, error) {
+		v, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		return !pyTruth(v), nil
+	}, nil
+}
+
+func (p *exprParser) parseComparison() (thunk, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	// Chained comparisons such as a < b < c hold when every link holds.
+	type link struct {
+		op    string
+		right thunk
+	}
+	var links []link
+	for {
+		var op string
+		switch {
+		case p.peek("==", "!=", "<", "<=", ">", ">=", "in"):
+			op = p.next().text
+		case p.peek("not") && p.pos+1 < len(p.toks) && p.toks[p.pos+1].text == "in":
+			p.pos += 2
+			op = "not in"
+		}
+		if op == "" {
+			break
+		}
+		right, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link{op, right})
+	}
+	if len(links) == 0 {
+		return left, nil
+	}
+	return func() (any, error) {
+		l, err := left()
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range links {
+			r, err := k.right()
+			if err != nil {
+				return nil, err
+			}
+			ok, err := compare(k.op, l, r)
+			if err != nil || !ok {
+				return false, err
+			}
+			l = r
+		}
+		return true, nil
+	}, nil
+}
+
+func (p *exprParser) parseSum() (thunk, error) {
+	left, err := p.parseTerm()
+	for err == nil && p.peek("+", "-") {
+		op := p.next().text
+		var right thunk
+		if right, err = p.parseTerm(); err == nil {
+			left = binary(op, left, right)
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseTerm() (thunk, error) {
+	left, err := p.parseUnary()
+	for err == nil && p.peek("*", "/", "//", "%") {
+		op := p.next().text
+		var right thunk
+		if right, err = p.parseUnary(); err == nil {
+			left = binary(op, left, right)
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseUnary() (thunk, error) {
+	if !p.peek("-", "+") {
+		return p.parsePower()
+	}
+	op := p.next().text
+	operand, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return func() (any, error) {
+		v, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		switch n := number(v).(type) {
+		case int64:
+			if op == "-" {
+				return -n, nil
+			}
+			return n, nil
+		case float64:
+			if op == "-" {
+				return -n, nil
+			}
+			return n, nil
+		}
+		return nil, fmt.Errorf("bad operand type for unary %s: %s", op, typeName(v))
+	}, nil
+}
+
+func (p *exprParser) parsePower() (thunk, error) {
+	base, err := p.parsePrimary()
+	if err != nil || !p.peek("**") {
+		return base, err
+	}
+	p.pos++
+	// ** binds tighter than unary minus on its left but not on its right.
+	exp, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return binary("**", base, exp), nil
+}
+
+// parsePrimary parses an atom followed by any calls, indexes, and
+// attribute lookups.
+func (p *exprParser) parsePrimary() (thunk, error) {
+	v, err := p.parseAtom()
+	for err == nil {
+		switch {
+		case p.peek("("):
+			p.pos++
+			var args []thunk
+			for !p.peek(")") {
+				arg, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				args = append(args, arg)
+				if !p.peek(",") {
+					break
+				}
+				p.pos++
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			v = call(v, args)
+		case p.peek("["):
+			p.pos++
+			index, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			v = subscript(v, index)
+		case p.peek("."):
+			p.pos++
+			if p.pos >= len(p.toks) || p.toks[p.pos].kind != tokName {
+				return nil, fmt.Errorf("expected a name after %q", ".")
+			}
+			v = attribute(v, p.next().text)
+		default:
+			return v, nil
+		}
+	}
+	return nil, err
+}
+
+func (p *exprParser) parseAtom() (thunk, error) {
+	if p.pos >= len(p.toks) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	t := p.next()
+	switch t.kind {
+	case tokNumber, tokString:
+		return constant(t.value), nil
+	case tokName:
+		return p.parseName(t.text)
+	}
+	switch t.text {
+	case "(":
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		return e, p.expect(")")
+	case "[":
+		var items []thunk
+		for !p.peek("]") {
+			item, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			if !p.peek(",") {
+				break
+			}
+			p.pos++
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return func() (any, error) {
+			list := make([]any, len(items))
+			for i, item := range items {
+				v, err := item()
+				if err != nil {
+					return nil, err
+				}
+				list[i] = v
+			}
+			return list, nil
+		}, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
+
+// parseName resolves a name, which may be followed by dotted parts naming an
+// include namespace (ns.prop), a module function (math.sin), or attributes
+// of a loaded YAML value (config.kinematics.shoulder).
+func (p *exprParser) parseName(name string) (thunk, error) {
+	switch name {
+	case "True":
+		return constant(true), nil
+	case "False":
+		return constant(false), nil
+	case "None":
+		return constant(nil), nil
+	}
+
+	parts := []string{name}
+	for p.pos+1 < len(p.toks) && p.peek(".") && p.toks[p.pos+1].kind == tokName {
+		parts = append(parts, p.toks[p.pos+1].text)
+		p.pos += 2
+	}
+	// The longest dotted prefix that names something wins; the remaining
+	// parts are attribute lookups on it.
+	for n := len(parts); n > 0; n-- {
+		qualified := strings.Join(parts[:n], ".")
+		var v thunk
+		if prop := p.s.property(qualified); prop != nil {
+			if r := p.x.refs; r != nil && !prop.declared {
+				r.local = true
+				r.written = r.written || prop.written != ""
+			}
+			v = func() (any, error) { return p.x.propertyValue(qualified, prop) }
+		} else if b, ok := p.builtin(qualified); ok {
+			v = constant(b)
+		} else {
+			continue
+		}
+		for _, attr := range parts[n:] {
+			v = attribute(v, attr)
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("name %q is not defined", strings.Join(parts, "."))
+}
+
+func constant(v any) thunk {
+	return func() (any, error) { return v, nil }
+}
+
+// builtinFunc is a callable value.
+type builtinFunc struct {
+	name string
+	fn   func(args []any) (any, error)
+}
+
+// builtin returns the math constant or function, Python builtin, or xacro
+// function with the given name.
+func (p *exprParser) builtin(name string) (any, bool) {
+	name = strings.TrimPrefix(name, "math.")
+	switch name {
+	case "pi":
+		return math.Pi, true
+	case "e":
+		return math.E, true
+	case "tau":
+		return 2 * math.Pi, true
+	case "inf":
+		return math.Inf(1), true
+	case "nan":
+		return math.NaN(), true
+	}
+	if f, ok := mathFuncs[name]; ok {
+		return builtinFunc{name, func(args []any) (any, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("%s() takes 1 argument, got %d", name, len(args))
+			}
+			x, err := toFloat(args[0])
+			if err != nil {
+				return nil, fmt.Errorf("%s(): %w", name, err)
+			}
+			return f(x), nil
+		}}, true
+	}
+	if f, ok := mathFuncs2[name]; ok {
+		return builtinFunc{name, func(args []any) (any, error) {
+			if len(args) != 2 {
+				return nil, fmt.Errorf("%s() takes 2 arguments, got %d", name, len(args))
+			}
+			x, err := toFloat(args[0])
+			if err != nil {
+				return nil, fmt.Errorf("%s(): %w", name, err)
+			}
+			y, err := toFloat(args[1])
+			if err != nil {
+				return nil, fmt.Errorf("%s(): %w", name, err)
+			}
+			return f(x, y), nil
+		}}, true
+	}
+	if f, ok := pyBuiltins[name]; ok {
+		return builtinFunc{name, f}, true
+	}
+	if name == "load_yaml" || name == "xacro.load_yaml" {
+		return builtinFunc{"load_yaml", p.x.loadYAML}, true
+	}
+	return nil, false
+}
+
+var mathFuncs = map[string]func(float64) float64{
+	"sin": math.Sin, "cos": math.Cos, "tan": math.Tan,
+	"asin": math.Asin, "acos": math.Acos, "atan": math.Atan,
+	"sinh": math.Sinh, "cosh": math.Cosh, "tanh": math.Tanh,
+	"exp": math.Exp, "log10": math.Log10, "log2": math.Log2,
+	"sqrt": math.Sqrt, "fabs": math.Abs,
+	"radians": func(d float64) float64 { return d * math.Pi / 180 },
+	"degrees": func(r float64) float64 { return r * 180 / math.Pi },
+}
+
+var mathFuncs2 = map[string]func(float64, float64) float64{
+	"atan2": math.Atan2, "pow": math.Pow, "hypot": math.Hypot, "fmod": math.Mod,
+}
+
+var pyBuiltins = map[string]func(args []any) (any, error){
+	"abs": func(args []any) (any, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("abs() takes 1 argument")
+		}
+		switch n := number(args[0]).(type) {
+		case int64:
+			if n < 0 {
+				return -n, nil
+			}
+			return n, nil
+		case float64:
+			return math.Abs(n), nil
+		}
+		return nil, fmt.Errorf("bad operand type for abs(): %s", typeName(args[0]))
+	},
+	"min": func(args []any) (any, error) { return extreme("min", args, "<") },
+	"max": func(args []any) (any, error) { return extreme("max", args, ">") },
+	"round": func(args []any) (any, error) {
+		if len(args) == 0 || len(args) > 2 {
+			return nil, fmt.Errorf("round() takes 1 or 2 arguments")
+		}
+		x, err := toFloat(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("round(): %w", err)
+		}
+		if len(args) == 1 {
+			return int64(math.RoundToEven(x)), nil
+		}
+		digits, ok := number(args[1]).(int64)
+		if !ok {
+			return nil, fmt.Errorf("round(): ndigits must be an int")
+		}
+		scale := math.Pow(10, float64(digits))
+		return math.RoundToEven(x*scale) / scale, nil
+	},
+	"floor": func(args []any) (any, error) { return intFunc("floor", args, math.Floor) },
+	"ceil":  func(args []any) (any, error) { return intFunc("ceil", args, math.Ceil) },
+	"log": func(args []any) (any, error) {
+		if len(args) == 0 || len(args) > 2 {
+			return nil, fmt.Errorf("log() takes 1 or 2 arguments")
+		}
+		x, err := toFloat(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("log(): %w", err)
+		}
+		if len(args) == 1 {
+			return math.Log(x), nil
+		}
+		base, err := toFloat(args[1])
+		if err != nil {
+			return nil, fmt.Errorf("log(): %w", err)
+		}
+		return math.Log(x) / math.Log(base), nil
+	},
+	"int": func(args []any) (any, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("int() takes 1 argument")
+		}
+		switch n := literal(args[0]).(type) {
+		case int64:
+			return n, nil
+		case float64:
+			return int64(n), nil
+		case bool:
+			return number(n), nil
+		}
+		return nil, fmt.Errorf("invalid literal for int(): %q", format(args[0]))
+	},
+	"float": func(args []any) (any, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("float() takes 1 argument")
+		}
+		return toFloat(literal(args[0]))
+	},
+	"str": func(args []any) (any, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("str() takes 1 argument")
+		}
+		return format(args[0]), nil
+	},
+	"bool": func(args []any) (any, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("bool() takes 1 argument")
+		}
+		return pyTruth(args[0]), nil
+	},
+	"len": func(args []any) (any, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("len() takes 1 argument")
+		}
+		switch t := args[0].(type) {
+		case string:
+			return int64(len([]rune(t))), nil
+		case []any:
+			return int64(len(t)), nil
+		case map[string]any:
+			return int64(len(t)), nil
+		}
+		return nil, fmt.Errorf("object of type %s has no len()", typeName(args[0]))
+	},
+}
+
+func intFunc(name string, args []any, f func(float64) float64) (any, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("%s() takes 1 argument", name)
+	}
+	x, err := toFloat(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s(): %w", name, err)
+	}
+	return int64(f(x)), nil
+}
+
+// extreme implements min and max over their arguments or a single list.
+func extreme(name string, args []any, op string) (any, error) {
+	if len(args) == 1 {
+		if list, ok := args[0].([]any); ok {
+			args = list
+		}
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s() needs at least one value", name)
+	}
+	best := args[0]
+	for _, v := range args[1:] {
+		better, err := compare(op, v, best)
+		if err != nil {
+			return nil, fmt.Errorf("%s(): %w", name, err)
+		}
+		if better {
+			best = v
+		}
+	}
+	return best, nil
+}
+
+// loadYAML implements xacro.load_yaml, reading a YAML file relative to the
+// file being expanded.
+func (x *expander) loadYAML(args []any) (any, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("load_yaml() takes 1 argument")
+	}
+	path := format(args[0])
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(x.files[len(x.files)-1]), path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("load_yaml(): %w", err)
+	}
+	var v any
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("load_yaml(%s): %w", path, err)
+	}
+	if !slices.Contains(x.included, path) {
+		x.included = append(x.included, path)
+	}
+	return fromYAML(v), nil
+}
+
+// fromYAML converts decoded YAML to expression values.
+func fromYAML(v any) any {
+	switch t := v.(type) {
+	case int:
+		return int64(t)
+	case map[string]any:
+		for k, e := range t {
+			t[k] = fromYAML(e)
+		}
+	case []any:
+		for i, e := range t {
+			t[i] = fromYAML(e)
+		}
+	}
+	return v
+}
+
+func call(fn thunk, args []thunk) thunk {
+	return func() (any, error) {
+		f, err := fn()
+		if err != nil {
+			return nil, err
+		}
+		b, ok := f.(builtinFunc)
+		if !ok {
+			return nil, fmt.Errorf("%s is not callable", typeName(f))
+		}
+		values := make([]any, len(args))
+		for i, arg := range args {
+			if values[i], err = arg(); err != nil {
+				return nil, err
+			}
+		}
+		return b.fn(values)
+	}
+}
+
+func subscript(container, index thunk) thunk {
+	return func() (any, error) {
+		c, err := container()
+		if err != nil {
+			return nil, err
+		}
+		i, err := index()
+		if err != nil {
+			return nil, err
+		}
+		switch t := c.(type) {
+		case map[string]any:
+			v, ok := t[format(i)]
+			if !ok {
+				return nil, fmt.Errorf("key %q not found", format(i))
+			}
+			return v, nil
+		case []any:
+			n, ok := number(i).(int64)
+			if !ok {
+				return nil, fmt.Errorf("list indices must be integers, not %s", typeName(i))
+			}
+			if n < 0 {
+				n += int64(len(t))
+			}
+			if n < 0 || n >= int64(len(t)) {
+				return nil, fmt.Errorf("list index %d out of range", n)
+			}
+			return t[n], nil
+		case string:
+			runes := []rune(t)
+			n, ok := number(i).(int64)
+			if !ok {
+				return nil, fmt.Errorf("string indices must be integers, not %s", typeName(i))
+			}
+			if n < 0 {
+				n += int64(len(runes))
+			}
+			if n < 0 || n >= int64(len(runes)) {
+				return nil, fmt.Errorf("string index %d out of range", n)
+			}
+			return string(runes[n]), nil
+		}
+		return nil, fmt.Errorf("%s is not subscriptable", typeName(c))
+	}
+}
+
+// attribute looks up a key of a YAML mapping with dotted syntax.
+func attribute(v thunk, name string) thunk {
+	return func() (any, error) {
+		c, err := v()
+		if err != nil {
+			return nil, err
+		}
+		m, ok := c.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s has no attribute %q", typeName(c), name)
+		}
+		value, ok := m[name]
+		if !ok {
+			return nil, fmt.Errorf("no attribute %q", name)
+		}
+		return value, nil
+	}
+}
+
+// number returns v as an int64 or float64, treating booleans as 0 and 1 as
+// Python does, or v unchanged if it is not a number.
+func number(v any) any {
+	switch t := v.(type) {
+	case bool:
+		if t {
+			return int64(1)
+		}
+		return int64(0)
+	case int:
+		return int64(t)
+	}
+	return v
+}
+
+func toFloat(v any) (float64, error) {
+	switch n := number(v).(type) {
+	case int64:
+		return float64(n), nil
+	case float64:
+		return n, nil
+	}
+	return 0, fmt.Errorf("must be a number, not %s", typeName(v))
+}
+
+func binary(op string, left, right thunk) thunk {
+	return func() (any, error) {
+		l, err := left()
+		if err != nil {
+			return nil, err
+		}
+		r, err := right()
+		if err != nil {
+			return nil, err
+		}
+		return arithmetic(op, l, r)
+	}
+}
+
+func arithmetic(op string, l, r any) (any, error) {
+	if op == "+" {
+		if ls, ok := l.(string); ok {
+			if rs, ok := r.(string); ok {
+				return ls + rs, nil
+			}
+		}
+		if ll, ok := l.([]any); ok {
+			if rl, ok := r.([]any); ok {
+				return append(append([]any(nil), ll...), rl...), nil
+			}
+		}
+	}
+
+	li, lInt := number(l).(int64)
+	ri, rInt := number(r).(int64)
+	if lInt && rInt {
+		switch op {
+		case "+":
+			return li + ri, nil
+		case "-":
+			return li - ri, nil
+		case "*":
+			return li * ri, nil
+		case "//", "%":
+			if ri == 0 {
+				return nil, fmt.Errorf("integer division or modulo by zero")
+			}
+			q, m := li/ri, li%ri
+			// Python rounds the quotient down and gives the remainder the
+			// sign of the divisor.
+			if m != 0 && (m < 0) != (ri < 0) {
+				q, m = q-1, m+ri
+			}
+			if op == "//" {
+				return q, nil
+			}
+			return m, nil
+		case "**":
+			if ri >= 0 {
+				result := int64(1)
+				for range ri {
+					result *= li
+				}
+				return result, nil
+			}
+		}
+	}
+
+	lf, err := toFloat(l)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported operand types for %s: %s and %s", op, typeName(l), typeName(r))
+	}
+	rf, err := toFloat(r)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported operand types for %s: %s and %s", op, typeName(l), typeName(r))
+	}
+	switch op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return lf / rf, nil
+	case "//":
+		if rf == 0 {
+			return nil, fmt.Errorf("float floor division by zero")
+		}
+		return math.Floor(lf / rf), nil
+	case "%":
+		if rf == 0 {
+			return nil, fmt.Errorf("float modulo by zero")
+		}
+		m := math.Mod(lf, rf)
+		if m != 0 && (m < 0) != (rf < 0) {
+			m += rf
+		}
+		return m, nil
+	case "**":
+		return math.Pow(lf, rf), nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", op)
+}
+
+func compare(op string, l, r any) (bool, error) {
+	switch op {
+	case "in", "not in":
+		found, err := contains(r, l)
+		return found == (op == "in"), err
+	case "==", "!=":
+		return equal(l, r) == (op == "=="), nil
+	}
+
+	var c int
+	ls, lStr := l.(string)
+	rs, rStr := r.(string)
+	switch {
+	case lStr && rStr:
+		c = strings.Compare(ls, rs)
+	default:
+		lf, lerr := toFloat(l)
+		rf, rerr := toFloat(r)
+		if lerr != nil || rerr != nil {
+			return false, fmt.Errorf("%s not supported between %s and %s", op, typeName(l), typeName(r))
+		}
+		switch {
+		case lf < rf:
+			c = -1
+		case lf > rf:
+			c = 1
+		}
+	}
+	switch op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	}
+	return false, fmt.Errorf("unknown comparison %s", op)
+}
+
+func equal(l, r any) bool {
+	lf, lerr := toFloat(l)
+	rf, rerr := toFloat(r)
+	if lerr == nil && rerr == nil {
+		return lf == rf
+	}
+	return reflect.DeepEqual(l, r)
+}
+
+func contains(container, item any) (bool, error) {
+	switch t := container.(type) {
+	case string:
+		s, ok := item.(string)
+		if !ok {
+			return false, fmt.Errorf("'in <string>' requires a string, not %s", typeName(item))
+		}
+		return strings.Contains(t, s), nil
+	case []any:
+		for _, v := range t {
+			if equal(v, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]any:
+		_, ok := t[format(item)]
+		return ok, nil
+	}
+	return false, fmt.Errorf("argument of type %s is not iterable", typeName(container))
+}
+
+// pyTruth is Python's truthiness: false for zero, empty strings and
+// containers, False and None.
+func pyTruth(v any) bool {
+	switch t := number(v).(type) {
+	case nil:
+		return false
+	case int64:
+		return t != 0
+	case float64:
+		return t != 0
+	case string:
+		return t != ""
+	case []any:
+		return len(t) > 0
+	case map[string]any:
+		return len(t) > 0
+	}
+	return true
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "NoneType"
+	case bool:
+		return "bool"
+	case int, int64:
+		return "int"
+	case float64:
+		return "float"
+	case string:
+		return "str"
+	case []any:
+		return "list"
+	case map[string]any:
+		return "dict"
+	case builtinFunc:
+		return "function"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// formatFloat formats f the way Python's repr does: the shortest
+// representation that round-trips, always with a decimal point or exponent.
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	exp := 0
+	if f != 0 {
+		e := strconv.FormatFloat(f, 'e', -1, 64)
+		exp, _ = strconv.Atoi(e[strings.IndexByte(e, 'e')+1:])
+	}
+	if exp < -4 || exp >= 16 {
+		return strconv.FormatFloat(f, 'e', -1, 64)
+	}
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
@@ -0,0 +1,91 @@
+package xacro
+
+import (
+	"strings"
+	"testing"
+)
+
+// expandName expands a document whose only link is named by the expression
+// expr, with the property x set to 3, and returns the link's name.
+func expandName(t *testing.T, expr string) (string, error) {
+	t.Helper()
+	doc := `<robot xmlns:xacro="http://www.ros.org/wiki/xacro" name="r">` +
+		`<xacro:property name="x" value="3"/><xacro:property name="names" value="${['a', 'b']}"/>` +
+		`<link name="${` + expr + `}"/></robot>`
+	r, err := Expand([]byte(doc), "r.urdf.xacro", Options{})
+	if err != nil {
+		return "", err
+	}
+	out := string(r.Data)
+	_, name, ok := strings.Cut(out, `<link name="`)
+	if !ok {
+		t.Fatalf("%s: no link in %s", expr, out)
+	}
+	name, _, _ = strings.Cut(name, `"`)
+	return name, nil
+}
+
+func TestEvalExpr(t *testing.T) {
+	for _, tt := range []struct {
+		expr, want string
+	}{
+		{"1+2", "3"},
+		{"2**3", "8"},
+		{"-2**2", "-4"},
+		{"1/2", "0.5"},
+		{"7//2", "3"},
+		{"7%3", "1"},
+		{"(1+2)*3", "9"},
+		{"x*2", "6"},
+		{"x/2", "1.5"},
+		{"pi", "3.141592653589793"},
+		{"radians(180)", "3.141592653589793"},
+		{"degrees(pi/2)", "90.0"},
+		{"'a' + 'b'", "ab"},
+		{"'link_' + str(x)", "link_3"},
+		{"[1, 2][1]", "2"},
+		{"names[0]", "a"},
+		{"len(names)", "2"},
+		{"True and False", "False"},
+		{"not False or False", "True"},
+		{"1 &lt; x &lt;= 3", "True"},
+		{"1 if x > 1 else 2", "1"},
+		{"abs(-3)", "3"},
+		{"int(2.7)", "2"},
+		{"float(1)", "1.0"},
+		{"max(1, 5)", "5"},
+		{"min(4, 2)", "2"},
+		{"round(2.567, 1)", "2.6"},
+	} {
+		got, err := expandName(t, tt.expr)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestEvalExprErrors(t *testing.T) {
+	for _, tt := range []struct {
+		expr, want string
+	}{
+		{"y", "y"},
+		{"1 +", ""},
+		{"(1", ""},
+		{"names[5]", ""},
+		{"'a' - 1", ""},
+		{"nosuchfunc(1)", "nosuchfunc"},
+	} {
+		_, err := expandName(t, tt.expr)
+		if err == nil {
+			t.Errorf("%s: no error", tt.expr)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error %q does not mention %q", tt.expr, err, tt.want)
+		}
+	}
+}
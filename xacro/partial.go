@@ -0,0 +1,169 @@
+package xacro
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Restore turns simplified, a plain URDF document derived from r.Data, back
+// into xacro: the top-level declarations are put first and the xacro
+// namespace is declared again, and every attribute whose value is still the
+// one an expression evaluated to gets the expression back. Attributes the
+// derivation changed keep their new value, as do the names of links and
+// joints that are not written the same way everywhere they appear, so that
+// references agree whatever values the declarations are given. r must come
+// from an expansion with Options.Partial.
+func (r *Result) Restore(simplified []byte) ([]byte, error) {
+	if r.tree == nil {
+		return nil, fmt.Errorf("restore needs a partial expansion")
+	}
+	doc, err := parse(simplified)
+	if err != nil {
+		return nil, err
+	}
+	forms := make(map[string]map[string]bool)
+	collectNames(r.tree.root, forms)
+	mixed := make(map[string]bool)
+	for value, written := range forms {
+		mixed[value] = len(written) > 1
+	}
+	root := restoreElement(doc.root, r.tree.root, mixed)
+	// Declare the xacro namespace again, as the root of the input did.
+	for _, a := range r.tree.root.kept {
+		if a.Name.Space == "xmlns" && isNamespace(a.Value) {
+			root.attrs = append(root.attrs, a)
+		}
+	}
+
+	var declarations []node
+	for _, n := range r.tree.root.children {
+		if d, ok := n.(declaration); ok {
+			declarations = append(declarations, xml.CharData("\n  "), d.e)
+		}
+	}
+	root.children = append(declarations, root.children...)
+	doc.root = root
+
+	var buf bytes.Buffer
+	if err := doc.write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// nameAttrs are the attributes that name a link or joint or refer to one.
+var nameAttrs = map[string]bool{"name": true, "link": true, "joint": true}
+
+// collectNames records, for the value of each name attribute under e, the
+// forms it is written in.
+func collectNames(e *element, forms map[string]map[string]bool) {
+	for i, a := range e.attrs {
+		if a.Name.Space != "" || !nameAttrs[a.Name.Local] {
+			continue
+		}
+		written := a.Value
+		if e.kept != nil {
+			written = e.kept[i].Value
+		}
+		if forms[a.Value] == nil {
+			forms[a.Value] = make(map[string]bool)
+		}
+		forms[a.Value][written] = true
+	}
+	for _, c := range e.children {
+		if child, ok := c.(*element); ok {
+			collectNames(child, forms)
+		}
+	}
+}
+
+// restoreElement copies e, an element of a derived document, putting back
+// the written attributes of its counterpart in the expansion where the
+// values still agree, except for names that are written in mixed forms.
+// Children are paired by tag and name attribute, or by their position among
+// siblings with the same tag.
+func restoreElement(e, counterpart *element, mixed map[string]bool) *element {
+	out := &element{prefix: e.prefix, local: e.local}
+	for _, a := range e.attrs {
+		if a.Name.Space == "" && nameAttrs[a.Name.Local] && mixed[a.Value] {
+			out.attrs = append(out.attrs, a)
+			continue
+		}
+		if counterpart != nil {
+			if i := attrIndex(counterpart.attrs, a.Name); i >= 0 && counterpart.kept != nil && sameValue(counterpart.attrs[i].Value, a.Value) {
+				a.Value = counterpart.kept[i].Value
+			}
+		}
+		out.attrs = append(out.attrs, a)
+	}
+
+	seen := make(map[string]int)
+	for _, c := range e.children {
+		child, ok := c.(*element)
+		if !ok {
+			out.children = append(out.children, c)
+			continue
+		}
+		var match *element
+		if counterpart != nil {
+			name, named := child.attr("name")
+			k := seen[child.name()]
+			seen[child.name()]++
+			for _, cc := range counterpart.children {
+				candidate, ok := cc.(*element)
+				if !ok || candidate.name() != child.name() {
+					continue
+				}
+				if named {
+					if n, _ := candidate.attr("name"); n == name {
+						match = candidate
+						break
+					}
+					continue
+				}
+				if k == 0 {
+					match = candidate
+					break
+				}
+				k--
+			}
+		}
+		out.children = append(out.children, restoreElement(child, match, mixed))
+	}
+	return out
+}
+
+func attrIndex(attrs []xml.Attr, name xml.Name) int {
+	for i, a := range attrs {
+		if a.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// sameValue reports whether two attribute values are equal as text or as
+// lists of numbers, since a derived document may format numbers differently.
+func sameValue(a, b string) bool {
+	if a == b {
+		return true
+	}
+	fa, fb := strings.Fields(a), strings.Fields(b)
+	if len(fa) == 0 || len(fa) != len(fb) {
+		return false
+	}
+	for i := range fa {
+		x, err := strconv.ParseFloat(fa[i], 64)
+		if err != nil {
+			return false
+		}
+		y, err := strconv.ParseFloat(fb[i], 64)
+		if err != nil || x != y {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,126 @@
+// Package xacro expands xacro robot descriptions into plain URDF without a
+// ROS installation: properties, ${...} expressions, macros with block
+// parameters, includes, conditionals, and arguments.
+package xacro
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Namespace is the XML namespace of xacro elements. Older files use
+// http://ros.org/wiki/xacro, which is accepted too.
+const Namespace = "http://www.ros.org/wiki/xacro"
+
+func isNamespace(uri string) bool {
+	return uri == Namespace || uri == "http://ros.org/wiki/xacro"
+}
+
+// Options controls expansion.
+type Options struct {
+	// FindPackage returns the directory of a ROS package, for $(find pkg)
+	// and package:// include filenames. Nil finds no packages.
+	FindPackage func(name string) (string, bool)
+	// Args sets the values of arguments read with $(arg name), overriding
+	// the defaults their xacro:arg elements declare, as name:=value does on
+	// the xacro command line.
+	Args map[string]string
+	// Partial keeps the result parameterizable: top-level xacro:property
+	// and xacro:arg declarations, and attribute expressions that use only
+	// them, are recorded as written for Result.Restore. Includes, macros and
+	// conditionals are still expanded, and Data is unaffected.
+	Partial bool
+}
+
+// IncludeError reports the includes whose files could not be found. When
+// it is returned, expansion has gone as far as it could without them.
+type IncludeError struct {
+	// Missing describes each unresolved include: its filename as written,
+	// the file it appears in, and why it could not be read.
+	Missing []string
+}
+
+func (e *IncludeError) Error() string {
+	return fmt.Sprintf("%d unresolved include(s):\n  %s", len(e.Missing), strings.Join(e.Missing, "\n  "))
+}
+
+// Result is an expanded document.
+type Result struct {
+	// Data is the expanded XML.
+	Data []byte
+	// Includes lists the files read through xacro:include and
+	// xacro.load_yaml, in the order they were first read.
+	Includes []string
+
+	// tree is the expanded document, kept for Restore when Options.Partial
+	// is set.
+	tree *document
+}
+
+// ExpandFile expands the xacro file at path.
+func ExpandFile(path string, opts Options) (*Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Expand(data, path, opts)
+}
+
+// Expand expands a xacro document. filename is the file the document was read
+// from, against which relative includes are resolved. If any include cannot
+// be found the error is an *IncludeError.
+func Expand(data []byte, filename string, opts Options) (*Result, error) {
+	doc, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	global := newScope(nil)
+	x := &expander{
+		opts:     opts,
+		global:   global,
+		files:    []string{filename},
+		prefixes: map[string]bool{"xacro": true},
+		args:     make(map[string]string),
+	}
+	for name, value := range opts.Args {
+		x.args[name] = value
+	}
+	root, err := x.root(doc.root, global)
+	// A missing include usually causes further errors, such as calls of
+	// macros it would have defined, so it is reported instead of them.
+	if len(x.missing) > 0 {
+		return nil, &IncludeError{Missing: x.missing}
+	}
+	if err != nil {
+		return nil, err
+	}
+	doc.root = root
+
+	var buf bytes.Buffer
+	if err := doc.write(&buf); err != nil {
+		return nil, err
+	}
+	result := &Result{Data: buf.Bytes(), Includes: x.included}
+	if opts.Partial {
+		result.tree = doc
+	}
+	return result, nil
+}
+
+// IsXacro reports whether a document needs expanding: its file name ends in
+// .xacro, it declares the xacro namespace, or it uses xacro: elements
+// without declaring it, which a URDF parser would silently skip.
+func IsXacro(filename string, data []byte) bool {
+	if strings.EqualFold(filepath.Ext(filename), ".xacro") {
+		return true
+	}
+	for _, ns := range []string{Namespace, "http://ros.org/wiki/xacro"} {
+		if bytes.Contains(data, []byte(fmt.Sprintf("%q", ns))) || bytes.Contains(data, []byte("'"+ns+"'")) {
+			return true
+		}
+	}
+	return bytes.Contains(data, []byte("<xacro:"))
+}
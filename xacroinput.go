@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/nfranczak/urdf-simplifier/xacro"
+)
+
+// xacroMode says how xacro input is expanded.
+type xacroMode string
+
+const (
+	// xacroBuiltin expands xacro input with the xacro package. It is the default.
+	xacroBuiltin xacroMode = "builtin"
+	// xacroExternal runs the ROS xacro program found on PATH.
+	xacroExternal xacroMode = "external"
+	// xacroOff refuses xacro input, for pipelines that expect plain URDF.
+	xacroOff xacroMode = "off"
+)
+
+// xacroInput holds the settings for reading xacro input.
+type xacroInput struct {
+	mode xacroMode
+	// args sets the arguments of xacro input.
+	args map[string]string
+}
+
+// xacroFlags holds the xacro flags every command reading a robot accepts.
+type xacroFlags struct {
+	mode string
+	args []string
+}
+
+func (f *xacroFlags) register(fs *flagSet) {
+	fs.StringVar(&f.mode, "", "xacro", "", "how to expand xacro input: builtin, external (run the xacro program), or off (default builtin)")
+	fs.StringsVar(&f.args, "", "xacro-arg", "set an argument of xacro input, given as NAME:=VALUE")
+}
+
+// parse validates the flags.
+func (f *xacroFlags) parse() (xacroInput, error) {
+	mode, err := parseXacroMode(f.mode)
+	if err != nil {
+		return xacroInput{}, err
+	}
+	args, err := parseXacroArgs(f.args)
+	if err != nil {
+		return xacroInput{}, err
+	}
+	return xacroInput{mode: mode, args: args}, nil
+}
+
+// parseXacroMode validates a --xacro value; empty means the default.
+func parseXacroMode(s string) (xacroMode, error) {
+	switch mode := xacroMode(s); mode {
+	case "":
+		return xacroBuiltin, nil
+	case xacroBuiltin, xacroExternal, xacroOff:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid xacro mode %q (want builtin, external, or off)", s)
+}
+
+// parseXacroArgs parses the NAME:=VALUE pairs given with --xacro-arg.
+func parseXacroArgs(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	args := make(map[string]string)
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, ":=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --xacro-arg %q (want NAME:=VALUE)", pair)
+		}
+		args[name] = value
+	}
+	return args, nil
+}
+
+// expandXacro expands the xacro document read from path as in.mode says,
+// partially if partial is set. Errors carry exitParse.
+func expandXacro(path string, data []byte, packages map[string]string, in xacroInput, partial bool) (*xacro.Result, error) {
+	var result *xacro.Result
+	var err error
+	switch in.mode {
+	case xacroOff:
+		return nil, withExitCode(exitParse, fmt.Errorf("%s is a xacro document and xacro expansion is off; "+
+			"expand it first (xacro %s > robot.urdf), or drop --xacro off to expand it here", displayPath(path), path))
+	case xacroExternal:
+		if partial {
+			return nil, withExitCode(exitUsage, errors.New("--keep-xacro needs the built-in xacro expander, not --xacro external"))
+		}
+		result, err = runXacro(path, in.args)
+	default:
+		result, err = xacro.Expand(data, path, xacro.Options{
+			FindPackage: meshResolver(path, packages).PackageDir,
+			Args:        in.args,
+			Partial:     partial,
+		})
+		if err != nil && path != "-" {
+			if _, lookErr := exec.LookPath("xacro"); lookErr == nil {
+				err = fmt.Errorf("%w\n(the xacro program is installed; --xacro external expands with it instead)", err)
+			}
+		}
+	}
+	if err != nil {
+		return nil, withExitCode(exitParse, fmt.Errorf("expanding xacro %s: %w", displayPath(path), err))
+	}
+	return result, nil
+}
+
+// runXacro expands the file at path with the xacro program, which finds ROS
+// packages through the ROS environment rather than --package-map.
+func runXacro(path string, args map[string]string) (*xacro.Result, error) {
+	if path == "-" {
+		return nil, errors.New("--xacro external needs an input file, not stdin")
+	}
+	program, err := exec.LookPath("xacro")
+	if err != nil {
+		return nil, errors.New("--xacro external: no xacro program on PATH; source a ROS installation or use --xacro builtin")
+	}
+	cmdArgs := []string{path}
+	for _, name := range slices.Sorted(maps.Keys(args)) {
+		cmdArgs = append(cmdArgs, name+":="+args[name])
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(program, cmdArgs...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %w\n%s", program, err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", program, err)
+	}
+	return &xacro.Result{Data: stdout.Bytes()}, nil
+}